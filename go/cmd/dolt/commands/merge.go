@@ -384,6 +384,15 @@ func printConflicts(tblToStats map[string]*merge.MergeStats) bool {
 			cli.Println("Auto-merging", tblName)
 			cli.Println("CONFLICT (content): Merge conflict in", tblName)
 
+			if stats.PrimaryKeyInsertConflicts > 0 {
+				cli.Println("CONFLICT (add/add): Different rows added with the same primary key in", tblName)
+			}
+
+			hasConflicts = true
+		}
+
+		if stats.TableConflict == merge.DeleteModifyTable {
+			cli.Println("CONFLICT (delete/modify):", tblName, "deleted in one branch and modified in the other. Drop or keep the table and then commit the result.")
 			hasConflicts = true
 		}
 	}
@@ -0,0 +1,310 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: dolt/services/mergeapi/v1alpha1/merge_stats.proto
+
+package mergeapi
+
+import (
+	fmt "fmt"
+	math "math"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// TableMergeOp is the change a merge made to a table.
+type TableMergeOp int32
+
+const (
+	TableMergeOp_TABLE_UNMODIFIED TableMergeOp = 0
+	TableMergeOp_TABLE_ADDED      TableMergeOp = 1
+	TableMergeOp_TABLE_REMOVED    TableMergeOp = 2
+	TableMergeOp_TABLE_MODIFIED   TableMergeOp = 3
+)
+
+var TableMergeOp_name = map[int32]string{
+	0: "TABLE_UNMODIFIED",
+	1: "TABLE_ADDED",
+	2: "TABLE_REMOVED",
+	3: "TABLE_MODIFIED",
+}
+
+var TableMergeOp_value = map[string]int32{
+	"TABLE_UNMODIFIED": 0,
+	"TABLE_ADDED":      1,
+	"TABLE_REMOVED":    2,
+	"TABLE_MODIFIED":   3,
+}
+
+func (x TableMergeOp) String() string {
+	return proto.EnumName(TableMergeOp_name, int32(x))
+}
+
+func (TableMergeOp) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_95a06799594e87eb, []int{0}
+}
+
+// TableConflict is a conflict between the changes the two sides of a merge made to a table as a whole.
+type TableConflict int32
+
+const (
+	TableConflict_NO_TABLE_CONFLICT   TableConflict = 0
+	TableConflict_DELETE_MODIFY_TABLE TableConflict = 1
+)
+
+var TableConflict_name = map[int32]string{
+	0: "NO_TABLE_CONFLICT",
+	1: "DELETE_MODIFY_TABLE",
+}
+
+var TableConflict_value = map[string]int32{
+	"NO_TABLE_CONFLICT":   0,
+	"DELETE_MODIFY_TABLE": 1,
+}
+
+func (x TableConflict) String() string {
+	return proto.EnumName(TableConflict_name, int32(x))
+}
+
+func (TableConflict) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_95a06799594e87eb, []int{1}
+}
+
+// MergeStats are the results of merging a table.
+type MergeStats struct {
+	Operation                 TableMergeOp `protobuf:"varint,1,opt,name=operation,proto3,enum=dolt.services.mergeapi.v1alpha1.TableMergeOp" json:"operation,omitempty"`
+	Adds                      int64        `protobuf:"varint,2,opt,name=adds,proto3" json:"adds,omitempty"`
+	Deletes                   int64        `protobuf:"varint,3,opt,name=deletes,proto3" json:"deletes,omitempty"`
+	Modifications             int64        `protobuf:"varint,4,opt,name=modifications,proto3" json:"modifications,omitempty"`
+	Conflicts                 int64        `protobuf:"varint,5,opt,name=conflicts,proto3" json:"conflicts,omitempty"`
+	PrimaryKeyInsertConflicts int64        `protobuf:"varint,6,opt,name=primary_key_insert_conflicts,json=primaryKeyInsertConflicts,proto3" json:"primary_key_insert_conflicts,omitempty"`
+	RowCount                  uint64       `protobuf:"varint,7,opt,name=row_count,json=rowCount,proto3" json:"row_count,omitempty"`
+	MergeRowCount             uint64       `protobuf:"varint,8,opt,name=merge_row_count,json=mergeRowCount,proto3" json:"merge_row_count,omitempty"`
+	MergedRowCount            uint64       `protobuf:"varint,9,opt,name=merged_row_count,json=mergedRowCount,proto3" json:"merged_row_count,omitempty"`
+	Identical                 bool         `protobuf:"varint,10,opt,name=identical,proto3" json:"identical,omitempty"`
+	// The message of the error encountered merging the table, if there was one.
+	Err                  string        `protobuf:"bytes,11,opt,name=err,proto3" json:"err,omitempty"`
+	TableRenamed         bool          `protobuf:"varint,12,opt,name=table_renamed,json=tableRenamed,proto3" json:"table_renamed,omitempty"`
+	TableConflict        TableConflict `protobuf:"varint,13,opt,name=table_conflict,json=tableConflict,proto3,enum=dolt.services.mergeapi.v1alpha1.TableConflict" json:"table_conflict,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *MergeStats) Reset()         { *m = MergeStats{} }
+func (m *MergeStats) String() string { return proto.CompactTextString(m) }
+func (*MergeStats) ProtoMessage()    {}
+func (*MergeStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_95a06799594e87eb, []int{0}
+}
+
+func (m *MergeStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MergeStats.Unmarshal(m, b)
+}
+func (m *MergeStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MergeStats.Marshal(b, m, deterministic)
+}
+func (m *MergeStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MergeStats.Merge(m, src)
+}
+func (m *MergeStats) XXX_Size() int {
+	return xxx_messageInfo_MergeStats.Size(m)
+}
+func (m *MergeStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_MergeStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MergeStats proto.InternalMessageInfo
+
+func (m *MergeStats) GetOperation() TableMergeOp {
+	if m != nil {
+		return m.Operation
+	}
+	return TableMergeOp_TABLE_UNMODIFIED
+}
+
+func (m *MergeStats) GetAdds() int64 {
+	if m != nil {
+		return m.Adds
+	}
+	return 0
+}
+
+func (m *MergeStats) GetDeletes() int64 {
+	if m != nil {
+		return m.Deletes
+	}
+	return 0
+}
+
+func (m *MergeStats) GetModifications() int64 {
+	if m != nil {
+		return m.Modifications
+	}
+	return 0
+}
+
+func (m *MergeStats) GetConflicts() int64 {
+	if m != nil {
+		return m.Conflicts
+	}
+	return 0
+}
+
+func (m *MergeStats) GetPrimaryKeyInsertConflicts() int64 {
+	if m != nil {
+		return m.PrimaryKeyInsertConflicts
+	}
+	return 0
+}
+
+func (m *MergeStats) GetRowCount() uint64 {
+	if m != nil {
+		return m.RowCount
+	}
+	return 0
+}
+
+func (m *MergeStats) GetMergeRowCount() uint64 {
+	if m != nil {
+		return m.MergeRowCount
+	}
+	return 0
+}
+
+func (m *MergeStats) GetMergedRowCount() uint64 {
+	if m != nil {
+		return m.MergedRowCount
+	}
+	return 0
+}
+
+func (m *MergeStats) GetIdentical() bool {
+	if m != nil {
+		return m.Identical
+	}
+	return false
+}
+
+func (m *MergeStats) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+func (m *MergeStats) GetTableRenamed() bool {
+	if m != nil {
+		return m.TableRenamed
+	}
+	return false
+}
+
+func (m *MergeStats) GetTableConflict() TableConflict {
+	if m != nil {
+		return m.TableConflict
+	}
+	return TableConflict_NO_TABLE_CONFLICT
+}
+
+// MergeStatsMap are the results of merging each table of a merge.
+type MergeStatsMap struct {
+	// The results of each table, keyed by the table's name.
+	Tables               map[string]*MergeStats `protobuf:"bytes,1,rep,name=tables,proto3" json:"tables,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *MergeStatsMap) Reset()         { *m = MergeStatsMap{} }
+func (m *MergeStatsMap) String() string { return proto.CompactTextString(m) }
+func (*MergeStatsMap) ProtoMessage()    {}
+func (*MergeStatsMap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_95a06799594e87eb, []int{1}
+}
+
+func (m *MergeStatsMap) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MergeStatsMap.Unmarshal(m, b)
+}
+func (m *MergeStatsMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MergeStatsMap.Marshal(b, m, deterministic)
+}
+func (m *MergeStatsMap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MergeStatsMap.Merge(m, src)
+}
+func (m *MergeStatsMap) XXX_Size() int {
+	return xxx_messageInfo_MergeStatsMap.Size(m)
+}
+func (m *MergeStatsMap) XXX_DiscardUnknown() {
+	xxx_messageInfo_MergeStatsMap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MergeStatsMap proto.InternalMessageInfo
+
+func (m *MergeStatsMap) GetTables() map[string]*MergeStats {
+	if m != nil {
+		return m.Tables
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("dolt.services.mergeapi.v1alpha1.TableMergeOp", TableMergeOp_name, TableMergeOp_value)
+	proto.RegisterEnum("dolt.services.mergeapi.v1alpha1.TableConflict", TableConflict_name, TableConflict_value)
+	proto.RegisterType((*MergeStats)(nil), "dolt.services.mergeapi.v1alpha1.MergeStats")
+	proto.RegisterType((*MergeStatsMap)(nil), "dolt.services.mergeapi.v1alpha1.MergeStatsMap")
+	proto.RegisterMapType((map[string]*MergeStats)(nil), "dolt.services.mergeapi.v1alpha1.MergeStatsMap.TablesEntry")
+}
+
+func init() {
+	proto.RegisterFile("dolt/services/mergeapi/v1alpha1/merge_stats.proto", fileDescriptor_95a06799594e87eb)
+}
+
+var fileDescriptor_95a06799594e87eb = []byte{
+	// 572 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x94, 0x5f, 0x4f, 0xdb, 0x3c,
+	0x14, 0x87, 0x09, 0x2d, 0x7f, 0x72, 0x4a, 0x4a, 0xf0, 0xfb, 0x4e, 0xf3, 0x36, 0xa4, 0x55, 0x6c,
+	0x9a, 0x22, 0x26, 0x12, 0xc1, 0x6e, 0x26, 0x76, 0x81, 0xa0, 0x09, 0x52, 0x05, 0xa5, 0x92, 0x29,
+	0x93, 0x36, 0x4d, 0x8a, 0x4c, 0x62, 0x8a, 0x45, 0x1a, 0x67, 0x8e, 0x0b, 0xea, 0x27, 0xda, 0x87,
+	0xd9, 0x97, 0x9a, 0x62, 0x13, 0x5a, 0xae, 0xba, 0xdd, 0xd9, 0x4f, 0x7f, 0xcf, 0xb1, 0x7a, 0x7c,
+	0x62, 0xd8, 0x4f, 0x45, 0xa6, 0x82, 0x92, 0xc9, 0x7b, 0x9e, 0xb0, 0x32, 0x18, 0x33, 0x39, 0x62,
+	0xb4, 0xe0, 0xc1, 0xfd, 0x3e, 0xcd, 0x8a, 0x5b, 0xba, 0x6f, 0x48, 0x5c, 0x2a, 0xaa, 0x4a, 0xbf,
+	0x90, 0x42, 0x09, 0xf4, 0xb6, 0x52, 0xfc, 0x5a, 0xf1, 0x6b, 0xc5, 0xaf, 0x95, 0x9d, 0x5f, 0x4d,
+	0x80, 0x7e, 0x45, 0x2f, 0x2b, 0x0b, 0x9d, 0x81, 0x2d, 0x0a, 0x26, 0xa9, 0xe2, 0x22, 0xc7, 0x56,
+	0xc7, 0xf2, 0xda, 0x07, 0x7b, 0xfe, 0x82, 0x1a, 0xfe, 0x90, 0x5e, 0x67, 0x4c, 0x17, 0x19, 0x14,
+	0x64, 0xe6, 0x23, 0x04, 0x4d, 0x9a, 0xa6, 0x25, 0x5e, 0xee, 0x58, 0x5e, 0x83, 0xe8, 0x35, 0xc2,
+	0xb0, 0x96, 0xb2, 0x8c, 0x29, 0x56, 0xe2, 0x86, 0xc6, 0xf5, 0x16, 0xbd, 0x07, 0x67, 0x2c, 0x52,
+	0x7e, 0xc3, 0x13, 0x6d, 0x97, 0xb8, 0xa9, 0x7f, 0x7f, 0x0e, 0xd1, 0x36, 0xd8, 0x89, 0xc8, 0x6f,
+	0x32, 0x9e, 0xa8, 0x12, 0xaf, 0xe8, 0xc4, 0x0c, 0xa0, 0x23, 0xd8, 0x2e, 0x24, 0x1f, 0x53, 0x39,
+	0x8d, 0xef, 0xd8, 0x34, 0xe6, 0x79, 0xc9, 0xa4, 0x8a, 0x67, 0xc2, 0xaa, 0x16, 0x5e, 0x3d, 0x66,
+	0xce, 0xd8, 0xb4, 0xa7, 0x13, 0xdd, 0xa7, 0x02, 0x6f, 0xc0, 0x96, 0xe2, 0x21, 0x4e, 0xc4, 0x24,
+	0x57, 0x78, 0xad, 0x63, 0x79, 0x4d, 0xb2, 0x2e, 0xc5, 0x43, 0xb7, 0xda, 0xa3, 0x0f, 0xb0, 0x69,
+	0x3a, 0x3c, 0x8b, 0xac, 0xeb, 0x88, 0xa3, 0x31, 0xa9, 0x73, 0x1e, 0xb8, 0x1a, 0xa4, 0x73, 0x41,
+	0x5b, 0x07, 0xdb, 0x86, 0x3f, 0x25, 0xb7, 0xc1, 0xe6, 0x29, 0xcb, 0x15, 0x4f, 0x68, 0x86, 0xa1,
+	0x63, 0x79, 0xeb, 0x64, 0x06, 0x90, 0x0b, 0x0d, 0x26, 0x25, 0x6e, 0x75, 0x2c, 0xcf, 0x26, 0xd5,
+	0x12, 0xbd, 0x03, 0x47, 0x55, 0xcd, 0x8e, 0x25, 0xcb, 0xe9, 0x98, 0xa5, 0x78, 0x43, 0x3b, 0x1b,
+	0x1a, 0x12, 0xc3, 0xd0, 0x15, 0xb4, 0x4d, 0xa8, 0xfe, 0xdf, 0xd8, 0xd1, 0x17, 0xe9, 0xff, 0xdd,
+	0x45, 0xd6, 0xcd, 0x20, 0x8e, 0x9a, 0xdf, 0xee, 0xfc, 0xb6, 0xc0, 0x99, 0x4d, 0x4a, 0x9f, 0x16,
+	0x88, 0xc0, 0xaa, 0x8e, 0x94, 0xd8, 0xea, 0x34, 0xbc, 0xd6, 0xc1, 0xe1, 0xc2, 0x03, 0x9e, 0xf9,
+	0xe6, 0xb8, 0x32, 0xca, 0x95, 0x9c, 0x92, 0xc7, 0x4a, 0xaf, 0x6f, 0xa0, 0x35, 0x87, 0xab, 0x16,
+	0xdc, 0xb1, 0xa9, 0x9e, 0x44, 0x9b, 0x54, 0x4b, 0x74, 0x0c, 0x2b, 0xf7, 0x34, 0x9b, 0x30, 0x3d,
+	0x55, 0xad, 0x83, 0x8f, 0xff, 0x70, 0x26, 0x31, 0xe6, 0xe1, 0xf2, 0x67, 0x6b, 0xf7, 0x07, 0x6c,
+	0xcc, 0x8f, 0x2d, 0xfa, 0x1f, 0xdc, 0xe1, 0xf1, 0xc9, 0x79, 0x14, 0x5f, 0x5d, 0xf4, 0x07, 0x61,
+	0xef, 0xb4, 0x17, 0x85, 0xee, 0x12, 0xda, 0x84, 0x96, 0xa1, 0xc7, 0x61, 0x18, 0x85, 0xae, 0x85,
+	0xb6, 0xc0, 0x31, 0x80, 0x44, 0xfd, 0xc1, 0xd7, 0x28, 0x74, 0x97, 0x11, 0x82, 0xb6, 0x41, 0x4f,
+	0x5e, 0x63, 0xf7, 0x08, 0x9c, 0x67, 0xbd, 0x44, 0x2f, 0x60, 0xeb, 0x62, 0x10, 0x9b, 0x5c, 0x77,
+	0x70, 0x71, 0x7a, 0xde, 0xeb, 0x0e, 0xdd, 0x25, 0xf4, 0x12, 0xfe, 0x0b, 0xa3, 0xf3, 0x68, 0xf8,
+	0x28, 0x7f, 0x33, 0x09, 0xd7, 0x3a, 0xb9, 0xfa, 0x7e, 0x39, 0xe2, 0xea, 0x76, 0x72, 0xed, 0x27,
+	0x62, 0x1c, 0x64, 0xfc, 0xe7, 0x84, 0xa7, 0x54, 0xd1, 0x3d, 0x9e, 0x27, 0x81, 0x7e, 0x05, 0x46,
+	0x22, 0x18, 0xb1, 0x3c, 0xd0, 0x5f, 0x79, 0xb0, 0xe0, 0x5d, 0xf8, 0x52, 0x93, 0xeb, 0x55, 0x9d,
+	0xff, 0xf4, 0x67, 0x00, 0x37, 0xdf, 0x1b, 0x07, 0x4a, 0x04, 0x00, 0x00,
+}
@@ -117,6 +117,24 @@ func NewTableDiffs(ctx context.Context, newer, older *doltdb.RootValue) (*TableD
 	return &TableDiffs{len(added), len(modified), len(removed), tblToType, tbls}, err
 }
 
+// ChangedTables returns the tables whose content differs between the roots of |from| and |to|, classified as added,
+// modified or removed in |to|. Tables are compared by hash, so no rows are read.
+func ChangedTables(ctx context.Context, from, to *doltdb.Commit) (*TableDiffs, error) {
+	fromRoot, err := from.GetRootValue()
+
+	if err != nil {
+		return nil, err
+	}
+
+	toRoot, err := to.GetRootValue()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTableDiffs(ctx, toRoot, fromRoot)
+}
+
 func (td *TableDiffs) Len() int {
 	return len(td.Tables)
 }
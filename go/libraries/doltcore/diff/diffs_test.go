@@ -0,0 +1,101 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestChangedTables(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "Bill Billerson", "bill@billerson.com"))
+
+	cs, _ := doltdb.NewCommitSpec("head", "master")
+	initCm, err := ddb.Resolve(ctx, cs)
+	require.NoError(t, err)
+	root, err := initCm.GetRootValue()
+	require.NoError(t, err)
+
+	// each table's id column needs its own tag
+	tags := map[string]uint64{"added": 0, "modified": 1, "removed": 2, "unchanged": 3}
+	putTable := func(root *doltdb.RootValue, tblName string, ids ...int) *doltdb.RootValue {
+		tag := tags[tblName]
+		colColl, err := schema.NewColCollection(schema.NewColumn("id", tag, types.IntKind, true, schema.NotNullConstraint{}))
+		require.NoError(t, err)
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(colColl))
+		require.NoError(t, err)
+
+		var kvs []types.Value
+		for _, id := range ids {
+			k, err := types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(id))
+			require.NoError(t, err)
+			v, err := types.NewTuple(vrw.Format())
+			require.NoError(t, err)
+			kvs = append(kvs, k, v)
+		}
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err = root.PutTable(ctx, tblName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	commit := func(root *doltdb.RootValue, parent *doltdb.Commit) *doltdb.Commit {
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta("Bill Billerson", "bill@billerson.com", "change tables")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, []*doltdb.Commit{parent}, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	root = putTable(root, "modified", 1)
+	root = putTable(root, "removed", 1)
+	root = putTable(root, "unchanged", 1)
+	from := commit(root, initCm)
+
+	root = putTable(root, "modified", 1, 2)
+	root = putTable(root, "added")
+	root, err = root.RemoveTables(ctx, "removed")
+	require.NoError(t, err)
+	to := commit(root, from)
+
+	tblDiffs, err := ChangedTables(ctx, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"added", "modified", "removed"}, tblDiffs.Tables)
+	assert.Equal(t, map[string]TableDiffType{"added": AddedTable, "modified": ModifiedTable, "removed": RemovedTable}, tblDiffs.TableToType)
+	assert.Equal(t, 1, tblDiffs.NumAdded)
+	assert.Equal(t, 1, tblDiffs.NumModified)
+	assert.Equal(t, 1, tblDiffs.NumRemoved)
+
+	tblDiffs, err = ChangedTables(ctx, to, to)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tblDiffs.Len())
+}
@@ -17,6 +17,7 @@ package doltdb
 import (
 	"context"
 	"errors"
+	"sort"
 
 	"github.com/liquidata-inc/dolt/go/store/datas"
 	"github.com/liquidata-inc/dolt/go/store/hash"
@@ -227,6 +228,112 @@ func getCommitAncestorRef(ctx context.Context, ref1, ref2 types.Ref, vrw types.V
 	return ancestorRef, nil
 }
 
+const (
+	reachableFromLeft uint8 = 1 << iota
+	reachableFromRight
+	belowMergeBase
+)
+
+// GetMergeBases returns the lowest common ancestors of |cm1| and |cm2|: the common ancestors which are not themselves
+// ancestors of another common ancestor. Most histories have a single merge base, but a criss-cross history, where
+// each branch has merged the other, has several.
+//
+// Commits are visited in order of decreasing height, each carrying flags for which of |cm1| and |cm2| it is reachable
+// from. A commit reachable from both that isn't below an earlier merge base is a merge base, and everything below it
+// is marked as such. Since a commit is taller than all its ancestors, every descendant of a commit has been visited
+// by the time it is reached, so its flags are final. The walk stops once every queued commit is below a merge base.
+// The merge bases are returned in order of decreasing height. If there are no common ancestors
+// ErrNoCommonAncestor is returned.
+func GetMergeBases(ctx context.Context, cm1, cm2 *Commit) ([]*Commit, error) {
+	ref1, err := types.NewRef(cm1.commitSt, cm1.vrw.Format())
+
+	if err != nil {
+		return nil, err
+	}
+
+	ref2, err := types.NewRef(cm2.commitSt, cm2.vrw.Format())
+
+	if err != nil {
+		return nil, err
+	}
+
+	flags := map[hash.Hash]uint8{ref1.TargetHash(): reachableFromLeft}
+	q := &types.RefByHeight{ref1}
+
+	if _, ok := flags[ref2.TargetHash()]; !ok {
+		q.PushBack(ref2)
+	}
+
+	flags[ref2.TargetHash()] |= reachableFromRight
+	sort.Sort(q)
+
+	var bases []*Commit
+	for !q.Empty() && !allBelowMergeBase(*q, flags) {
+		refs := q.PopRefsOfHeight(q.MaxHeight())
+
+		// visit commits of the same height in a stable order so that the merge bases are deterministic
+		sort.Slice(refs, func(i, j int) bool {
+			return refs[i].TargetHash().Less(refs[j].TargetHash())
+		})
+
+		for _, r := range refs {
+			targetVal, err := r.TargetValue(ctx, cm1.vrw)
+
+			if err != nil {
+				return nil, err
+			}
+
+			cm := &Commit{cm1.vrw, targetVal.(types.Struct)}
+			f := flags[r.TargetHash()]
+
+			if f&belowMergeBase == 0 && f&reachableFromLeft != 0 && f&reachableFromRight != 0 {
+				bases = append(bases, cm)
+				f |= belowMergeBase
+			}
+
+			parents, err := cm.getParents()
+
+			if err != nil {
+				return nil, err
+			}
+
+			err = parents.IterAll(ctx, func(parentVal types.Value) error {
+				parentRef := parentVal.(types.Ref)
+				parentFlags, seen := flags[parentRef.TargetHash()]
+				flags[parentRef.TargetHash()] = parentFlags | f
+
+				if !seen {
+					q.PushBack(parentRef)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sort.Sort(q)
+	}
+
+	if len(bases) == 0 {
+		return nil, ErrNoCommonAncestor
+	}
+
+	return bases, nil
+}
+
+func allBelowMergeBase(refs types.RefByHeight, flags map[hash.Hash]uint8) bool {
+	for _, r := range refs {
+		if flags[r.TargetHash()]&belowMergeBase == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (c *Commit) CanFastForwardTo(ctx context.Context, new *Commit) (bool, error) {
 	ancestor, err := GetCommitAncestor(ctx, c, new)
 
@@ -0,0 +1,184 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"container/heap"
+	"context"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// WalkOrder is the order in which WalkCommitsInOrder visits commits.
+type WalkOrder int
+
+const (
+	// BreadthFirstWalkOrder visits the start commit, then its parents, then their parents and so on.
+	BreadthFirstWalkOrder WalkOrder = iota
+
+	// TopologicalWalkOrder visits every commit before any of its ancestors. The tallest pending commit is visited
+	// first.
+	TopologicalWalkOrder
+
+	// TimestampWalkOrder visits the pending commit with the latest user timestamp first.
+	TimestampWalkOrder
+)
+
+type walkItem struct {
+	commit    *Commit
+	seq       int
+	height    uint64
+	timestamp int64
+}
+
+type walkQueue struct {
+	items []*walkItem
+	order WalkOrder
+}
+
+func (wq *walkQueue) Len() int {
+	return len(wq.items)
+}
+
+func (wq *walkQueue) Less(i, j int) bool {
+	a, b := wq.items[i], wq.items[j]
+
+	switch wq.order {
+	case TopologicalWalkOrder:
+		if a.height != b.height {
+			return a.height > b.height
+		}
+	case TimestampWalkOrder:
+		if a.timestamp != b.timestamp {
+			return a.timestamp > b.timestamp
+		}
+	}
+
+	return a.seq < b.seq
+}
+
+func (wq *walkQueue) Swap(i, j int) {
+	wq.items[i], wq.items[j] = wq.items[j], wq.items[i]
+}
+
+func (wq *walkQueue) Push(x interface{}) {
+	wq.items = append(wq.items, x.(*walkItem))
+}
+
+func (wq *walkQueue) Pop() interface{} {
+	item := wq.items[len(wq.items)-1]
+	wq.items = wq.items[:len(wq.items)-1]
+	return item
+}
+
+// WalkCommits visits |start| and each of its ancestors once, breadth first, until |visit| returns false.
+func WalkCommits(ctx context.Context, start *Commit, visit func(*Commit) (bool, error)) error {
+	return WalkCommitsInOrder(ctx, start, BreadthFirstWalkOrder, visit)
+}
+
+// WalkCommitsInOrder is like WalkCommits, but visits commits in the given order. A commit reachable from |start| by
+// multiple paths is visited once.
+func WalkCommitsInOrder(ctx context.Context, start *Commit, order WalkOrder, visit func(*Commit) (bool, error)) error {
+	wq := &walkQueue{order: order}
+	seen := make(hash.HashSet)
+
+	push := func(cm *Commit) error {
+		item := &walkItem{commit: cm, seq: len(seen)}
+
+		var err error
+		item.height, err = cm.Height()
+
+		if err != nil {
+			return err
+		}
+
+		if order == TimestampWalkOrder {
+			meta, err := cm.GetCommitMeta()
+
+			if err != nil {
+				return err
+			}
+
+			item.timestamp = meta.UserTimestamp
+		}
+
+		heap.Push(wq, item)
+		return nil
+	}
+
+	h, err := start.HashOf()
+
+	if err != nil {
+		return err
+	}
+
+	seen.Insert(h)
+	err = push(start)
+
+	if err != nil {
+		return err
+	}
+
+	for wq.Len() > 0 {
+		cm := heap.Pop(wq).(*walkItem).commit
+		ok, err := visit(cm)
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+
+		parents, err := cm.getParents()
+
+		if err != nil {
+			return err
+		}
+
+		var parentRefs []types.Ref
+		err = parents.IterAll(ctx, func(parentVal types.Value) error {
+			parentRef := parentVal.(types.Ref)
+			if !seen.Has(parentRef.TargetHash()) {
+				seen.Insert(parentRef.TargetHash())
+				parentRefs = append(parentRefs, parentRef)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, parentRef := range parentRefs {
+			parentVal, err := parentRef.TargetValue(ctx, cm.vrw)
+
+			if err != nil {
+				return err
+			}
+
+			err = push(NewCommit(cm.vrw, parentVal.(types.Struct)))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
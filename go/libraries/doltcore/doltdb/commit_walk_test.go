@@ -0,0 +1,114 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestWalkCommits(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_7_18, InMemDoltDB)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "Bill Billerson", "bill@billerson.com"))
+
+	cs, _ := NewCommitSpec("head", "master")
+	initCm, err := ddb.Resolve(ctx, cs)
+	require.NoError(t, err)
+	root, err := initCm.GetRootValue()
+	require.NoError(t, err)
+	rootHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+
+	start := time.Now().Add(time.Hour)
+	commit := func(desc string, minutes int, parents ...*Commit) *Commit {
+		meta, err := NewCommitMetaWithUserTS("Bill Billerson", "bill@billerson.com", desc, start.Add(time.Duration(minutes)*time.Minute))
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, rootHash, parents, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	// init--a--b-------m
+	//        \        /
+	//         c-----c2
+	a := commit("a", 1, initCm)
+	b := commit("b", 5, a)
+	c := commit("c", 2, a)
+	c2 := commit("c2", 3, c)
+	m := commit("m", 6, b, c2)
+
+	hashes := func(cms ...*Commit) []hash.Hash {
+		var hs []hash.Hash
+		for _, cm := range cms {
+			h, err := cm.HashOf()
+			require.NoError(t, err)
+			hs = append(hs, h)
+		}
+		return hs
+	}
+
+	walk := func(order WalkOrder, limit int) []hash.Hash {
+		var visited []*Commit
+		err := WalkCommitsInOrder(ctx, m, order, func(cm *Commit) (bool, error) {
+			visited = append(visited, cm)
+			return len(visited) != limit, nil
+		})
+		require.NoError(t, err)
+		return hashes(visited...)
+	}
+
+	t.Run("breadth first", func(t *testing.T) {
+		visited := walk(BreadthFirstWalkOrder, -1)
+		require.Len(t, visited, 6)
+		assert.Equal(t, hashes(m), visited[:1])
+		assert.ElementsMatch(t, hashes(b, c2), visited[1:3])
+		assert.ElementsMatch(t, hashes(a, c, initCm), visited[3:])
+
+		var walked []*Commit
+		err := WalkCommits(ctx, m, func(cm *Commit) (bool, error) {
+			walked = append(walked, cm)
+			return true, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, visited, hashes(walked...))
+	})
+
+	t.Run("topological", func(t *testing.T) {
+		visited := walk(TopologicalWalkOrder, -1)
+		require.Len(t, visited, 6)
+		assert.Equal(t, hashes(m, c2), visited[:2])
+		assert.ElementsMatch(t, hashes(b, c), visited[2:4])
+		assert.Equal(t, hashes(a, initCm), visited[4:])
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		visited := walk(TimestampWalkOrder, -1)
+		assert.Equal(t, hashes(m, b, c2, c, a, initCm), visited)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		visited := walk(TimestampWalkOrder, 3)
+		assert.Equal(t, hashes(m, b, c2), visited)
+	})
+}
@@ -23,6 +23,7 @@ var ErrInvalidAncestorSpec = errors.New("invalid ancestor spec")
 var ErrInvalidBranchOrHash = errors.New("string is not a valid branch or hash")
 
 var ErrFoundHashNotACommit = errors.New("the value retrieved for this hash is not a commit")
+var ErrFoundHashNotARootValue = errors.New("the value retrieved for this hash is not a root value")
 
 var ErrHashNotFound = errors.New("could not find a value for this hash")
 var ErrBranchNotFound = errors.New("branch not found")
@@ -0,0 +1,38 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import "github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+
+func init() {
+	schema.RegisterReservedTag(DocNameTag, "DocNameTag", "doc_name column of the dolt_docs table")
+	schema.RegisterReservedTag(DocTextTag, "DocTextTag", "doc_text column of the dolt_docs table")
+
+	schema.RegisterReservedTag(HistoryCommitterTag, "HistoryCommitterTag", "committer column of dolt_history_ tables")
+	schema.RegisterReservedTag(HistoryCommitHashTag, "HistoryCommitHashTag", "commit_hash column of dolt_history_ tables")
+	schema.RegisterReservedTag(HistoryCommitDateTag, "HistoryCommitDateTag", "commit_date column of dolt_history_ tables")
+
+	schema.RegisterReservedTag(DiffCommitTag, "DiffCommitTag", "commit column of dolt_diff_ tables")
+
+	schema.RegisterReservedTag(QueryCatalogIdTag, "QueryCatalogIdTag", "id column of the dolt_query_catalog table")
+	schema.RegisterReservedTag(QueryCatalogOrderTag, "QueryCatalogOrderTag", "display_order column of the dolt_query_catalog table")
+	schema.RegisterReservedTag(QueryCatalogNameTag, "QueryCatalogNameTag", "name column of the dolt_query_catalog table")
+	schema.RegisterReservedTag(QueryCatalogQueryTag, "QueryCatalogQueryTag", "query column of the dolt_query_catalog table")
+	schema.RegisterReservedTag(QueryCatalogDescriptionTag, "QueryCatalogDescriptionTag", "description column of the dolt_query_catalog table")
+
+	schema.RegisterReservedTag(DoltSchemasTypeTag, "DoltSchemasTypeTag", "type column of the dolt_schemas table")
+	schema.RegisterReservedTag(DoltSchemasNameTag, "DoltSchemasNameTag", "name column of the dolt_schemas table")
+	schema.RegisterReservedTag(DoltSchemasFragmentTag, "DoltSchemasFragmentTag", "fragment column of the dolt_schemas table")
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+)
+
+func TestReservedTags(t *testing.T) {
+	infos := schema.ListReservedTags()
+	assert.NotEmpty(t, infos)
+
+	seen := make(map[uint64]bool)
+	for i, info := range infos {
+		assert.False(t, seen[info.Tag], "tag %d of %s is registered twice", info.Tag, info.Name)
+		assert.True(t, info.Tag >= schema.ReservedTagMin, "tag %d of %s is not reserved", info.Tag, info.Name)
+		assert.NotEmpty(t, info.Name)
+		assert.NotEmpty(t, info.Description)
+		seen[info.Tag] = true
+
+		if i > 0 {
+			assert.True(t, infos[i-1].Tag < info.Tag)
+		}
+	}
+
+	assert.True(t, seen[DocNameTag])
+	assert.True(t, seen[DocTextTag])
+}
@@ -87,6 +87,39 @@ func newRootValue(vrw types.ValueReadWriter, st types.Struct) *RootValue {
 	return &RootValue{vrw, st}
 }
 
+// LoadRootValue reads the RootValue with the hash |h| from |vrw|.
+func LoadRootValue(ctx context.Context, vrw types.ValueReadWriter, h hash.Hash) (*RootValue, error) {
+	val, err := vrw.ReadValue(ctx, h)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		return nil, ErrHashNotFound
+	}
+
+	st, ok := val.(types.Struct)
+
+	if !ok || st.Name() != ddbRootStructName {
+		return nil, ErrFoundHashNotARootValue
+	}
+
+	return newRootValue(vrw, st), nil
+}
+
+// StoreRootValue writes |root| to its ValueReadWriter and returns its hash. Unlike DoltDB.WriteRootValue it does not
+// flush the write.
+func StoreRootValue(ctx context.Context, root *RootValue) (hash.Hash, error) {
+	ref, err := root.vrw.WriteValue(ctx, root.valueSt)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	return ref.TargetHash(), nil
+}
+
 func emptyRootValue(ctx context.Context, vrw types.ValueReadWriter) (*RootValue, error) {
 	m, err := types.NewMap(ctx, vrw)
 
@@ -278,8 +311,37 @@ func (root *RootValue) GenerateTagsForNewColumns(ctx context.Context, tableName
 
 	newTags := make([]uint64, len(newColNames))
 	existingTags := set.NewUint64Set(rootSuperSchema.AllTags())
+
+	// virtual columns aren't in super schemas, but share their tag space
+	tblNames, err := root.GetTableNames(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tblName := range tblNames {
+		tbl, _, err := root.GetTable(ctx, tblName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sch, err := tbl.GetSchema(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range sch.VirtualCols().Tags {
+			existingTags.Add(tag)
+		}
+	}
+
 	for i := range newTags {
-		newTags[i] = schema.AutoGenerateTag(existingTags, tableName, existingColKinds, newColNames[i], newColKinds[i])
+		newTags[i], err = schema.TryAutoGenerateTag(existingTags, tableName, existingColKinds, newColNames[i], newColKinds[i])
+		if err != nil {
+			return nil, err
+		}
 		existingColKinds = append(existingColKinds, newColKinds[i])
 		existingTags.Add(newTags[i])
 	}
@@ -526,6 +588,48 @@ func (root *RootValue) TablesInConflict(ctx context.Context) ([]string, error) {
 	return names, nil
 }
 
+// TableConflictCounts returns a map from the name of each table in conflict to the number of rows in conflict in
+// that table.
+func (root *RootValue) TableConflictCounts(ctx context.Context) (map[string]int, error) {
+	tableMap, err := root.getTableMap()
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	err = tableMap.Iter(ctx, func(key, tblRefVal types.Value) (stop bool, err error) {
+		tblVal, err := tblRefVal.(types.Ref).TargetValue(ctx, root.vrw)
+
+		if err != nil {
+			return false, err
+		}
+
+		tblSt := tblVal.(types.Struct)
+		tbl := &Table{root.vrw, tblSt}
+		if has, err := tbl.HasConflicts(); err != nil {
+			return false, err
+		} else if !has {
+			return false, nil
+		}
+
+		n, err := tbl.NumRowsInConflict(ctx)
+
+		if err != nil {
+			return false, err
+		}
+
+		counts[string(key.(types.String))] = int(n)
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
 func (root *RootValue) HasConflicts(ctx context.Context) (bool, error) {
 	cnfTbls, err := root.TablesInConflict(ctx)
 
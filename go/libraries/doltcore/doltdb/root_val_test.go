@@ -19,9 +19,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
@@ -348,3 +350,53 @@ func createTestRows(t *testing.T, vrw types.ValueReadWriter, sch schema.Schema,
 
 	return m, rows
 }
+
+func TestGenerateTagsForNewColumnsAvoidsVirtualTags(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_7_18, InMemDoltDB)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "billy bob", "bigbillieb@fake.horse"))
+
+	cs, _ := NewCommitSpec("head", "master")
+	cm, err := ddb.Resolve(ctx, cs)
+	require.NoError(t, err)
+	root, err := cm.GetRootValue()
+	require.NoError(t, err)
+
+	genTag := func(root *RootValue) uint64 {
+		tags, err := root.GenerateTagsForNewColumns(ctx, "new_table", []string{"col"}, []types.NomsKind{types.StringKind})
+		require.NoError(t, err)
+		return tags[0]
+	}
+
+	putTable := func(virtualTags ...uint64) *RootValue {
+		cols, err := schema.NewColCollection(schema.NewColumn("pk", 1, types.IntKind, true, schema.NotNullConstraint{}))
+		require.NoError(t, err)
+		sch := schema.SchemaFromCols(cols)
+
+		var virtualCols []schema.Column
+		for _, tag := range virtualTags {
+			virtualCols = append(virtualCols, schema.NewColumn("virtual", tag, types.StringKind, false))
+		}
+
+		virtualColl, err := schema.NewColCollection(virtualCols...)
+		require.NoError(t, err)
+		sch, err = schema.SchemaWithVirtualCols(sch, virtualColl)
+		require.NoError(t, err)
+
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, ddb.ValueReadWriter(), sch)
+		require.NoError(t, err)
+		rows, err := types.NewMap(ctx, ddb.ValueReadWriter())
+		require.NoError(t, err)
+		tbl, err := NewTable(ctx, ddb.ValueReadWriter(), schVal, rows)
+		require.NoError(t, err)
+		root, err := root.PutTable(ctx, "tbl", tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	tag := genTag(root)
+	require.NotEqual(t, uint64(1), tag)
+	assert.Equal(t, tag, genTag(putTable()))
+	assert.NotEqual(t, tag, genTag(putTable(tag)))
+}
@@ -51,6 +51,12 @@ const (
 
 	// SystemTableReservedMin defines the lower bound of the tag space reserved for system tables
 	SystemTableReservedMin uint64 = schema.ReservedTagMin << 1
+
+	// DoltConflictsTablePrefix is the name prefix of the tables which hold the merge conflicts of another table
+	DoltConflictsTablePrefix = "dolt_conflicts_"
+
+	// ConflictsTableTagMin defines the lower bound of the tag space reserved for the columns of conflicts tables
+	ConflictsTableTagMin uint64 = schema.ReservedTagMin << 2
 )
 
 const (
@@ -145,6 +151,11 @@ func IsSystemTable(name string) bool {
 	return HasDoltPrefix(name) && !userSpaceReservedTables.Contains(name)
 }
 
+// ConflictsTableName returns the name of the table which holds the merge conflicts of the table |tblName|.
+func ConflictsTableName(tblName string) string {
+	return DoltConflictsTablePrefix + tblName
+}
+
 var ErrSystemTableCannotBeModified = errors.New("system tables cannot be dropped or altered")
 
 // Table is a struct which holds row data, as well as a reference to it's schema.
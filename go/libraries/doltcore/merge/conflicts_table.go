@@ -0,0 +1,207 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+const (
+	ConflictsBaseColPrefix  = "base_"
+	ConflictsOurColPrefix   = "our_"
+	ConflictsTheirColPrefix = "their_"
+)
+
+// conflictsTagsPerCol is the number of conflicts table columns derived from each column of the conflicted table: a
+// key column and one column for each of the base, our and their versions.
+const conflictsTagsPerCol = 4
+
+// conflictsKeyColTag returns the tag of the conflicts table key column for the primary key column tagged |tag|. Tags
+// are unique within a root, so the tags derived from them are unique across all the conflicts tables in the root.
+func conflictsKeyColTag(tag uint64) uint64 {
+	return doltdb.ConflictsTableTagMin + tag*conflictsTagsPerCol
+}
+
+// conflictsColTag returns the tag of the conflicts table column holding |version| of the column tagged |tag|.
+func conflictsColTag(tag uint64, version MergeVersion) uint64 {
+	return conflictsKeyColTag(tag) + 1 + uint64(version)
+}
+
+// ConflictsTableSchema returns the schema of the conflicts table for a table with schema |sch|. Its primary key
+// is made of the primary key columns of |sch|. Every column of |sch| then appears three times, prefixed with
+// ConflictsBaseColPrefix, ConflictsOurColPrefix and ConflictsTheirColPrefix, holding that version of the row. A
+// version's columns are all NULL when the row doesn't exist in that version.
+func ConflictsTableSchema(sch schema.Schema) (schema.Schema, error) {
+	var cols []schema.Column
+	err := sch.GetPKCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		keyCol, err := schema.NewColumnWithTypeInfo(col.Name, conflictsKeyColTag(tag), col.TypeInfo, true, schema.NotNullConstraint{})
+		cols = append(cols, keyCol)
+		return err != nil, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range []MergeVersion{BaseVersion, OurVersion, TheirVersion} {
+		prefix := conflictsColPrefix(version)
+		err = sch.GetAllCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+			versionCol, err := schema.NewColumnWithTypeInfo(prefix+col.Name, conflictsColTag(tag, version), col.TypeInfo, false)
+			cols = append(cols, versionCol)
+			return err != nil, err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	colColl, err := schema.NewColCollection(cols...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.SchemaFromCols(colColl), nil
+}
+
+func conflictsColPrefix(version MergeVersion) string {
+	switch version {
+	case BaseVersion:
+		return ConflictsBaseColPrefix
+	case OurVersion:
+		return ConflictsOurColPrefix
+	default:
+		return ConflictsTheirColPrefix
+	}
+}
+
+// NewConflictsTable returns a table with one row for each conflict of |tbl|, using the schema given by
+// ConflictsTableSchema. It is meant to be stored in a root as doltdb.ConflictsTableName so that the conflicts can be
+// queried. The conflicts table is a snapshot; it is not updated as the conflicts of |tbl| are resolved.
+func NewConflictsTable(ctx context.Context, vrw types.ValueReadWriter, tbl *doltdb.Table) (*doltdb.Table, error) {
+	sch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	confSch, err := ConflictsTableSchema(sch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, conflicts, err := tbl.GetConflicts(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := types.NewMap(ctx, vrw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	me := rows.Edit()
+	err = conflicts.IterAll(ctx, func(key, value types.Value) error {
+		conflict, err := doltdb.ConflictFromTuple(value.(types.Tuple))
+
+		if err != nil {
+			return err
+		}
+
+		r, err := conflictsRow(vrw.Format(), sch, confSch, key.(types.Tuple), conflict)
+
+		if err != nil {
+			return err
+		}
+
+		me.Set(r.NomsMapKey(confSch), r.NomsMapValue(confSch))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = me.Map(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, confSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doltdb.NewTable(ctx, vrw, schVal, rows)
+}
+
+func conflictsRow(nbf *types.NomsBinFormat, sch, confSch schema.Schema, key types.Tuple, conflict doltdb.Conflict) (row.Row, error) {
+	keyVals, err := row.ParseTaggedValues(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	confVals := make(row.TaggedValues)
+	for tag, val := range keyVals {
+		confVals[conflictsKeyColTag(tag)] = val
+	}
+
+	versions := []struct {
+		version MergeVersion
+		val     types.Value
+	}{
+		{BaseVersion, conflict.Base},
+		{OurVersion, conflict.Value},
+		{TheirVersion, conflict.MergeValue},
+	}
+
+	for _, v := range versions {
+		if types.IsNull(v.val) {
+			continue
+		}
+
+		vals, err := row.ParseTaggedValues(v.val.(types.Tuple))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for tag, val := range keyVals {
+			confVals[conflictsColTag(tag, v.version)] = val
+		}
+
+		for tag, val := range vals {
+			// columns which aren't in the merged schema can't be represented
+			if _, ok := sch.GetAllCols().GetByTag(tag); ok {
+				confVals[conflictsColTag(tag, v.version)] = val
+			}
+		}
+	}
+
+	return row.New(nbf, confSch, confVals)
+}
@@ -0,0 +1,240 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestConflictsTableSchema(t *testing.T) {
+	confSch, err := ConflictsTableSchema(sch)
+	require.NoError(t, err)
+
+	expectedNames := []string{
+		"id",
+		"base_id", "base_name", "base_title",
+		"our_id", "our_name", "our_title",
+		"their_id", "their_name", "their_title",
+	}
+	assert.ElementsMatch(t, expectedNames, confSch.GetAllCols().GetColumnNames())
+	assert.Equal(t, []string{"id"}, confSch.GetPKCols().GetColumnNames())
+
+	tags := make(map[uint64]bool)
+	for _, tag := range confSch.GetAllCols().SortedTags {
+		assert.True(t, tag >= doltdb.ConflictsTableTagMin)
+		tags[tag] = true
+	}
+	assert.Len(t, tags, len(expectedNames))
+
+	for _, name := range expectedNames[1:] {
+		col, _ := confSch.GetAllCols().GetByName(name)
+		assert.False(t, col.IsPartOfPK)
+		assert.Empty(t, col.Constraints)
+	}
+}
+
+func TestMergeTableConflictsTable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+	require.NoError(t, err)
+
+	rowVal := func(name string) types.Value {
+		return valsToTestTupleWithoutPks([]types.Value{types.String(name), types.NullValue})
+	}
+
+	rootWithRows := func(kvs ...types.Value) *doltdb.RootValue {
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	// row 0 is modified on both sides and row 1 is modified on our side and deleted on theirs
+	ancRoot := rootWithRows(keyTuples[0], rowVal("a"), keyTuples[1], rowVal("a"))
+	root := rootWithRows(keyTuples[0], rowVal("b"), keyTuples[1], rowVal("b"))
+	mergeRoot := rootWithRows(keyTuples[0], rowVal("c"))
+
+	_, stats, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Conflicts)
+	assert.Nil(t, stats.ConflictsTable)
+
+	merger := NewMerger(ctx, root, mergeRoot, ancRoot, vrw)
+	merger.BuildConflictsTables()
+	mergedTbl, stats, err := merger.MergeTable(ctx, tableName)
+	require.NoError(t, err)
+	require.NotNil(t, stats.ConflictsTable)
+
+	confTblName := doltdb.ConflictsTableName(tableName)
+	newRoot, err := root.PutTable(ctx, tableName, mergedTbl)
+	require.NoError(t, err)
+	newRoot, err = newRoot.PutTable(ctx, confTblName, stats.ConflictsTable)
+	require.NoError(t, err)
+
+	confTbl, ok, err := newRoot.GetTable(ctx, confTblName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	confSch, err := confTbl.GetSchema(ctx)
+	require.NoError(t, err)
+	expectedSch, err := ConflictsTableSchema(sch)
+	require.NoError(t, err)
+	eq, err := schema.SchemasAreEqual(expectedSch, confSch)
+	require.NoError(t, err)
+	assert.True(t, eq)
+
+	confRows, err := confTbl.GetRowData(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), confRows.Len())
+
+	getRow := func(i int) row.Row {
+		k, err := types.NewTuple(types.Format_7_18, types.Uint(conflictsKeyColTag(idTag)), uuids[i])
+		require.NoError(t, err)
+		v, ok, err := confRows.MaybeGet(ctx, k)
+		require.NoError(t, err)
+		require.True(t, ok)
+		r, err := row.FromNoms(confSch, k, v.(types.Tuple))
+		require.NoError(t, err)
+		return r
+	}
+
+	colVal := func(r row.Row, tag uint64, version MergeVersion) types.Value {
+		val, _ := r.GetColVal(conflictsColTag(tag, version))
+		return val
+	}
+
+	r := getRow(0)
+	assert.Equal(t, types.String("a"), colVal(r, nameTag, BaseVersion))
+	assert.Equal(t, types.String("b"), colVal(r, nameTag, OurVersion))
+	assert.Equal(t, types.String("c"), colVal(r, nameTag, TheirVersion))
+	assert.Equal(t, uuids[0], colVal(r, idTag, TheirVersion))
+
+	r = getRow(1)
+	assert.Equal(t, types.String("a"), colVal(r, nameTag, BaseVersion))
+	assert.Equal(t, types.String("b"), colVal(r, nameTag, OurVersion))
+	assert.Nil(t, colVal(r, nameTag, TheirVersion))
+	assert.Nil(t, colVal(r, idTag, TheirVersion))
+}
+
+func TestMergeCommitsBuildConflictsTables(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+	require.NoError(t, err)
+
+	commitName := func(parent *doltdb.Commit, name string) *doltdb.Commit {
+		rows, err := types.NewMap(ctx, vrw, keyTuples[0], valsToTestTupleWithoutPks([]types.Value{types.String(name), types.NullValue}))
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, []*doltdb.Commit{parent}, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	// row 0 is modified differently on both sides
+	anc := commitName(masterHead, "a")
+	ours := commitName(anc, "b")
+	theirs := commitName(anc, "c")
+
+	confTblName := doltdb.ConflictsTableName(tableName)
+	mergedRoot, _, err := MergeCommits(ctx, ddb, ours, theirs)
+	require.NoError(t, err)
+	has, err := mergedRoot.HasTable(ctx, confTblName)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	mergedRoot, tblToStats, err := MergeCommitsWithOptions(ctx, ddb, ours, theirs, MergeOptions{BuildConflictsTables: true})
+	require.NoError(t, err)
+	require.NotNil(t, tblToStats[tableName].ConflictsTable)
+	_, has = tblToStats[confTblName]
+	assert.False(t, has)
+
+	// the merged root, with its conflicts table, is read back as it was written
+	h, err := ddb.WriteRootValue(ctx, mergedRoot)
+	require.NoError(t, err)
+	readRoot, err := ddb.ReadRootValue(ctx, h)
+	require.NoError(t, err)
+
+	inConflict, err := readRoot.TablesInConflict(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{tableName}, inConflict)
+
+	confTbl, ok, err := readRoot.GetTable(ctx, confTblName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	confSch, err := confTbl.GetSchema(ctx)
+	require.NoError(t, err)
+	expectedSch, err := ConflictsTableSchema(sch)
+	require.NoError(t, err)
+	eq, err := schema.SchemasAreEqual(expectedSch, confSch)
+	require.NoError(t, err)
+	assert.True(t, eq)
+
+	confRows, err := confTbl.GetRowData(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), confRows.Len())
+	k, err := types.NewTuple(types.Format_7_18, types.Uint(conflictsKeyColTag(idTag)), uuids[0])
+	require.NoError(t, err)
+	v, ok, err := confRows.MaybeGet(ctx, k)
+	require.NoError(t, err)
+	require.True(t, ok)
+	r, err := row.FromNoms(confSch, k, v.(types.Tuple))
+	require.NoError(t, err)
+
+	for version, expected := range map[MergeVersion]string{BaseVersion: "a", OurVersion: "b", TheirVersion: "c"} {
+		val, _ := r.GetColVal(conflictsColTag(nameTag, version))
+		assert.Equal(t, types.String(expected), val)
+	}
+}
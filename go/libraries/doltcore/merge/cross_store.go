@@ -0,0 +1,243 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/ref"
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/datas"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// ErrMergeBaseNotFound is returned by MergeAcrossStores when the merge base is missing from either store.
+var ErrMergeBaseNotFound = errors.New("merge base not found")
+
+// crossStoreBatchSize is the number of chunk addresses MergeAcrossStores checks for and copies at a time.
+const crossStoreBatchSize = 256
+
+// MergeAcrossStores merges the head of |theirBranch| in the dolt store |theirs| into the head of |ourBranch| in the
+// dolt store |ours|, using the commit with hash |base| as the merge base. Both stores must have |base|, and it must be
+// an ancestor of both heads. Only the chunks reachable from their head but not from |base|, and which |ours| does not
+// already have, are copied from |theirs| into |ours|, after which their head and its history since |base| can be read
+// from |ours|. Tables merge as they do in MergeCommits, with their conflicts recorded in the merged tables. The merged
+// RootValue is written to |ours| and its hash is returned. No refs are moved: as with MergeCommits, it is up to the
+// caller to commit the merged RootValue with both heads as its parents.
+func MergeAcrossStores(ctx context.Context, ours, theirs chunks.ChunkStore, ourBranch, theirBranch ref.DoltRef, base hash.Hash) (hash.Hash, error) {
+	nbf, err := types.GetFormatForVersionString(ours.Version())
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	ddb, theirDDB := doltdb.DoltDBFromCS(ours), doltdb.DoltDBFromCS(theirs)
+	theirBase, err := resolveMergeBase(ctx, theirs, theirDDB, base)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	theirHead, err := resolveCommit(ctx, theirDDB, theirBranch.String())
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	ok, err := isAncestor(ctx, theirBase, theirHead)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	if !ok {
+		return hash.Hash{}, ErrNotMergeBase
+	}
+
+	ancCommit, err := resolveMergeBase(ctx, ours, ddb, base)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	commit, err := resolveCommit(ctx, ddb, ourBranch.String())
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	ok, err = isAncestor(ctx, ancCommit, commit)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	if !ok {
+		return hash.Hash{}, ErrNotMergeBase
+	}
+
+	theirHeadHash, err := theirHead.HashOf()
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	err = pullChunkDiff(ctx, ours, theirs, nbf, base, theirHeadHash)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	mergeCommit, err := resolveCommit(ctx, ddb, theirHeadHash.String())
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	ancRoot, err := ancCommit.GetRootValue()
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	mergedRoot, _, err := mergeCommitRoots(ctx, ddb, commit, mergeCommit, ancRoot, MergeOptions{})
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	return ddb.WriteRootValue(ctx, mergedRoot)
+}
+
+// resolveMergeBase returns the commit with hash |base| from |ddb|, the dolt database of |cs|, or ErrMergeBaseNotFound
+// if |cs| doesn't have it.
+func resolveMergeBase(ctx context.Context, cs chunks.ChunkStore, ddb *doltdb.DoltDB, base hash.Hash) (*doltdb.Commit, error) {
+	has, err := cs.Has(ctx, base)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		return nil, ErrMergeBaseNotFound
+	}
+
+	return resolveCommit(ctx, ddb, base.String())
+}
+
+// resolveCommit returns the commit of |ddb| named by the ref or commit hash |spec|.
+func resolveCommit(ctx context.Context, ddb *doltdb.DoltDB, spec string) (*doltdb.Commit, error) {
+	cs, err := doltdb.NewCommitSpec(spec, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.Resolve(ctx, cs)
+}
+
+// pullChunkDiff copies from |src| into |sink| each chunk reachable from |toRoot| but not from |fromRoot| which |sink|
+// does not have.
+func pullChunkDiff(ctx context.Context, sink, src chunks.ChunkStore, nbf *types.NomsBinFormat, fromRoot, toRoot hash.Hash) error {
+	diffCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ae := atomicerr.New()
+	diffChan := make(chan hash.Hash, crossStoreBatchSize)
+	go func() {
+		defer close(diffChan)
+		err := datas.ChunkDiff(diffCtx, src, nbf, fromRoot, toRoot, diffChan)
+		ae.SetIfError(err)
+	}()
+
+	batch := make(hash.HashSet, crossStoreBatchSize)
+	for h := range diffChan {
+		batch.Insert(h)
+
+		if len(batch) < crossStoreBatchSize {
+			continue
+		}
+
+		err := copyChunks(ctx, sink, src, batch)
+
+		if err != nil {
+			cancel()
+			for range diffChan {
+			}
+
+			return err
+		}
+
+		batch = make(hash.HashSet, crossStoreBatchSize)
+	}
+
+	if err := ae.Get(); err != nil {
+		return err
+	}
+
+	return copyChunks(ctx, sink, src, batch)
+}
+
+// copyChunks puts into |sink| each chunk of |hashes| which |sink| is missing, reading it from |src|.
+func copyChunks(ctx context.Context, sink, src chunks.ChunkStore, hashes hash.HashSet) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	absent, err := sink.HasMany(ctx, hashes)
+
+	if err != nil {
+		return err
+	}
+
+	if len(absent) == 0 {
+		return nil
+	}
+
+	ae := atomicerr.New()
+	found := make(chan *chunks.Chunk, crossStoreBatchSize)
+	go func() {
+		defer close(found)
+		err := src.GetMany(ctx, absent, found)
+		ae.SetIfError(err)
+	}()
+
+	// |absent| is still being read by GetMany, so the chunks received are tracked separately
+	received := make(hash.HashSet, len(absent))
+	for c := range found {
+		if ae.IsSet() {
+			continue
+		}
+
+		received.Insert(c.Hash())
+		err := sink.Put(ctx, *c)
+		ae.SetIfError(err)
+	}
+
+	if err := ae.Get(); err != nil {
+		return err
+	}
+
+	if len(received) != len(absent) {
+		return fmt.Errorf("%d chunks missing from the source store", len(absent)-len(received))
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/ref"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/constants"
+	"github.com/liquidata-inc/dolt/go/store/datas"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/nbs"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+const (
+	crossStorePkTag  = 800
+	crossStoreValTag = 801
+)
+
+var crossStoreSch = schema.SchemaFromCols(mustColColl(
+	schema.NewColumn("pk", crossStorePkTag, types.IntKind, true, schema.NotNullConstraint{}),
+	schema.NewColumn("val", crossStoreValTag, types.IntKind, false),
+))
+
+// countingChunkStore counts the chunks put into it.
+type countingChunkStore struct {
+	chunks.ChunkStore
+	puts int
+}
+
+func (ccs *countingChunkStore) Put(ctx context.Context, c chunks.Chunk) error {
+	ccs.puts++
+	return ccs.ChunkStore.Put(ctx, c)
+}
+
+// newCrossStoreTestStore returns a local store in a new temp dir holding an empty dolt repo, the repo's DoltDB, and a
+// func which closes the store and removes the dir.
+func newCrossStoreTestStore(t *testing.T) (chunks.ChunkStore, *doltdb.DoltDB, func()) {
+	dir, err := ioutil.TempDir("", "cross_store")
+	require.NoError(t, err)
+
+	cs, err := nbs.NewLocalStore(context.Background(), constants.Format718String, dir, 1<<20)
+	require.NoError(t, err)
+
+	ddb := doltdb.DoltDBFromCS(cs)
+	require.NoError(t, ddb.WriteEmptyRepo(context.Background(), name, email))
+
+	return cs, ddb, func() {
+		cs.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// commitCrossStoreRows commits to master of |ddb| a root holding the table |tableName| with |rows|, which map a pk to a
+// val, and returns the commit.
+func commitCrossStoreRows(t *testing.T, ddb *doltdb.DoltDB, rows map[int64]int64, desc string) *doltdb.Commit {
+	ctx := context.Background()
+	vrw := ddb.ValueReadWriter()
+
+	master := ref.NewBranchRef("master")
+	head, err := resolveCommit(ctx, ddb, master.String())
+	require.NoError(t, err)
+	root, err := head.GetRootValue()
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, crossStoreSch)
+	require.NoError(t, err)
+
+	var kvs []types.Value
+	for pk, val := range rows {
+		k, err := types.NewTuple(vrw.Format(), types.Uint(crossStorePkTag), types.Int(pk))
+		require.NoError(t, err)
+		v, err := types.NewTuple(vrw.Format(), types.Uint(crossStoreValTag), types.Int(val))
+		require.NoError(t, err)
+		kvs = append(kvs, k, v)
+	}
+
+	rowData, err := types.NewMap(ctx, vrw, kvs...)
+	require.NoError(t, err)
+	tbl, err := doltdb.NewTable(ctx, vrw, schVal, rowData)
+	require.NoError(t, err)
+	root, err = root.PutTable(ctx, tableName, tbl)
+	require.NoError(t, err)
+	h, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+
+	meta, err := doltdb.NewCommitMeta(name, email, desc)
+	require.NoError(t, err)
+	cm, err := ddb.CommitWithParentCommits(ctx, h, master, []*doltdb.Commit{head}, meta)
+	require.NoError(t, err)
+
+	return cm
+}
+
+// fixCommitTime makes every commit written until the returned func is called have the same timestamp, so that
+// identical commits written to different stores have the same hash.
+func fixCommitTime() func() {
+	now := doltdb.CommitNowFunc
+	doltdb.CommitNowFunc = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	return func() { doltdb.CommitNowFunc = now }
+}
+
+func TestMergeAcrossStores(t *testing.T) {
+	ctx := context.Background()
+	baseRows := map[int64]int64{1: 1, 2: 2, 3: 3}
+	master := ref.NewBranchRef("master")
+
+	defer fixCommitTime()()
+
+	ours, ddb, closeOurs := newCrossStoreTestStore(t)
+	defer closeOurs()
+	theirs, theirDDB, closeTheirs := newCrossStoreTestStore(t)
+	defer closeTheirs()
+
+	baseHash, err := commitCrossStoreRows(t, ddb, baseRows, "base").HashOf()
+	require.NoError(t, err)
+	theirBaseHash, err := commitCrossStoreRows(t, theirDDB, baseRows, "base").HashOf()
+	require.NoError(t, err)
+	require.Equal(t, baseHash, theirBaseHash)
+
+	ourHead := commitCrossStoreRows(t, ddb, map[int64]int64{1: 1, 2: 20, 3: 3, 4: 4}, "ours")
+	theirHead := commitCrossStoreRows(t, theirDDB, map[int64]int64{1: 1, 2: 2, 3: 30, 5: 5}, "theirs")
+	theirHeadHash, err := theirHead.HashOf()
+	require.NoError(t, err)
+
+	mergedHash, err := MergeAcrossStores(ctx, ours, theirs, master, master, baseHash)
+	require.NoError(t, err)
+
+	// refs are left as they were
+	head, err := resolveCommit(ctx, ddb, master.String())
+	require.NoError(t, err)
+	ourHeadHash, err := ourHead.HashOf()
+	require.NoError(t, err)
+	headHash, err := head.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, ourHeadHash, headHash)
+
+	_, err = MergeAcrossStores(ctx, ours, theirs, master, master, hash.Of([]byte("missing")))
+	assert.Equal(t, ErrMergeBaseNotFound, err)
+
+	// their head is in ours now, but isn't an ancestor of our head
+	_, err = MergeAcrossStores(ctx, ours, theirs, master, master, theirHeadHash)
+	assert.Equal(t, ErrNotMergeBase, err)
+
+	mergedRoot, err := ddb.ReadRootValue(ctx, mergedHash)
+	require.NoError(t, err)
+	tbl, ok, err := mergedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	rowData, err := tbl.GetRowData(ctx)
+	require.NoError(t, err)
+
+	merged := make(map[int64]int64)
+	err = rowData.IterAll(ctx, func(k, v types.Value) error {
+		pk, err := k.(types.Tuple).Get(1)
+		require.NoError(t, err)
+		val, err := v.(types.Tuple).Get(1)
+		require.NoError(t, err)
+		merged[int64(pk.(types.Int))] = int64(val.(types.Int))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{1: 1, 2: 20, 3: 30, 4: 4, 5: 5}, merged)
+
+	// their head is now in ours, so the merged root can be committed as a merge, and nothing is left to copy
+	mergeCommit, err := resolveCommit(ctx, ddb, theirHeadHash.String())
+	require.NoError(t, err)
+	meta, err := doltdb.NewCommitMeta(name, email, "merge")
+	require.NoError(t, err)
+	_, err = ddb.CommitWithParentCommits(ctx, mergedHash, master, []*doltdb.Commit{ourHead, mergeCommit}, meta)
+	require.NoError(t, err)
+
+	sink := &countingChunkStore{ChunkStore: ours}
+	err = pullChunkDiff(ctx, sink, theirs, types.Format_7_18, baseHash, theirHeadHash)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sink.puts)
+
+	branches, err := ddb.GetBranches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ref.DoltRef{master}, branches)
+
+}
+
+func TestPullChunkDiffCopiesOnlyTheDelta(t *testing.T) {
+	ctx := context.Background()
+	baseRows := map[int64]int64{1: 1, 2: 2, 3: 3}
+
+	defer fixCommitTime()()
+
+	ours, ddb, closeOurs := newCrossStoreTestStore(t)
+	defer closeOurs()
+	theirs, theirDDB, closeTheirs := newCrossStoreTestStore(t)
+	defer closeTheirs()
+
+	baseHash, err := commitCrossStoreRows(t, ddb, baseRows, "base").HashOf()
+	require.NoError(t, err)
+	_ = commitCrossStoreRows(t, theirDDB, baseRows, "base")
+
+	theirHeadHash, err := commitCrossStoreRows(t, theirDDB, map[int64]int64{1: 1, 2: 2, 3: 30}, "theirs").HashOf()
+	require.NoError(t, err)
+
+	nbf := types.Format_7_18
+	diffChan := make(chan hash.Hash, 1024)
+	err = datas.ChunkDiff(ctx, theirs, nbf, baseHash, theirHeadHash, diffChan)
+	require.NoError(t, err)
+	close(diffChan)
+
+	var diff int
+	for range diffChan {
+		diff++
+	}
+
+	sink := &countingChunkStore{ChunkStore: ours}
+	err = pullChunkDiff(ctx, sink, theirs, nbf, baseHash, theirHeadHash)
+	require.NoError(t, err)
+	assert.Equal(t, diff, sink.puts)
+	assert.True(t, diff > 0)
+
+	has, err := ours.Has(ctx, theirHeadHash)
+	require.NoError(t, err)
+	assert.True(t, has)
+}
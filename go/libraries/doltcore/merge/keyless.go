@@ -0,0 +1,194 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// mergeKeylessTableData merges the row data of a keyless table, which maps each distinct row to the number of copies
+// of it in the table. The table is treated as a multiset: the copies added and removed on each side relative to the
+// ancestor are applied to the ancestor. When both sides changed the copies of the same row in the same direction,
+// their changes are assumed to overlap, as when the same row is inserted on both sides, and the larger change is
+// kept. Only a row which one side added copies of while the other removed copies of is a conflict.
+func mergeKeylessTableData(ctx context.Context, rows, mergeRows, ancRows types.Map, vrw types.ValueReadWriter) (types.Map, types.Map, *MergeStats, error) {
+	ae := atomicerr.New()
+	changeChan, mergeChangeChan := make(chan types.ValueChanged, 32), make(chan types.ValueChanged, 32)
+	stopChan, mergeStopChan := make(chan struct{}, 1), make(chan struct{}, 1)
+
+	go func() {
+		rows.Diff(ctx, ancRows, ae, changeChan, stopChan)
+		close(changeChan)
+	}()
+
+	go func() {
+		mergeRows.Diff(ctx, ancRows, ae, mergeChangeChan, mergeStopChan)
+		close(mergeChangeChan)
+	}()
+
+	defer stopAndDrain(stopChan, changeChan)
+	defer stopAndDrain(mergeStopChan, mergeChangeChan)
+
+	conflictValChan := make(chan types.Value)
+	conflictMapChan := types.NewStreamingMap(ctx, vrw, ae, conflictValChan)
+	mapEditor := rows.Edit()
+	stats := &MergeStats{Operation: TableModified}
+
+	f := func() error {
+		defer close(conflictValChan)
+
+		var change, mergeChange types.ValueChanged
+		for !ae.IsSet() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if change.Key == nil {
+				change = <-changeChan
+			}
+			if mergeChange.Key == nil {
+				mergeChange = <-mergeChangeChan
+			}
+
+			key, mergeKey := change.Key, mergeChange.Key
+
+			if key == nil && mergeKey == nil {
+				break
+			}
+
+			var err error
+			keyLess, mergeKeyLess := mergeKey == nil, key == nil
+			if key != nil && mergeKey != nil {
+				keyLess, err = key.Less(vrw.Format(), mergeKey)
+
+				if err != nil {
+					return err
+				}
+
+				mergeKeyLess, err = mergeKey.Less(vrw.Format(), key)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			switch {
+			case keyLess:
+				// change will already be in the map
+				change = types.ValueChanged{}
+			case mergeKeyLess:
+				applyKeylessCount(mapEditor, stats, mergeKey, keylessCount(mergeChange.OldValue), keylessCount(mergeChange.NewValue))
+				mergeChange = types.ValueChanged{}
+			default:
+				base := keylessCount(change.OldValue)
+				ours, theirs := keylessCount(change.NewValue), keylessCount(mergeChange.NewValue)
+				merged, isConflict := keylessCountMerge(base, ours, theirs)
+
+				if isConflict {
+					stats.Conflicts++
+
+					conflictTuple, err := doltdb.NewConflict(change.OldValue, change.NewValue, mergeChange.NewValue).ToNomsList(vrw)
+
+					if err != nil {
+						return err
+					}
+
+					addConflict(conflictValChan, key, conflictTuple)
+				} else {
+					applyKeylessCount(mapEditor, stats, key, ours, merged)
+				}
+
+				change = types.ValueChanged{}
+				mergeChange = types.ValueChanged{}
+			}
+		}
+
+		return nil
+	}
+
+	err := f()
+
+	if err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	if err := ae.Get(); err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	conflicts := <-conflictMapChan
+	mergedData, err := mapEditor.Map(ctx)
+
+	if err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	return mergedData, conflicts, stats, nil
+}
+
+// keylessCount returns the number of copies of a row in a keyless table given the row's value, which is nil when
+// the row is absent.
+func keylessCount(v types.Value) uint64 {
+	if v == nil {
+		return 0
+	}
+
+	return uint64(v.(types.Uint))
+}
+
+func keylessCountMerge(base, ours, theirs uint64) (uint64, bool) {
+	switch {
+	case ours == theirs || theirs == base:
+		return ours, false
+	case ours == base:
+		return theirs, false
+	case ours > base && theirs > base:
+		if ours > theirs {
+			return ours, false
+		}
+		return theirs, false
+	case ours < base && theirs < base:
+		if ours < theirs {
+			return ours, false
+		}
+		return theirs, false
+	default:
+		return 0, true
+	}
+}
+
+// applyKeylessCount changes the number of copies of the row |key| in |me| from |old| to |new|, counting each copy
+// added or removed in |stats|.
+func applyKeylessCount(me *types.MapEditor, stats *MergeStats, key types.Value, old, new uint64) {
+	switch {
+	case new == 0:
+		stats.Deletes += int(old)
+		me.Remove(key)
+	case new > old:
+		stats.Adds += int(new - old)
+		me.Set(key, types.Uint(new))
+	case new < old:
+		stats.Deletes += int(old - new)
+		me.Set(key, types.Uint(new))
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+const (
+	keylessNameTag  = 300
+	keylessTitleTag = 301
+)
+
+var keylessSch = schema.KeylessSchemaFromCols(mustColColl(
+	schema.NewColumn("name", keylessNameTag, types.StringKind, false),
+	schema.NewColumn("title", keylessTitleTag, types.StringKind, false),
+))
+
+func mustColColl(cols ...schema.Column) *schema.ColCollection {
+	colColl, err := schema.NewColCollection(cols...)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return colColl
+}
+
+func keylessKey(name, title string) types.Value {
+	tv := row.TaggedValues{keylessNameTag: types.String(name), keylessTitleTag: types.String(title)}
+	v, err := tv.NomsTupleForTags(types.Format_7_18, keylessSch.GetAllCols().SortedTags, true).Value(context.Background())
+
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func TestMergeKeylessTable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, keylessSch)
+	require.NoError(t, err)
+
+	bill := keylessKey("bill", "dev")
+	jill := keylessKey("jill", "ops")
+	zed := keylessKey("zed", "qa")
+	amy := keylessKey("amy", "pm")
+	bob := keylessKey("bob", "cto")
+
+	rootWithRows := func(kvs ...types.Value) *doltdb.RootValue {
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	tests := []struct {
+		name              string
+		anc, ours, theirs []types.Value
+		expected          []types.Value
+		expectedConflicts int
+		expectedAdds      int
+		expectedDeletes   int
+	}{
+		{
+			name:     "concurrent inserts of different rows",
+			anc:      []types.Value{bill, types.Uint(1)},
+			ours:     []types.Value{bill, types.Uint(1), zed, types.Uint(1)},
+			theirs:   []types.Value{amy, types.Uint(1), bill, types.Uint(1)},
+			expected: []types.Value{amy, types.Uint(1), bill, types.Uint(1), zed, types.Uint(1)},
+
+			expectedAdds: 1,
+		},
+		{
+			name:     "concurrent inserts of the same row",
+			anc:      []types.Value{bill, types.Uint(1)},
+			ours:     []types.Value{bill, types.Uint(1), zed, types.Uint(1)},
+			theirs:   []types.Value{bill, types.Uint(1), zed, types.Uint(1)},
+			expected: []types.Value{bill, types.Uint(1), zed, types.Uint(1)},
+		},
+		{
+			name:     "duplicates added on both sides",
+			anc:      []types.Value{bill, types.Uint(1)},
+			ours:     []types.Value{bill, types.Uint(2)},
+			theirs:   []types.Value{bill, types.Uint(3)},
+			expected: []types.Value{bill, types.Uint(3)},
+
+			expectedAdds: 1,
+		},
+		{
+			name:     "duplicate added on their side",
+			anc:      []types.Value{bill, types.Uint(2), jill, types.Uint(1)},
+			ours:     []types.Value{bill, types.Uint(2), jill, types.Uint(1), bob, types.Uint(1)},
+			theirs:   []types.Value{bill, types.Uint(3), jill, types.Uint(1)},
+			expected: []types.Value{bob, types.Uint(1), bill, types.Uint(3), jill, types.Uint(1)},
+
+			expectedAdds: 1,
+		},
+		{
+			name:     "duplicates removed on both sides",
+			anc:      []types.Value{bill, types.Uint(3), jill, types.Uint(2)},
+			ours:     []types.Value{bill, types.Uint(2), jill, types.Uint(2)},
+			theirs:   []types.Value{bill, types.Uint(1)},
+			expected: []types.Value{bill, types.Uint(1)},
+
+			expectedDeletes: 3,
+		},
+		{
+			name:     "duplicate added on one side and removed on the other",
+			anc:      []types.Value{bill, types.Uint(2)},
+			ours:     []types.Value{bill, types.Uint(3)},
+			theirs:   []types.Value{bill, types.Uint(1)},
+			expected: []types.Value{bill, types.Uint(3)},
+
+			expectedConflicts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, mergeRoot, ancRoot := rootWithRows(test.ours...), rootWithRows(test.theirs...), rootWithRows(test.anc...)
+
+			mergedTbl, stats, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConflicts, stats.Conflicts)
+			assert.Equal(t, test.expectedAdds, stats.Adds)
+			assert.Equal(t, test.expectedDeletes, stats.Deletes)
+
+			mergedSch, err := mergedTbl.GetSchema(ctx)
+			require.NoError(t, err)
+			assert.True(t, schema.IsKeyless(mergedSch))
+
+			mergedRows, err := mergedTbl.GetRowData(ctx)
+			require.NoError(t, err)
+			expectedRows, err := types.NewMap(ctx, vrw, test.expected...)
+			require.NoError(t, err)
+			assert.True(t, expectedRows.Equals(mergedRows))
+
+			has, err := mergedTbl.HasConflicts()
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConflicts > 0, has)
+		})
+	}
+}
+
+func TestMergeKeylessSchemaChange(t *testing.T) {
+	_, err := mergeTableSchema(sch, keylessSch, sch)
+	assert.Equal(t, ErrKeylessSchemaChange, err)
+
+	mergedSch, err := mergeTableSchema(keylessSch, keylessSch, keylessSch)
+	require.NoError(t, err)
+	assert.True(t, schema.IsKeyless(mergedSch))
+}
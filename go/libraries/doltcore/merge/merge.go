@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/liquidata-inc/dolt/go/store/atomicerr"
@@ -29,29 +30,141 @@ import (
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/table/typed"
 	"github.com/liquidata-inc/dolt/go/libraries/utils/valutil"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
 var ErrFastForward = errors.New("fast forward")
-var ErrSameTblAddedTwice = errors.New("table with same name added in 2 commits can't be merged")
+var ErrSameTblAddedTwice = errors.New("table with same name added in 2 commits with different schemas can't be merged")
+var ErrKeylessSchemaChange = errors.New("table changed between keyless and keyed can't be merged")
+var ErrTableNameCaseConflict = errors.New("tables with names differing only in case can't be merged")
+var ErrUniqueViolation = errors.New("merged rows violate a unique constraint")
+var ErrRowTransformNilRow = errors.New("row transform returned no row")
+var ErrRowTransformChangedKey = errors.New("row transform changed the row's primary key")
+var ErrNotMergeBase = errors.New("merge base is not an ancestor of both commits")
 
 type Merger struct {
 	root      *doltdb.RootValue
 	mergeRoot *doltdb.RootValue
 	ancRoot   *doltdb.RootValue
 	vrw       types.ValueReadWriter
+
+	buildConflictsTables bool
+	tiebreaker           Tiebreaker
+	rowTransform         RowTransform
 }
 
 // NewMerger creates a new merger utility object.
 func NewMerger(ctx context.Context, root, mergeRoot, ancRoot *doltdb.RootValue, vrw types.ValueReadWriter) *Merger {
-	return &Merger{root, mergeRoot, ancRoot, vrw}
+	return &Merger{root: root, mergeRoot: mergeRoot, ancRoot: ancRoot, vrw: vrw}
+}
+
+// BuildConflictsTables makes MergeTable build a conflicts table, as described by NewConflictsTable, for every keyed
+// table which merges with conflicts. The conflicts table is returned in MergeStats.ConflictsTable, and callers may
+// store it in their root under doltdb.ConflictsTableName.
+func (merger *Merger) BuildConflictsTables() {
+	merger.buildConflictsTables = true
+}
+
+// UseTiebreaker makes MergeTable resolve the rows of keyed tables which would otherwise conflict with |tb|, as
+// described by MergeOptions.Tiebreaker.
+func (merger *Merger) UseTiebreaker(tb Tiebreaker) {
+	merger.tiebreaker = tb
+}
+
+// UseRowTransform makes MergeTable apply |rt| to each row of the keyed tables it outputs, as described by
+// MergeOptions.RowTransform.
+func (merger *Merger) UseRowTransform(rt RowTransform) {
+	merger.rowTransform = rt
 }
 
-// MergeTable merges schema and table data for the table tblName.
+// MergeTable merges schema and table data for the table tblName. Rows are merged in primary key order, so the same
+// three roots always produce the same merged table.
+//
+// When one side added columns to the table's primary key, the rows of the other side and of the merge base are re-keyed
+// to match before they are merged. Any other difference between the primary keys is a *PrimaryKeyMismatchError.
 func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.Table, *MergeStats, error) {
-	tbl, ok, err := merger.root.GetTable(ctx, tblName)
+	return merger.mergeTableWithNames(ctx, mergeTableNames{tblName, tblName, tblName, tblName})
+}
+
+// mergeTableNames are the names of a table in each root of a merge, and the name of the merged table. They differ
+// only when table names are matched case-insensitively, or when one side renamed the table. A name is empty when the
+// table is missing from that root.
+type mergeTableNames struct {
+	name, mergeName, ancName string
+	mergedName               string
+}
+
+func (merger *Merger) mergeTableWithNames(ctx context.Context, names mergeTableNames) (*doltdb.Table, *MergeStats, error) {
+	mergedTbl, stats, err := merger.mergeTable(ctx, names)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if merger.rowTransform != nil && mergedTbl != nil {
+		mergedTbl, err = transformTable(ctx, names.mergedName, mergedTbl, merger.rowTransform)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stats.TableRenamed = names.ancName != "" && !strings.EqualFold(names.ancName, names.mergedName)
+	stats.RowCount, err = rootTableRowCount(ctx, merger.root, names.name)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.MergeRowCount, err = rootTableRowCount(ctx, merger.mergeRoot, names.mergeName)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.MergedRowCount, err = tableRowCount(ctx, mergedTbl)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergedTbl, stats, nil
+}
+
+func rootTableRowCount(ctx context.Context, root *doltdb.RootValue, tblName string) (uint64, error) {
+	tbl, ok, err := root.GetTable(ctx, tblName)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		return 0, nil
+	}
+
+	return tableRowCount(ctx, tbl)
+}
+
+// tableRowCount returns the number of rows in |tbl|, which has no rows if it is nil.
+func tableRowCount(ctx context.Context, tbl *doltdb.Table) (uint64, error) {
+	if tbl == nil {
+		return 0, nil
+	}
+
+	rows, err := tbl.GetRowData(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rows.Len(), nil
+}
+
+func (merger *Merger) mergeTable(ctx context.Context, names mergeTableNames) (*doltdb.Table, *MergeStats, error) {
+	tbl, ok, err := merger.root.GetTable(ctx, names.name)
 
 	if err != nil {
 		return nil, nil, err
@@ -66,7 +179,7 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		}
 	}
 
-	mergeTbl, mergeOk, err := merger.mergeRoot.GetTable(ctx, tblName)
+	mergeTbl, mergeOk, err := merger.mergeRoot.GetTable(ctx, names.mergeName)
 
 	if err != nil {
 		return nil, nil, err
@@ -81,7 +194,11 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		}
 	}
 
-	ancTbl, ancOk, err := merger.ancRoot.GetTable(ctx, tblName)
+	if ok && mergeOk && h == mh {
+		return tbl, &MergeStats{Operation: TableUnmodified, Identical: true}, nil
+	}
+
+	ancTbl, ancOk, err := merger.ancRoot.GetTable(ctx, names.ancName)
 
 	if err != nil {
 		return nil, nil, err
@@ -96,14 +213,21 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		}
 	}
 
-	if ok && mergeOk && h == mh {
-		return tbl, &MergeStats{Operation: TableUnmodified}, nil
-	}
+	if !ancOk && ok && mergeOk {
+		// both sides added the table, so their rows are merged as if it had been added empty
+		ancTbl, err = emptyAncestorTable(ctx, merger.vrw, tbl, mergeTbl)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		anch, err = ancTbl.HashOf()
 
-	if !ancOk {
-		if mergeOk && ok {
-			return nil, nil, ErrSameTblAddedTwice
-		} else if ok {
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if !ancOk {
+		if ok {
 			return tbl, &MergeStats{Operation: TableUnmodified}, nil
 		} else {
 			return mergeTbl, &MergeStats{Operation: TableAdded}, nil
@@ -116,6 +240,11 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		return tbl, &MergeStats{Operation: TableUnmodified}, nil
 	}
 
+	if !ok || !mergeOk {
+		// one side deleted the table and the other modified it, so the modified table is kept for the user to resolve
+		return merger.deleteModifyTable(ctx, ancTbl, tbl, mergeTbl)
+	}
+
 	tblSchema, err := tbl.GetSchema(ctx)
 
 	if err != nil {
@@ -134,6 +263,30 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		return nil, nil, err
 	}
 
+	pkSchema, err := primaryKeySchema(names.mergedName, tblSchema, mergeTblSchema, ancTblSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tbl, tblSchema, err = rekeyTable(ctx, merger.vrw, names.mergedName, tbl, tblSchema, pkSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeTbl, mergeTblSchema, err = rekeyTable(ctx, merger.vrw, names.mergedName, mergeTbl, mergeTblSchema, pkSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ancTbl, ancTblSchema, err = rekeyTable(ctx, merger.vrw, names.mergedName, ancTbl, ancTblSchema, pkSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
 	postMergeSchema, err := mergeTableSchema(tblSchema, mergeTblSchema, ancTblSchema)
 
 	if err != nil {
@@ -146,30 +299,65 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 		return nil, nil, err
 	}
 
+	rows, err = widenRowData(ctx, rows, tblSchema, postMergeSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
 	mergeRows, err := mergeTbl.GetRowData(ctx)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
+	mergeRows, err = widenRowData(ctx, mergeRows, mergeTblSchema, postMergeSchema)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ancRows, err := ancTbl.GetRowData(ctx)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
-	mergedRowData, conflicts, stats, err := mergeTableData(ctx, postMergeSchema, rows, mergeRows, ancRows, merger.vrw)
+	ancRows, err = widenRowData(ctx, ancRows, ancTblSchema, postMergeSchema)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
+	var mergedRowData, conflicts types.Map
+	var stats *MergeStats
+	if schema.IsKeyless(postMergeSchema) {
+		mergedRowData, conflicts, stats, err = mergeKeylessTableData(ctx, rows, mergeRows, ancRows, merger.vrw)
+	} else {
+		mergedRowData, conflicts, stats, err = mergeTableData(ctx, postMergeSchema, rows, mergeRows, ancRows, merger.vrw, merger.tiebreaker)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !schema.IsKeyless(postMergeSchema) {
+		err = checkUniqueConstraints(ctx, merger.vrw.Format(), postMergeSchema, mergedRowData)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	schUnionVal, err := encoding.MarshalSchemaAsNomsValue(ctx, merger.vrw, postMergeSchema)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// The merged table is built from scratch, so it only carries what is merged here: a table's schema, row data and
+	// conflicts. Tables have no secondary indexes yet. Index data added to tables must be merged or rebuilt from
+	// |mergedRowData| here, or it is dropped by every merge of a table changed on both sides.
 	mergedTable, err := doltdb.NewTable(ctx, merger.vrw, schUnionVal, mergedRowData)
 
 	if err != nil {
@@ -198,121 +386,561 @@ func (merger *Merger) MergeTable(ctx context.Context, tblName string) (*doltdb.T
 
 		schemas := doltdb.NewConflict(asr, sr, msr)
 		mergedTable, err = mergedTable.SetConflicts(ctx, schemas, conflicts)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if merger.buildConflictsTables && !schema.IsKeyless(postMergeSchema) {
+			stats.ConflictsTable, err = NewConflictsTable(ctx, merger.vrw, mergedTable)
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
 	return mergedTable, stats, nil
 }
 
-func stopAndDrain(stop chan<- struct{}, drain <-chan types.ValueChanged) {
-	close(stop)
-	for range drain {
+// deleteModifyTable returns the table which one side of a merge deleted and the other modified, as it was modified,
+// with a conflict for each row the modifying side changed. Exactly one of |tbl| and |mergeTbl| is nil, for the side
+// which deleted the table; its rows are nil in the conflicts. The conflicts mark the table as conflicted in the merged
+// root, even when only its schema changed, so that the merge can't be committed until the user resolves them by
+// keeping the table or dropping it.
+func (merger *Merger) deleteModifyTable(ctx context.Context, ancTbl, tbl, mergeTbl *doltdb.Table) (*doltdb.Table, *MergeStats, error) {
+	modTbl, stats := tbl, &MergeStats{Operation: TableUnmodified, TableConflict: DeleteModifyTable}
+	if tbl == nil {
+		modTbl, stats.Operation = mergeTbl, TableAdded
 	}
-}
 
-func mergeTableSchema(sch, mergeSch, ancSch schema.Schema) (schema.Schema, error) {
-	// (sch - ancSch) ∪ (mergeSch - ancSch) ∪ (sch ∩ mergeSch)
+	modRows, err := modTbl.GetRowData(ctx)
 
-	// columns remaining on both branches since the common ancestor
-	intersection, err := typed.TypedColCollectionIntersection(sch, mergeSch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ancRows, err := ancTbl.GetRowData(ctx)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// columns added on the main branch since the common ancestor
-	sub, err := typed.TypedColCollectionSubtraction(sch, ancSch)
+	ae := atomicerr.New()
+	changeChan, stopChan := make(chan types.ValueChanged, 32), make(chan struct{}, 1)
+	go func() {
+		defer close(changeChan)
+		modRows.Diff(ctx, ancRows, ae, changeChan, stopChan)
+	}()
+	defer stopAndDrain(stopChan, changeChan)
+
+	var kvs []types.Value
+	for change := range changeChan {
+		cnf := doltdb.NewConflict(change.OldValue, change.NewValue, nil)
+		if tbl == nil {
+			cnf = doltdb.NewConflict(change.OldValue, nil, change.NewValue)
+		}
+
+		cnfTuple, err := cnf.ToNomsList(merger.vrw)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kvs = append(kvs, change.Key, cnfTuple)
+		stats.Conflicts++
+	}
+
+	if err := ae.Get(); err != nil {
+		return nil, nil, err
+	}
+
+	// a cancelled diff ends early, so the conflicts are incomplete even though no error was seen
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	conflicts, err := types.NewMap(ctx, merger.vrw, kvs...)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// columns added on the merge branch since the common ancestor
-	mergeSub, err := typed.TypedColCollectionSubtraction(mergeSch, ancSch)
+	// the deleting side has no schema of its own, so its rows are given the ancestor's
+	asr, err := ancTbl.GetSchemaRef()
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// check for name collisions
-	err = sub.Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
-		ln := strings.ToLower(col.Name)
-		if mergeCol, found := mergeSub.LowerNameToCol[ln]; found {
-			if !col.Equals(mergeCol) {
-				return true, fmt.Errorf("name collision during merge for colummn %s, %v %v", ln, col, mergeCol)
-			}
-		}
-		return false, nil
-	})
+	sr, msr := asr, asr
+	if tbl != nil {
+		sr, err = tbl.GetSchemaRef()
+	} else {
+		msr, err = mergeTbl.GetSchemaRef()
+	}
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// order of args here is important for correct column ordering in merged schema
-	// to be before any column in the intersection
-	// TODO: column ordering will break if a column added on sub or merge was reordered
-	union, err := typed.TypedColCollUnion(intersection, sub, mergeSub)
+	conflictedTbl, err := modTbl.SetConflicts(ctx, doltdb.NewConflict(asr, sr, msr), conflicts)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if merger.buildConflictsTables && stats.Conflicts > 0 {
+		sch, err := conflictedTbl.GetSchema(ctx)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !schema.IsKeyless(sch) {
+			stats.ConflictsTable, err = NewConflictsTable(ctx, merger.vrw, conflictedTbl)
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
-	return schema.SchemaFromCols(union), nil
+	return conflictedTbl, stats, nil
 }
 
-func mergeTableData(ctx context.Context, sch schema.Schema, rows, mergeRows, ancRows types.Map, vrw types.ValueReadWriter) (types.Map, types.Map, *MergeStats, error) {
-	//changeChan1, changeChan2 := make(chan diff.Difference, 32), make(chan diff.Difference, 32)
-	ae := atomicerr.New()
-	changeChan, mergeChangeChan := make(chan types.ValueChanged, 32), make(chan types.ValueChanged, 32)
-	stopChan, mergeStopChan := make(chan struct{}, 1), make(chan struct{}, 1)
+// UniqueViolationError is returned by MergeTable when two rows of the merged table have the same value for a unique
+// column, as when each side inserted a row with that value.
+type UniqueViolationError struct {
+	Column string
+	Tag    uint64
+	Value  types.Value
+}
 
-	go func() {
-		//diff.Diff(rows1, ancRows, changeChan1, stopChan1, true, dontDescend)
-		rows.Diff(ctx, ancRows, ae, changeChan, stopChan)
-		close(changeChan)
-	}()
+func (e *UniqueViolationError) Error() string {
+	return fmt.Sprintf("%s: column %s (tag %d)", ErrUniqueViolation.Error(), e.Column, e.Tag)
+}
 
-	go func() {
-		//diff.Diff(rows2, ancRows, changeChan2, stopChan2, true, dontDescend)
-		mergeRows.Diff(ctx, ancRows, ae, mergeChangeChan, mergeStopChan)
-		close(mergeChangeChan)
-	}()
+// Is reports whether |target| is ErrUniqueViolation.
+func (e *UniqueViolationError) Is(target error) bool {
+	return target == ErrUniqueViolation
+}
 
-	defer stopAndDrain(stopChan, changeChan)
-	defer stopAndDrain(mergeStopChan, mergeChangeChan)
+// checkUniqueConstraints returns a *UniqueViolationError if two of |rows| have the same non-null value for a column of
+// |sch| with a schema.UniqueConstraint. Every row is read when |sch| has such a column.
+func checkUniqueConstraints(ctx context.Context, nbf *types.NomsBinFormat, sch schema.Schema, rows types.Map) error {
+	var uniqueCols []schema.Column
+	for _, col := range sch.GetNonPKCols().GetColumns() {
+		if col.IsUnique() {
+			uniqueCols = append(uniqueCols, col)
+		}
+	}
 
-	conflictValChan := make(chan types.Value)
-	conflictMapChan := types.NewStreamingMap(ctx, vrw, ae, conflictValChan)
-	mapEditor := rows.Edit()
-	stats := &MergeStats{Operation: TableModified}
+	if len(uniqueCols) == 0 {
+		return nil
+	}
 
-	f := func() error {
-		defer close(conflictValChan)
+	seen := make([]hash.HashSet, len(uniqueCols))
+	for i := range seen {
+		seen[i] = make(hash.HashSet)
+	}
 
-		var change, mergeChange types.ValueChanged
-		for {
-			if ae.IsSet() {
-				break
-			}
+	return rows.IterAll(ctx, func(key, value types.Value) error {
+		r, err := row.FromNoms(sch, key.(types.Tuple), value.(types.Tuple))
 
-			// Get the next change from both a and b. If either diff(a, parent) or diff(b, parent) is complete, aChange or bChange will get an empty types.ValueChanged containing a nil Value. Generally, though, this allows us to proceed through both diffs in (key) order, considering the "current" change from both diffs at the same time.
-			if change.Key == nil {
-				change = <-changeChan
-			}
-			if mergeChange.Key == nil {
-				mergeChange = <-mergeChangeChan
+		if err != nil {
+			return err
+		}
+
+		for i, col := range uniqueCols {
+			val, ok := r.GetColVal(col.Tag)
+
+			if !ok || types.IsNull(val) {
+				continue
 			}
 
-			key, mergeKey := change.Key, mergeChange.Key
+			h, err := val.Hash(nbf)
 
-			// Both channels are producing zero values, so we're done.
-			if key == nil && mergeKey == nil {
-				break
+			if err != nil {
+				return err
 			}
 
-			var err error
-			var processed bool
-			if key != nil {
+			if seen[i].Has(h) {
+				return &UniqueViolationError{Column: col.Name, Tag: col.Tag, Value: val}
+			}
+
+			seen[i].Insert(h)
+		}
+
+		return nil
+	})
+}
+
+// emptyAncestorTable returns an empty table with the schema of |tbl| and |mergeTbl|, which were both added since
+// their merge base, to use as that base. It is an ErrSameTblAddedTwice for their schemas to differ.
+func emptyAncestorTable(ctx context.Context, vrw types.ValueReadWriter, tbl, mergeTbl *doltdb.Table) (*doltdb.Table, error) {
+	sch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mergeSch, err := mergeTbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	eq, err := schema.SchemasAreEqual(sch, mergeSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !eq {
+		return nil, ErrSameTblAddedTwice
+	}
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := types.NewMap(ctx, vrw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doltdb.NewTable(ctx, vrw, schVal, rows)
+}
+
+func stopAndDrain(stop chan<- struct{}, drain <-chan types.ValueChanged) {
+	close(stop)
+	for range drain {
+	}
+}
+
+func mergeTableSchema(sch, mergeSch, ancSch schema.Schema) (schema.Schema, error) {
+	// (sch - ancSch) ∪ (mergeSch - ancSch) ∪ (sch ∩ mergeSch)
+
+	keyless := schema.IsKeyless(ancSch)
+	if schema.IsKeyless(sch) != keyless || schema.IsKeyless(mergeSch) != keyless {
+		return nil, ErrKeylessSchemaChange
+	}
+
+	// columns remaining on both branches since the common ancestor
+	intersection, err := typed.TypedColCollectionIntersection(sch, mergeSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	intersection, err = mergeColumnTypes(intersection, mergeSch, ancSch, keyless)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// columns added on the main branch since the common ancestor
+	sub, err := typed.TypedColCollectionSubtraction(sch, ancSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// columns added on the merge branch since the common ancestor
+	mergeSub, err := typed.TypedColCollectionSubtraction(mergeSch, ancSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// check for name collisions
+	err = sub.Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		ln := strings.ToLower(col.Name)
+		if mergeCol, found := mergeSub.LowerNameToCol[ln]; found {
+			if !col.Equals(mergeCol) {
+				return true, fmt.Errorf("name collision during merge for colummn %s, %v %v", ln, col, mergeCol)
+			}
+		}
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// order of args here is important for correct column ordering in merged schema
+	// to be before any column in the intersection
+	// TODO: column ordering will break if a column added on sub or merge was reordered
+	union, err := typed.TypedColCollUnion(intersection, sub, mergeSub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var merged schema.Schema
+	if keyless {
+		merged = schema.KeylessSchemaFromCols(union)
+	} else {
+		merged = schema.SchemaFromCols(union)
+	}
+
+	virtualCols, err := mergeVirtualCols(sch, mergeSch, ancSch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.SchemaWithVirtualCols(merged, virtualCols)
+}
+
+// mergeVirtualCols merges the virtual columns of |sch| and |mergeSch| by tag, as mergeTableSchema does their stored
+// columns. A virtual column changed on only one side since |ancSch| takes that side's definition. One changed
+// differently on both sides is a conflict.
+func mergeVirtualCols(sch, mergeSch, ancSch schema.Schema) (*schema.ColCollection, error) {
+	var cols []schema.Column
+	err := sch.VirtualCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		mergeCol, inMerge := mergeSch.VirtualCols().GetByTag(tag)
+		ancCol, inAnc := ancSch.VirtualCols().GetByTag(tag)
+
+		switch {
+		case inMerge && col.Equals(mergeCol):
+			cols = append(cols, col)
+		case inMerge && inAnc && col.Equals(ancCol):
+			cols = append(cols, mergeCol)
+		case inMerge && inAnc && mergeCol.Equals(ancCol):
+			cols = append(cols, col)
+		case inMerge:
+			return true, fmt.Errorf("conflict during merge for virtual column %s, %v %v", col.Name, col, mergeCol)
+		case !inAnc:
+			// added on the main branch since the common ancestor
+			cols = append(cols, col)
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = mergeSch.VirtualCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		_, inSch := sch.VirtualCols().GetByTag(tag)
+		_, inAnc := ancSch.VirtualCols().GetByTag(tag)
+
+		// added on the merge branch since the common ancestor
+		if !inSch && !inAnc {
+			cols = append(cols, col)
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cols) == 0 {
+		return schema.EmptyColColl, nil
+	}
+
+	return schema.NewColCollection(cols...)
+}
+
+// mergeColumnTypes resolves the types of the columns in |intersection|, which come from our side of the merge, with
+// those of the same columns in |mergeSch|. A column whose type was widened, as reported by Column.CanWidenTo, on
+// one or both sides takes the widest type. Any other change to a column's type is a conflict. The columns' comments
+// are merged by mergeColumnComment.
+func mergeColumnTypes(intersection *schema.ColCollection, mergeSch, ancSch schema.Schema, keyless bool) (*schema.ColCollection, error) {
+	var cols []schema.Column
+	err := intersection.Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		mergeCol, _ := mergeSch.GetAllCols().GetByTag(tag)
+		col.Comment = mergeColumnComment(col, mergeCol, ancSch)
+
+		if col.TypeInfo.Equals(mergeCol.TypeInfo) {
+			cols = append(cols, col)
+			return false, nil
+		}
+
+		ti, ok := mergeColumnType(col, mergeCol, ancSch, keyless)
+
+		if !ok {
+			return true, fmt.Errorf("type conflict during merge for column %s, %v %v", col.Name, col.TypeInfo, mergeCol.TypeInfo)
+		}
+
+		col.TypeInfo = ti
+		col.Kind = ti.NomsKind()
+		cols = append(cols, col)
+
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cols) == 0 {
+		return schema.EmptyColColl, nil
+	}
+
+	return schema.NewColCollection(cols...)
+}
+
+// mergeColumnComment returns the comment of a column in the merged schema. Comments don't conflict: a comment changed
+// only on their side is taken from their side, and otherwise ours is kept.
+func mergeColumnComment(col, mergeCol schema.Column, ancSch schema.Schema) string {
+	ancCol, ok := ancSch.GetAllCols().GetByTag(col.Tag)
+
+	if ok && col.Comment == ancCol.Comment {
+		return mergeCol.Comment
+	}
+
+	return col.Comment
+}
+
+// mergeColumnType returns the type of a column whose type is different in |col| and |mergeCol|, as described by
+// mergeColumnTypes, or false if the types can't be merged.
+func mergeColumnType(col, mergeCol schema.Column, ancSch schema.Schema, keyless bool) (typeinfo.TypeInfo, bool) {
+	if ancCol, ok := ancSch.GetAllCols().GetByTag(col.Tag); ok {
+		if !ancCol.CanWidenTo(col) || !ancCol.CanWidenTo(mergeCol) {
+			return nil, false
+		}
+	}
+
+	ti := mergeCol.TypeInfo
+	if mergeCol.CanWidenTo(col) {
+		ti = col.TypeInfo
+	} else if !col.CanWidenTo(mergeCol) {
+		return nil, false
+	}
+
+	// key values can't be converted without rewriting the keys of the table
+	if (col.IsPartOfPK || keyless) && (col.Kind != ti.NomsKind() || mergeCol.Kind != ti.NomsKind()) {
+		return nil, false
+	}
+
+	return ti, true
+}
+
+// widenRowData converts the values in |rows|, whose schema is |sch|, of each column whose type was widened in
+// |mergedSch|.
+func widenRowData(ctx context.Context, rows types.Map, sch, mergedSch schema.Schema) (types.Map, error) {
+	widened := make(map[uint64][2]typeinfo.TypeInfo)
+	err := sch.GetNonPKCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		if mergedCol, ok := mergedSch.GetNonPKCols().GetByTag(tag); ok && col.Kind != mergedCol.Kind {
+			widened[tag] = [2]typeinfo.TypeInfo{col.TypeInfo, mergedCol.TypeInfo}
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return types.EmptyMap, err
+	}
+
+	if len(widened) == 0 {
+		return rows, nil
+	}
+
+	me := rows.Edit()
+	err = rows.IterAll(ctx, func(key, value types.Value) error {
+		vals, err := row.ParseTaggedValues(value.(types.Tuple))
+
+		if err != nil {
+			return err
+		}
+
+		tags := make([]uint64, 0, len(vals))
+		for tag, val := range vals {
+			tags = append(tags, tag)
+
+			if tis, ok := widened[tag]; ok {
+				vals[tag], err = typeinfo.WidenValue(val, tis[0], tis[1])
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+		v, err := vals.NomsTupleForTags(rows.Format(), tags, false).Value(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		me.Set(key, v)
+		return nil
+	})
+
+	if err != nil {
+		return types.EmptyMap, err
+	}
+
+	return me.Map(ctx)
+}
+
+func mergeTableData(ctx context.Context, sch schema.Schema, rows, mergeRows, ancRows types.Map, vrw types.ValueReadWriter, tb Tiebreaker) (types.Map, types.Map, *MergeStats, error) {
+	//changeChan1, changeChan2 := make(chan diff.Difference, 32), make(chan diff.Difference, 32)
+	ae := atomicerr.New()
+	changeChan, mergeChangeChan := make(chan types.ValueChanged, 32), make(chan types.ValueChanged, 32)
+	stopChan, mergeStopChan := make(chan struct{}, 1), make(chan struct{}, 1)
+
+	go func() {
+		//diff.Diff(rows1, ancRows, changeChan1, stopChan1, true, dontDescend)
+		rows.Diff(ctx, ancRows, ae, changeChan, stopChan)
+		close(changeChan)
+	}()
+
+	go func() {
+		//diff.Diff(rows2, ancRows, changeChan2, stopChan2, true, dontDescend)
+		mergeRows.Diff(ctx, ancRows, ae, mergeChangeChan, mergeStopChan)
+		close(mergeChangeChan)
+	}()
+
+	defer stopAndDrain(stopChan, changeChan)
+	defer stopAndDrain(mergeStopChan, mergeChangeChan)
+
+	conflictValChan := make(chan types.Value)
+	conflictMapChan := types.NewStreamingMap(ctx, vrw, ae, conflictValChan)
+	mapEditor := rows.Edit()
+	stats := &MergeStats{Operation: TableModified}
+
+	f := func() error {
+		defer close(conflictValChan)
+
+		var change, mergeChange types.ValueChanged
+		for {
+			if ae.IsSet() {
+				break
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// Get the next change from both a and b. If either diff(a, parent) or diff(b, parent) is complete, aChange or bChange will get an empty types.ValueChanged containing a nil Value. Generally, though, this allows us to proceed through both diffs in (key) order, considering the "current" change from both diffs at the same time.
+			if change.Key == nil {
+				change = <-changeChan
+			}
+			if mergeChange.Key == nil {
+				mergeChange = <-mergeChangeChan
+			}
+
+			key, mergeKey := change.Key, mergeChange.Key
+
+			// Both channels are producing zero values, so we're done.
+			if key == nil && mergeKey == nil {
+				break
+			}
+
+			var err error
+			var processed bool
+			if key != nil {
 				mkNilOrKeyLess := mergeKey == nil
 				if !mkNilOrKeyLess {
 					mkNilOrKeyLess, err = key.Less(vrw.Format(), mergeKey)
@@ -339,245 +967,986 @@ func mergeTableData(ctx context.Context, sch schema.Schema, rows, mergeRows, anc
 					}
 				}
 
-				if keyNilOrMKLess {
-					applyChange(mapEditor, stats, mergeChange)
-					mergeChange = types.ValueChanged{}
-					processed = true
-				}
+				if keyNilOrMKLess {
+					applyChange(mapEditor, stats, mergeChange)
+					mergeChange = types.ValueChanged{}
+					processed = true
+				}
+			}
+
+			if !processed {
+				r, mergeRow, ancRow := change.NewValue, mergeChange.NewValue, change.OldValue
+				mergedRow, isConflict, err := rowMerge(ctx, vrw.Format(), sch, r, mergeRow, ancRow)
+
+				if err != nil {
+					return err
+				}
+
+				if isConflict && tb != nil {
+					mergedRow, err = breakTie(ctx, sch, tb, key.(types.Tuple), r, mergeRow)
+
+					if err != nil {
+						return err
+					}
+
+					if tbChange, ok := tiebreakChange(key, r, mergedRow); ok {
+						applyChange(mapEditor, stats, tbChange)
+					}
+				} else if isConflict {
+					stats.Conflicts++
+
+					if ancRow == nil && r != nil && mergeRow != nil {
+						stats.PrimaryKeyInsertConflicts++
+					}
+
+					conflictTuple, err := doltdb.NewConflict(ancRow, r, mergeRow).ToNomsList(vrw)
+
+					if err != nil {
+						return err
+					}
+
+					addConflict(conflictValChan, key, conflictTuple)
+				} else {
+					applyChange(mapEditor, stats, types.ValueChanged{ChangeType: change.ChangeType, Key: key, OldValue: r, NewValue: mergedRow})
+				}
+
+				change = types.ValueChanged{}
+				mergeChange = types.ValueChanged{}
+			}
+		}
+
+		return nil
+	}
+
+	err := f()
+
+	if err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	if err := ae.Get(); err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	// a cancelled diff ends early, so the merge is incomplete even though no error was seen
+	if err := ctx.Err(); err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	conflicts := <-conflictMapChan
+	mergedData, err := mapEditor.Map(ctx)
+
+	if err != nil {
+		return types.EmptyMap, types.EmptyMap, nil, err
+	}
+
+	return mergedData, conflicts, stats, nil
+}
+
+func addConflict(conflictChan chan types.Value, key types.Value, value types.Tuple) {
+	conflictChan <- key
+	conflictChan <- value
+}
+
+func applyChange(me *types.MapEditor, stats *MergeStats, change types.ValueChanged) {
+	switch change.ChangeType {
+	case types.DiffChangeAdded:
+		stats.Adds++
+		me.Set(change.Key, change.NewValue)
+	case types.DiffChangeModified:
+		stats.Modifications++
+		me.Set(change.Key, change.NewValue)
+	case types.DiffChangeRemoved:
+		stats.Deletes++
+		me.Remove(change.Key)
+	}
+}
+
+// breakTie returns the value of the row |tb| picks from the conflicting rows |r| and |mergeRow| with key |key|. A
+// nil row or value is a deleted row.
+func breakTie(ctx context.Context, sch schema.Schema, tb Tiebreaker, key types.Tuple, r, mergeRow types.Value) (types.Value, error) {
+	toRow := func(v types.Value) (row.Row, error) {
+		if v == nil {
+			return nil, nil
+		}
+
+		return row.FromNoms(sch, key, v.(types.Tuple))
+	}
+
+	ours, err := toRow(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	theirs, err := toRow(mergeRow)
+
+	if err != nil {
+		return nil, err
+	}
+
+	picked := tb(ours, theirs)
+
+	if picked == nil {
+		return nil, nil
+	}
+
+	return picked.NomsMapValue(sch).Value(ctx)
+}
+
+// transformTable returns the merged table |tbl|, named |tblName|, with |rt| applied to each of its rows. The rows of
+// keyless tables are not transformed.
+func transformTable(ctx context.Context, tblName string, tbl *doltdb.Table, rt RowTransform) (*doltdb.Table, error) {
+	sch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if schema.IsKeyless(sch) {
+		return tbl, nil
+	}
+
+	rows, err := tbl.GetRowData(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transform := newRowTransformer(tblName, sch, rt)
+	me := rows.Edit()
+	err = rows.Iter(ctx, func(key, value types.Value) (stop bool, err error) {
+		transformed, err := transform(ctx, key.(types.Tuple), value)
+
+		if err != nil {
+			return true, err
+		}
+
+		if !transformed.Equals(value) {
+			me.Set(key, transformed)
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	transformedRows, err := me.Map(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl.UpdateRows(ctx, transformedRows)
+}
+
+// rowTransformer returns the value of the row with key |key| and value |v| after a RowTransform.
+type rowTransformer func(ctx context.Context, key types.Tuple, v types.Value) (types.Value, error)
+
+// newRowTransformer returns a rowTransformer applying |rt| to the rows of the table |tblName| with schema |sch|, or
+// nil if |rt| is nil. Its errors name the table and the row's key.
+func newRowTransformer(tblName string, sch schema.Schema, rt RowTransform) rowTransformer {
+	if rt == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, key types.Tuple, v types.Value) (types.Value, error) {
+		transformed, err := transformRow(ctx, sch, rt, tblName, key, v)
+
+		if err != nil {
+			keyStr, encErr := types.EncodedValue(ctx, key)
+
+			if encErr != nil {
+				return nil, encErr
+			}
+
+			return nil, fmt.Errorf("table %s, key %s: %w", tblName, keyStr, err)
+		}
+
+		return transformed, nil
+	}
+}
+
+func transformRow(ctx context.Context, sch schema.Schema, rt RowTransform, tblName string, key types.Tuple, v types.Value) (types.Value, error) {
+	r, err := row.FromNoms(sch, key, v.(types.Tuple))
+
+	if err != nil {
+		return nil, err
+	}
+
+	transformed, err := rt(tblName, sch, r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if transformed == nil {
+		return nil, ErrRowTransformNilRow
+	}
+
+	newKey, err := transformed.NomsMapKey(sch).Value(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !newKey.Equals(key) {
+		return nil, ErrRowTransformChangedKey
+	}
+
+	return transformed.NomsMapValue(sch).Value(ctx)
+}
+
+// tiebreakChange returns the change to our row |r| with key |key| which makes it |mergedRow|, and false if there is
+// no change.
+func tiebreakChange(key, r, mergedRow types.Value) (types.ValueChanged, bool) {
+	switch {
+	case r == nil && mergedRow == nil:
+		return types.ValueChanged{}, false
+	case r == nil:
+		return types.ValueChanged{ChangeType: types.DiffChangeAdded, Key: key, NewValue: mergedRow}, true
+	case mergedRow == nil:
+		return types.ValueChanged{ChangeType: types.DiffChangeRemoved, Key: key, OldValue: r}, true
+	case r.Equals(mergedRow):
+		return types.ValueChanged{}, false
+	default:
+		return types.ValueChanged{ChangeType: types.DiffChangeModified, Key: key, OldValue: r, NewValue: mergedRow}, true
+	}
+}
+
+func rowMerge(ctx context.Context, nbf *types.NomsBinFormat, sch schema.Schema, r, mergeRow, baseRow types.Value) (types.Value, bool, error) {
+	var baseVals row.TaggedValues
+	if baseRow == nil {
+		if r.Equals(mergeRow) {
+			// same row added to both
+			return r, false, nil
+		}
+
+		// different rows added with the same primary key. There is no base to merge columns against.
+		return nil, true, nil
+	} else if r == nil && mergeRow == nil {
+		// same row removed from both
+		return nil, false, nil
+	} else if r == nil || mergeRow == nil {
+		// removed from one and modified in another
+		return nil, true, nil
+	} else {
+		var err error
+		baseVals, err = row.ParseTaggedValues(baseRow.(types.Tuple))
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	rowVals, err := row.ParseTaggedValues(r.(types.Tuple))
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	mergeVals, err := row.ParseTaggedValues(mergeRow.(types.Tuple))
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	processTagFunc := func(tag uint64) (resultVal types.Value, isConflict bool) {
+		baseVal, _ := baseVals.Get(tag)
+		val, _ := rowVals.Get(tag)
+		mergeVal, _ := mergeVals.Get(tag)
+
+		if valutil.NilSafeEqCheck(val, mergeVal) {
+			return val, false
+		} else {
+			modified := !valutil.NilSafeEqCheck(val, baseVal)
+			mergeModified := !valutil.NilSafeEqCheck(mergeVal, baseVal)
+			switch {
+			case modified && mergeModified:
+				return nil, true
+			case modified:
+				return val, false
+			default:
+				return mergeVal, false
+			}
+		}
+
+	}
+
+	resultVals := make(row.TaggedValues)
+
+	var isConflict bool
+	err = sch.GetNonPKCols().Iter(func(tag uint64, _ schema.Column) (stop bool, err error) {
+		var val types.Value
+		val, isConflict = processTagFunc(tag)
+		resultVals[tag] = val
+
+		return isConflict, nil
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if isConflict {
+		return nil, true, nil
+	}
+
+	tpl := resultVals.NomsTupleForTags(nbf, sch.GetNonPKCols().SortedTags, false)
+	v, err := tpl.Value(ctx)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return v, false, nil
+}
+
+// MergeOptions configure MergeCommitsWithOptions.
+type MergeOptions struct {
+	// IsolateTables merges each table independently, as described by MergeCommitsIsolatingTables.
+	IsolateTables bool
+
+	// CaseInsensitiveTableNames merges tables whose names differ only in case as one table, as SQL resolves them.
+	// When one side changed the case of a table's name since the merge base, the merged table takes that side's
+	// name. It is an ErrTableNameCaseConflict for both sides to change it differently, or for a root to have several
+	// tables whose names differ only in case.
+	CaseInsensitiveTableNames bool
+
+	// Tiebreaker resolves the rows of keyed tables which were changed differently on both sides, such as a primary
+	// key inserted on both sides with different values, instead of recording them as conflicts. When it is nil,
+	// those rows conflict and the merged table keeps our row.
+	Tiebreaker Tiebreaker
+
+	// RowTransform is applied to each row of every keyed table a merge outputs, whether the row was merged, picked by
+	// the Tiebreaker, kept from one side or left as it was, and whether or not the table was changed on both sides.
+	// Rows left in conflict are transformed as they are in the merged table, but their conflicts are not. Rows the
+	// transform changes are not counted in MergeStats. When it is nil, rows are written as merged.
+	RowTransform RowTransform
+
+	// BuildConflictsTables stores a conflicts table, as described by NewConflictsTable, in the merged root for every
+	// keyed table which merges with conflicts, under doltdb.ConflictsTableName. It replaces any conflicts table of an
+	// earlier merge, and is also returned in the table's MergeStats.ConflictsTable.
+	BuildConflictsTables bool
+
+	// StreamTables writes the root being merged into after each table is merged, and flushes it when the
+	// ValueReadWriter can be flushed, so that each merged table can be released before the next is merged. It bounds
+	// the memory a merge needs to that of merging a single table, at the cost of a write per table. The merged root is
+	// the same as without it.
+	StreamTables bool
+}
+
+// Tiebreaker picks the merged row from our and their versions of a row which was changed differently on both sides
+// of a merge. A nil row is a deleted row: |ours| or |theirs| is nil when that side deleted the row, and returning nil
+// deletes it from the merged table.
+type Tiebreaker func(ours, theirs row.Row) row.Row
+
+// RowTransform returns the row to write in place of |r|, a row of the table |table| with schema |sch|, such as |r| with
+// a column normalized. It must not change the row's primary key or return a nil row. An error aborts the merge, and is
+// returned wrapped with the table's name and the row's key.
+type RowTransform func(table string, sch schema.Schema, r row.Row) (row.Row, error)
+
+// MergeCommits merges every table in |mergeCommit| into |commit|. If any table fails to merge, the whole merge fails.
+// The merge stops with the context's error if |ctx| is cancelled, and no partially merged root is returned.
+func MergeCommits(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	return MergeCommitsWithOptions(ctx, ddb, commit, mergeCommit, MergeOptions{})
+}
+
+// MergeCommitsIsolatingTables is like MergeCommits, but merges each table independently. A table which fails to merge
+// does not fail the merge. Instead its MergeStats carries the error in Err, and the table is left unchanged in the
+// returned root.
+func MergeCommitsIsolatingTables(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	return MergeCommitsWithOptions(ctx, ddb, commit, mergeCommit, MergeOptions{IsolateTables: true})
+}
+
+// MergeCommitsWithOptions merges every table in |mergeCommit| into |commit| as configured by |opts|.
+func MergeCommitsWithOptions(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit, opts MergeOptions) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	return mergeCommits(ctx, ddb, commit, mergeCommit, opts)
+}
+
+// MergeCommitsWithBase is like MergeCommits, but merges the changes made since |base| rather than since the merge
+// base of the commits, which saves walking their history when the caller already knows it, as when merging several
+// commits which share a base. |base| must be an ancestor of both commits, or one of them, and ErrNotMergeBase is
+// returned otherwise. A base older than the commits' merge base makes changes merged on both sides since then look
+// like conflicts.
+func MergeCommitsWithBase(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit, base *doltdb.Commit) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	for _, cm := range []*doltdb.Commit{commit, mergeCommit} {
+		ok, err := isAncestor(ctx, base, cm)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			return nil, nil, ErrNotMergeBase
+		}
+	}
+
+	ancRoot, err := base.GetRootValue()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergeCommitRoots(ctx, ddb, commit, mergeCommit, ancRoot, MergeOptions{})
+}
+
+// isAncestor returns whether |ancestor| is |cm| or one of its ancestors. Commits are walked tallest first, so no
+// commit shorter than |ancestor| is visited.
+func isAncestor(ctx context.Context, ancestor, cm *doltdb.Commit) (bool, error) {
+	h, err := ancestor.HashOf()
+
+	if err != nil {
+		return false, err
+	}
+
+	height, err := ancestor.Height()
+
+	if err != nil {
+		return false, err
+	}
+
+	var found bool
+	err = doltdb.WalkCommitsInOrder(ctx, cm, doltdb.TopologicalWalkOrder, func(visited *doltdb.Commit) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		visitedHeight, err := visited.Height()
+
+		if err != nil {
+			return false, err
+		}
+
+		if visitedHeight < height {
+			return false, nil
+		}
+
+		visitedHash, err := visited.HashOf()
+
+		if err != nil {
+			return false, err
+		}
+
+		found = visitedHash == h
+		return !found, nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// EstimateMergeConflicts returns the number of tables which might conflict when merging |mergeCommit| into |commit|.
+// It only compares table hashes, counting the tables which were changed differently on both sides since a merge
+// base, so it is an upper bound. Tables changed on both sides often still merge cleanly.
+func EstimateMergeConflicts(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (int, error) {
+	bases, err := doltdb.GetMergeBases(ctx, commit, mergeCommit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	root, err := commit.GetRootValue()
+
+	if err != nil {
+		return 0, err
+	}
+
+	mergeRoot, err := mergeCommit.GetRootValue()
+
+	if err != nil {
+		return 0, err
+	}
+
+	ancRoots := make([]*doltdb.RootValue, len(bases))
+	for i, base := range bases {
+		ancRoots[i], err = base.GetRootValue()
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	tblNames, err := doltdb.UnionTableNames(ctx, root, mergeRoot)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, tblName := range tblNames {
+		// a missing table has the empty hash
+		h, _, err := root.GetTableHash(ctx, tblName)
+
+		if err != nil {
+			return 0, err
+		}
+
+		mh, _, err := mergeRoot.GetTableHash(ctx, tblName)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if h == mh {
+			continue
+		}
+
+		for _, ancRoot := range ancRoots {
+			anch, _, err := ancRoot.GetTableHash(ctx, tblName)
+
+			if err != nil {
+				return 0, err
+			}
+
+			if h != anch && mh != anch {
+				count++
+				break
+			}
+		}
+	}
+
+	return count, nil
+}
+
+func mergeCommits(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit, opts MergeOptions) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	ancRoot, err := mergeBaseRoot(ctx, ddb, commit, mergeCommit)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergeCommitRoots(ctx, ddb, commit, mergeCommit, ancRoot, opts)
+}
+
+func mergeCommitRoots(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit, ancRoot *doltdb.RootValue, opts MergeOptions) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	root, err := commit.GetRootValue()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeRoot, err := mergeCommit.GetRootValue()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergeRoots(ctx, ddb.ValueReadWriter(), root, mergeRoot, ancRoot, opts)
+}
+
+// mergeBaseRoot returns the root to use as the base of a three-way merge of |commit| and |mergeCommit|. When the
+// commits have a single lowest common ancestor, that is the ancestor's root. In a criss-cross history they have
+// several, and picking any one of them would make changes already merged on both sides look like conflicts. Instead
+// the merge bases are merged with each other, recursively using their own merge bases, into a virtual base which is
+// never committed. Rows which conflict within the virtual base keep the value of the taller merge base.
+func mergeBaseRoot(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (*doltdb.RootValue, error) {
+	bases, err := doltdb.GetMergeBases(ctx, commit, mergeCommit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	virtualRoot, err := bases[0].GetRootValue()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, base := range bases[1:] {
+		baseRoot, err := base.GetRootValue()
+
+		if err != nil {
+			return nil, err
+		}
+
+		ancRoot, err := mergeBaseRoot(ctx, ddb, bases[0], base)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var tblToStats map[string]*MergeStats
+		virtualRoot, tblToStats, err = mergeRoots(ctx, ddb.ValueReadWriter(), virtualRoot, baseRoot, ancRoot, MergeOptions{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for tblName, stats := range tblToStats {
+			if stats.Conflicts == 0 && stats.TableConflict == NoTableConflict {
+				continue
+			}
+
+			tbl, _, err := virtualRoot.GetTable(ctx, tblName)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tbl, err = tbl.ClearConflicts()
+
+			if err != nil {
+				return nil, err
+			}
+
+			virtualRoot, err = virtualRoot.PutTable(ctx, tblName, tbl)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return virtualRoot, nil
+}
+
+func mergeRoots(ctx context.Context, vrw types.ValueReadWriter, root, mergeRoot, ancRoot *doltdb.RootValue, opts MergeOptions) (*doltdb.RootValue, map[string]*MergeStats, error) {
+	merger := NewMerger(ctx, root, mergeRoot, ancRoot, vrw)
+	merger.UseTiebreaker(opts.Tiebreaker)
+	merger.UseRowTransform(opts.RowTransform)
+
+	if opts.BuildConflictsTables {
+		merger.BuildConflictsTables()
+	}
+
+	tblNames, err := resolveMergeTableNames(ctx, root, mergeRoot, ancRoot, opts.CaseInsensitiveTableNames)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tblNames, err = pairRenamedTables(ctx, root, mergeRoot, ancRoot, tblNames)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tblToStats := make(map[string]*MergeStats)
+
+	newRoot := root
+	var unconflicted []string
+	// need to validate merges can be done on all tables before starting the actual merges.
+	for _, names := range tblNames {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		tblName := names.mergedName
+		mergedTable, stats, err := merger.mergeTableWithNames(ctx, names)
+
+		if err != nil {
+			if opts.IsolateTables && ctx.Err() == nil {
+				tblToStats[tblName] = &MergeStats{Operation: TableUnmodified, Err: err}
+				continue
+			}
+
+			return nil, nil, err
+		}
+
+		if mergedTable != nil {
+			tblToStats[tblName] = stats
+
+			if stats.Conflicts == 0 && stats.TableConflict == NoTableConflict {
+				unconflicted = append(unconflicted, tblName)
 			}
 
-			if !processed {
-				r, mergeRow, ancRow := change.NewValue, mergeChange.NewValue, change.OldValue
-				mergedRow, isConflict, err := rowMerge(ctx, vrw.Format(), sch, r, mergeRow, ancRow)
+			var err error
+			if names.name != "" && names.name != tblName {
+				newRoot, err = newRoot.RemoveTables(ctx, names.name)
 
 				if err != nil {
-					return err
+					return nil, nil, err
 				}
+			}
 
-				if isConflict {
-					stats.Conflicts++
-					conflictTuple, err := doltdb.NewConflict(ancRow, r, mergeRow).ToNomsList(vrw)
+			newRoot, err = newRoot.PutTable(ctx, tblName, mergedTable)
 
-					if err != nil {
-						return err
-					}
+			if err != nil {
+				return nil, nil, err
+			}
 
-					addConflict(conflictValChan, key, conflictTuple)
-				} else {
-					applyChange(mapEditor, stats, types.ValueChanged{ChangeType: change.ChangeType, Key: key, OldValue: r, NewValue: mergedRow})
-				}
+			if stats.ConflictsTable != nil {
+				newRoot, err = newRoot.PutTable(ctx, doltdb.ConflictsTableName(tblName), stats.ConflictsTable)
 
-				change = types.ValueChanged{}
-				mergeChange = types.ValueChanged{}
+				if err != nil {
+					return nil, nil, err
+				}
 			}
-		}
 
-		return nil
-	}
-
-	err := f()
+			if opts.StreamTables {
+				newRoot, err = persistRoot(ctx, vrw, newRoot)
 
-	if err != nil {
-		return types.EmptyMap, types.EmptyMap, nil, err
-	}
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		} else if has, err := newRoot.HasTable(ctx, names.name); err != nil {
+			return nil, nil, err
+		} else if has {
+			stats.Operation = TableRemoved
+			tblToStats[tblName] = stats
+			newRoot, err = newRoot.RemoveTables(ctx, names.name)
 
-	if err := ae.Get(); err != nil {
-		return types.EmptyMap, types.EmptyMap, nil, err
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			// we deleted the table and they left it unchanged, so it stays deleted
+			tblToStats[tblName] = stats
+		}
 	}
 
-	conflicts := <-conflictMapChan
-	mergedData, err := mapEditor.Map(ctx)
+	newRoot, err = newRoot.UpdateSuperSchemasFromOther(ctx, unconflicted, mergeRoot)
 
 	if err != nil {
-		return types.EmptyMap, types.EmptyMap, nil, err
+		return nil, nil, err
 	}
 
-	return mergedData, conflicts, stats, nil
+	return newRoot, tblToStats, nil
 }
 
-func addConflict(conflictChan chan types.Value, key types.Value, value types.Tuple) {
-	conflictChan <- key
-	conflictChan <- value
-}
+// persistRoot writes |root| to |vrw|, flushing it when |vrw| can be flushed, and returns it read back, so that it no
+// longer holds the values it was built from.
+func persistRoot(ctx context.Context, vrw types.ValueReadWriter, root *doltdb.RootValue) (*doltdb.RootValue, error) {
+	h, err := doltdb.StoreRootValue(ctx, root)
 
-func applyChange(me *types.MapEditor, stats *MergeStats, change types.ValueChanged) {
-	switch change.ChangeType {
-	case types.DiffChangeAdded:
-		stats.Adds++
-		me.Set(change.Key, change.NewValue)
-	case types.DiffChangeModified:
-		stats.Modifications++
-		me.Set(change.Key, change.NewValue)
-	case types.DiffChangeRemoved:
-		stats.Deletes++
-		me.Remove(change.Key)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func rowMerge(ctx context.Context, nbf *types.NomsBinFormat, sch schema.Schema, r, mergeRow, baseRow types.Value) (types.Value, bool, error) {
-	var baseVals row.TaggedValues
-	if baseRow == nil {
-		if r.Equals(mergeRow) {
-			// same row added to both
-			return r, false, nil
-		}
-	} else if r == nil && mergeRow == nil {
-		// same row removed from both
-		return nil, false, nil
-	} else if r == nil || mergeRow == nil {
-		// removed from one and modified in another
-		return nil, true, nil
-	} else {
-		var err error
-		baseVals, err = row.ParseTaggedValues(baseRow.(types.Tuple))
+	if f, ok := vrw.(interface{ Flush(context.Context) error }); ok {
+		err = f.Flush(ctx)
 
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 	}
 
-	rowVals, err := row.ParseTaggedValues(r.(types.Tuple))
-
-	if err != nil {
-		return nil, false, err
-	}
+	return doltdb.LoadRootValue(ctx, vrw, h)
+}
 
-	mergeVals, err := row.ParseTaggedValues(mergeRow.(types.Tuple))
+// resolveMergeTableNames returns the names of every table in |root| and |mergeRoot|. When |caseInsensitive| is set,
+// names which differ only in case are the same table, as described by MergeOptions.CaseInsensitiveTableNames.
+func resolveMergeTableNames(ctx context.Context, root, mergeRoot, ancRoot *doltdb.RootValue, caseInsensitive bool) ([]mergeTableNames, error) {
+	allNames, err := doltdb.UnionTableNames(ctx, root, mergeRoot)
 
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	processTagFunc := func(tag uint64) (resultVal types.Value, isConflict bool) {
-		baseVal, _ := baseVals.Get(tag)
-		val, _ := rowVals.Get(tag)
-		mergeVal, _ := mergeVals.Get(tag)
+	// merge tables in the same order on every run, so that stats and errors are reproducible
+	sort.Strings(allNames)
 
-		if valutil.NilSafeEqCheck(val, mergeVal) {
-			return val, false
-		} else {
-			modified := !valutil.NilSafeEqCheck(val, baseVal)
-			mergeModified := !valutil.NilSafeEqCheck(mergeVal, baseVal)
-			switch {
-			case modified && mergeModified:
-				return nil, true
-			case modified:
-				return val, false
-			default:
-				return mergeVal, false
-			}
+	if !caseInsensitive {
+		tblNames := make([]mergeTableNames, len(allNames))
+		for i, tblName := range allNames {
+			tblNames[i] = mergeTableNames{tblName, tblName, tblName, tblName}
 		}
 
+		return tblNames, nil
 	}
 
-	resultVals := make(row.TaggedValues)
+	namesByLower := func(r *doltdb.RootValue) (map[string]string, error) {
+		names, err := r.GetTableNames(ctx)
 
-	var isConflict bool
-	err = sch.GetNonPKCols().Iter(func(tag uint64, _ schema.Column) (stop bool, err error) {
-		var val types.Value
-		val, isConflict = processTagFunc(tag)
-		resultVals[tag] = val
+		if err != nil {
+			return nil, err
+		}
 
-		return isConflict, nil
-	})
+		byLower := make(map[string]string, len(names))
+		for _, name := range names {
+			lwr := strings.ToLower(name)
 
-	if err != nil {
-		return nil, false, err
-	}
+			if _, ok := byLower[lwr]; ok {
+				return nil, ErrTableNameCaseConflict
+			}
 
-	if isConflict {
-		return nil, true, nil
+			byLower[lwr] = name
+		}
+
+		return byLower, nil
 	}
 
-	tpl := resultVals.NomsTupleForTags(nbf, sch.GetNonPKCols().SortedTags, false)
-	v, err := tpl.Value(ctx)
+	byLower, err := namesByLower(root)
 
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	return v, false, nil
-}
-
-func MergeCommits(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (*doltdb.RootValue, map[string]*MergeStats, error) {
-	ancCommit, err := doltdb.GetCommitAncestor(ctx, commit, mergeCommit)
+	mergeByLower, err := namesByLower(mergeRoot)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	root, err := commit.GetRootValue()
+	ancByLower, err := namesByLower(ancRoot)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	mergeRoot, err := mergeCommit.GetRootValue()
+	var tblNames []mergeTableNames
+	seen := make(map[string]bool)
+	for _, tblName := range allNames {
+		lwr := strings.ToLower(tblName)
 
-	if err != nil {
-		return nil, nil, err
+		if seen[lwr] {
+			continue
+		}
+
+		seen[lwr] = true
+		names := mergeTableNames{name: byLower[lwr], mergeName: mergeByLower[lwr], ancName: ancByLower[lwr]}
+
+		switch {
+		case names.name == "":
+			names.mergedName = names.mergeName
+		case names.mergeName == "" || names.mergeName == names.name || names.mergeName == names.ancName:
+			names.mergedName = names.name
+		case names.name == names.ancName:
+			names.mergedName = names.mergeName
+		default:
+			return nil, ErrTableNameCaseConflict
+		}
+
+		tblNames = append(tblNames, names)
 	}
 
-	ancRoot, err := ancCommit.GetRootValue()
+	return tblNames, nil
+}
+
+// pairRenamedTables finds the tables which one side of a merge renamed since |ancRoot|, and merges each of them with
+// the table under its old name on the other side, which would otherwise look like one side dropping a table and
+// adding another, and lose the other side's changes to it. A table was renamed when one side is missing it and has a
+// table which is missing from both the merge base and the other side, and which has exactly the same column tags.
+// Tags identify columns, so such a table can only be a renamed copy. No rename is assumed when there are several.
+func pairRenamedTables(ctx context.Context, root, mergeRoot, ancRoot *doltdb.RootValue, tblNames []mergeTableNames) ([]mergeTableNames, error) {
+	tblNames, err := pairTablesRenamedOnSide(ctx, root, mergeRoot, ancRoot, tblNames, true)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	merger := NewMerger(ctx, root, mergeRoot, ancRoot, ddb.ValueReadWriter())
+	return pairTablesRenamedOnSide(ctx, mergeRoot, root, ancRoot, tblNames, false)
+}
 
-	tblNames, err := doltdb.UnionTableNames(ctx, root, mergeRoot)
+// pairTablesRenamedOnSide pairs the tables renamed in |sideRoot|, which is the root being merged into when |ours| is
+// set, and the root being merged otherwise.
+func pairTablesRenamedOnSide(ctx context.Context, sideRoot, otherRoot, ancRoot *doltdb.RootValue, tblNames []mergeTableNames, ours bool) ([]mergeTableNames, error) {
+	names := func(n mergeTableNames) (side, other string) {
+		if ours {
+			return n.name, n.mergeName
+		}
 
-	if err != nil {
-		return nil, nil, err
+		return n.mergeName, n.name
 	}
 
-	tblToStats := make(map[string]*MergeStats)
+	renamedFrom := make(map[string][]int)
+	renamedTo := make(map[string][]int)
+	for i, n := range tblNames {
+		sideName, otherName := names(n)
 
-	newRoot := root
-	var unconflicted []string
-	// need to validate merges can be done on all tables before starting the actual merges.
-	for _, tblName := range tblNames {
-		mergedTable, stats, err := merger.MergeTable(ctx, tblName)
+		inSide, err := hasTable(ctx, sideRoot, sideName)
 
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		if mergedTable != nil {
-			tblToStats[tblName] = stats
+		inOther, err := hasTable(ctx, otherRoot, otherName)
 
-			if stats.Conflicts == 0 {
-				unconflicted = append(unconflicted, tblName)
-			}
+		if err != nil {
+			return nil, err
+		}
 
-			var err error
-			newRoot, err = newRoot.PutTable(ctx, tblName, mergedTable)
+		inAnc, err := hasTable(ctx, ancRoot, n.ancName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if inAnc && !inSide && inOther {
+			key, err := tableTagsKey(ctx, ancRoot, n.ancName)
 
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-		} else if has, err := newRoot.HasTable(ctx, tblName); err != nil {
-			return nil, nil, err
-		} else if has {
-			tblToStats[tblName] = &MergeStats{Operation: TableRemoved}
-			newRoot, err = newRoot.RemoveTables(ctx, tblName)
+
+			renamedFrom[key] = append(renamedFrom[key], i)
+		} else if !inAnc && inSide && !inOther {
+			key, err := tableTagsKey(ctx, sideRoot, sideName)
 
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
+
+			renamedTo[key] = append(renamedTo[key], i)
+		}
+	}
+
+	paired := append([]mergeTableNames(nil), tblNames...)
+	dropped := make(map[int]bool)
+	for key, from := range renamedFrom {
+		to := renamedTo[key]
+
+		if len(from) != 1 || len(to) != 1 {
+			continue
+		}
+
+		oldNames, newNames := tblNames[from[0]], tblNames[to[0]]
+		renamed := mergeTableNames{ancName: oldNames.ancName}
+
+		if ours {
+			renamed.name, renamed.mergeName, renamed.mergedName = newNames.name, oldNames.mergeName, newNames.name
 		} else {
-			panic("?")
+			renamed.name, renamed.mergeName, renamed.mergedName = oldNames.name, newNames.mergeName, newNames.mergeName
+		}
+
+		paired[to[0]] = renamed
+		dropped[from[0]] = true
+	}
+
+	if len(dropped) == 0 {
+		return tblNames, nil
+	}
+
+	result := make([]mergeTableNames, 0, len(paired)-len(dropped))
+	for i, n := range paired {
+		if !dropped[i] {
+			result = append(result, n)
 		}
 	}
 
-	newRoot, err = newRoot.UpdateSuperSchemasFromOther(ctx, unconflicted, mergeRoot)
+	return result, nil
+}
+
+func hasTable(ctx context.Context, root *doltdb.RootValue, tblName string) (bool, error) {
+	if tblName == "" {
+		return false, nil
+	}
+
+	return root.HasTable(ctx, tblName)
+}
+
+// tableTagsKey returns a key which is equal for tables with the same set of column tags.
+func tableTagsKey(ctx context.Context, root *doltdb.RootValue, tblName string) (string, error) {
+	tbl, _, err := root.GetTable(ctx, tblName)
 
 	if err != nil {
-		return nil, nil, err
+		return "", err
 	}
 
-	return newRoot, tblToStats, nil
+	sch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(sch.GetAllCols().SortedTags), nil
 }
 
 func GetTablesInConflict(ctx context.Context, dEnv *env.DoltEnv) (workingInConflict, stagedInConflict, headInConflict []string, err error) {
@@ -622,6 +1991,50 @@ func GetTablesInConflict(ctx context.Context, dEnv *env.DoltEnv) (workingInConfl
 	return workingInConflict, stagedInConflict, headInConflict, err
 }
 
+// GetConflictCounts is like GetTablesInConflict, but returns the number of rows in conflict for each table in conflict
+// in the working, staged and head roots.
+func GetConflictCounts(ctx context.Context, dEnv *env.DoltEnv) (workingCounts, stagedCounts, headCounts map[string]int, err error) {
+	var headRoot, stagedRoot, workingRoot *doltdb.RootValue
+
+	headRoot, err = dEnv.HeadRoot(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stagedRoot, err = dEnv.StagedRoot(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	workingRoot, err = dEnv.WorkingRoot(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	headCounts, err = headRoot.TableConflictCounts(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stagedCounts, err = stagedRoot.TableConflictCounts(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	workingCounts, err = workingRoot.TableConflictCounts(ctx)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return workingCounts, stagedCounts, headCounts, nil
+}
+
 func GetDocsInConflict(ctx context.Context, dEnv *env.DoltEnv) (*diff.DocDiffs, error) {
 	docDetails, err := dEnv.GetAllValidDocDetails()
 	if err != nil {
@@ -14,6 +14,14 @@
 
 package merge
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+)
+
 type TableMergeOp int
 
 const (
@@ -23,10 +31,165 @@ const (
 	TableModified
 )
 
+// TableConflict is a conflict between changes the two sides of a merge made to a table as a whole, rather than to its
+// rows.
+type TableConflict int
+
+const (
+	NoTableConflict TableConflict = iota
+
+	// DeleteModifyTable is a table which one side deleted and the other modified since the merge base. The merge
+	// keeps the modified table, with a row conflict for each row the modifying side changed, so that the user can
+	// choose to keep it or to honor the delete by removing it.
+	DeleteModifyTable
+)
+
 type MergeStats struct {
 	Operation     TableMergeOp
 	Adds          int
 	Deletes       int
 	Modifications int
 	Conflicts     int
+
+	// PrimaryKeyInsertConflicts is the number of Conflicts caused by both sides inserting different rows with the same
+	// primary key. These conflicts have no base row.
+	PrimaryKeyInsertConflicts int
+
+	// RowCount and MergeRowCount are the number of rows in the table in the root being merged into and in the root
+	// being merged, and MergedRowCount is the number of rows in the merged table. A table missing from a root has no
+	// rows. The rows of a keyless table are counted once for each distinct row, regardless of how many copies of it
+	// there are.
+	RowCount       uint64
+	MergeRowCount  uint64
+	MergedRowCount uint64
+
+	// Identical is set when both sides of the merge have byte-identical versions of the table, in which case the
+	// table is returned as is without a row level merge.
+	Identical bool
+
+	// TableRenamed is set when one side renamed the table since the merge base, and the changes the other side made
+	// to it under its old name were merged into it under its new name, which it is keyed by in the merge's stats.
+	TableRenamed bool
+
+	// TableConflict is set when the changes to the table as a whole conflict. Its row conflicts are counted in
+	// Conflicts. A table which was deleted on one side and not changed on the other is removed cleanly, with a
+	// TableRemoved Operation.
+	TableConflict TableConflict
+
+	// Err is the error encountered merging the table when merging with MergeCommitsIsolatingTables. A table which
+	// failed to merge is left as it was in the root being merged into.
+	Err error
+
+	// ConflictsTable holds the table's conflicts when the Merger was asked to BuildConflictsTables and the table
+	// merged with conflicts.
+	ConflictsTable *doltdb.Table
+}
+
+// MergeSummaryInfo summarizes the result of merging several tables, as computed by SummarizeMerge. Each list of table
+// names is sorted.
+type MergeSummaryInfo struct {
+	// Merged are the tables which were changed on both sides and merged without conflicts.
+	Merged []string
+
+	// Conflicted are the tables which merged with conflicts, including those with a TableConflict.
+	Conflicted []string
+
+	// Added and Removed are the tables which were added or removed by the merge.
+	Added   []string
+	Removed []string
+
+	// Failed are the tables which could not be merged, as reported by MergeCommitsIsolatingTables.
+	Failed []string
+
+	// Adds, Deletes, Modifications and Conflicts are the totals of the rows added, deleted, modified and left in
+	// conflict across all the tables.
+	Adds          int
+	Deletes       int
+	Modifications int
+	Conflicts     int
+}
+
+// SummarizeMerge summarizes the per table |stats| of a merge. It uses only the stats, not the merged data.
+func SummarizeMerge(stats map[string]*MergeStats) MergeSummaryInfo {
+	var info MergeSummaryInfo
+	for tblName, tblStats := range stats {
+		switch {
+		case tblStats.Err != nil:
+			info.Failed = append(info.Failed, tblName)
+			continue
+		case tblStats.TableConflict != NoTableConflict:
+			info.Conflicted = append(info.Conflicted, tblName)
+		case tblStats.Operation == TableAdded:
+			info.Added = append(info.Added, tblName)
+		case tblStats.Operation == TableRemoved:
+			info.Removed = append(info.Removed, tblName)
+		case tblStats.Operation == TableModified && tblStats.Conflicts > 0:
+			info.Conflicted = append(info.Conflicted, tblName)
+		case tblStats.Operation == TableModified:
+			info.Merged = append(info.Merged, tblName)
+		}
+
+		info.Adds += tblStats.Adds
+		info.Deletes += tblStats.Deletes
+		info.Modifications += tblStats.Modifications
+		info.Conflicts += tblStats.Conflicts
+	}
+
+	for _, tblNames := range [][]string{info.Merged, info.Conflicted, info.Added, info.Removed, info.Failed} {
+		sort.Strings(tblNames)
+	}
+
+	return info
+}
+
+// MergeSummary returns a human readable summary of the per table |stats| of a merge, suitable for a merge commit
+// message. It uses only the stats, not the merged data.
+func MergeSummary(stats map[string]*MergeStats) string {
+	info := SummarizeMerge(stats)
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "%s added, %s deleted, %s modified, %s",
+		pluralize(info.Adds, "row"), pluralize(info.Deletes, "row"), pluralize(info.Modifications, "row"), pluralize(info.Conflicts, "conflict"))
+
+	writeTables := func(heading string, tblNames []string, detail func(tblName string) string) {
+		if len(tblNames) == 0 {
+			return
+		}
+
+		fmt.Fprintf(sb, "\n\n%s:", heading)
+		for _, tblName := range tblNames {
+			fmt.Fprintf(sb, "\n\t%s%s", tblName, detail(tblName))
+		}
+	}
+
+	rowCounts := func(tblName string) string {
+		tblStats := stats[tblName]
+		return fmt.Sprintf(" (%d added, %d deleted, %d modified)", tblStats.Adds, tblStats.Deletes, tblStats.Modifications)
+	}
+
+	noDetail := func(string) string { return "" }
+
+	writeTables("Merged cleanly", info.Merged, rowCounts)
+	writeTables("Conflicts", info.Conflicted, func(tblName string) string {
+		if stats[tblName].TableConflict == DeleteModifyTable {
+			return " (deleted on one side and modified on the other)"
+		}
+
+		return fmt.Sprintf(" (%s)", pluralize(stats[tblName].Conflicts, "conflict"))
+	})
+	writeTables("Added", info.Added, noDetail)
+	writeTables("Removed", info.Removed, noDetail)
+	writeTables("Failed", info.Failed, func(tblName string) string {
+		return fmt.Sprintf(": %v", stats[tblName].Err)
+	})
+
+	return sb.String()
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
 }
@@ -0,0 +1,126 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+
+	mergeapi "github.com/liquidata-inc/dolt/go/gen/proto/dolt/services/mergeapi/v1alpha1"
+)
+
+func (ms *MergeStats) toProto() *mergeapi.MergeStats {
+	pb := &mergeapi.MergeStats{
+		Operation:                 mergeapi.TableMergeOp(ms.Operation),
+		Adds:                      int64(ms.Adds),
+		Deletes:                   int64(ms.Deletes),
+		Modifications:             int64(ms.Modifications),
+		Conflicts:                 int64(ms.Conflicts),
+		PrimaryKeyInsertConflicts: int64(ms.PrimaryKeyInsertConflicts),
+		RowCount:                  ms.RowCount,
+		MergeRowCount:             ms.MergeRowCount,
+		MergedRowCount:            ms.MergedRowCount,
+		Identical:                 ms.Identical,
+		TableRenamed:              ms.TableRenamed,
+		TableConflict:             mergeapi.TableConflict(ms.TableConflict),
+	}
+
+	if ms.Err != nil {
+		pb.Err = ms.Err.Error()
+	}
+
+	return pb
+}
+
+func mergeStatsFromProto(pb *mergeapi.MergeStats) *MergeStats {
+	ms := &MergeStats{
+		Operation:                 TableMergeOp(pb.Operation),
+		Adds:                      int(pb.Adds),
+		Deletes:                   int(pb.Deletes),
+		Modifications:             int(pb.Modifications),
+		Conflicts:                 int(pb.Conflicts),
+		PrimaryKeyInsertConflicts: int(pb.PrimaryKeyInsertConflicts),
+		RowCount:                  pb.RowCount,
+		MergeRowCount:             pb.MergeRowCount,
+		MergedRowCount:            pb.MergedRowCount,
+		Identical:                 pb.Identical,
+		TableRenamed:              pb.TableRenamed,
+		TableConflict:             TableConflict(pb.TableConflict),
+	}
+
+	if pb.Err != "" {
+		ms.Err = errors.New(pb.Err)
+	}
+
+	return ms
+}
+
+// Marshal returns the encoding of |ms| as a mergeapi.MergeStats protobuf message, which can be decoded by
+// UnmarshalMergeStats. Builds with more or fewer MergeStats fields can decode it, as protobuf skips unknown fields and
+// leaves missing ones zero. Err is encoded as its message, and ConflictsTable, which only has meaning within a
+// process, is not encoded.
+func (ms *MergeStats) Marshal() ([]byte, error) {
+	return proto.Marshal(ms.toProto())
+}
+
+// UnmarshalMergeStats decodes MergeStats encoded by MergeStats.Marshal. A decoded Err has the original error's
+// message, but not its type.
+func UnmarshalMergeStats(data []byte) (*MergeStats, error) {
+	pb := &mergeapi.MergeStats{}
+	err := proto.Unmarshal(data, pb)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeStatsFromProto(pb), nil
+}
+
+// MarshalMergeStatsMap encodes the per table |stats| of a merge as a mergeapi.MergeStatsMap protobuf message. The
+// same stats always have the same encoding.
+func MarshalMergeStatsMap(stats map[string]*MergeStats) ([]byte, error) {
+	pb := &mergeapi.MergeStatsMap{Tables: make(map[string]*mergeapi.MergeStats, len(stats))}
+	for tblName, tblStats := range stats {
+		pb.Tables[tblName] = tblStats.toProto()
+	}
+
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	err := buf.Marshal(pb)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMergeStatsMap decodes per table MergeStats encoded by MarshalMergeStatsMap.
+func UnmarshalMergeStatsMap(data []byte) (map[string]*MergeStats, error) {
+	pb := &mergeapi.MergeStatsMap{}
+	err := proto.Unmarshal(data, pb)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*MergeStats, len(pb.Tables))
+	for tblName, tblStats := range pb.Tables {
+		stats[tblName] = mergeStatsFromProto(tblStats)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeStatsMarshalRoundTrip(t *testing.T) {
+	tests := []*MergeStats{
+		{},
+		{Operation: TableModified, Adds: 3, Deletes: 1, Modifications: 2, Conflicts: 4, PrimaryKeyInsertConflicts: 1, RowCount: 10, MergeRowCount: 12, MergedRowCount: 1 << 40},
+		{Operation: TableUnmodified, Identical: true},
+		{Operation: TableModified, Adds: 1, TableRenamed: true},
+		{Operation: TableAdded, TableConflict: DeleteModifyTable},
+		{Operation: TableUnmodified, Err: errors.New("schema conflict")},
+	}
+
+	for _, stats := range tests {
+		data, err := stats.Marshal()
+		require.NoError(t, err)
+		decoded, err := UnmarshalMergeStats(data)
+		require.NoError(t, err)
+
+		if stats.Err != nil {
+			require.Error(t, decoded.Err)
+			assert.Equal(t, stats.Err.Error(), decoded.Err.Error())
+			decoded.Err = stats.Err
+		}
+
+		assert.Equal(t, stats, decoded)
+	}
+
+	// zero fields are omitted
+	data, err := (&MergeStats{}).Marshal()
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestMergeStatsMapMarshalRoundTrip(t *testing.T) {
+	stats := map[string]*MergeStats{
+		"people":  {Operation: TableModified, Adds: 2, Conflicts: 1},
+		"places":  {Operation: TableAdded},
+		"things":  {Operation: TableUnmodified},
+		"removed": {Operation: TableRemoved, Deletes: 7},
+	}
+
+	data, err := MarshalMergeStatsMap(stats)
+	require.NoError(t, err)
+	decoded, err := UnmarshalMergeStatsMap(data)
+	require.NoError(t, err)
+	assert.Equal(t, stats, decoded)
+
+	data, err = MarshalMergeStatsMap(nil)
+	require.NoError(t, err)
+	decoded, err = UnmarshalMergeStatsMap(data)
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSummary(t *testing.T) {
+	stats := map[string]*MergeStats{
+		"people":    {Operation: TableModified, Adds: 3, Deletes: 1, Modifications: 2},
+		"orders":    {Operation: TableModified, Adds: 1, Conflicts: 2},
+		"invoices":  {Operation: TableModified, Conflicts: 1},
+		"products":  {Operation: TableAdded},
+		"customers": {Operation: TableRemoved},
+		"same":      {Operation: TableUnmodified, Identical: true},
+		"broken":    {Operation: TableUnmodified, Err: errors.New("type conflict")},
+		"dropped":   {Operation: TableUnmodified, TableConflict: DeleteModifyTable},
+	}
+
+	info := SummarizeMerge(stats)
+	assert.Equal(t, MergeSummaryInfo{
+		Merged:        []string{"people"},
+		Conflicted:    []string{"dropped", "invoices", "orders"},
+		Added:         []string{"products"},
+		Removed:       []string{"customers"},
+		Failed:        []string{"broken"},
+		Adds:          4,
+		Deletes:       1,
+		Modifications: 2,
+		Conflicts:     3,
+	}, info)
+
+	expected := `4 rows added, 1 row deleted, 2 rows modified, 3 conflicts
+
+Merged cleanly:
+	people (3 added, 1 deleted, 2 modified)
+
+Conflicts:
+	dropped (deleted on one side and modified on the other)
+	invoices (1 conflict)
+	orders (2 conflicts)
+
+Added:
+	products
+
+Removed:
+	customers
+
+Failed:
+	broken: type conflict`
+	assert.Equal(t, expected, MergeSummary(stats))
+
+	assert.Equal(t, "0 rows added, 0 rows deleted, 0 rows modified, 0 conflicts", MergeSummary(nil))
+}
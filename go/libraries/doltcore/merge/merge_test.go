@@ -16,7 +16,9 @@ package merge
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -24,9 +26,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/dtestutils"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/ref"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
+	"github.com/liquidata-inc/dolt/go/store/hash"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
@@ -138,6 +144,14 @@ func TestRowMerge(t *testing.T) {
 			nil,
 			true,
 		),
+		createRowMergeStruct(
+			"add diff row with values in different columns",
+			[]types.Value{types.String("one"), types.NullValue},
+			[]types.Value{types.NullValue, types.String("two")},
+			nil,
+			nil,
+			true,
+		),
 		createRowMergeStruct(
 			"both delete row",
 			nil,
@@ -468,3 +482,1485 @@ func TestMergeCommits(t *testing.T) {
 		}
 	}
 }
+
+func putTableWithConflicts(t *testing.T, root *doltdb.RootValue, vrw types.ValueReadWriter, tblName string, pkTag uint64, numConflicts int) *doltdb.RootValue {
+	ctx := context.Background()
+	cols, err := schema.NewColCollection(schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}))
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+	rows, err := types.NewMap(ctx, vrw)
+	require.NoError(t, err)
+	tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+	require.NoError(t, err)
+
+	if numConflicts > 0 {
+		var kvs []types.Value
+		for i := 0; i < numConflicts; i++ {
+			key := mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(i)))
+			row := mustTuple(types.NewTuple(vrw.Format()))
+			cnf := doltdb.NewConflict(nil, row, row)
+			kvs = append(kvs, key, mustTuple(cnf.ToNomsList(vrw)))
+		}
+
+		conflicts, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		schRef, err := tbl.GetSchemaRef()
+		require.NoError(t, err)
+		tbl, err = tbl.SetConflicts(ctx, doltdb.NewConflict(schRef, schRef, schRef), conflicts)
+		require.NoError(t, err)
+	}
+
+	root, err = root.PutTable(ctx, tblName, tbl)
+	require.NoError(t, err)
+
+	return root
+}
+
+func TestGetConflictCounts(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+	vrw := dEnv.DoltDB.ValueReadWriter()
+
+	working, err := dEnv.WorkingRoot(ctx)
+	require.NoError(t, err)
+	working = putTableWithConflicts(t, working, vrw, "customers", 1000, 3)
+	working = putTableWithConflicts(t, working, vrw, "orders", 1001, 1)
+	working = putTableWithConflicts(t, working, vrw, "products", 1002, 0)
+	require.NoError(t, dEnv.UpdateWorkingRoot(ctx, working))
+
+	staged, err := dEnv.StagedRoot(ctx)
+	require.NoError(t, err)
+	staged = putTableWithConflicts(t, staged, vrw, "customers", 1000, 2)
+	_, err = dEnv.UpdateStagedRoot(ctx, staged)
+	require.NoError(t, err)
+
+	workingCounts, stagedCounts, headCounts, err := GetConflictCounts(ctx, dEnv)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"customers": 3, "orders": 1}, workingCounts)
+	assert.Equal(t, map[string]int{"customers": 2}, stagedCounts)
+	assert.Empty(t, headCounts)
+
+	workingInConflict, _, _, err := GetTablesInConflict(ctx, dEnv)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"customers", "orders"}, workingInConflict)
+}
+
+func TestMergeCommitsIdenticalTable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+	require.NoError(t, err)
+	initialRows, err := types.NewMap(ctx, vrw,
+		keyTuples[0], valsToTestTupleWithoutPks([]types.Value{types.String("person 1"), types.NullValue}),
+	)
+	require.NoError(t, err)
+	changedRows, err := initialRows.Edit().Set(keyTuples[1], valsToTestTupleWithoutPks([]types.Value{types.String("person 2"), types.NullValue})).Map(ctx)
+	require.NoError(t, err)
+
+	initialTbl, err := doltdb.NewTable(ctx, vrw, schVal, initialRows)
+	require.NoError(t, err)
+	changedTbl, err := doltdb.NewTable(ctx, vrw, schVal, changedRows)
+	require.NoError(t, err)
+
+	root, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+	initialRoot, err := root.PutTable(ctx, tableName, initialTbl)
+	require.NoError(t, err)
+	changedRoot, err := initialRoot.PutTable(ctx, tableName, changedTbl)
+	require.NoError(t, err)
+
+	initialHash, err := ddb.WriteRootValue(ctx, initialRoot)
+	require.NoError(t, err)
+	changedHash, err := ddb.WriteRootValue(ctx, changedRoot)
+	require.NoError(t, err)
+
+	meta, err := doltdb.NewCommitMeta(name, email, "fake")
+	require.NoError(t, err)
+	initialCommit, err := ddb.Commit(ctx, initialHash, ref.NewBranchRef("master"), meta)
+	require.NoError(t, err)
+	require.NoError(t, ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("cherry-pick"), initialCommit))
+
+	// the same change is committed independently on both branches
+	commit, err := ddb.Commit(ctx, changedHash, ref.NewBranchRef("master"), meta)
+	require.NoError(t, err)
+	mergeCommit, err := ddb.Commit(ctx, changedHash, ref.NewBranchRef("cherry-pick"), meta)
+	require.NoError(t, err)
+
+	mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	stats := tblToStats[tableName]
+	require.NotNil(t, stats)
+	assert.True(t, stats.Identical)
+	assert.Equal(t, TableUnmodified, stats.Operation)
+	assert.Equal(t, 0, stats.Conflicts)
+
+	mergedTbl, ok, err := mergedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	h, err := mergedTbl.HashOf()
+	require.NoError(t, err)
+	eh, err := changedTbl.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, eh, h)
+}
+
+func TestMergeTableDataConcurrentInserts(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+
+	ancRows, err := types.NewMap(ctx, vrw,
+		keyTuples[0], valsToTestTupleWithoutPks([]types.Value{types.String("person 1"), types.NullValue}),
+	)
+	require.NoError(t, err)
+
+	sameRow := valsToTestTupleWithoutPks([]types.Value{types.String("person 2"), types.NullValue})
+	row := valsToTestTupleWithoutPks([]types.Value{types.String("person 3"), types.String("ours")})
+	mergeRow := valsToTestTupleWithoutPks([]types.Value{types.String("person 3"), types.String("theirs")})
+
+	rows, err := ancRows.Edit().Set(keyTuples[1], sameRow).Set(keyTuples[2], row).Map(ctx)
+	require.NoError(t, err)
+	mergeRows, err := ancRows.Edit().Set(keyTuples[1], sameRow).Set(keyTuples[2], mergeRow).Map(ctx)
+	require.NoError(t, err)
+
+	merged, conflicts, stats, err := mergeTableData(ctx, sch, rows, mergeRows, ancRows, vrw, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Conflicts)
+	assert.Equal(t, 1, stats.PrimaryKeyInsertConflicts)
+	assert.Equal(t, 1, stats.Adds)
+
+	// identical inserts merge silently
+	v, ok, err := merged.MaybeGet(ctx, keyTuples[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, v.Equals(sameRow))
+
+	// differing inserts keep our row and record both inserted rows in the conflict
+	v, ok, err = merged.MaybeGet(ctx, keyTuples[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, v.Equals(row))
+
+	cnfVal, ok, err := conflicts.MaybeGet(ctx, keyTuples[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+	cnf, err := doltdb.ConflictFromTuple(cnfVal.(types.Tuple))
+	require.NoError(t, err)
+	assert.True(t, types.IsNull(cnf.Base))
+	assert.True(t, cnf.Value.Equals(row))
+	assert.True(t, cnf.MergeValue.Equals(mergeRow))
+}
+
+func TestMergeTableDataTiebreaker(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+
+	ancRows, err := types.NewMap(ctx, vrw,
+		keyTuples[0], valsToTestTupleWithoutPks([]types.Value{types.String("person 1"), types.NullValue}),
+	)
+	require.NoError(t, err)
+
+	modifiedRow := valsToTestTupleWithoutPks([]types.Value{types.String("person 1"), types.String("ours")})
+	ourRow := valsToTestTupleWithoutPks([]types.Value{types.String("person 3"), types.String("ours")})
+	mergeRow := valsToTestTupleWithoutPks([]types.Value{types.String("person 3"), types.String("theirs")})
+
+	// we modified the first row which they deleted, and both sides inserted the third row
+	rows, err := ancRows.Edit().Set(keyTuples[0], modifiedRow).Set(keyTuples[2], ourRow).Map(ctx)
+	require.NoError(t, err)
+	mergeRows, err := ancRows.Edit().Remove(keyTuples[0]).Set(keyTuples[2], mergeRow).Map(ctx)
+	require.NoError(t, err)
+
+	// pick the row with the larger title, keeping a row over a deletion
+	larger := func(ours, theirs row.Row) row.Row {
+		if ours == nil || theirs == nil {
+			if ours == nil {
+				return theirs
+			}
+			return ours
+		}
+
+		ourTitle, _ := ours.GetColVal(titleTag)
+		theirTitle, _ := theirs.GetColVal(titleTag)
+		if less, err := ourTitle.Less(types.Format_7_18, theirTitle); err == nil && less {
+			return theirs
+		}
+		return ours
+	}
+
+	merged, conflicts, stats, err := mergeTableData(ctx, sch, rows, mergeRows, ancRows, vrw, larger)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, stats.Conflicts)
+	assert.Equal(t, 0, stats.Deletes)
+	assert.Equal(t, 1, stats.Modifications)
+	assert.Equal(t, uint64(0), conflicts.Len())
+
+	v, ok, err := merged.MaybeGet(ctx, keyTuples[0])
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, v.Equals(modifiedRow))
+
+	v, ok, err = merged.MaybeGet(ctx, keyTuples[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, v.Equals(mergeRow))
+}
+
+func TestMergeCommitsIsolatingTables(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	root, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+	require.NoError(t, err)
+	newTable := func(kvs ...types.Value) *doltdb.Table {
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		return tbl
+	}
+	commitRoot := func(root *doltdb.RootValue, branch string) *doltdb.Commit {
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "fake")
+		require.NoError(t, err)
+		cm, err := ddb.Commit(ctx, h, ref.NewBranchRef(branch), meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	row0 := valsToTestTupleWithoutPks([]types.Value{types.String("person 1"), types.NullValue})
+	row1 := valsToTestTupleWithoutPks([]types.Value{types.String("person 2"), types.NullValue})
+
+	initialRoot, err := root.PutTable(ctx, "good", newTable(keyTuples[0], row0))
+	require.NoError(t, err)
+	initialCommit := commitRoot(initialRoot, "master")
+	require.NoError(t, ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("other"), initialCommit))
+
+	// "bad" is added on both branches with different schemas, which can't be merged
+	pkCol := schema.NewColumn("pk", 200, types.IntKind, true, schema.NotNullConstraint{})
+	newBadTable := func(pk int64, cols ...schema.Column) *doltdb.Table {
+		badSchVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(mustColColl(append([]schema.Column{pkCol}, cols...)...)))
+		require.NoError(t, err)
+		key := mustTuple(types.NewTuple(vrw.Format(), types.Uint(200), types.Int(pk)))
+		rows, err := types.NewMap(ctx, vrw, key, mustTuple(types.NewTuple(vrw.Format())))
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, badSchVal, rows)
+		require.NoError(t, err)
+		return tbl
+	}
+
+	ours, err := initialRoot.PutTable(ctx, "bad", newBadTable(0))
+	require.NoError(t, err)
+	theirs, err := initialRoot.PutTable(ctx, "bad", newBadTable(1, schema.NewColumn("extra", 201, types.StringKind, false)))
+	require.NoError(t, err)
+	theirs, err = theirs.PutTable(ctx, "good", newTable(keyTuples[0], row0, keyTuples[1], row1))
+	require.NoError(t, err)
+
+	commit := commitRoot(ours, "master")
+	mergeCommit := commitRoot(theirs, "other")
+
+	_, _, err = MergeCommits(ctx, ddb, commit, mergeCommit)
+	assert.Equal(t, ErrSameTblAddedTwice, err)
+
+	mergedRoot, tblToStats, err := MergeCommitsIsolatingTables(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	require.Contains(t, tblToStats, "bad")
+	assert.Equal(t, ErrSameTblAddedTwice, tblToStats["bad"].Err)
+	require.Contains(t, tblToStats, "good")
+	assert.NoError(t, tblToStats["good"].Err)
+	assert.Equal(t, TableModified, tblToStats["good"].Operation)
+
+	badTbl, ok, err := mergedRoot.GetTable(ctx, "bad")
+	require.NoError(t, err)
+	require.True(t, ok)
+	oursBadTbl, _, err := ours.GetTable(ctx, "bad")
+	require.NoError(t, err)
+	h, err := badTbl.HashOf()
+	require.NoError(t, err)
+	eh, err := oursBadTbl.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, eh, h)
+
+	goodTbl, _, err := mergedRoot.GetTable(ctx, "good")
+	require.NoError(t, err)
+	goodRows, err := goodTbl.GetRowData(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), goodRows.Len())
+}
+
+func TestMergeCommitsCrissCross(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+	require.NoError(t, err)
+
+	commitRows := func(msg string, names []string, parents ...*doltdb.Commit) *doltdb.Commit {
+		var kvs []types.Value
+		for i, n := range names {
+			kvs = append(kvs, keyTuples[i], valsToTestTupleWithoutPks([]types.Value{types.String(n), types.NullValue}))
+		}
+
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, msg)
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	// row 0 is changed on one branch and row 1 on the other. Each branch then merges the other, so later merges have
+	// two merge bases. Afterwards rows 0 and 1 are changed again on one side only.
+	a := commitRows("a", []string{"x", "x"}, masterHead)
+	b := commitRows("b", []string{"y", "x"}, a)
+	c := commitRows("c", []string{"x", "z"}, a)
+	bc := commitRows("merge c into b", []string{"y", "z"}, b, c)
+	cb := commitRows("merge b into c", []string{"y", "z"}, c, b)
+	commit := commitRows("w", []string{"w", "q"}, bc)
+	mergeCommit := commitRows("v", []string{"y", "z", "v"}, cb)
+
+	bases, err := doltdb.GetMergeBases(ctx, commit, mergeCommit)
+	require.NoError(t, err)
+	require.Len(t, bases, 2)
+
+	// merging against either merge base alone reports a false conflict
+	root, err := commit.GetRootValue()
+	require.NoError(t, err)
+	mergeRoot, err := mergeCommit.GetRootValue()
+	require.NoError(t, err)
+	for _, base := range bases {
+		baseRoot, err := base.GetRootValue()
+		require.NoError(t, err)
+		_, stats, err := NewMerger(ctx, root, mergeRoot, baseRoot, vrw).MergeTable(ctx, tableName)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Conflicts)
+	}
+
+	mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tblToStats[tableName].Conflicts)
+
+	expected := commitRows("expected", []string{"w", "q", "v"})
+	expectedRoot, err := expected.GetRootValue()
+	require.NoError(t, err)
+	mergedTbl, _, err := mergedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	expectedTbl, _, err := expectedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	mergedRows, err := mergedTbl.GetRowData(ctx)
+	require.NoError(t, err)
+	expectedRows, err := expectedTbl.GetRowData(ctx)
+	require.NoError(t, err)
+	assert.True(t, mergedRows.Equals(expectedRows))
+}
+
+func TestEstimateMergeConflicts(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	tblTags := map[string]uint64{"both": 200, "ours": 201, "same": 202, "removed": 203, "added": 204}
+
+	// each table holds a single row with the given primary key
+	commitTables := func(tblToPk map[string]int64, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, pk := range tblToPk {
+			tag := tblTags[tblName]
+			cols, err := schema.NewColCollection(schema.NewColumn("pk", tag, types.IntKind, true, schema.NotNullConstraint{}))
+			require.NoError(t, err)
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+			require.NoError(t, err)
+			key := mustTuple(types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(pk)))
+			rows, err := types.NewMap(ctx, vrw, key, mustTuple(types.NewTuple(vrw.Format())))
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	base := commitTables(map[string]int64{"both": 0, "ours": 0, "same": 0, "removed": 0}, masterHead)
+	commit := commitTables(map[string]int64{"both": 1, "ours": 1, "same": 1, "removed": 1, "added": 1}, base)
+	mergeCommit := commitTables(map[string]int64{"both": 2, "ours": 0, "same": 1, "added": 2}, base)
+
+	// "both" and "added" were changed differently on both sides, as was "removed" which was modified on one side and
+	// dropped on the other.
+	n, err := EstimateMergeConflicts(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = EstimateMergeConflicts(ctx, ddb, base, commit)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestMergeTableTypeWidening(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag, valTag = 400, 401
+
+	schWithValType := func(ti typeinfo.TypeInfo) schema.Schema {
+		valCol, err := schema.NewColumnWithTypeInfo("val", valTag, ti, false)
+		require.NoError(t, err)
+		cols, err := schema.NewColCollection(schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}), valCol)
+		require.NoError(t, err)
+		return schema.SchemaFromCols(cols)
+	}
+
+	// |kvs| alternates primary keys and values
+	rootWithRows := func(sch schema.Schema, kvs ...types.Value) *doltdb.RootValue {
+		var tuples []types.Value
+		for i := 0; i < len(kvs); i += 2 {
+			tuples = append(tuples,
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), kvs[i])),
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), kvs[i+1])))
+		}
+
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+		require.NoError(t, err)
+		rows, err := types.NewMap(ctx, vrw, tuples...)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	t.Run("widening", func(t *testing.T) {
+		uintSch, intSch := schWithValType(typeinfo.Uint32Type), schWithValType(typeinfo.Int64Type)
+
+		// row 1 is modified and row 3 added on our side, which leaves val a uint. Their side widens val to an int,
+		// converting its values, and modifies row 2.
+		ancRoot := rootWithRows(uintSch, types.Int(1), types.Uint(1), types.Int(2), types.Uint(2))
+		root := rootWithRows(uintSch, types.Int(1), types.Uint(10), types.Int(2), types.Uint(2), types.Int(3), types.Uint(30))
+		mergeRoot := rootWithRows(intSch, types.Int(1), types.Int(1), types.Int(2), types.Int(-5))
+
+		for _, roots := range [][2]*doltdb.RootValue{{root, mergeRoot}, {mergeRoot, root}} {
+			mergedTbl, stats, err := NewMerger(ctx, roots[0], roots[1], ancRoot, vrw).MergeTable(ctx, tableName)
+			require.NoError(t, err)
+			assert.Equal(t, 0, stats.Conflicts)
+
+			mergedSch, err := mergedTbl.GetSchema(ctx)
+			require.NoError(t, err)
+			eq, err := schema.SchemasAreEqual(intSch, mergedSch)
+			require.NoError(t, err)
+			assert.True(t, eq)
+
+			expectedTbl, _, err := rootWithRows(intSch, types.Int(1), types.Int(10), types.Int(2), types.Int(-5), types.Int(3), types.Int(30)).GetTable(ctx, tableName)
+			require.NoError(t, err)
+			expectedRows, err := expectedTbl.GetRowData(ctx)
+			require.NoError(t, err)
+			mergedRows, err := mergedTbl.GetRowData(ctx)
+			require.NoError(t, err)
+			assert.True(t, expectedRows.Equals(mergedRows))
+		}
+	})
+
+	t.Run("narrowing", func(t *testing.T) {
+		wideSch, narrowSch := schWithValType(typeinfo.Int64Type), schWithValType(typeinfo.Int32Type)
+
+		ancRoot := rootWithRows(wideSch, types.Int(1), types.Int(1))
+		root := rootWithRows(wideSch, types.Int(1), types.Int(2))
+		mergeRoot := rootWithRows(narrowSch, types.Int(1), types.Int(1), types.Int(2), types.Int(2))
+
+		_, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		assert.Error(t, err)
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		intSch, strSch := schWithValType(typeinfo.Int64Type), schWithValType(typeinfo.StringDefaultType)
+
+		ancRoot := rootWithRows(intSch, types.Int(1), types.Int(1))
+		root := rootWithRows(intSch, types.Int(1), types.Int(2))
+		mergeRoot := rootWithRows(strSch, types.Int(1), types.String("1"))
+
+		_, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		assert.Error(t, err)
+	})
+}
+
+func TestMergeTableColumnComments(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag, valTag = 880, 881
+
+	schWithComment := func(comment string) schema.Schema {
+		valCol := schema.NewColumn("val", valTag, types.StringKind, false)
+		valCol.Comment = comment
+		cols, err := schema.NewColCollection(schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}), valCol)
+		require.NoError(t, err)
+		return schema.SchemaFromCols(cols)
+	}
+
+	// each side writes a different row, so that the table is modified on both sides
+	rootWithRow := func(sch schema.Schema, pk int) *doltdb.RootValue {
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+		require.NoError(t, err)
+		rows, err := types.NewMap(ctx, vrw,
+			mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))),
+			mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), types.String("val"))))
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	tests := []struct {
+		name                   string
+		ancestor, ours, theirs string
+		expected               string
+	}{
+		{"changed on their side", "base", "base", "theirs", "theirs"},
+		{"changed on our side", "base", "ours", "base", "ours"},
+		{"changed on both sides", "base", "ours", "theirs", "ours"},
+		{"added on their side", "", "", "theirs", "theirs"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ancRoot := rootWithRow(schWithComment(test.ancestor), 1)
+			root := rootWithRow(schWithComment(test.ours), 2)
+			mergeRoot := rootWithRow(schWithComment(test.theirs), 3)
+
+			mergedTbl, stats, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+			require.NoError(t, err)
+			assert.Equal(t, 0, stats.Conflicts)
+
+			mergedSch, err := mergedTbl.GetSchema(ctx)
+			require.NoError(t, err)
+			valCol, ok := mergedSch.GetAllCols().GetByTag(valTag)
+			require.True(t, ok)
+			assert.Equal(t, test.expected, valCol.Comment)
+		})
+	}
+}
+
+func TestMergeCommitsRowCounts(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	tblTags := map[string]uint64{"people": 500, "removed": 501}
+
+	// each table holds one row for each of the given primary keys
+	commitTables := func(tblToPks map[string][]int64, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, pks := range tblToPks {
+			tag := tblTags[tblName]
+			cols, err := schema.NewColCollection(schema.NewColumn("pk", tag, types.IntKind, true, schema.NotNullConstraint{}))
+			require.NoError(t, err)
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+			require.NoError(t, err)
+
+			var kvs []types.Value
+			for _, pk := range pks {
+				kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+			}
+
+			rows, err := types.NewMap(ctx, vrw, kvs...)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	pkRange := func(start, end int64) []int64 {
+		var pks []int64
+		for pk := start; pk < end; pk++ {
+			pks = append(pks, pk)
+		}
+		return pks
+	}
+
+	base := commitTables(map[string][]int64{"people": pkRange(0, 10), "removed": pkRange(0, 3)}, masterHead)
+	commit := commitTables(map[string][]int64{"people": pkRange(0, 15), "removed": pkRange(0, 3)}, base)
+	mergeCommit := commitTables(map[string][]int64{"people": append(pkRange(1, 10), pkRange(20, 25)...)}, base)
+
+	_, tblToStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	people := tblToStats["people"]
+	require.NotNil(t, people)
+	assert.Equal(t, uint64(15), people.RowCount)
+	assert.Equal(t, uint64(14), people.MergeRowCount)
+	assert.Equal(t, uint64(19), people.MergedRowCount)
+
+	removed := tblToStats["removed"]
+	require.NotNil(t, removed)
+	assert.Equal(t, TableRemoved, removed.Operation)
+	assert.Equal(t, uint64(3), removed.RowCount)
+	assert.Equal(t, uint64(0), removed.MergeRowCount)
+	assert.Equal(t, uint64(0), removed.MergedRowCount)
+}
+
+func TestMergeCommitsStreamTables(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	tblTags := map[string]uint64{"a": 900, "b": 901, "c": 902, "d": 903}
+
+	// each table holds one row for each of the given primary keys
+	commitTables := func(tblToPks map[string][]int64, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, pks := range tblToPks {
+			tag := tblTags[tblName]
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(mustColColl(schema.NewColumn("pk", tag, types.IntKind, true, schema.NotNullConstraint{}))))
+			require.NoError(t, err)
+
+			var kvs []types.Value
+			for _, pk := range pks {
+				kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+			}
+
+			rows, err := types.NewMap(ctx, vrw, kvs...)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	// a is modified on both sides, b only on theirs, c is removed on theirs and d is added on theirs
+	base := commitTables(map[string][]int64{"a": {1, 2, 3}, "b": {1}, "c": {1}}, masterHead)
+	commit := commitTables(map[string][]int64{"a": {1, 2, 3, 4}, "b": {1}, "c": {1}}, base)
+	mergeCommit := commitTables(map[string][]int64{"a": {2, 3}, "b": {1, 2}, "d": {1}}, base)
+
+	batchRoot, batchStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+	streamedRoot, streamedStats, err := MergeCommitsWithOptions(ctx, ddb, commit, mergeCommit, MergeOptions{StreamTables: true})
+	require.NoError(t, err)
+
+	batchHash, err := batchRoot.HashOf()
+	require.NoError(t, err)
+	streamedHash, err := streamedRoot.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, batchHash, streamedHash)
+	assert.Equal(t, batchStats, streamedStats)
+
+	tblNames, err := streamedRoot.GetTableNames(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, tblNames)
+}
+
+func TestMergeCommitsDeleteModifyTable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	tblTags := map[string]uint64{"ours_dropped": 904, "theirs_dropped": 905, "cleanly_dropped": 906}
+
+	// each table holds one row for each of the given primary keys
+	commitTables := func(tblToPks map[string][]int64, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, pks := range tblToPks {
+			tag := tblTags[tblName]
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(mustColColl(schema.NewColumn("pk", tag, types.IntKind, true, schema.NotNullConstraint{}))))
+			require.NoError(t, err)
+
+			var kvs []types.Value
+			for _, pk := range pks {
+				kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+			}
+
+			rows, err := types.NewMap(ctx, vrw, kvs...)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	base := commitTables(map[string][]int64{"ours_dropped": {1}, "theirs_dropped": {1}, "cleanly_dropped": {1}}, masterHead)
+	commit := commitTables(map[string][]int64{"theirs_dropped": {1, 2}, "cleanly_dropped": {1}}, base)
+	mergeCommit := commitTables(map[string][]int64{"ours_dropped": {1, 3}}, base)
+
+	mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	tests := []struct {
+		tblName       string
+		operation     TableMergeOp
+		tableConflict TableConflict
+		conflicts     int
+		rowCount      uint64
+	}{
+		{"ours_dropped", TableAdded, DeleteModifyTable, 1, 2},
+		{"theirs_dropped", TableUnmodified, DeleteModifyTable, 1, 2},
+		{"cleanly_dropped", TableRemoved, NoTableConflict, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.tblName, func(t *testing.T) {
+			stats := tblToStats[test.tblName]
+			require.NotNil(t, stats)
+			assert.Equal(t, test.operation, stats.Operation)
+			assert.Equal(t, test.tableConflict, stats.TableConflict)
+			assert.Equal(t, test.conflicts, stats.Conflicts)
+
+			// the modified table is kept until the user resolves the conflict
+			tbl, ok, err := mergedRoot.GetTable(ctx, test.tblName)
+			require.NoError(t, err)
+			assert.Equal(t, test.rowCount > 0, ok)
+			rowCount, err := tableRowCount(ctx, tbl)
+			require.NoError(t, err)
+			assert.Equal(t, test.rowCount, rowCount)
+		})
+	}
+
+	info := SummarizeMerge(tblToStats)
+	assert.Equal(t, []string{"ours_dropped", "theirs_dropped"}, info.Conflicted)
+	assert.Equal(t, []string{"cleanly_dropped"}, info.Removed)
+
+	// the conflicts are recorded in the merged root, which can't be committed until they're resolved
+	conflictCounts, err := mergedRoot.TableConflictCounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"ours_dropped": 1, "theirs_dropped": 1}, conflictCounts)
+
+	// the row only the modifying side has conflicts with the deleting side's delete of it
+	tbl, _, err := mergedRoot.GetTable(ctx, "ours_dropped")
+	require.NoError(t, err)
+	_, conflicts, err := tbl.GetConflicts(ctx)
+	require.NoError(t, err)
+	cnfVal, ok, err := conflicts.MaybeGet(ctx, mustTuple(types.NewTuple(vrw.Format(), types.Uint(904), types.Int(3))))
+	require.NoError(t, err)
+	require.True(t, ok)
+	cnf, err := doltdb.ConflictFromTuple(cnfVal.(types.Tuple))
+	require.NoError(t, err)
+	assert.True(t, types.NullValue.Equals(cnf.Base))
+	assert.True(t, types.NullValue.Equals(cnf.Value))
+	assert.True(t, mustTuple(types.NewTuple(vrw.Format())).Equals(cnf.MergeValue))
+}
+
+func TestMergeCommitsWithBase(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	tblTags := map[string]uint64{"people": 907, "places": 908}
+
+	// each table holds one row for each of the given primary keys
+	commitTables := func(tblToPks map[string][]int64, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, pks := range tblToPks {
+			tag := tblTags[tblName]
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(mustColColl(schema.NewColumn("pk", tag, types.IntKind, true, schema.NotNullConstraint{}))))
+			require.NoError(t, err)
+
+			var kvs []types.Value
+			for _, pk := range pks {
+				kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(tag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+			}
+
+			rows, err := types.NewMap(ctx, vrw, kvs...)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	base := commitTables(map[string][]int64{"people": {1, 2, 3}}, masterHead)
+	commit := commitTables(map[string][]int64{"people": {1, 2, 3, 4}}, base)
+	commit = commitTables(map[string][]int64{"people": {1, 2, 3, 4}, "places": {1}}, commit)
+	mergeCommit := commitTables(map[string][]int64{"people": {2, 3}}, base)
+
+	expectedRoot, expectedStats, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+	expectedHash, err := expectedRoot.HashOf()
+	require.NoError(t, err)
+
+	mergedRoot, tblToStats, err := MergeCommitsWithBase(ctx, ddb, commit, mergeCommit, base)
+	require.NoError(t, err)
+	mergedHash, err := mergedRoot.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, mergedHash)
+	assert.Equal(t, expectedStats, tblToStats)
+
+	// an older common ancestor is a valid, if less precise, base
+	_, _, err = MergeCommitsWithBase(ctx, ddb, commit, mergeCommit, masterHead)
+	assert.NoError(t, err)
+
+	// |mergeCommit| is not an ancestor of |commit|, nor is |commit| of |mergeCommit|
+	_, _, err = MergeCommitsWithBase(ctx, ddb, commit, mergeCommit, mergeCommit)
+	assert.Equal(t, ErrNotMergeBase, err)
+	_, _, err = MergeCommitsWithBase(ctx, ddb, commit, mergeCommit, commit)
+	assert.Equal(t, ErrNotMergeBase, err)
+}
+
+func TestMergeCommitsCaseInsensitiveTableNames(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag = 600
+	cols, err := schema.NewColCollection(schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}))
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+
+	rowsWithPks := func(pks ...int64) types.Map {
+		var kvs []types.Value
+		for _, pk := range pks {
+			kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+		}
+
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		return rows
+	}
+
+	commitTable := func(tblName string, rows types.Map, parents ...*doltdb.Commit) *doltdb.Commit {
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tblName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	opts := MergeOptions{CaseInsensitiveTableNames: true}
+	base := commitTable("customers", rowsWithPks(0), masterHead)
+	commit := commitTable("customers", rowsWithPks(0, 1), base)
+	mergeCommit := commitTable("Customers", rowsWithPks(0, 2), base)
+
+	for _, cms := range [][2]*doltdb.Commit{{commit, mergeCommit}, {mergeCommit, commit}} {
+		mergedRoot, tblToStats, err := MergeCommitsWithOptions(ctx, ddb, cms[0], cms[1], opts)
+		require.NoError(t, err)
+
+		// The side which changed the name's case since the merge base wins.
+		tblNames, err := mergedRoot.GetTableNames(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Customers"}, tblNames)
+		require.Contains(t, tblToStats, "Customers")
+		assert.Equal(t, 0, tblToStats["Customers"].Conflicts)
+
+		tbl, _, err := mergedRoot.GetTable(ctx, "Customers")
+		require.NoError(t, err)
+		mergedRows, err := tbl.GetRowData(ctx)
+		require.NoError(t, err)
+		assert.True(t, rowsWithPks(0, 1, 2).Equals(mergedRows))
+	}
+
+	otherCommit := commitTable("CUSTOMERS", rowsWithPks(0, 3), base)
+	_, _, err = MergeCommitsWithOptions(ctx, ddb, otherCommit, mergeCommit, opts)
+	assert.Equal(t, ErrTableNameCaseConflict, err)
+}
+
+func TestMergeRootsDeterministic(t *testing.T) {
+	ctx := context.Background()
+
+	mergedRootHash := func() hash.Hash {
+		// each merge is of inputs written to a separate database
+		vrw, commit, mergeCommit, _, _ := setupMergeTest()
+		root, err := commit.GetRootValue()
+		require.NoError(t, err)
+		mergeRoot, err := mergeCommit.GetRootValue()
+		require.NoError(t, err)
+		ancCm, err := doltdb.GetCommitAncestor(ctx, commit, mergeCommit)
+		require.NoError(t, err)
+		ancRoot, err := ancCm.GetRootValue()
+		require.NoError(t, err)
+
+		merged, _, err := mergeRoots(ctx, vrw, root, mergeRoot, ancRoot, MergeOptions{})
+		require.NoError(t, err)
+		h, err := merged.HashOf()
+		require.NoError(t, err)
+		return h
+	}
+
+	expected := mergedRootHash()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, expected, mergedRootHash())
+	}
+}
+
+// cancelAfterCtx is a context which is cancelled once its Err method has been called |n| times.
+type cancelAfterCtx struct {
+	context.Context
+	n int
+}
+
+func (ctx *cancelAfterCtx) Err() error {
+	if ctx.n <= 0 {
+		return context.Canceled
+	}
+
+	ctx.n--
+	return ctx.Context.Err()
+}
+
+func TestMergeRootsCancelled(t *testing.T) {
+	vrw, commit, mergeCommit, _, _ := setupMergeTest()
+	root, err := commit.GetRootValue()
+	require.NoError(t, err)
+	mergeRoot, err := mergeCommit.GetRootValue()
+	require.NoError(t, err)
+	ancCm, err := doltdb.GetCommitAncestor(context.Background(), commit, mergeCommit)
+	require.NoError(t, err)
+	ancRoot, err := ancCm.GetRootValue()
+	require.NoError(t, err)
+
+	// cancel before the merge starts, then part way through merging the rows
+	for _, n := range []int{0, 1, 2, 5} {
+		for _, opts := range []MergeOptions{{}, {IsolateTables: true}} {
+			merged, stats, err := mergeRoots(&cancelAfterCtx{context.Background(), n}, vrw, root, mergeRoot, ancRoot, opts)
+			assert.Equal(t, context.Canceled, err, "cancelled after %d checks", n)
+			assert.Nil(t, merged)
+			assert.Nil(t, stats)
+		}
+	}
+
+	merged, _, err := mergeRoots(context.Background(), vrw, root, mergeRoot, ancRoot, MergeOptions{})
+	require.NoError(t, err)
+	assert.NotNil(t, merged)
+}
+
+func TestMergeCommitsRenamedTable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag = 820
+	cols, err := schema.NewColCollection(schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}))
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+
+	rowsWithPks := func(pks ...int64) types.Map {
+		var kvs []types.Value
+		for _, pk := range pks {
+			kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))), mustTuple(types.NewTuple(vrw.Format())))
+		}
+
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		return rows
+	}
+
+	commitTable := func(tblName string, rows types.Map, parents ...*doltdb.Commit) *doltdb.Commit {
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tblName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	base := commitTable("customers", rowsWithPks(0, 1), masterHead)
+	renamed := commitTable("clients", rowsWithPks(0, 1, 2), base)
+	edited := commitTable("customers", rowsWithPks(1, 3), base)
+
+	for _, cms := range [][2]*doltdb.Commit{{renamed, edited}, {edited, renamed}} {
+		mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, cms[0], cms[1])
+		require.NoError(t, err)
+
+		tblNames, err := mergedRoot.GetTableNames(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"clients"}, tblNames)
+		require.Len(t, tblToStats, 1)
+		require.Contains(t, tblToStats, "clients")
+		assert.True(t, tblToStats["clients"].TableRenamed)
+		assert.Equal(t, 0, tblToStats["clients"].Conflicts)
+
+		tbl, _, err := mergedRoot.GetTable(ctx, "clients")
+		require.NoError(t, err)
+		mergedRows, err := tbl.GetRowData(ctx)
+		require.NoError(t, err)
+		assert.True(t, rowsWithPks(1, 2, 3).Equals(mergedRows))
+	}
+}
+
+func TestMergeCommitsTableAddedOnBothSides(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag, valTag = 840, 841
+	cols, err := schema.NewColCollection(
+		schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}),
+		schema.NewColumn("val", valTag, types.StringKind, false),
+	)
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+
+	rowsWithVals := func(pkToVal map[int64]string) types.Map {
+		var kvs []types.Value
+		for pk, val := range pkToVal {
+			kvs = append(kvs,
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))),
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), types.String(val))))
+		}
+
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		return rows
+	}
+
+	commitRows := func(rows types.Map) *doltdb.Commit {
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, []*doltdb.Commit{masterHead}, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	ours := commitRows(rowsWithVals(map[int64]string{1: "a", 2: "b"}))
+
+	t.Run("distinct rows", func(t *testing.T) {
+		theirs := commitRows(rowsWithVals(map[int64]string{2: "b", 3: "c"}))
+		mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, ours, theirs)
+		require.NoError(t, err)
+		require.Contains(t, tblToStats, tableName)
+		assert.Equal(t, 0, tblToStats[tableName].Conflicts)
+
+		tbl, ok, err := mergedRoot.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		require.True(t, ok)
+		mergedRows, err := tbl.GetRowData(ctx)
+		require.NoError(t, err)
+		assert.True(t, rowsWithVals(map[int64]string{1: "a", 2: "b", 3: "c"}).Equals(mergedRows))
+	})
+
+	t.Run("same key inserted with different values", func(t *testing.T) {
+		theirs := commitRows(rowsWithVals(map[int64]string{2: "x"}))
+		mergedRoot, tblToStats, err := MergeCommits(ctx, ddb, ours, theirs)
+		require.NoError(t, err)
+		require.Contains(t, tblToStats, tableName)
+		assert.Equal(t, 1, tblToStats[tableName].Conflicts)
+		assert.Equal(t, 1, tblToStats[tableName].PrimaryKeyInsertConflicts)
+
+		tbl, ok, err := mergedRoot.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		require.True(t, ok)
+		has, err := tbl.HasConflicts()
+		require.NoError(t, err)
+		assert.True(t, has)
+	})
+}
+
+func TestMergeRootsTableDeletedOnOneSide(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	cols, err := schema.NewColCollection(schema.NewColumn("pk", 850, types.IntKind, true, schema.NotNullConstraint{}))
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+	rows, err := types.NewMap(ctx, vrw)
+	require.NoError(t, err)
+	tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+	require.NoError(t, err)
+	withTbl, err := emptyRoot.PutTable(ctx, tableName, tbl)
+	require.NoError(t, err)
+
+	// deleted on our side, unchanged on theirs
+	merged, tblToStats, err := mergeRoots(ctx, vrw, emptyRoot, withTbl, withTbl, MergeOptions{})
+	require.NoError(t, err)
+	has, err := merged.HasTable(ctx, tableName)
+	require.NoError(t, err)
+	assert.False(t, has)
+	require.Contains(t, tblToStats, tableName)
+	assert.Equal(t, TableUnmodified, tblToStats[tableName].Operation)
+
+	// unchanged on our side, deleted on theirs
+	merged, tblToStats, err = mergeRoots(ctx, vrw, withTbl, emptyRoot, withTbl, MergeOptions{})
+	require.NoError(t, err)
+	has, err = merged.HasTable(ctx, tableName)
+	require.NoError(t, err)
+	assert.False(t, has)
+	require.Contains(t, tblToStats, tableName)
+	assert.Equal(t, TableRemoved, tblToStats[tableName].Operation)
+}
+
+func TestMergeTableUniqueViolation(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	uniqueSch := schema.SchemaFromCols(mustColColl(
+		schema.NewColumn("id", 830, types.IntKind, true, schema.NotNullConstraint{}),
+		schema.NewColumn("email", 831, types.StringKind, false, schema.UniqueConstraint{}),
+	))
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, uniqueSch)
+	require.NoError(t, err)
+
+	rootWithEmails := func(emails map[int64]string) *doltdb.RootValue {
+		me, err := types.NewMap(ctx, vrw)
+		require.NoError(t, err)
+		ed := me.Edit()
+		for id, e := range emails {
+			r, err := row.New(types.Format_7_18, uniqueSch, row.TaggedValues{830: types.Int(id), 831: types.String(e)})
+			require.NoError(t, err)
+			ed = ed.Set(r.NomsMapKey(uniqueSch), r.NomsMapValue(uniqueSch))
+		}
+		rows, err := ed.Map(ctx)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	ancRoot := rootWithEmails(map[int64]string{0: "a@example.com"})
+
+	// each side inserts a different row with the same email
+	root := rootWithEmails(map[int64]string{0: "a@example.com", 1: "b@example.com"})
+	mergeRoot := rootWithEmails(map[int64]string{0: "a@example.com", 2: "b@example.com"})
+	_, _, err = NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUniqueViolation), "%v", err)
+	uverr, ok := err.(*UniqueViolationError)
+	require.True(t, ok)
+	assert.Equal(t, "email", uverr.Column)
+	assert.Equal(t, types.String("b@example.com"), uverr.Value)
+
+	mergeRoot = rootWithEmails(map[int64]string{0: "a@example.com", 2: "c@example.com"})
+	_, stats, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Adds)
+}
+
+func TestMergeCommitsRowTransform(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	const pkTag, valTag = 860, 861
+	cols, err := schema.NewColCollection(
+		schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}),
+		schema.NewColumn("val", valTag, types.StringKind, false),
+	)
+	require.NoError(t, err)
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(cols))
+	require.NoError(t, err)
+
+	rowsWithVals := func(pkToVal map[int64]string) types.Map {
+		var kvs []types.Value
+		for pk, val := range pkToVal {
+			kvs = append(kvs,
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))),
+				mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), types.String(val))))
+		}
+
+		rows, err := types.NewMap(ctx, vrw, kvs...)
+		require.NoError(t, err)
+		return rows
+	}
+
+	commitRows := func(parent *doltdb.Commit, pkToVal map[int64]string) *doltdb.Commit {
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rowsWithVals(pkToVal))
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, []*doltdb.Commit{parent}, meta)
+		require.NoError(t, err)
+		return cm
+	}
+
+	// we inserted 2 and they inserted 3, and both sides changed 4 to the same value
+	anc := commitRows(masterHead, map[int64]string{1: "a", 4: "d"})
+	ours := commitRows(anc, map[int64]string{1: "a", 2: "b", 4: "dd"})
+	theirs := commitRows(anc, map[int64]string{1: "a", 3: "c", 4: "dd"})
+
+	upper := func(table string, sch schema.Schema, r row.Row) (row.Row, error) {
+		assert.Equal(t, tableName, table)
+		val, _ := r.GetColVal(valTag)
+		return r.SetColVal(valTag, types.String(strings.ToUpper(string(val.(types.String)))), sch)
+	}
+
+	mergedRoot, _, err := MergeCommitsWithOptions(ctx, ddb, ours, theirs, MergeOptions{RowTransform: upper})
+	require.NoError(t, err)
+	tbl, ok, err := mergedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	mergedRows, err := tbl.GetRowData(ctx)
+	require.NoError(t, err)
+	assert.True(t, rowsWithVals(map[int64]string{1: "A", 2: "B", 3: "C", 4: "DD"}).Equals(mergedRows))
+
+	// the rows of a table changed on only one side are transformed too
+	mergedRoot, _, err = MergeCommitsWithOptions(ctx, ddb, ours, anc, MergeOptions{RowTransform: upper})
+	require.NoError(t, err)
+	tbl, ok, err = mergedRoot.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	mergedRows, err = tbl.GetRowData(ctx)
+	require.NoError(t, err)
+	assert.True(t, rowsWithVals(map[int64]string{1: "A", 2: "B", 4: "DD"}).Equals(mergedRows))
+
+	errTransform := errors.New("transform failed")
+	failing := func(table string, sch schema.Schema, r row.Row) (row.Row, error) {
+		return nil, errTransform
+	}
+	_, _, err = MergeCommitsWithOptions(ctx, ddb, ours, theirs, MergeOptions{RowTransform: failing})
+	assert.True(t, errors.Is(err, errTransform))
+	assert.Contains(t, err.Error(), tableName)
+
+	changeKey := func(table string, sch schema.Schema, r row.Row) (row.Row, error) {
+		return r.SetColVal(pkTag, types.Int(100), sch)
+	}
+	_, _, err = MergeCommitsWithOptions(ctx, ddb, ours, theirs, MergeOptions{RowTransform: changeKey})
+	assert.True(t, errors.Is(err, ErrRowTransformChangedKey))
+}
+
+func TestMergeTableSchemaVirtualCols(t *testing.T) {
+	withVirtual := func(sch schema.Schema, cols ...schema.Column) schema.Schema {
+		withVirtual, err := schema.SchemaWithVirtualCols(sch, mustColColl(cols...))
+		require.NoError(t, err)
+		return withVirtual
+	}
+
+	nameLen := schema.NewColumn("name_len", 200, types.UintKind, false)
+	nameLenInt := schema.NewColumn("name_len", 200, types.IntKind, false)
+	nameLenStr := schema.NewColumn("name_len", 200, types.StringKind, false)
+	titleLen := schema.NewColumn("title_len", 201, types.UintKind, false)
+	ancSch := withVirtual(sch, nameLen)
+
+	// added on our side, changed on theirs
+	mergedSch, err := mergeTableSchema(withVirtual(sch, nameLen, titleLen), withVirtual(sch, nameLenInt), ancSch)
+	require.NoError(t, err)
+	assert.Equal(t, mustColColl(nameLenInt, titleLen), mergedSch.VirtualCols())
+	assert.Equal(t, sch.GetAllCols().GetColumns(), mergedSch.GetAllCols().GetColumns())
+
+	// dropped on their side
+	mergedSch, err = mergeTableSchema(ancSch, sch, ancSch)
+	require.NoError(t, err)
+	assert.Equal(t, 0, mergedSch.VirtualCols().Size())
+
+	// changed differently on both sides
+	_, err = mergeTableSchema(withVirtual(sch, nameLenInt), withVirtual(sch, nameLenStr), ancSch)
+	assert.Error(t, err)
+
+	// a virtual column added on one side and a stored column added on the other with the same tag
+	storedTitleLen := schema.NewColumn("title_len", 201, types.UintKind, false)
+	mergeSch := withVirtual(schema.SchemaFromCols(mustColColl(append(colColl.GetColumns(), storedTitleLen)...)), nameLen)
+	_, err = mergeTableSchema(withVirtual(sch, nameLen, titleLen), mergeSch, ancSch)
+	assert.Equal(t, schema.ErrColTagCollision, err)
+}
@@ -0,0 +1,426 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/diff"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/marshal"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+var ErrPatchConflicts = errors.New("merge has conflicts, which can't be described by a patch")
+var ErrPatchDoesNotApply = errors.New("patch does not apply to root")
+
+// Patch is the set of changes a merge makes to the root being merged into, as returned by MergePatch. It describes
+// the tables' schemas and rows. Changes to the super schemas of the root's tables, which record the columns the
+// tables have ever had, are not included.
+type Patch struct {
+	// Tables are the patches of the tables the merge changes, ordered by name.
+	Tables []TablePatch
+}
+
+// TablePatch is the change a merge makes to a single table.
+type TablePatch struct {
+	Name string
+
+	// Operation is TableAdded, TableRemoved or TableModified.
+	Operation TableMergeOp
+
+	// OldSchema and Schema are the table's schemas before and after the merge. OldSchema is nil for an added table,
+	// and Schema for a removed one.
+	OldSchema schema.Schema
+	Schema    schema.Schema
+
+	// RowChanges are the changes to the table's row data, in key order. Each change's OldValue is the row's value
+	// before the merge, and NewValue its value after. The changed cells of a modified row are those which differ
+	// between them. A removed table has no row changes.
+	RowChanges []types.ValueChanged
+}
+
+// SchemaChanges returns the changes to the columns of the table, as reported by diff.DiffSchemas. Unchanged columns
+// are left out.
+func (tp TablePatch) SchemaChanges() []diff.SchemaDifference {
+	oldSch, sch := tp.OldSchema, tp.Schema
+	if oldSch == nil {
+		oldSch = schema.EmptySchema
+	}
+	if sch == nil {
+		sch = schema.EmptySchema
+	}
+
+	var changes []diff.SchemaDifference
+	diffs, tags := diff.DiffSchemas(oldSch, sch)
+	for _, tag := range tags {
+		if diffs[tag].DiffType != diff.SchDiffNone {
+			changes = append(changes, diffs[tag])
+		}
+	}
+
+	return changes
+}
+
+// MergePatch returns the changes MergeCommits would make to the root of |commit| to merge |mergeCommit| into it,
+// without returning a merged root. A merge which has conflicts fails with ErrPatchConflicts. The patch can be
+// applied later with ApplyPatch, and stored with its ToNomsValue.
+func MergePatch(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) (*Patch, error) {
+	mergedRoot, tblToStats, err := mergeCommits(ctx, ddb, commit, mergeCommit, MergeOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicted []string
+	for tblName, stats := range tblToStats {
+		if stats.Conflicts > 0 || stats.TableConflict != NoTableConflict {
+			conflicted = append(conflicted, tblName)
+		}
+	}
+
+	if len(conflicted) > 0 {
+		sort.Strings(conflicted)
+		return nil, fmt.Errorf("%w: tables %v have conflicts", ErrPatchConflicts, conflicted)
+	}
+
+	root, err := commit.GetRootValue()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRootsAsPatch(ctx, root, mergedRoot)
+}
+
+// diffRootsAsPatch returns the Patch which changes the tables of |root| into those of |newRoot|.
+func diffRootsAsPatch(ctx context.Context, root, newRoot *doltdb.RootValue) (*Patch, error) {
+	tblNames, err := doltdb.UnionTableNames(ctx, root, newRoot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(tblNames)
+
+	patch := &Patch{}
+	for _, tblName := range tblNames {
+		h, ok, err := root.GetTableHash(ctx, tblName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		newh, newOk, err := newRoot.GetTableHash(ctx, tblName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok == newOk && h == newh {
+			continue
+		}
+
+		tp := TablePatch{Name: tblName, Operation: TableModified}
+		oldRows, err := types.NewMap(ctx, root.VRW())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			tbl, _, err := root.GetTable(ctx, tblName)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tp.OldSchema, err = tbl.GetSchema(ctx)
+
+			if err != nil {
+				return nil, err
+			}
+
+			oldRows, err = tbl.GetRowData(ctx)
+
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			tp.Operation = TableAdded
+		}
+
+		if !newOk {
+			tp.Operation = TableRemoved
+			patch.Tables = append(patch.Tables, tp)
+			continue
+		}
+
+		newTbl, _, err := newRoot.GetTable(ctx, tblName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tp.Schema, err = newTbl.GetSchema(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		newRows, err := newTbl.GetRowData(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tp.RowChanges, err = diffRowData(ctx, newRows, oldRows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		patch.Tables = append(patch.Tables, tp)
+	}
+
+	return patch, nil
+}
+
+// diffRowData returns the changes from |oldRows| to |rows|, in key order.
+func diffRowData(ctx context.Context, rows, oldRows types.Map) ([]types.ValueChanged, error) {
+	ae := atomicerr.New()
+	changeChan, stopChan := make(chan types.ValueChanged, 32), make(chan struct{}, 1)
+
+	go func() {
+		rows.Diff(ctx, oldRows, ae, changeChan, stopChan)
+		close(changeChan)
+	}()
+
+	var changes []types.ValueChanged
+	for change := range changeChan {
+		changes = append(changes, change)
+	}
+
+	if err := ae.Get(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// ApplyPatch returns |root| with the changes of |patch| made to its tables. Each change must apply to the root as it
+// was before the merge the patch was made from: an added table must be missing from |root|, and each changed row must
+// have its OldValue. Otherwise ApplyPatch returns an ErrPatchDoesNotApply error.
+func ApplyPatch(ctx context.Context, root *doltdb.RootValue, patch *Patch) (*doltdb.RootValue, error) {
+	vrw := root.VRW()
+	for _, tp := range patch.Tables {
+		tbl, ok, err := root.GetTable(ctx, tp.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok && tp.Operation == TableAdded {
+			return nil, fmt.Errorf("%w: added table %s already exists", ErrPatchDoesNotApply, tp.Name)
+		} else if !ok && tp.Operation != TableAdded {
+			return nil, fmt.Errorf("%w: table %s does not exist", ErrPatchDoesNotApply, tp.Name)
+		}
+
+		if tp.Operation == TableRemoved {
+			root, err = root.RemoveTables(ctx, tp.Name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		rows, err := types.NewMap(ctx, vrw)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rows, err = tbl.GetRowData(ctx)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		me := rows.Edit()
+		for _, change := range tp.RowChanges {
+			v, found, err := rows.MaybeGet(ctx, change.Key)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if found != (change.OldValue != nil) || (found && !v.Equals(change.OldValue)) {
+				return nil, fmt.Errorf("%w: a row of table %s was changed since the patch was made", ErrPatchDoesNotApply, tp.Name)
+			}
+
+			if change.NewValue == nil {
+				me.Remove(change.Key)
+			} else {
+				me.Set(change.Key, change.NewValue)
+			}
+		}
+
+		rows, err = me.Map(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, tp.Schema)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tbl, err = doltdb.NewTable(ctx, vrw, schVal, rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		root, err = root.PutTable(ctx, tp.Name, tbl)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+type encodedPatch struct {
+	Tables []encodedTablePatch `noms:"tables"`
+}
+
+type encodedTablePatch struct {
+	Name       string             `noms:"name"`
+	Operation  int                `noms:"operation"`
+	OldSchema  types.Value        `noms:"old_schema,omitempty"`
+	Schema     types.Value        `noms:"schema,omitempty"`
+	RowChanges []encodedRowChange `noms:"row_changes"`
+}
+
+type encodedRowChange struct {
+	Key      types.Value `noms:"key"`
+	OldValue types.Value `noms:"old_value,omitempty"`
+	NewValue types.Value `noms:"new_value,omitempty"`
+}
+
+// ToNomsValue returns the patch as a Noms value, which can be written to a database and read back with
+// PatchFromNomsValue.
+func (p *Patch) ToNomsValue(ctx context.Context, vrw types.ValueReadWriter) (types.Value, error) {
+	enc := encodedPatch{Tables: make([]encodedTablePatch, len(p.Tables))}
+	for i, tp := range p.Tables {
+		encTP := encodedTablePatch{
+			Name:       tp.Name,
+			Operation:  int(tp.Operation),
+			RowChanges: make([]encodedRowChange, len(tp.RowChanges)),
+		}
+
+		var err error
+		if tp.OldSchema != nil {
+			encTP.OldSchema, err = encoding.MarshalSchemaAsNomsValue(ctx, vrw, tp.OldSchema)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if tp.Schema != nil {
+			encTP.Schema, err = encoding.MarshalSchemaAsNomsValue(ctx, vrw, tp.Schema)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for j, change := range tp.RowChanges {
+			encTP.RowChanges[j] = encodedRowChange{change.Key, change.OldValue, change.NewValue}
+		}
+
+		enc.Tables[i] = encTP
+	}
+
+	return marshal.Marshal(ctx, vrw, enc)
+}
+
+// PatchFromNomsValue returns the Patch encoded in |v| by Patch.ToNomsValue.
+func PatchFromNomsValue(ctx context.Context, nbf *types.NomsBinFormat, v types.Value) (*Patch, error) {
+	var enc encodedPatch
+	err := marshal.Unmarshal(ctx, nbf, v, &enc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &Patch{Tables: make([]TablePatch, len(enc.Tables))}
+	for i, encTP := range enc.Tables {
+		tp := TablePatch{
+			Name:       encTP.Name,
+			Operation:  TableMergeOp(encTP.Operation),
+			RowChanges: make([]types.ValueChanged, len(encTP.RowChanges)),
+		}
+
+		if encTP.OldSchema != nil {
+			tp.OldSchema, err = encoding.UnmarshalSchemaNomsValue(ctx, nbf, encTP.OldSchema)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if encTP.Schema != nil {
+			tp.Schema, err = encoding.UnmarshalSchemaNomsValue(ctx, nbf, encTP.Schema)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for j, encChange := range encTP.RowChanges {
+			change := types.ValueChanged{ChangeType: types.DiffChangeModified, Key: encChange.Key, OldValue: encChange.OldValue, NewValue: encChange.NewValue}
+			if change.OldValue == nil {
+				change.ChangeType = types.DiffChangeAdded
+			} else if change.NewValue == nil {
+				change.ChangeType = types.DiffChangeRemoved
+			}
+
+			tp.RowChanges[j] = change
+		}
+
+		patch.Tables[i] = tp
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/diff"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestMergePatch(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	// the primary key and value tags of each table
+	const extraTag = 892
+	tblTags := map[string][2]uint64{"people": {890, 891}, "added": {893, 894}, "removed": {895, 896}, "ours": {897, 898}}
+
+	type tableData struct {
+		// extra adds a column, which the row with primary key 100 has a value for
+		extra bool
+		rows  map[int64]string
+	}
+
+	commitTables := func(tables map[string]tableData, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, data := range tables {
+			pkTag, valTag := tblTags[tblName][0], tblTags[tblName][1]
+			cols := []schema.Column{
+				schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}),
+				schema.NewColumn("val", valTag, types.StringKind, false),
+			}
+			if data.extra {
+				cols = append(cols, schema.NewColumn("extra", extraTag, types.StringKind, false))
+			}
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, schema.SchemaFromCols(mustColColl(cols...)))
+			require.NoError(t, err)
+
+			var kvs []types.Value
+			for pk, val := range data.rows {
+				v := mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), types.String(val)))
+				if pk == 100 {
+					v = mustTuple(types.NewTuple(vrw.Format(), types.Uint(valTag), types.String(val), types.Uint(extraTag), types.String("extra")))
+				}
+				kvs = append(kvs, mustTuple(types.NewTuple(vrw.Format(), types.Uint(pkTag), types.Int(pk))), v)
+			}
+
+			rows, err := types.NewMap(ctx, vrw, kvs...)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	base := commitTables(map[string]tableData{
+		"people":  {rows: map[int64]string{1: "one", 2: "two", 3: "three"}},
+		"removed": {rows: map[int64]string{1: "one"}},
+	}, masterHead)
+	commit := commitTables(map[string]tableData{
+		"people":  {rows: map[int64]string{1: "uno", 2: "two", 3: "three"}},
+		"removed": {rows: map[int64]string{1: "one"}},
+		"ours":    {rows: map[int64]string{1: "one"}},
+	}, base)
+	mergeCommit := commitTables(map[string]tableData{
+		"people": {extra: true, rows: map[int64]string{1: "one", 3: "tres", 4: "four", 100: "hundred"}},
+		"added":  {rows: map[int64]string{1: "one"}},
+	}, base)
+
+	root, err := commit.GetRootValue()
+	require.NoError(t, err)
+	mergedRoot, _, err := MergeCommits(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	// the patch applied to our root must give the merged tables
+	assertAppliesToMerged := func(t *testing.T, patch *Patch) {
+		patchedRoot, err := ApplyPatch(ctx, root, patch)
+		require.NoError(t, err)
+
+		tblNames, err := patchedRoot.GetTableNames(ctx)
+		require.NoError(t, err)
+		mergedTblNames, err := mergedRoot.GetTableNames(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, mergedTblNames, tblNames)
+
+		for _, tblName := range mergedTblNames {
+			tbl, _, err := patchedRoot.GetTable(ctx, tblName)
+			require.NoError(t, err)
+			mergedTbl, _, err := mergedRoot.GetTable(ctx, tblName)
+			require.NoError(t, err)
+
+			sch, err := tbl.GetSchema(ctx)
+			require.NoError(t, err)
+			mergedSch, err := mergedTbl.GetSchema(ctx)
+			require.NoError(t, err)
+			eq, err := schema.SchemasAreEqual(sch, mergedSch)
+			require.NoError(t, err)
+			assert.True(t, eq, "schema of table %s", tblName)
+
+			rows, err := tbl.GetRowData(ctx)
+			require.NoError(t, err)
+			mergedRows, err := mergedTbl.GetRowData(ctx)
+			require.NoError(t, err)
+			assert.True(t, rows.Equals(mergedRows), "rows of table %s", tblName)
+		}
+	}
+
+	patch, err := MergePatch(ctx, ddb, commit, mergeCommit)
+	require.NoError(t, err)
+
+	t.Run("tables", func(t *testing.T) {
+		var tblNames []string
+		for _, tp := range patch.Tables {
+			tblNames = append(tblNames, tp.Name)
+		}
+		require.Equal(t, []string{"added", "people", "removed"}, tblNames)
+
+		added, people, removed := patch.Tables[0], patch.Tables[1], patch.Tables[2]
+		assert.Equal(t, TableAdded, added.Operation)
+		assert.Nil(t, added.OldSchema)
+		assert.Len(t, added.RowChanges, 1)
+
+		assert.Equal(t, TableRemoved, removed.Operation)
+		assert.Nil(t, removed.Schema)
+		assert.Empty(t, removed.RowChanges)
+
+		assert.Equal(t, TableModified, people.Operation)
+		schChanges := people.SchemaChanges()
+		require.Len(t, schChanges, 1)
+		assert.Equal(t, diff.SchDiffColAdded, schChanges[0].DiffType)
+		assert.Equal(t, uint64(extraTag), schChanges[0].New.Tag)
+
+		// 2 is removed, 3 is modified, and 4 and 100 are added
+		var changeTypes []types.DiffChangeType
+		for _, change := range people.RowChanges {
+			changeTypes = append(changeTypes, change.ChangeType)
+		}
+		assert.Equal(t, []types.DiffChangeType{types.DiffChangeRemoved, types.DiffChangeModified, types.DiffChangeAdded, types.DiffChangeAdded}, changeTypes)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		assertAppliesToMerged(t, patch)
+	})
+
+	t.Run("apply after round trip", func(t *testing.T) {
+		v, err := patch.ToNomsValue(ctx, vrw)
+		require.NoError(t, err)
+		decoded, err := PatchFromNomsValue(ctx, vrw.Format(), v)
+		require.NoError(t, err)
+		require.Len(t, decoded.Tables, len(patch.Tables))
+		for i := range patch.Tables {
+			assert.Equal(t, patch.Tables[i].Name, decoded.Tables[i].Name)
+			assert.Equal(t, patch.Tables[i].Operation, decoded.Tables[i].Operation)
+			require.Len(t, decoded.Tables[i].RowChanges, len(patch.Tables[i].RowChanges))
+			for j, change := range patch.Tables[i].RowChanges {
+				decodedChange := decoded.Tables[i].RowChanges[j]
+				assert.Equal(t, change.ChangeType, decodedChange.ChangeType)
+				assert.True(t, change.Key.Equals(decodedChange.Key))
+				assert.Equal(t, change.OldValue == nil, decodedChange.OldValue == nil)
+				assert.True(t, change.OldValue == nil || change.OldValue.Equals(decodedChange.OldValue))
+				assert.True(t, change.NewValue == nil || change.NewValue.Equals(decodedChange.NewValue))
+			}
+		}
+
+		assertAppliesToMerged(t, decoded)
+	})
+
+	t.Run("apply to another root", func(t *testing.T) {
+		// the added table already exists
+		_, err = ApplyPatch(ctx, mergedRoot, patch)
+		assert.True(t, errors.Is(err, ErrPatchDoesNotApply))
+
+		// the row with primary key 2 was already removed
+		changed := commitTables(map[string]tableData{
+			"people":  {rows: map[int64]string{1: "uno", 3: "three"}},
+			"removed": {rows: map[int64]string{1: "one"}},
+		}, commit)
+		changedRoot, err := changed.GetRootValue()
+		require.NoError(t, err)
+		_, err = ApplyPatch(ctx, changedRoot, patch)
+		assert.True(t, errors.Is(err, ErrPatchDoesNotApply))
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		conflicting := commitTables(map[string]tableData{
+			"people":  {rows: map[int64]string{1: "ein", 2: "two", 3: "three"}},
+			"removed": {rows: map[int64]string{1: "one"}},
+		}, base)
+
+		_, err := MergePatch(ctx, ddb, commit, conflicting)
+		assert.True(t, errors.Is(err, ErrPatchConflicts))
+	})
+}
+
+func TestMergePatchNoChanges(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+
+	patch, err := MergePatch(ctx, ddb, masterHead, masterHead)
+	require.NoError(t, err)
+	assert.Empty(t, patch.Tables)
+
+	root, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+	patchedRoot, err := ApplyPatch(ctx, root, patch)
+	require.NoError(t, err)
+	tblNames, err := patchedRoot.GetTableNames(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tblNames)
+}
@@ -0,0 +1,237 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/diff"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+)
+
+// SchemaIncompatibilityKind is the reason the schemas of a table can't be merged.
+type SchemaIncompatibilityKind int
+
+const (
+	// KeylessIncompatibility is a table which is keyless on one side of the merge and keyed on the other.
+	KeylessIncompatibility SchemaIncompatibilityKind = iota
+
+	// TypeIncompatibility is a column with the same tag on both sides whose types can't be merged.
+	TypeIncompatibility
+
+	// TagIncompatibility is a column with the same name on both sides but different tags.
+	TagIncompatibility
+
+	// AddedIncompatibility is a table added on both sides with different schemas.
+	AddedIncompatibility
+)
+
+// SchemaIncompatibility is a difference between the schemas of a table on either side of a merge which would fail
+// the merge.
+type SchemaIncompatibility struct {
+	TableName string
+	Kind      SchemaIncompatibilityKind
+	Column    string
+	Message   string
+}
+
+func (si SchemaIncompatibility) String() string {
+	if si.Column == "" {
+		return fmt.Sprintf("%s: %s", si.TableName, si.Message)
+	}
+
+	return fmt.Sprintf("%s.%s: %s", si.TableName, si.Column, si.Message)
+}
+
+// SchemaIncompatibilityError is returned by CheckMergeable, listing every incompatibility found.
+type SchemaIncompatibilityError struct {
+	Incompatibilities []SchemaIncompatibility
+}
+
+func (e *SchemaIncompatibilityError) Error() string {
+	msgs := make([]string, len(e.Incompatibilities))
+	for i, si := range e.Incompatibilities {
+		msgs[i] = si.String()
+	}
+
+	return "schemas can't be merged:\n\t" + strings.Join(msgs, "\n\t")
+}
+
+// CheckMergeable checks, before any rows are merged, that the schema of each table in both |commit| and
+// |mergeCommit| can be merged. Only tables which changed on both sides since their merge base have their schemas
+// merged, so only they are checked. It returns a *SchemaIncompatibilityError listing every incompatibility found.
+func CheckMergeable(ctx context.Context, ddb *doltdb.DoltDB, commit, mergeCommit *doltdb.Commit) error {
+	ancRoot, err := mergeBaseRoot(ctx, ddb, commit, mergeCommit)
+
+	if err != nil {
+		return err
+	}
+
+	root, err := commit.GetRootValue()
+
+	if err != nil {
+		return err
+	}
+
+	mergeRoot, err := mergeCommit.GetRootValue()
+
+	if err != nil {
+		return err
+	}
+
+	return checkRootsMergeable(ctx, root, mergeRoot, ancRoot)
+}
+
+func checkRootsMergeable(ctx context.Context, root, mergeRoot, ancRoot *doltdb.RootValue) error {
+	tblNames, err := doltdb.UnionTableNames(ctx, root, mergeRoot)
+
+	if err != nil {
+		return err
+	}
+
+	// incompatibilities are listed in table name order
+	sort.Strings(tblNames)
+
+	var incompatibilities []SchemaIncompatibility
+	for _, tblName := range tblNames {
+		tblIncompatibilities, err := checkTableMergeable(ctx, tblName, root, mergeRoot, ancRoot)
+
+		if err != nil {
+			return err
+		}
+
+		incompatibilities = append(incompatibilities, tblIncompatibilities...)
+	}
+
+	if len(incompatibilities) > 0 {
+		return &SchemaIncompatibilityError{incompatibilities}
+	}
+
+	return nil
+}
+
+func checkTableMergeable(ctx context.Context, tblName string, root, mergeRoot, ancRoot *doltdb.RootValue) ([]SchemaIncompatibility, error) {
+	h, ok, err := root.GetTableHash(ctx, tblName)
+
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	mh, mergeOk, err := mergeRoot.GetTableHash(ctx, tblName)
+
+	if err != nil || !mergeOk || h == mh {
+		return nil, err
+	}
+
+	anch, ancOk, err := ancRoot.GetTableHash(ctx, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ancOk && (h == anch || mh == anch) {
+		return nil, nil
+	}
+
+	sch, err := tableSchema(ctx, root, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mergeSch, err := tableSchema(ctx, mergeRoot, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ancSch schema.Schema
+	if ancOk {
+		ancSch, err = tableSchema(ctx, ancRoot, tblName)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schemaIncompatibilities(tblName, sch, mergeSch, ancSch), nil
+}
+
+func tableSchema(ctx context.Context, root *doltdb.RootValue, tblName string) (schema.Schema, error) {
+	tbl, _, err := root.GetTable(ctx, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl.GetSchema(ctx)
+}
+
+// schemaIncompatibilities returns the reasons mergeTableSchema would fail to merge |sch| and |mergeSch|. |ancSch| is
+// nil when the table was added on both sides.
+func schemaIncompatibilities(tblName string, sch, mergeSch, ancSch schema.Schema) []SchemaIncompatibility {
+	keyless := schema.IsKeyless(sch)
+	if schema.IsKeyless(mergeSch) != keyless || (ancSch != nil && schema.IsKeyless(ancSch) != keyless) {
+		return []SchemaIncompatibility{{TableName: tblName, Kind: KeylessIncompatibility, Message: ErrKeylessSchemaChange.Error()}}
+	}
+
+	if ancSch == nil {
+		if eq, err := schema.SchemasAreEqual(sch, mergeSch); err != nil || !eq {
+			return []SchemaIncompatibility{{TableName: tblName, Kind: AddedIncompatibility, Message: ErrSameTblAddedTwice.Error()}}
+		}
+
+		return nil
+	}
+
+	var incompatibilities []SchemaIncompatibility
+	diffs, tags := diff.DiffSchemas(sch, mergeSch)
+	for _, tag := range tags {
+		d := diffs[tag]
+
+		if d.DiffType != diff.SchDiffColModified || d.Old.TypeInfo.Equals(d.New.TypeInfo) {
+			continue
+		}
+
+		if _, ok := mergeColumnType(*d.Old, *d.New, ancSch, keyless); !ok {
+			incompatibilities = append(incompatibilities, SchemaIncompatibility{
+				TableName: tblName,
+				Kind:      TypeIncompatibility,
+				Column:    d.Old.Name,
+				Message:   fmt.Sprintf("tag %d has incompatible types %v and %v", tag, d.Old.TypeInfo, d.New.TypeInfo),
+			})
+		}
+	}
+
+	_ = sch.GetAllCols().Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+		mergeCol, ok := mergeSch.GetAllCols().GetByNameCaseInsensitive(col.Name)
+
+		if ok && mergeCol.Tag != tag {
+			incompatibilities = append(incompatibilities, SchemaIncompatibility{
+				TableName: tblName,
+				Kind:      TagIncompatibility,
+				Column:    col.Name,
+				Message:   fmt.Sprintf("same name has tags %d and %d", tag, mergeCol.Tag),
+			})
+		}
+
+		return false, nil
+	})
+
+	return incompatibilities
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestCheckMergeable(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	col := func(name string, tag uint64, ti typeinfo.TypeInfo) schema.Column {
+		c, err := schema.NewColumnWithTypeInfo(name, tag, ti, false)
+		require.NoError(t, err)
+		return c
+	}
+
+	// tags are unique across tables, so each table has its own
+	tblTags := map[string]uint64{"t1": 700, "t2": 710, "t3": 720}
+
+	// |extra| columns follow a val column of type |ti|
+	keyedSch := func(tblName string, ti typeinfo.TypeInfo, extra ...schema.Column) schema.Schema {
+		pkTag := tblTags[tblName]
+		cols := append([]schema.Column{schema.NewColumn("pk", pkTag, types.IntKind, true, schema.NotNullConstraint{}), col("val", pkTag+1, ti)}, extra...)
+		return schema.SchemaFromCols(mustColColl(cols...))
+	}
+
+	commitSchemas := func(tblToSch map[string]schema.Schema, parents ...*doltdb.Commit) *doltdb.Commit {
+		root := emptyRoot
+		for tblName, sch := range tblToSch {
+			schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+			require.NoError(t, err)
+			rows, err := types.NewMap(ctx, vrw)
+			require.NoError(t, err)
+			tbl, err := doltdb.NewTable(ctx, vrw, schVal, rows)
+			require.NoError(t, err)
+			root, err = root.PutTable(ctx, tblName, tbl)
+			require.NoError(t, err)
+		}
+
+		h, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := doltdb.NewCommitMeta(name, email, "commit")
+		require.NoError(t, err)
+		cm, err := ddb.CommitDanglingWithParentCommits(ctx, h, parents, meta)
+		require.NoError(t, err)
+
+		return cm
+	}
+
+	base := commitSchemas(map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type), "t2": keyedSch("t2", typeinfo.Int32Type)}, masterHead)
+
+	tests := []struct {
+		name          string
+		ours, theirs  map[string]schema.Schema
+		expectedKinds []SchemaIncompatibilityKind
+	}{
+		{
+			name:   "clean",
+			ours:   map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type, col("a", 702, typeinfo.StringDefaultType)), "t2": keyedSch("t2", typeinfo.Int32Type)},
+			theirs: map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int64Type), "t2": keyedSch("t2", typeinfo.StringDefaultType)},
+		},
+		{
+			name:          "same tag, incompatible type",
+			ours:          map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.StringDefaultType)},
+			theirs:        map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type, col("b", 703, typeinfo.StringDefaultType))},
+			expectedKinds: []SchemaIncompatibilityKind{TypeIncompatibility},
+		},
+		{
+			name:          "same name, different tag",
+			ours:          map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type, col("c", 704, typeinfo.StringDefaultType))},
+			theirs:        map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type, col("c", 705, typeinfo.StringDefaultType))},
+			expectedKinds: []SchemaIncompatibilityKind{TagIncompatibility},
+		},
+		{
+			name:          "keyless change",
+			ours:          map[string]schema.Schema{"t1": schema.KeylessSchemaFromCols(mustColColl(col("val", 701, typeinfo.Int32Type)))},
+			theirs:        map[string]schema.Schema{"t1": keyedSch("t1", typeinfo.Int32Type, col("d", 706, typeinfo.StringDefaultType))},
+			expectedKinds: []SchemaIncompatibilityKind{KeylessIncompatibility},
+		},
+		{
+			name:   "added on both sides",
+			ours:   map[string]schema.Schema{"t3": keyedSch("t3", typeinfo.Int32Type, col("e", 707, typeinfo.StringDefaultType))},
+			theirs: map[string]schema.Schema{"t3": keyedSch("t3", typeinfo.Int32Type, col("e", 707, typeinfo.StringDefaultType))},
+		},
+		{
+			name:          "added on both sides with different schemas",
+			ours:          map[string]schema.Schema{"t3": keyedSch("t3", typeinfo.Int32Type, col("e", 707, typeinfo.StringDefaultType))},
+			theirs:        map[string]schema.Schema{"t3": keyedSch("t3", typeinfo.Int32Type, col("f", 708, typeinfo.StringDefaultType))},
+			expectedKinds: []SchemaIncompatibilityKind{AddedIncompatibility},
+		},
+		{
+			name: "several tables",
+			ours: map[string]schema.Schema{
+				"t1": keyedSch("t1", typeinfo.StringDefaultType),
+				"t2": keyedSch("t2", typeinfo.Int32Type, col("c", 714, typeinfo.StringDefaultType)),
+			},
+			theirs: map[string]schema.Schema{
+				"t1": keyedSch("t1", typeinfo.Int32Type, col("b", 703, typeinfo.StringDefaultType)),
+				"t2": keyedSch("t2", typeinfo.Int32Type, col("c", 715, typeinfo.StringDefaultType)),
+			},
+			expectedKinds: []SchemaIncompatibilityKind{TypeIncompatibility, TagIncompatibility},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commit, mergeCommit := commitSchemas(test.ours, base), commitSchemas(test.theirs, base)
+			err := CheckMergeable(ctx, ddb, commit, mergeCommit)
+			_, _, mergeErr := MergeCommits(ctx, ddb, commit, mergeCommit)
+
+			if len(test.expectedKinds) == 0 {
+				assert.NoError(t, err)
+				assert.NoError(t, mergeErr)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Error(t, mergeErr)
+
+			incompatErr, ok := err.(*SchemaIncompatibilityError)
+			require.True(t, ok)
+
+			var kinds []SchemaIncompatibilityKind
+			for _, si := range incompatErr.Incompatibilities {
+				kinds = append(kinds, si.Kind)
+				assert.Contains(t, err.Error(), si.String())
+			}
+			assert.Equal(t, test.expectedKinds, kinds)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+var ErrPrimaryKeyMismatch = errors.New("primary keys differ between the sides of the merge")
+
+// PrimaryKeyMismatchError is returned by MergeTable when the primary key of a table was changed since the merge base
+// in a way that the rows of each side can't be aligned. Each key is the names of its columns, in order.
+type PrimaryKeyMismatchError struct {
+	Table    string
+	OurKey   []string
+	TheirKey []string
+	BaseKey  []string
+}
+
+func (e *PrimaryKeyMismatchError) Error() string {
+	return fmt.Sprintf("%s: table %s has primary key (%s) on our side, (%s) on theirs and (%s) in the merge base",
+		ErrPrimaryKeyMismatch.Error(), e.Table, strings.Join(e.OurKey, ", "), strings.Join(e.TheirKey, ", "), strings.Join(e.BaseKey, ", "))
+}
+
+// Is reports whether |target| is ErrPrimaryKeyMismatch.
+func (e *PrimaryKeyMismatchError) Is(target error) bool {
+	return target == ErrPrimaryKeyMismatch
+}
+
+// primaryKeySchema returns the schema among |sch|, |mergeSch| and |ancSch| whose primary key the merged table takes.
+// When only one side changed the primary key, or both changed it the same way, it is that side's. The rows of the
+// other schemas can then be re-keyed by rekeyTable, as long as the new key only adds columns to theirs, and those
+// columns have the same types in them. Any other change is a *PrimaryKeyMismatchError.
+func primaryKeySchema(tblName string, sch, mergeSch, ancSch schema.Schema) (schema.Schema, error) {
+	if schema.IsKeyless(sch) || schema.IsKeyless(mergeSch) || schema.IsKeyless(ancSch) {
+		// changes to or from a keyless table are ErrKeylessSchemaChange
+		return sch, nil
+	}
+
+	pk, mergePK, ancPK := sch.GetPKCols().Tags, mergeSch.GetPKCols().Tags, ancSch.GetPKCols().Tags
+
+	var pkSch schema.Schema
+	switch {
+	case tagsEqual(pk, mergePK):
+		pkSch = sch
+	case tagsEqual(pk, ancPK):
+		pkSch = mergeSch
+	case tagsEqual(mergePK, ancPK):
+		pkSch = sch
+	}
+
+	if pkSch == nil || !canRekey(sch, pkSch) || !canRekey(mergeSch, pkSch) || !canRekey(ancSch, pkSch) {
+		return nil, &PrimaryKeyMismatchError{
+			Table:    tblName,
+			OurKey:   sch.GetPKCols().GetColumnNames(),
+			TheirKey: mergeSch.GetPKCols().GetColumnNames(),
+			BaseKey:  ancSch.GetPKCols().GetColumnNames(),
+		}
+	}
+
+	return pkSch, nil
+}
+
+func tagsEqual(tags, otherTags []uint64) bool {
+	if len(tags) != len(otherTags) {
+		return false
+	}
+
+	for i := range tags {
+		if tags[i] != otherTags[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// canRekey returns whether the rows of |sch| can be keyed by the primary key of |pkSch|.
+func canRekey(sch, pkSch schema.Schema) bool {
+	for _, tag := range sch.GetPKCols().Tags {
+		if _, ok := pkSch.GetPKCols().GetByTag(tag); !ok {
+			return false
+		}
+	}
+
+	for _, pkCol := range pkSch.GetPKCols().GetColumns() {
+		col, ok := sch.GetAllCols().GetByTag(pkCol.Tag)
+
+		if !ok || !col.TypeInfo.Equals(pkCol.TypeInfo) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rekeyTable returns |tbl|, whose schema is |sch|, with the primary key of |pkSch|, which canRekey must allow. The
+// columns added to the key take their definitions from |pkSch|. It is an ErrPrimaryKeyMismatch for a row to have a
+// null value for one of them. Keyless tables are returned as they are.
+func rekeyTable(ctx context.Context, vrw types.ValueReadWriter, tblName string, tbl *doltdb.Table, sch, pkSch schema.Schema) (*doltdb.Table, schema.Schema, error) {
+	if schema.IsKeyless(sch) || schema.IsKeyless(pkSch) || tagsEqual(sch.GetPKCols().Tags, pkSch.GetPKCols().Tags) {
+		return tbl, sch, nil
+	}
+
+	var nonPKCols []schema.Column
+	for _, col := range sch.GetNonPKCols().GetColumns() {
+		if _, ok := pkSch.GetPKCols().GetByTag(col.Tag); !ok {
+			nonPKCols = append(nonPKCols, col)
+		}
+	}
+
+	nonPKColl, err := schema.NewColCollection(nonPKCols...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rekeyedSch, err := schema.SchemaFromPKAndNonPKCols(pkSch.GetPKCols(), nonPKColl)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sch.VirtualCols().Size() > 0 {
+		rekeyedSch, err = schema.SchemaWithVirtualCols(rekeyedSch, sch.VirtualCols())
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rows, err := tbl.GetRowData(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rekeyedRows, err := types.NewMap(ctx, vrw)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	me := rekeyedRows.Edit()
+	err = rows.IterAll(ctx, func(key, value types.Value) error {
+		r, err := row.FromNoms(sch, key.(types.Tuple), value.(types.Tuple))
+
+		if err != nil {
+			return err
+		}
+
+		taggedVals, err := row.GetTaggedVals(r)
+
+		if err != nil {
+			return err
+		}
+
+		for _, col := range pkSch.GetPKCols().GetColumns() {
+			if val, ok := taggedVals.Get(col.Tag); !ok || types.IsNull(val) {
+				return fmt.Errorf("%w: table %s can't be re-keyed, a row has no value for primary key column %s", ErrPrimaryKeyMismatch, tblName, col.Name)
+			}
+		}
+
+		rekeyed, err := row.New(vrw.Format(), rekeyedSch, taggedVals)
+
+		if err != nil {
+			return err
+		}
+
+		me.Set(rekeyed.NomsMapKey(rekeyedSch), rekeyed.NomsMapValue(rekeyedSch))
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rekeyedRows, err = me.Map(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, rekeyedSch)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rekeyedTbl, err := doltdb.NewTable(ctx, vrw, schVal, rekeyedRows)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rekeyedTbl, rekeyedSch, nil
+}
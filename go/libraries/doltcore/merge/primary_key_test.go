@@ -0,0 +1,167 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+const (
+	pkIdTag     = 870
+	pkRegionTag = 871
+	pkValTag    = 872
+)
+
+// pkSchema returns a schema of the columns id, region and val, with the primary key |pkTags|.
+func pkSchema(pkTags ...uint64) schema.Schema {
+	names := map[uint64]string{pkIdTag: "id", pkRegionTag: "region", pkValTag: "val"}
+	kinds := map[uint64]types.NomsKind{pkIdTag: types.IntKind, pkRegionTag: types.StringKind, pkValTag: types.StringKind}
+
+	var pkCols, nonPKCols []schema.Column
+	for _, tag := range pkTags {
+		pkCols = append(pkCols, schema.NewColumn(names[tag], tag, kinds[tag], true, schema.NotNullConstraint{}))
+		delete(names, tag)
+	}
+
+	for _, tag := range []uint64{pkIdTag, pkRegionTag, pkValTag} {
+		if name, ok := names[tag]; ok {
+			nonPKCols = append(nonPKCols, schema.NewColumn(name, tag, kinds[tag], false))
+		}
+	}
+
+	sch, err := schema.SchemaFromPKAndNonPKCols(mustColColl(pkCols...), mustColColl(nonPKCols...))
+
+	if err != nil {
+		panic(err)
+	}
+
+	return sch
+}
+
+func TestMergeTablePrimaryKeyChange(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_7_18, doltdb.InMemDoltDB)
+	require.NoError(t, err)
+	vrw := ddb.ValueReadWriter()
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, name, email))
+
+	masterHeadSpec, _ := doltdb.NewCommitSpec("head", "master")
+	masterHead, err := ddb.Resolve(ctx, masterHeadSpec)
+	require.NoError(t, err)
+	emptyRoot, err := masterHead.GetRootValue()
+	require.NoError(t, err)
+
+	type pkRow struct {
+		id          int64
+		region, val string
+	}
+
+	rowData := func(sch schema.Schema, pkRows ...pkRow) types.Map {
+		me, err := types.NewMap(ctx, vrw)
+		require.NoError(t, err)
+		ed := me.Edit()
+		for _, pr := range pkRows {
+			r, err := row.New(vrw.Format(), sch, row.TaggedValues{
+				pkIdTag:     types.Int(pr.id),
+				pkRegionTag: types.String(pr.region),
+				pkValTag:    types.String(pr.val),
+			})
+			require.NoError(t, err)
+			ed.Set(r.NomsMapKey(sch), r.NomsMapValue(sch))
+		}
+		m, err := ed.Map(ctx)
+		require.NoError(t, err)
+		return m
+	}
+
+	rootWithRows := func(sch schema.Schema, pkRows ...pkRow) *doltdb.RootValue {
+		schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, sch)
+		require.NoError(t, err)
+		tbl, err := doltdb.NewTable(ctx, vrw, schVal, rowData(sch, pkRows...))
+		require.NoError(t, err)
+		root, err := emptyRoot.PutTable(ctx, tableName, tbl)
+		require.NoError(t, err)
+		return root
+	}
+
+	idSch, idRegionSch, idValSch, regionSch := pkSchema(pkIdTag), pkSchema(pkIdTag, pkRegionTag), pkSchema(pkIdTag, pkValTag), pkSchema(pkRegionTag)
+	ancRows := []pkRow{{1, "us", "a"}, {2, "eu", "b"}}
+	ancRoot := rootWithRows(idSch, ancRows...)
+
+	t.Run("key column added on our side", func(t *testing.T) {
+		// we added region to the key and changed a row, and they inserted a row
+		root := rootWithRows(idRegionSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "bb"})
+		mergeRoot := rootWithRows(idSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "b"}, pkRow{3, "us", "c"})
+
+		mergedTbl, stats, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		require.NoError(t, err)
+		assert.Equal(t, 0, stats.Conflicts)
+
+		mergedSch, err := mergedTbl.GetSchema(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{pkIdTag, pkRegionTag}, mergedSch.GetPKCols().Tags)
+
+		mergedRows, err := mergedTbl.GetRowData(ctx)
+		require.NoError(t, err)
+		expected := rowData(idRegionSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "bb"}, pkRow{3, "us", "c"})
+		assert.True(t, expected.Equals(mergedRows))
+	})
+
+	t.Run("key column added on their side", func(t *testing.T) {
+		root := rootWithRows(idSch, pkRow{1, "us", "aa"}, pkRow{2, "eu", "b"})
+		mergeRoot := rootWithRows(idRegionSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "b"}, pkRow{3, "us", "c"})
+
+		mergedTbl, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		require.NoError(t, err)
+
+		mergedRows, err := mergedTbl.GetRowData(ctx)
+		require.NoError(t, err)
+		expected := rowData(idRegionSch, pkRow{1, "us", "aa"}, pkRow{2, "eu", "b"}, pkRow{3, "us", "c"})
+		assert.True(t, expected.Equals(mergedRows))
+	})
+
+	t.Run("keys changed differently", func(t *testing.T) {
+		root := rootWithRows(idRegionSch, pkRow{1, "us", "aa"}, pkRow{2, "eu", "b"})
+		mergeRoot := rootWithRows(idValSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "bb"})
+
+		_, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		assert.True(t, errors.Is(err, ErrPrimaryKeyMismatch))
+
+		var pkErr *PrimaryKeyMismatchError
+		require.True(t, errors.As(err, &pkErr))
+		assert.Equal(t, []string{"id", "region"}, pkErr.OurKey)
+		assert.Equal(t, []string{"id", "val"}, pkErr.TheirKey)
+		assert.Equal(t, []string{"id"}, pkErr.BaseKey)
+	})
+
+	t.Run("key column removed", func(t *testing.T) {
+		root := rootWithRows(regionSch, pkRow{1, "us", "aa"}, pkRow{2, "eu", "b"})
+		mergeRoot := rootWithRows(idSch, pkRow{1, "us", "a"}, pkRow{2, "eu", "bb"})
+
+		_, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+		assert.True(t, errors.Is(err, ErrPrimaryKeyMismatch))
+	})
+}
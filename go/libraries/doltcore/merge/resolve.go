@@ -16,14 +16,19 @@ package merge
 
 import (
 	"context"
+	"errors"
 
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/table"
+	"github.com/liquidata-inc/dolt/go/store/hash"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
+var ErrNoConflictForKey = errors.New("no conflict for key")
+
 type AutoResolver func(key types.Value, conflict doltdb.Conflict) (types.Value, error)
 
 func Ours(key types.Value, cnf doltdb.Conflict) (types.Value, error) {
@@ -34,6 +39,44 @@ func Theirs(key types.Value, cnf doltdb.Conflict) (types.Value, error) {
 	return cnf.MergeValue, nil
 }
 
+type resolutionKind int
+
+const (
+	takeOurs resolutionKind = iota
+	takeTheirs
+	takeValue
+)
+
+// Resolution is the choice made for a single row in conflict.
+type Resolution struct {
+	kind  resolutionKind
+	value types.Value
+}
+
+var (
+	// TakeOurs resolves a conflict by keeping the row as it is on our side.
+	TakeOurs = Resolution{kind: takeOurs}
+
+	// TakeTheirs resolves a conflict by taking the row as it is on their side.
+	TakeTheirs = Resolution{kind: takeTheirs}
+)
+
+// TakeValue resolves a conflict by replacing the row's value with |v|. A null |v| removes the row.
+func TakeValue(v types.Value) Resolution {
+	return Resolution{kind: takeValue, value: v}
+}
+
+func (res Resolution) resolve(cnf doltdb.Conflict) types.Value {
+	switch res.kind {
+	case takeTheirs:
+		return cnf.MergeValue
+	case takeValue:
+		return res.value
+	default:
+		return cnf.Value
+	}
+}
+
 func ResolveTable(ctx context.Context, vrw types.ValueReadWriter, tbl *doltdb.Table, autoResFunc AutoResolver) (*doltdb.Table, error) {
 	if has, err := tbl.HasConflicts(); err != nil {
 		return nil, err
@@ -85,22 +128,10 @@ func ResolveTable(ctx context.Context, vrw types.ValueReadWriter, tbl *doltdb.Ta
 			return false, err
 		}
 
-		if types.IsNull(updated) {
-			rowEditor.Remove(key)
-		} else {
-			r, err := row.FromNoms(tblSch, key.(types.Tuple), updated.(types.Tuple))
+		err = applyResolvedRow(rowEditor, tblSch, key, updated)
 
-			if err != nil {
-				return false, err
-			}
-
-			if has, err := row.IsValid(r, tblSch); err != nil {
-				return false, err
-			} else if !has {
-				return false, table.NewBadRow(r)
-			}
-
-			rowEditor.Set(key, updated)
+		if err != nil {
+			return false, err
 		}
 
 		return false, nil
@@ -136,3 +167,134 @@ func ResolveTable(ctx context.Context, vrw types.ValueReadWriter, tbl *doltdb.Ta
 
 	return newTbl, nil
 }
+
+// ResolveConflicts applies |resolutions| to the conflicts of the table |tblName| in |root| and returns the updated
+// root. |resolutions| is keyed by the hash of the key tuple of each row in conflict. The resolved rows are removed
+// from the table's conflicts, and the table's conflicts are cleared once none remain. ErrNoConflictForKey is returned
+// if a resolution is given for a key which is not in conflict.
+func ResolveConflicts(ctx context.Context, root *doltdb.RootValue, tblName string, resolutions map[hash.Hash]Resolution) (*doltdb.RootValue, error) {
+	tbl, ok, err := root.GetTable(ctx, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, doltdb.ErrTableNotFound
+	}
+
+	tblSch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, conflicts, err := tbl.GetConflicts(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowData, err := tbl.GetRowData(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowEditor := rowData.Edit()
+	cnfEditor := conflicts.Edit()
+	resolved := 0
+	err = conflicts.Iter(ctx, func(key, value types.Value) (stop bool, err error) {
+		h, err := key.Hash(root.VRW().Format())
+
+		if err != nil {
+			return false, err
+		}
+
+		res, ok := resolutions[h]
+
+		if !ok {
+			return false, nil
+		}
+
+		cnf, err := doltdb.ConflictFromTuple(value.(types.Tuple))
+
+		if err != nil {
+			return false, err
+		}
+
+		err = applyResolvedRow(rowEditor, tblSch, key, res.resolve(cnf))
+
+		if err != nil {
+			return false, err
+		}
+
+		cnfEditor.Remove(key)
+		resolved++
+
+		return false, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved != len(resolutions) {
+		return nil, ErrNoConflictForKey
+	}
+
+	m, err := rowEditor.Map(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err = tbl.UpdateRows(ctx, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved == int(conflicts.Len()) {
+		tbl, err = tbl.ClearConflicts()
+	} else {
+		conflicts, err = cnfEditor.Map(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tbl, err = tbl.SetConflicts(ctx, schemas, conflicts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return root.PutTable(ctx, tblName, tbl)
+}
+
+// applyResolvedRow sets the row |key| to the resolved value |updated| in |rowEditor|, removing the row if |updated|
+// is null.
+func applyResolvedRow(rowEditor *types.MapEditor, tblSch schema.Schema, key, updated types.Value) error {
+	if types.IsNull(updated) {
+		rowEditor.Remove(key)
+		return nil
+	}
+
+	r, err := row.FromNoms(tblSch, key.(types.Tuple), updated.(types.Tuple))
+
+	if err != nil {
+		return err
+	}
+
+	if has, err := row.IsValid(r, tblSch); err != nil {
+		return err
+	} else if !has {
+		return table.NewBadRow(r)
+	}
+
+	rowEditor.Set(key, updated)
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	ctx := context.Background()
+	vrw, commit, mergeCommit, _, _ := setupMergeTest()
+
+	root, err := commit.GetRootValue()
+	require.NoError(t, err)
+	mergeRoot, err := mergeCommit.GetRootValue()
+	require.NoError(t, err)
+	ancCm, err := doltdb.GetCommitAncestor(ctx, commit, mergeCommit)
+	require.NoError(t, err)
+	ancRoot, err := ancCm.GetRootValue()
+	require.NoError(t, err)
+
+	merged, _, err := NewMerger(ctx, root, mergeRoot, ancRoot, vrw).MergeTable(ctx, tableName)
+	require.NoError(t, err)
+	root, err = root.PutTable(ctx, tableName, merged)
+	require.NoError(t, err)
+
+	keyHash := func(i int) hash.Hash {
+		h, err := keyTuples[i].Hash(vrw.Format())
+		require.NoError(t, err)
+		return h
+	}
+
+	getRow := func(root *doltdb.RootValue, i int) types.Value {
+		tbl, _, err := root.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		rows, err := tbl.GetRowData(ctx)
+		require.NoError(t, err)
+		return mustGetValue(rows.MaybeGet(ctx, keyTuples[i]))
+	}
+
+	_, err = ResolveConflicts(ctx, root, tableName, map[hash.Hash]Resolution{keyHash(0): TakeTheirs})
+	assert.Equal(t, ErrNoConflictForKey, err)
+
+	custom := valsToTestTupleWithoutPks([]types.Value{types.String("person 13"), types.String("custom")})
+	oursRow, theirsRow := getRow(root, 8), getRow(mergeRoot, 8)
+
+	t.Run("ours and custom", func(t *testing.T) {
+		resolved, err := ResolveConflicts(ctx, root, tableName, map[hash.Hash]Resolution{keyHash(8): TakeOurs, keyHash(12): TakeValue(custom)})
+		require.NoError(t, err)
+		assert.True(t, oursRow.Equals(getRow(resolved, 8)))
+		assert.True(t, custom.Equals(getRow(resolved, 12)))
+
+		tbl, _, err := resolved.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		has, err := tbl.HasConflicts()
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("theirs then remove", func(t *testing.T) {
+		resolved, err := ResolveConflicts(ctx, root, tableName, map[hash.Hash]Resolution{keyHash(8): TakeTheirs})
+		require.NoError(t, err)
+		assert.True(t, theirsRow.Equals(getRow(resolved, 8)))
+
+		tbl, _, err := resolved.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		n, err := tbl.NumRowsInConflict(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), n)
+
+		resolved, err = ResolveConflicts(ctx, resolved, tableName, map[hash.Hash]Resolution{keyHash(12): TakeValue(types.NullValue)})
+		require.NoError(t, err)
+		assert.Nil(t, getRow(resolved, 12))
+		assert.True(t, theirsRow.Equals(getRow(resolved, 8)))
+
+		tbl, _, err = resolved.GetTable(ctx, tableName)
+		require.NoError(t, err)
+		has, err := tbl.HasConflicts()
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+}
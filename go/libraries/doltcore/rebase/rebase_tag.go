@@ -795,7 +795,10 @@ func buildTagMapping(ctx context.Context, root, rebasedParentRoot *doltdb.RootVa
 		// generate tags with the same method as root.GenerateTagsForNewColumns()
 		newTags := make([]uint64, len(newColNames))
 		for i := range newTags {
-			newTags[i] = schema.AutoGenerateTag(existingRebasedTags, tn, existingColKinds, newColNames[i], newColKinds[i])
+			newTags[i], err = schema.TryAutoGenerateTag(existingRebasedTags, tn, existingColKinds, newColNames[i], newColKinds[i])
+			if err != nil {
+				return nil, err
+			}
 			existingColKinds = append(existingColKinds, newColKinds[i])
 			existingRebasedTags.Add(newTags[i])
 		}
@@ -138,7 +138,7 @@ func addColumnToSchema(sch schema.Schema, tag uint64, newColName string, typeInf
 		return nil, err
 	}
 
-	return schema.SchemaFromCols(collection), nil
+	return schema.SchemaWithVirtualCols(schema.SchemaFromCols(collection), sch.VirtualCols())
 }
 
 func createColumn(nullable Nullable, newColName string, tag uint64, typeInfo typeinfo.TypeInfo) (schema.Column, error) {
@@ -161,8 +161,7 @@ func validateNewColumn(ctx context.Context, root *doltdb.RootValue, tbl *doltdb.
 		return err
 	}
 
-	cols := sch.GetAllCols()
-	err = cols.Iter(func(currColTag uint64, currCol schema.Column) (stop bool, err error) {
+	checkCol := func(currColTag uint64, currCol schema.Column) (stop bool, err error) {
 		if currColTag == tag {
 			return false, schema.ErrTagPrevUsed(tag, newColName, tblName)
 		} else if currCol.Name == newColName {
@@ -171,7 +170,16 @@ func validateNewColumn(ctx context.Context, root *doltdb.RootValue, tbl *doltdb.
 		}
 
 		return false, nil
-	})
+	}
+
+	err = sch.GetAllCols().Iter(checkCol)
+
+	if err != nil {
+		return err
+	}
+
+	// virtual columns share the stored columns' tag space
+	err = sch.VirtualCols().Iter(checkCol)
 
 	if err != nil {
 		return err
@@ -23,10 +23,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/dtestutils"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/env"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/encoding"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/table"
 	"github.com/liquidata-inc/dolt/go/libraries/doltcore/table/typed/noms"
@@ -316,6 +318,59 @@ func TestAddColumnToTable(t *testing.T) {
 	}
 }
 
+func TestAddColumnToTableWithVirtualCols(t *testing.T) {
+	ctx := context.Background()
+	root, tbl, virtualCols := createTableWithVirtualCol(t)
+
+	updatedTable, err := AddColumnToTable(ctx, root, tbl, tableName, dtestutils.NextTag, "newCol", typeinfo.FromKind(types.StringKind), Null, nil, nil)
+	require.NoError(t, err)
+	sch, err := updatedTable.GetSchema(ctx)
+	require.NoError(t, err)
+	_, ok := sch.GetAllCols().GetByTag(dtestutils.NextTag)
+	assert.True(t, ok)
+	assert.Equal(t, virtualCols, sch.VirtualCols())
+
+	_, err = AddColumnToTable(ctx, root, tbl, tableName, virtualColTag, "newCol", typeinfo.FromKind(types.StringKind), Null, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("the tag %d was already used", virtualColTag))
+
+	_, err = addColumnToSchema(sch, virtualColTag, "newCol2", typeinfo.FromKind(types.StringKind), Null, nil)
+	assert.Equal(t, schema.ErrColTagCollision, err)
+}
+
+// virtualColTag is the tag of the virtual column of the table created by createTableWithVirtualCol.
+const virtualColTag = dtestutils.NextTag + 1
+
+// createTableWithVirtualCol returns the seed data table of createEnvWithSeedData with a virtual column added to its
+// schema, and the root holding it.
+func createTableWithVirtualCol(t *testing.T) (*doltdb.RootValue, *doltdb.Table, *schema.ColCollection) {
+	ctx := context.Background()
+	dEnv := createEnvWithSeedData(t)
+
+	root, err := dEnv.WorkingRoot(ctx)
+	require.NoError(t, err)
+	tbl, _, err := root.GetTable(ctx, tableName)
+	require.NoError(t, err)
+
+	sch, err := tbl.GetSchema(ctx)
+	require.NoError(t, err)
+	virtualCols, err := schema.NewColCollection(schema.NewColumn("name_len", virtualColTag, types.UintKind, false))
+	require.NoError(t, err)
+	sch, err = schema.SchemaWithVirtualCols(sch, virtualCols)
+	require.NoError(t, err)
+
+	schVal, err := encoding.MarshalSchemaAsNomsValue(ctx, tbl.ValueReadWriter(), sch)
+	require.NoError(t, err)
+	rowData, err := tbl.GetRowData(ctx)
+	require.NoError(t, err)
+	tbl, err = doltdb.NewTable(ctx, tbl.ValueReadWriter(), schVal, rowData)
+	require.NoError(t, err)
+	root, err = root.PutTable(ctx, tableName, tbl)
+	require.NoError(t, err)
+
+	return root, tbl, virtualCols
+}
+
 func createEnvWithSeedData(t *testing.T) *env.DoltEnv {
 	dEnv := dtestutils.CreateTestEnv()
 	imt, sch := dtestutils.CreateTestDataTable(true)
@@ -0,0 +1,114 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alterschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// BackfillColumn sets the column with tag |colTag| to |defaultVal| in every row of the table |tblName| which has no
+// value for it, as needed when a column with a default is added to a table with existing rows, and returns the root
+// with the updated table. Rows which already have a value for the column keep it. The column must already be in the
+// table's schema, and can't be part of the primary key. A null |defaultVal| leaves the table as it is.
+func BackfillColumn(ctx context.Context, root *doltdb.RootValue, tblName string, colTag uint64, defaultVal types.Value) (*doltdb.RootValue, error) {
+	tbl, ok, err := root.GetTable(ctx, tblName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, doltdb.ErrTableNotFound
+	}
+
+	sch, err := tbl.GetSchema(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if schema.IsKeyless(sch) {
+		return nil, errors.New("Cannot backfill a column of a keyless table")
+	}
+
+	col, ok := sch.GetAllCols().GetByTag(colTag)
+
+	if !ok {
+		return nil, schema.ErrColNotFound
+	} else if col.IsPartOfPK {
+		return nil, errors.New("Cannot backfill column in primary key")
+	}
+
+	if types.IsNull(defaultVal) {
+		return root, nil
+	}
+
+	if !col.TypeInfo.IsValid(defaultVal) {
+		return nil, fmt.Errorf("Default value (%v) is invalid for column (%v)", defaultVal, col.TypeInfo.String())
+	}
+
+	rowData, err := tbl.GetRowData(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	me := rowData.Edit()
+	err = rowData.IterAll(ctx, func(k, v types.Value) error {
+		r, err := row.FromNoms(sch, k.(types.Tuple), v.(types.Tuple))
+
+		if err != nil {
+			return err
+		}
+
+		if _, ok := r.GetColVal(colTag); ok {
+			return nil
+		}
+
+		r, err = r.SetColVal(colTag, defaultVal, sch)
+
+		if err != nil {
+			return err
+		}
+
+		me.Set(k, r.NomsMapValue(sch))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := me.Map(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err = tbl.UpdateRows(ctx, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return root.PutTable(ctx, tblName, tbl)
+}
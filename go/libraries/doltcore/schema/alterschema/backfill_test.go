@@ -0,0 +1,106 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alterschema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/row"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema"
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestBackfillColumn(t *testing.T) {
+	dEnv := createEnvWithSeedData(t)
+	ctx := context.Background()
+
+	root, err := dEnv.WorkingRoot(ctx)
+	require.NoError(t, err)
+	tbl, _, err := root.GetTable(ctx, tableName)
+	require.NoError(t, err)
+
+	tbl, err = AddColumnToTable(ctx, root, tbl, tableName, dtestutils.NextTag, "newCol", typeinfo.FromKind(types.StringKind), Null, nil, nil)
+	require.NoError(t, err)
+	sch, err := tbl.GetSchema(ctx)
+	require.NoError(t, err)
+
+	// give one row a value already, which the backfill must keep
+	rowData, err := tbl.GetRowData(ctx)
+	require.NoError(t, err)
+	k, v, err := rowData.First(ctx)
+	require.NoError(t, err)
+	r, err := row.FromNoms(sch, k.(types.Tuple), v.(types.Tuple))
+	require.NoError(t, err)
+	r, err = r.SetColVal(dtestutils.NextTag, types.String("kept"), sch)
+	require.NoError(t, err)
+	rowData, err = rowData.Edit().Set(k, r.NomsMapValue(sch)).Map(ctx)
+	require.NoError(t, err)
+	tbl, err = tbl.UpdateRows(ctx, rowData)
+	require.NoError(t, err)
+	root, err = root.PutTable(ctx, tableName, tbl)
+	require.NoError(t, err)
+
+	backfilled, err := BackfillColumn(ctx, root, tableName, dtestutils.NextTag, types.String("default"))
+	require.NoError(t, err)
+
+	h, err := root.HashOf()
+	require.NoError(t, err)
+	bh, err := backfilled.HashOf()
+	require.NoError(t, err)
+	assert.NotEqual(t, h, bh)
+
+	tbl, _, err = backfilled.GetTable(ctx, tableName)
+	require.NoError(t, err)
+	rowData, err = tbl.GetRowData(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(dtestutils.TypedRows)), rowData.Len())
+
+	err = rowData.IterAll(ctx, func(key, value types.Value) error {
+		r, err := row.FromNoms(sch, key.(types.Tuple), value.(types.Tuple))
+		require.NoError(t, err)
+		val, ok := r.GetColVal(dtestutils.NextTag)
+		require.True(t, ok)
+
+		if key.Equals(k) {
+			assert.Equal(t, types.String("kept"), val)
+		} else {
+			assert.Equal(t, types.String("default"), val)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// a second backfill has nothing to do
+	again, err := BackfillColumn(ctx, backfilled, tableName, dtestutils.NextTag, types.String("other"))
+	require.NoError(t, err)
+	ah, err := again.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, bh, ah)
+
+	_, err = BackfillColumn(ctx, root, tableName, dtestutils.NextTag+1, types.String("default"))
+	assert.Equal(t, schema.ErrColNotFound, err)
+	_, err = BackfillColumn(ctx, root, "missing", dtestutils.NextTag, types.String("default"))
+	assert.Equal(t, doltdb.ErrTableNotFound, err)
+	_, err = BackfillColumn(ctx, root, tableName, dtestutils.NextTag, types.Int(1))
+	assert.Error(t, err)
+}
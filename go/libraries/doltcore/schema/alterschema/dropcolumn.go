@@ -65,7 +65,11 @@ func DropColumn(ctx context.Context, tbl *doltdb.Table, colName string) (*doltdb
 		return nil, err
 	}
 
-	newSch := schema.SchemaFromCols(colColl)
+	newSch, err := schema.SchemaWithVirtualCols(schema.SchemaFromCols(colColl), tblSch.VirtualCols())
+
+	if err != nil {
+		return nil, err
+	}
 
 	vrw := tbl.ValueReadWriter()
 	schemaVal, err := encoding.MarshalSchemaAsNomsValue(ctx, vrw, newSch)
@@ -138,3 +138,16 @@ func TestDropColumn(t *testing.T) {
 		})
 	}
 }
+
+func TestDropColumnWithVirtualCols(t *testing.T) {
+	ctx := context.Background()
+	_, tbl, virtualCols := createTableWithVirtualCol(t)
+
+	updatedTable, err := DropColumn(ctx, tbl, "age")
+	require.NoError(t, err)
+	sch, err := updatedTable.GetSchema(ctx)
+	require.NoError(t, err)
+	_, ok := sch.GetAllCols().GetByTag(dtestutils.AgeTag)
+	assert.False(t, ok)
+	assert.Equal(t, virtualCols, sch.VirtualCols())
+}
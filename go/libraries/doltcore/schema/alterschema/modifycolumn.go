@@ -176,5 +176,5 @@ func replaceColumnInSchema(sch schema.Schema, oldColName string, newCol schema.C
 		return nil, err
 	}
 
-	return schema.SchemaFromCols(collection), nil
+	return schema.SchemaWithVirtualCols(schema.SchemaFromCols(collection), sch.VirtualCols())
 }
@@ -162,3 +162,22 @@ func TestModifyColumn(t *testing.T) {
 		})
 	}
 }
+
+func TestModifyColumnWithVirtualCols(t *testing.T) {
+	ctx := context.Background()
+	_, tbl, virtualCols := createTableWithVirtualCol(t)
+
+	existingCol := schema.NewColumn("title", dtestutils.TitleTag, types.StringKind, false)
+	updatedTable, err := ModifyColumn(ctx, tbl, existingCol, schema.NewColumn("job", dtestutils.TitleTag, types.StringKind, false), nil, nil)
+	require.NoError(t, err)
+	sch, err := updatedTable.GetSchema(ctx)
+	require.NoError(t, err)
+	col, ok := sch.GetAllCols().GetByTag(dtestutils.TitleTag)
+	require.True(t, ok)
+	assert.Equal(t, "job", col.Name)
+	assert.Equal(t, virtualCols, sch.VirtualCols())
+
+	// a stored column can't take a virtual column's name
+	_, err = ModifyColumn(ctx, tbl, existingCol, schema.NewColumn("name_len", dtestutils.TitleTag, types.StringKind, false), nil, nil)
+	assert.Equal(t, schema.ErrColNameCollision, err)
+}
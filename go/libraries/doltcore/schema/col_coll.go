@@ -34,6 +34,12 @@ var ErrColNameCollision = errors.New("two different columns with the same name e
 // ErrNoPrimaryKeyColumns is an error that is returned when wo
 var ErrNoPrimaryKeyColumns = errors.New("no primary key columns")
 
+// ErrVirtualPKColumn is returned by ValidateSchema when a virtual column is part of the primary key.
+var ErrVirtualPKColumn = errors.New("virtual columns can't be part of the primary key")
+
+// ErrVirtualUniqueColumn is returned by ValidateSchema when a virtual column has a UniqueConstraint.
+var ErrVirtualUniqueColumn = errors.New("virtual columns can't be unique")
+
 var EmptyColColl = &ColCollection{
 	[]Column{},
 	[]uint64{},
@@ -43,6 +43,7 @@ var (
 		false,
 		typeinfo.UnknownType,
 		nil,
+		"",
 	}
 )
 
@@ -72,6 +73,10 @@ type Column struct {
 
 	// Constraints are rules that can be checked on each column to say if the columns value is valid
 	Constraints []ColConstraint
+
+	// Comment is free text documenting the column. It is stored with the schema, but is not part of the column's
+	// definition, so it's ignored by Equals.
+	Comment string
 }
 
 // NewColumn creates a Column instance with the default type info for the NomsKind
@@ -103,6 +108,7 @@ func NewColumnWithTypeInfo(name string, tag uint64, typeInfo typeinfo.TypeInfo,
 		partOfPK,
 		typeInfo,
 		constraints,
+		"",
 	}, nil
 }
 
@@ -116,7 +122,28 @@ func (c Column) IsNullable() bool {
 	return true
 }
 
-// Equals tests equality between two columns.
+// IsUnique returns whether no two rows can have the same non-null value for the column.
+func (c Column) IsUnique() bool {
+	return IndexOfConstraint(c.Constraints, UniqueConstraintType) != -1
+}
+
+// IsNumeric returns whether the column's type is an integer, floating point or decimal type.
+func (c Column) IsNumeric() bool {
+	switch c.TypeInfo.GetTypeIdentifier() {
+	case typeinfo.IntTypeIdentifier, typeinfo.UintTypeIdentifier, typeinfo.FloatTypeIdentifier, typeinfo.DecimalTypeIdentifier:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanWidenTo returns whether the column's type can be changed to that of |other| without losing data, as reported by
+// typeinfo.IsWidening. Only the types of the columns are compared.
+func (c Column) CanWidenTo(other Column) bool {
+	return typeinfo.IsWidening(c.TypeInfo, other.TypeInfo)
+}
+
+// Equals tests equality between two columns. Their comments aren't compared.
 func (c Column) Equals(other Column) bool {
 	return c.Name == other.Name &&
 		c.Tag == other.Tag &&
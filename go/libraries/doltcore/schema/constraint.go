@@ -40,6 +40,7 @@ type ColConstraint interface {
 
 const (
 	NotNullConstraintType = "not_null"
+	UniqueConstraintType  = "unique"
 )
 
 // ColConstraintFromTypeAndParams takes in a string representing the type of the constraint and a map of parameters
@@ -50,6 +51,8 @@ func ColConstraintFromTypeAndParams(colCnstType string, params map[string]string
 	switch colCnstType {
 	case NotNullConstraintType:
 		return NotNullConstraint{}
+	case UniqueConstraintType:
+		return UniqueConstraint{}
 	}
 	panic("Unknown column constraint type: " + colCnstType)
 }
@@ -78,6 +81,31 @@ func (nnc NotNullConstraint) String() string {
 	return "Not null"
 }
 
+// UniqueConstraint requires that no two rows of a table have the same non-null value for a column. It constrains a
+// table rather than a single value, so SatisfiesConstraint accepts every value, and it is checked where a whole table's
+// rows are available, as when rows are merged.
+type UniqueConstraint struct{}
+
+// SatisfiesConstraint returns true, as a single value can't violate uniqueness.
+func (uc UniqueConstraint) SatisfiesConstraint(value types.Value) bool {
+	return true
+}
+
+// GetConstraintType returns "unique"
+func (uc UniqueConstraint) GetConstraintType() string {
+	return UniqueConstraintType
+}
+
+// GetConstraintParams returns nil as this constraint does not require any parameters.
+func (uc UniqueConstraint) GetConstraintParams() map[string]string {
+	return nil
+}
+
+// String returns a useful description of the constraint
+func (uc UniqueConstraint) String() string {
+	return "Unique"
+}
+
 // IndexOfConstraint returns the index in the supplied slice of the first constraint of matching type.  If none are
 // found then -1 is returned
 func IndexOfConstraint(constraints []ColConstraint, constraintType string) int {
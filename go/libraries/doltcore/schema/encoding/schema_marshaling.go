@@ -47,6 +47,8 @@ type encodedColumn struct {
 
 	Constraints []encodedConstraint `noms:"col_constraints" json:"col_constraints"`
 
+	Comment string `noms:"comment,omitempty" json:"comment,omitempty"`
+
 	// NB: all new fields must have the 'omitempty' annotation. See comment above
 }
 
@@ -83,6 +85,7 @@ func encodeColumn(col schema.Column) encodedColumn {
 		col.IsPartOfPK,
 		encodeTypeInfo(col.TypeInfo),
 		encodeAllColConstraints(col.Constraints),
+		col.Comment,
 	}
 }
 
@@ -100,7 +103,14 @@ func (nfd encodedColumn) decodeColumn() (schema.Column, error) {
 		return schema.Column{}, errors.New("cannot decode column due to unknown schema format")
 	}
 	colConstraints := decodeAllColConstraint(nfd.Constraints)
-	return schema.NewColumnWithTypeInfo(nfd.Name, nfd.Tag, typeInfo, nfd.IsPartOfPK, colConstraints...)
+	col, err := schema.NewColumnWithTypeInfo(nfd.Name, nfd.Tag, typeInfo, nfd.IsPartOfPK, colConstraints...)
+
+	if err != nil {
+		return schema.Column{}, err
+	}
+
+	col.Comment = nfd.Comment
+	return col, nil
 }
 
 type encodedConstraint struct {
@@ -131,15 +141,15 @@ func (enc encodedTypeInfo) decodeTypeInfo() (typeinfo.TypeInfo, error) {
 }
 
 type schemaData struct {
-	Columns []encodedColumn `noms:"columns" json:"columns"`
+	Columns        []encodedColumn `noms:"columns" json:"columns"`
+	VirtualColumns []encodedColumn `noms:"virtual_columns,omitempty" json:"virtual_columns,omitempty"`
 }
 
-func toSchemaData(sch schema.Schema) (schemaData, error) {
-	allCols := sch.GetAllCols()
-	encCols := make([]encodedColumn, allCols.Size())
+func encodeColColl(cols *schema.ColCollection) ([]encodedColumn, error) {
+	encCols := make([]encodedColumn, cols.Size())
 
 	i := 0
-	err := allCols.Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
+	err := cols.Iter(func(tag uint64, col schema.Column) (stop bool, err error) {
 		encCols[i] = encodeColumn(col)
 		i++
 
@@ -147,31 +157,92 @@ func toSchemaData(sch schema.Schema) (schemaData, error) {
 	})
 
 	if err != nil {
-		return schemaData{}, err
+		return nil, err
 	}
 
-	return schemaData{encCols}, nil
+	return encCols, nil
 }
 
-func (sd schemaData) decodeSchema() (schema.Schema, error) {
-	numCols := len(sd.Columns)
-	cols := make([]schema.Column, numCols)
+func decodeColumns(encCols []encodedColumn) ([]schema.Column, error) {
+	cols := make([]schema.Column, len(encCols))
 
 	var err error
-	for i, col := range sd.Columns {
+	for i, col := range encCols {
 		cols[i], err = col.decodeColumn()
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	return cols, nil
+}
+
+func keyed(cols []schema.Column) bool {
+	for _, col := range cols {
+		if col.IsPartOfPK {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toSchemaData(sch schema.Schema) (schemaData, error) {
+	encCols, err := encodeColColl(sch.GetAllCols())
+
+	if err != nil {
+		return schemaData{}, err
+	}
+
+	var encVirtualCols []encodedColumn
+	if sch.VirtualCols().Size() > 0 {
+		encVirtualCols, err = encodeColColl(sch.VirtualCols())
+
+		if err != nil {
+			return schemaData{}, err
+		}
+	}
+
+	return schemaData{encCols, encVirtualCols}, nil
+}
+
+func (sd schemaData) decodeSchema() (schema.Schema, error) {
+	cols, err := decodeColumns(sd.Columns)
+
+	if err != nil {
+		return nil, err
+	}
+
 	colColl, err := schema.NewColCollection(cols...)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return schema.SchemaFromCols(colColl), nil
+	var sch schema.Schema
+	if keyed(cols) {
+		sch = schema.SchemaFromCols(colColl)
+	} else {
+		sch = schema.KeylessSchemaFromCols(colColl)
+	}
+
+	if len(sd.VirtualColumns) == 0 {
+		return sch, nil
+	}
+
+	virtualCols, err := decodeColumns(sd.VirtualColumns)
+
+	if err != nil {
+		return nil, err
+	}
+
+	virtualColColl, err := schema.NewColCollection(virtualCols...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.SchemaWithVirtualCols(sch, virtualColColl)
 }
 
 // MarshalSchemaAsNomsValue takes a Schema and converts it to a types.Value
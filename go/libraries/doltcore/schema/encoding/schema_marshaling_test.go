@@ -96,6 +96,76 @@ func TestNomsMarshalling(t *testing.T) {
 
 }
 
+func TestVirtualColsMarshalling(t *testing.T) {
+	ctx := context.Background()
+	tSchema := createTestSchema()
+	db, err := dbfactory.MemFactory{}.CreateDB(ctx, types.Format_7_18, nil, nil)
+	require.NoError(t, err)
+
+	// schemas without virtual columns are written as they were before virtual columns existed
+	val, err := MarshalSchemaAsNomsValue(ctx, db, tSchema)
+	require.NoError(t, err)
+	_, ok, err := val.(types.Struct).MaybeGet("virtual_columns")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	virtualCols, err := schema.NewColCollection(schema.NewColumn("full_name", 5, types.StringKind, false))
+	require.NoError(t, err)
+	withVirtual, err := schema.SchemaWithVirtualCols(tSchema, virtualCols)
+	require.NoError(t, err)
+
+	val, err = MarshalSchemaAsNomsValue(ctx, db, withVirtual)
+	require.NoError(t, err)
+	unMarshalled, err := UnmarshalSchemaNomsValue(ctx, types.Format_7_18, val)
+	require.NoError(t, err)
+	assert.Equal(t, withVirtual, unMarshalled)
+
+	jsonStr, err := MarshalAsJson(withVirtual)
+	require.NoError(t, err)
+	jsonUnmarshalled, err := UnmarshalJson(jsonStr)
+	require.NoError(t, err)
+	assert.Equal(t, withVirtual, jsonUnmarshalled)
+}
+
+func TestColumnCommentMarshalling(t *testing.T) {
+	ctx := context.Background()
+	db, err := dbfactory.MemFactory{}.CreateDB(ctx, types.Format_7_18, nil, nil)
+	require.NoError(t, err)
+
+	// columns without comments are written as they were before comments existed
+	val, err := MarshalSchemaAsNomsValue(ctx, db, createTestSchema())
+	require.NoError(t, err)
+	cols, _, err := val.(types.Struct).MaybeGet("columns")
+	require.NoError(t, err)
+	col, err := cols.(types.List).Get(ctx, 0)
+	require.NoError(t, err)
+	_, ok, err := col.(types.Struct).MaybeGet("comment")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	commented := schema.NewColumn("first", 1, types.StringKind, false)
+	commented.Comment = "the given name"
+	colColl, err := schema.NewColCollection(schema.NewColumn("id", 4, types.UUIDKind, true), commented)
+	require.NoError(t, err)
+	tSchema := schema.SchemaFromCols(colColl)
+
+	val, err = MarshalSchemaAsNomsValue(ctx, db, tSchema)
+	require.NoError(t, err)
+	unMarshalled, err := UnmarshalSchemaNomsValue(ctx, types.Format_7_18, val)
+	require.NoError(t, err)
+	assert.Equal(t, tSchema, unMarshalled)
+
+	unMarshalledCol, ok := unMarshalled.GetAllCols().GetByTag(1)
+	require.True(t, ok)
+	assert.Equal(t, "the given name", unMarshalledCol.Comment)
+
+	jsonStr, err := MarshalAsJson(tSchema)
+	require.NoError(t, err)
+	jsonUnmarshalled, err := UnmarshalJson(jsonStr)
+	require.NoError(t, err)
+	assert.Equal(t, tSchema, jsonUnmarshalled)
+}
+
 func TestJSONMarshalling(t *testing.T) {
 	tSchema := createTestSchema()
 	jsonStr, err := MarshalAsJson(tSchema)
@@ -224,6 +294,10 @@ type testEncodedColumn struct {
 	TypeInfo encodedTypeInfo `noms:"typeinfo" json:"typeinfo"`
 
 	Constraints []encodedConstraint `noms:"col_constraints" json:"col_constraints"`
+
+	// Comment is the exception to the rule above. It is omitted when empty, so that schemas without comments are
+	// written as they were before comments existed.
+	Comment string `noms:"comment,omitempty" json:"comment,omitempty"`
 }
 
 type testSchemaData struct {
@@ -244,7 +318,14 @@ func (tec testEncodedColumn) decodeColumn() (schema.Column, error) {
 		return schema.Column{}, errors.New("cannot decode column due to unknown schema format")
 	}
 	colConstraints := decodeAllColConstraint(tec.Constraints)
-	return schema.NewColumnWithTypeInfo(tec.Name, tec.Tag, typeInfo, tec.IsPartOfPK, colConstraints...)
+	col, err := schema.NewColumnWithTypeInfo(tec.Name, tec.Tag, typeInfo, tec.IsPartOfPK, colConstraints...)
+
+	if err != nil {
+		return schema.Column{}, err
+	}
+
+	col.Comment = tec.Comment
+	return col, nil
 }
 
 func (tsd testSchemaData) decodeSchema() (schema.Schema, error) {
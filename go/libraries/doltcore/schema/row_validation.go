@@ -0,0 +1,133 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// These are the kinds of RowValidationError. Use errors.Is to test whether an error returned by ValidateRow is of a
+// given kind.
+var (
+	// ErrRowTagNotInSchema indicates that a row has a value for a tag which is not a stored column of the schema.
+	ErrRowTagNotInSchema = errors.New("row has a value for a tag which is not in the schema")
+
+	// ErrRowTypeMismatch indicates that a row's value for a column is not valid for the column's type.
+	ErrRowTypeMismatch = errors.New("row value does not match the type of its column")
+
+	// ErrRowNullViolation indicates that a row has no value for a NOT NULL column.
+	ErrRowNullViolation = errors.New("row has no value for a NOT NULL column")
+
+	// ErrRowConstraintViolation indicates that a row's value for a column fails one of its other constraints.
+	ErrRowConstraintViolation = errors.New("row value violates a column constraint")
+)
+
+// RowValidationError is returned by ValidateRow for the first problem it finds with a row.
+type RowValidationError struct {
+	// Kind is one of ErrRowTagNotInSchema, ErrRowTypeMismatch, ErrRowNullViolation or ErrRowConstraintViolation.
+	Kind error
+
+	// Tag is the tag of the offending value or column.
+	Tag uint64
+
+	// Column is the name of the offending column. It is empty when Kind is ErrRowTagNotInSchema.
+	Column string
+}
+
+func (e *RowValidationError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("%s: tag %d", e.Kind.Error(), e.Tag)
+	}
+
+	return fmt.Sprintf("%s: column %s (tag %d)", e.Kind.Error(), e.Column, e.Tag)
+}
+
+// Is reports whether |target| is the kind of this error.
+func (e *RowValidationError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// ColValIterator is the part of row.Row which ValidateRow needs. It is declared here because the row package depends
+// on this one.
+type ColValIterator interface {
+	// IterCols calls |cb| with the tag and value of each column the row has a value for.
+	IterCols(cb func(tag uint64, val types.Value) (stop bool, err error)) (bool, error)
+}
+
+// ValidateRow checks that |r| can be stored in a table with the schema |sch|: every tag it has a value for is a
+// stored column of |sch|, every non-null value is valid for its column's type, and every column's constraints,
+// including NOT NULL, are satisfied. It returns a *RowValidationError describing the first problem found, or nil.
+// A UniqueConstraint can't be checked against a single row, so it is always satisfied here.
+func ValidateRow(sch Schema, r ColValIterator) error {
+	allCols := sch.GetAllCols()
+
+	vals := make(map[uint64]types.Value)
+	var verr *RowValidationError
+	_, err := r.IterCols(func(tag uint64, val types.Value) (stop bool, err error) {
+		col, ok := allCols.GetByTag(tag)
+
+		if !ok {
+			verr = &RowValidationError{Kind: ErrRowTagNotInSchema, Tag: tag}
+			return true, nil
+		}
+
+		if !types.IsNull(val) && (val.Kind() != col.Kind || !col.TypeInfo.IsValid(val)) {
+			verr = &RowValidationError{Kind: ErrRowTypeMismatch, Tag: tag, Column: col.Name}
+			return true, nil
+		}
+
+		vals[tag] = val
+		return false, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if verr != nil {
+		return verr
+	}
+
+	err = allCols.Iter(func(tag uint64, col Column) (stop bool, err error) {
+		for _, cnst := range col.Constraints {
+			if cnst.SatisfiesConstraint(vals[tag]) {
+				continue
+			}
+
+			kind := ErrRowConstraintViolation
+			if _, ok := cnst.(NotNullConstraint); ok {
+				kind = ErrRowNullViolation
+			}
+
+			verr = &RowValidationError{Kind: kind, Tag: tag, Column: col.Name}
+			return true, nil
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if verr != nil {
+		return verr
+	}
+
+	return nil
+}
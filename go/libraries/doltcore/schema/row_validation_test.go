@@ -0,0 +1,117 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// testRow is a ColValIterator over a map of tags to values, visited in no particular order.
+type testRow map[uint64]types.Value
+
+func (tr testRow) IterCols(cb func(tag uint64, val types.Value) (stop bool, err error)) (bool, error) {
+	for tag, val := range tr {
+		stop, err := cb(tag, val)
+
+		if err != nil || stop {
+			return stop, err
+		}
+	}
+
+	return false, nil
+}
+
+func TestValidateRow(t *testing.T) {
+	age, err := NewColumnWithTypeInfo("age", 2, typeinfo.Uint8Type, false)
+	require.NoError(t, err)
+	colColl, err := NewColCollection(
+		NewColumn("id", 0, types.IntKind, true, NotNullConstraint{}),
+		NewColumn("name", 1, types.StringKind, false, NotNullConstraint{}),
+		age,
+	)
+	require.NoError(t, err)
+	sch := SchemaFromCols(colColl)
+
+	tests := []struct {
+		name         string
+		row          testRow
+		expectedKind error
+		expectedTag  uint64
+	}{
+		{
+			name: "valid",
+			row:  testRow{0: types.Int(1), 1: types.String("bill"), 2: types.Uint(30)},
+		},
+		{
+			name: "nullable column missing",
+			row:  testRow{0: types.Int(1), 1: types.String("bill")},
+		},
+		{
+			name:         "missing tag",
+			row:          testRow{0: types.Int(1), 1: types.String("bill"), 3: types.Uint(30)},
+			expectedKind: ErrRowTagNotInSchema,
+			expectedTag:  3,
+		},
+		{
+			name:         "type mismatch",
+			row:          testRow{0: types.Int(1), 1: types.Int(2)},
+			expectedKind: ErrRowTypeMismatch,
+			expectedTag:  1,
+		},
+		{
+			name:         "value out of range for type",
+			row:          testRow{0: types.Int(1), 1: types.String("bill"), 2: types.Uint(300)},
+			expectedKind: ErrRowTypeMismatch,
+			expectedTag:  2,
+		},
+		{
+			name:         "not null column missing",
+			row:          testRow{0: types.Int(1)},
+			expectedKind: ErrRowNullViolation,
+			expectedTag:  1,
+		},
+		{
+			name:         "not null column null",
+			row:          testRow{0: types.Int(1), 1: types.NullValue},
+			expectedKind: ErrRowNullViolation,
+			expectedTag:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateRow(sch, test.row)
+
+			if test.expectedKind == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, test.expectedKind), "%v", err)
+
+			var verr *RowValidationError
+			if assert.True(t, errors.As(err, &verr)) {
+				assert.Equal(t, test.expectedTag, verr.Tag)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@
 
 package schema
 
+import "github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
+
 // Schema is an interface for retrieving the columns that make up a schema
 type Schema interface {
 	// GetPKCols gets the collection of columns which make the primary key.
@@ -24,6 +26,22 @@ type Schema interface {
 
 	// GetAllCols gets the collection of all columns (pk and non-pk)
 	GetAllCols() *ColCollection
+
+	// VirtualCols gets the collection of virtual columns, whose values are computed rather than stored. Virtual
+	// columns share the tag space of the stored columns, but are not included in GetAllCols, and so are not part of
+	// the encoding of a row.
+	VirtualCols() *ColCollection
+
+	// Constraints gets the constraints of each stored column which has any, keyed by the column's tag.
+	Constraints() map[uint64][]ColConstraint
+
+	// ColumnTypes gets the type of each stored column, keyed by the column's tag.
+	ColumnTypes() map[uint64]typeinfo.TypeInfo
+}
+
+// IsKeyless returns whether the schema has no primary key columns. See KeylessSchemaFromCols.
+func IsKeyless(sch Schema) bool {
+	return sch.GetPKCols().Size() == 0
 }
 
 // ColFromTag returns a schema.Column from a schema and a tag
@@ -53,9 +71,16 @@ func ExtractAllColNames(sch Schema) (map[uint64]string, error) {
 
 // SchemasAreEqual tests equality of two schemas.
 func SchemasAreEqual(sch1, sch2 Schema) (bool, error) {
-	all1 := sch1.GetAllCols()
-	all2 := sch2.GetAllCols()
+	eq, err := colCollsAreEqual(sch1.GetAllCols(), sch2.GetAllCols())
+
+	if err != nil || !eq {
+		return eq, err
+	}
+
+	return colCollsAreEqual(sch1.VirtualCols(), sch2.VirtualCols())
+}
 
+func colCollsAreEqual(all1, all2 *ColCollection) (bool, error) {
 	if all1.Size() != all2.Size() {
 		return false, nil
 	}
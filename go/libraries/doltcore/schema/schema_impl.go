@@ -17,6 +17,8 @@ package schema
 import (
 	"strconv"
 	"strings"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/schema/typeinfo"
 )
 
 // EmptySchema is an instance of a schema with no columns.
@@ -24,10 +26,11 @@ var EmptySchema = &schemaImpl{
 	EmptyColColl,
 	EmptyColColl,
 	EmptyColColl,
+	EmptyColColl,
 }
 
 type schemaImpl struct {
-	pkCols, nonPKCols, allCols *ColCollection
+	pkCols, nonPKCols, allCols, virtualCols *ColCollection
 }
 
 // SchemaFromCols creates a Schema from a collection of columns
@@ -51,7 +54,23 @@ func SchemaFromCols(allCols *ColCollection) Schema {
 	nonPKColColl, _ := NewColCollection(nonPKCols...)
 
 	return &schemaImpl{
-		pkColColl, nonPKColColl, allCols,
+		pkColColl, nonPKColColl, allCols, EmptyColColl,
+	}
+}
+
+// KeylessSchemaFromCols creates a Schema for a table without a primary key. The row data of a keyless table is keyed
+// by the tuple of all of a row's values, and each key maps to the number of copies of that row in the table.
+func KeylessSchemaFromCols(allCols *ColCollection) Schema {
+	for _, c := range allCols.cols {
+		if c.IsPartOfPK {
+			panic("primary key column specified in keyless schema")
+		}
+	}
+
+	pkColColl, _ := NewColCollection()
+
+	return &schemaImpl{
+		pkColColl, allCols, allCols, EmptyColColl,
 	}
 }
 
@@ -104,7 +123,7 @@ func UnkeyedSchemaFromCols(allCols *ColCollection) Schema {
 	nonPKColColl, _ := NewColCollection(nonPKCols...)
 
 	return &schemaImpl{
-		pkColColl, nonPKColColl, nonPKColColl,
+		pkColColl, nonPKColColl, nonPKColColl, EmptyColColl,
 	}
 }
 
@@ -138,10 +157,71 @@ func SchemaFromPKAndNonPKCols(pkCols, nonPKCols *ColCollection) (Schema, error)
 	}
 
 	return &schemaImpl{
-		pkCols, nonPKCols, allColColl,
+		pkCols, nonPKCols, allColColl, EmptyColColl,
 	}, nil
 }
 
+// SchemaWithVirtualCols returns a copy of |sch| whose virtual columns, described by Schema.VirtualCols, are
+// |virtualCols|. It returns an error if the resulting schema is invalid, as described by ValidateSchema.
+func SchemaWithVirtualCols(sch Schema, virtualCols *ColCollection) (Schema, error) {
+	withVirtual := &schemaImpl{sch.GetPKCols(), sch.GetNonPKCols(), sch.GetAllCols(), virtualCols}
+	err := ValidateSchema(withVirtual)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return withVirtual, nil
+}
+
+// ValidateSchema returns an error if two of the stored and virtual columns of |sch| have the same tag or name, or if
+// a virtual column is part of the primary key or unique.
+func ValidateSchema(sch Schema) error {
+	colNames := make(map[string]bool)
+	colTags := make(map[uint64]bool)
+
+	validate := func(tag uint64, col Column) (stop bool, err error) {
+		if colTags[tag] {
+			return true, ErrColTagCollision
+		}
+		colTags[tag] = true
+
+		lwr := strings.ToLower(col.Name)
+		if colNames[lwr] {
+			return true, ErrColNameCollision
+		}
+		colNames[lwr] = true
+
+		return false, nil
+	}
+
+	err := sch.GetAllCols().Iter(validate)
+
+	if err != nil {
+		return err
+	}
+
+	return sch.VirtualCols().Iter(func(tag uint64, col Column) (stop bool, err error) {
+		if col.IsPartOfPK {
+			return true, ErrVirtualPKColumn
+		}
+
+		if col.IsUnique() {
+			return true, ErrVirtualUniqueColumn
+		}
+
+		return validate(tag, col)
+	})
+}
+
+// AllTags returns the tags of the stored columns of |sch| followed by those of its virtual columns. New tags must
+// not collide with any of them.
+func AllTags(sch Schema) []uint64 {
+	allTags := make([]uint64, 0, sch.GetAllCols().Size()+sch.VirtualCols().Size())
+	allTags = append(allTags, sch.GetAllCols().Tags...)
+	return append(allTags, sch.VirtualCols().Tags...)
+}
+
 // GetAllCols gets the collection of all columns (pk and non-pk)
 func (si *schemaImpl) GetAllCols() *ColCollection {
 	return si.allCols
@@ -157,6 +237,32 @@ func (si *schemaImpl) GetPKCols() *ColCollection {
 	return si.pkCols
 }
 
+// VirtualCols gets the collection of virtual columns, whose values are computed rather than stored.
+func (si *schemaImpl) VirtualCols() *ColCollection {
+	return si.virtualCols
+}
+
+// Constraints gets the constraints of each stored column which has any, keyed by the column's tag.
+func (si *schemaImpl) Constraints() map[uint64][]ColConstraint {
+	constraints := make(map[uint64][]ColConstraint)
+	for _, col := range si.allCols.cols {
+		if len(col.Constraints) > 0 {
+			constraints[col.Tag] = col.Constraints
+		}
+	}
+
+	return constraints
+}
+
+func (si *schemaImpl) ColumnTypes() map[uint64]typeinfo.TypeInfo {
+	colTypes := make(map[uint64]typeinfo.TypeInfo, len(si.allCols.cols))
+	for _, col := range si.allCols.cols {
+		colTypes[col.Tag] = col.TypeInfo
+	}
+
+	return colTypes
+}
+
 func (si *schemaImpl) String() string {
 	var b strings.Builder
 	writeColFn := func(tag uint64, col Column) (stop bool, err error) {
@@ -183,6 +289,15 @@ func (si *schemaImpl) String() string {
 		return err.Error()
 	}
 
+	if si.virtualCols.Size() > 0 {
+		b.WriteString("]\nvirtualCols: [")
+		err = si.virtualCols.Iter(writeColFn)
+
+		if err != nil {
+			return err.Error()
+		}
+	}
+
 	b.WriteString("]")
 	return b.String()
 }
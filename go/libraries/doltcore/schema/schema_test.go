@@ -47,14 +47,14 @@ var ageVal = types.Uint(53)
 var titleVal = types.NullValue
 
 var pkCols = []Column{
-	{lnColName, lnColTag, types.StringKind, true, typeinfo.StringDefaultType, nil},
-	{fnColName, fnColTag, types.StringKind, true, typeinfo.StringDefaultType, nil},
+	{lnColName, lnColTag, types.StringKind, true, typeinfo.StringDefaultType, nil, ""},
+	{fnColName, fnColTag, types.StringKind, true, typeinfo.StringDefaultType, nil, ""},
 }
 var nonPkCols = []Column{
-	{addrColName, addrColTag, types.StringKind, false, typeinfo.StringDefaultType, nil},
-	{ageColName, ageColTag, types.UintKind, false, typeinfo.FromKind(types.UintKind), nil},
-	{titleColName, titleColTag, types.StringKind, false, typeinfo.StringDefaultType, nil},
-	{reservedColName, reservedColTag, types.StringKind, false, typeinfo.StringDefaultType, nil},
+	{addrColName, addrColTag, types.StringKind, false, typeinfo.StringDefaultType, nil, ""},
+	{ageColName, ageColTag, types.UintKind, false, typeinfo.FromKind(types.UintKind), nil, ""},
+	{titleColName, titleColTag, types.StringKind, false, typeinfo.StringDefaultType, nil, ""},
+	{reservedColName, reservedColTag, types.StringKind, false, typeinfo.StringDefaultType, nil, ""},
 }
 
 var allCols = append(append([]Column(nil), pkCols...), nonPkCols...)
@@ -77,6 +77,47 @@ func TestSchema(t *testing.T) {
 	assert.True(t, eq, "schemas should be equal")
 }
 
+func TestSchemaConstraints(t *testing.T) {
+	emailCol := NewColumn("email", 70, types.StringKind, false, NotNullConstraint{}, UniqueConstraint{})
+	colColl, err := NewColCollection(append(allCols, emailCol)...)
+	require.NoError(t, err)
+	sch := SchemaFromCols(colColl)
+	require.NoError(t, ValidateSchema(sch))
+
+	assert.Equal(t, map[uint64][]ColConstraint{70: {NotNullConstraint{}, UniqueConstraint{}}}, sch.Constraints())
+	assert.True(t, emailCol.IsUnique())
+	assert.False(t, emailCol.IsNullable())
+	assert.False(t, allCols[0].IsUnique())
+	assert.Equal(t, UniqueConstraint{}, ColConstraintFromTypeAndParams(UniqueConstraintType, nil))
+}
+
+func TestColumnTypes(t *testing.T) {
+	intCol := NewColumn("int", 0, types.IntKind, false)
+	intCol.TypeInfo = typeinfo.Int32Type
+	bigintCol := NewColumn("bigint", 1, types.IntKind, false)
+	varcharCol := NewColumn("varchar", 2, types.StringKind, false)
+
+	assert.True(t, intCol.CanWidenTo(bigintCol))
+	assert.False(t, bigintCol.CanWidenTo(intCol))
+	assert.False(t, intCol.CanWidenTo(varcharCol))
+	assert.True(t, intCol.CanWidenTo(intCol))
+
+	assert.True(t, intCol.IsNumeric())
+	assert.True(t, NewColumn("float", 3, types.FloatKind, false).IsNumeric())
+	assert.True(t, NewColumn("uint", 4, types.UintKind, false).IsNumeric())
+	assert.False(t, varcharCol.IsNumeric())
+	assert.False(t, NewColumn("bool", 5, types.BoolKind, false).IsNumeric())
+
+	colColl, err := NewColCollection(intCol, bigintCol, varcharCol)
+	require.NoError(t, err)
+	sch := UnkeyedSchemaFromCols(colColl)
+	assert.Equal(t, map[uint64]typeinfo.TypeInfo{
+		0: typeinfo.Int32Type,
+		1: typeinfo.Int64Type,
+		2: typeinfo.StringDefaultType,
+	}, sch.ColumnTypes())
+}
+
 func TestSchemaWithNoPKs(t *testing.T) {
 	colColl, err := NewColCollection(nonPkCols...)
 	require.NoError(t, err)
@@ -98,7 +139,7 @@ func TestValidateForInsert(t *testing.T) {
 	})
 
 	t.Run("Name collision", func(t *testing.T) {
-		cols := append(allCols, Column{titleColName, 100, types.StringKind, false, typeinfo.StringDefaultType, nil})
+		cols := append(allCols, Column{titleColName, 100, types.StringKind, false, typeinfo.StringDefaultType, nil, ""})
 		colColl, err := NewColCollection(cols...)
 		require.NoError(t, err)
 
@@ -117,6 +158,48 @@ func TestValidateForInsert(t *testing.T) {
 	})
 }
 
+func TestVirtualCols(t *testing.T) {
+	colColl, err := NewColCollection(allCols...)
+	require.NoError(t, err)
+	sch := SchemaFromCols(colColl)
+	assert.Equal(t, 0, sch.VirtualCols().Size())
+
+	fullNameCol := Column{"full_name", 60, types.StringKind, false, typeinfo.StringDefaultType, nil, ""}
+	virtualColl, err := NewColCollection(fullNameCol)
+	require.NoError(t, err)
+
+	withVirtual, err := SchemaWithVirtualCols(sch, virtualColl)
+	require.NoError(t, err)
+	testSchema("SchemaWithVirtualCols", withVirtual, t)
+	validateCols(t, []Column{fullNameCol}, withVirtual.VirtualCols(), "VirtualCols")
+	assert.Equal(t, append(append([]uint64(nil), colColl.Tags...), 60), AllTags(withVirtual))
+	assert.NoError(t, ValidateSchema(withVirtual))
+
+	eq, err := SchemasAreEqual(sch, withVirtual)
+	require.NoError(t, err)
+	assert.False(t, eq)
+
+	tests := []struct {
+		name        string
+		col         Column
+		expectedErr error
+	}{
+		{"tag collision", Column{"other", ageColTag, types.StringKind, false, typeinfo.StringDefaultType, nil, ""}, ErrColTagCollision},
+		{"name collision", Column{"Age", 61, types.StringKind, false, typeinfo.StringDefaultType, nil, ""}, ErrColNameCollision},
+		{"primary key", Column{"other", 61, types.StringKind, true, typeinfo.StringDefaultType, nil, ""}, ErrVirtualPKColumn},
+		{"unique", Column{"other", 61, types.StringKind, false, typeinfo.StringDefaultType, []ColConstraint{UniqueConstraint{}}, ""}, ErrVirtualUniqueColumn},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			virtualColl, err := NewColCollection(fullNameCol, test.col)
+			require.NoError(t, err)
+			_, err = SchemaWithVirtualCols(sch, virtualColl)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
 func testSchema(method string, sch Schema, t *testing.T) {
 	validateCols(t, allCols, sch.GetAllCols(), method+"GetAllCols")
 	validateCols(t, pkCols, sch.GetPKCols(), method+"GetPKCols")
@@ -17,6 +17,7 @@ package schema
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/liquidata-inc/dolt/go/libraries/utils/set"
 )
@@ -309,7 +310,11 @@ func SuperSchemaUnion(superSchemas ...*SuperSchema) (*SuperSchema, error) {
 	for tag, nameSet := range tagNameSets {
 		nn := []string{latestNames[tag]}
 		nameSet.Remove(latestNames[tag])
-		tn[tag] = append(nn, nameSet.AsSlice()...)
+
+		// sort the older names so the union, and so its serialization, is the same on every run
+		older := nameSet.AsSlice()
+		sort.Strings(older)
+		tn[tag] = append(nn, older...)
 	}
 
 	return &SuperSchema{cc, tn}, nil
@@ -54,7 +54,7 @@ var nameCollisionWithSch1 = mustSchema([]Column{
 
 var tagCollisionWithSch1 = mustSchema([]Column{
 	strCol("a", 1, true),
-	{"collision", 2, types.IntKind, false, typeinfo.Int32Type, nil},
+	{"collision", 2, types.IntKind, false, typeinfo.Int32Type, nil, ""},
 })
 
 type SuperSchemaTest struct {
@@ -203,6 +203,7 @@ func testSuperSchemaUnion(t *testing.T) {
 	gs, err := unionSuperSchema.GenerateSchema()
 	require.NoError(t, err)
 	assert.Equal(t, expectedGeneratedSchema, gs)
+	assert.Equal(t, []string{"a", "aa", "aaa"}, unionSuperSchema.AllColumnNames(1))
 
 	// ensure that SuperSchemaUnion() respects order
 	unionSuperSchema, err = SuperSchemaUnion(ss34, ss12)
@@ -238,5 +239,5 @@ func mustColColl(cols []Column) *ColCollection {
 }
 
 func strCol(name string, tag uint64, isPK bool) Column {
-	return Column{name, tag, types.StringKind, isPK, typeinfo.StringDefaultType, nil}
+	return Column{name, tag, types.StringKind, isPK, typeinfo.StringDefaultType, nil, ""}
 }
@@ -17,9 +17,11 @@ package schema
 import (
 	"crypto/sha512"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/liquidata-inc/dolt/go/libraries/utils/set"
@@ -31,42 +33,116 @@ const (
 	ReservedTagMin uint64 = 1 << 50
 )
 
+// ReservedTagInfo describes a tag in the reserved tag space, as registered with RegisterReservedTag.
+type ReservedTagInfo struct {
+	// Tag is the reserved tag.
+	Tag uint64
+
+	// Name is the name of the constant which defines the tag.
+	Name string
+
+	// Description is what the tag is reserved for.
+	Description string
+}
+
+var reservedTags = make(map[uint64]ReservedTagInfo)
+
+// RegisterReservedTag records |tag| as reserved for the use described by |description|, so that it is returned by
+// ListReservedTags. It panics if |tag| is below ReservedTagMin or is already registered, and is meant to be called
+// from init functions by the packages which define reserved tags.
+func RegisterReservedTag(tag uint64, name, description string) {
+	if tag < ReservedTagMin {
+		panic(fmt.Sprintf("reserved tag %s (%d) is below ReservedTagMin", name, tag))
+	}
+
+	if info, ok := reservedTags[tag]; ok {
+		panic(fmt.Sprintf("reserved tag %s (%d) is already registered as %s", name, tag, info.Name))
+	}
+
+	reservedTags[tag] = ReservedTagInfo{tag, name, description}
+}
+
+// ListReservedTags returns every registered reserved tag, ordered by tag.
+func ListReservedTags() []ReservedTagInfo {
+	infos := make([]ReservedTagInfo, 0, len(reservedTags))
+	for _, info := range reservedTags {
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Tag < infos[j].Tag
+	})
+
+	return infos
+}
+
 func ErrTagPrevUsed(tag uint64, newColName, tableName string) error {
 	return fmt.Errorf("Cannot create column %s, the tag %d was already used in table %s", newColName, tag, tableName)
 }
 
-// AutoGenerateTag generates a random tag that doesn't exist in the provided SuperSchema.
+// ErrTagSpaceExhausted is returned by TryAutoGenerateTag when no unused tag below ReservedTagMin can be found.
+var ErrTagSpaceExhausted = errors.New("no unused tags are available below the reserved tag range")
+
+// maxTagGenerationAttempts bounds the number of random tags tried before giving up on finding an unused one. At most
+// half of the tags being chosen from are in use, so the bound is never reached in practice.
+const maxTagGenerationAttempts = 1 << 16
+
+// tagGenerator is the source of random tags. It is satisfied by *rand.Rand.
+type tagGenerator interface {
+	Int63n(n int64) int64
+}
+
+// AutoGenerateTag is like TryAutoGenerateTag, but panics if no tag is available.
+func AutoGenerateTag(existingTags *set.Uint64Set, tableName string, existingColKinds []types.NomsKind, newColName string, newColKind types.NomsKind) uint64 {
+	tag, err := TryAutoGenerateTag(existingTags, tableName, existingColKinds, newColName, newColKind)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return tag
+}
+
+// TryAutoGenerateTag generates a random tag that doesn't exist in the provided SuperSchema, or returns
+// ErrTagSpaceExhausted if there is none. Virtual columns aren't in super schemas, so their tags, as returned by
+// AllTags, must be added to |existingTags|.
 // It uses a deterministic random number generator that is seeded with the NomsKinds of any existing columns in the
 // schema and the NomsKind of the column being added to the schema. Deterministic tag generation means that branches
 // and repositories that perform the same sequence of mutations to a database will get equivalent databases as a result.
 // DETERMINISTIC MUTATION IS A CRITICAL INVARIANT TO MAINTAINING COMPATIBILITY BETWEEN REPOSITORIES.
 // DO NOT ALTER THIS METHOD.
-func AutoGenerateTag(existingTags *set.Uint64Set, tableName string, existingColKinds []types.NomsKind, newColName string, newColKind types.NomsKind) uint64 {
+func TryAutoGenerateTag(existingTags *set.Uint64Set, tableName string, existingColKinds []types.NomsKind, newColName string, newColKind types.NomsKind) (uint64, error) {
 	// DO NOT ALTER THIS METHOD (see above)
+	randGen := deterministicRandomTagGenerator(tableName, newColName, existingColKinds, newColKind)
+	return generateTag(existingTags, ReservedTagMin, randGen)
+}
+
+// generateTag draws tags from |randGen| until it finds one not in |existingTags|. The range the tags are drawn from
+// grows with the number of existing tags, up to |tagLimit|.
+// DO NOT ALTER THIS METHOD (see TryAutoGenerateTag)
+func generateTag(existingTags *set.Uint64Set, tagLimit uint64, randGen tagGenerator) (uint64, error) {
 	var maxTagVal uint64 = 128 * 128
 
 	for maxTagVal/2 < uint64(existingTags.Size()) {
-		if maxTagVal >= ReservedTagMin-1 {
-			panic("There is no way anyone should ever have this many columns.  You are a bad person if you hit this panic.")
+		if maxTagVal >= tagLimit-1 {
+			return 0, ErrTagSpaceExhausted
 		} else if maxTagVal*128 < maxTagVal {
-			maxTagVal = ReservedTagMin - 1
+			maxTagVal = tagLimit - 1
 			break
 		} else {
 			maxTagVal = maxTagVal * 128
 		}
 	}
 
-	randGen := deterministicRandomTagGenerator(tableName, newColName, existingColKinds, newColKind)
-	var randTag uint64
-	for {
-		randTag = uint64(randGen.Int63n(int64(maxTagVal)))
+	for i := 0; i < maxTagGenerationAttempts; i++ {
+		randTag := uint64(randGen.Int63n(int64(maxTagVal)))
 
 		if !existingTags.Contains(randTag) {
-			break
+			return randTag, nil
 		}
 	}
 
-	return randTag
+	return 0, ErrTagSpaceExhausted
 }
 
 // randomTagGeneratorFromKinds creates a deterministic random number generator that is seeded with the NomsKinds of any
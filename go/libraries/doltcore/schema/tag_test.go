@@ -0,0 +1,111 @@
+// Copyright 2020 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/libraries/utils/set"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// sequentialTagGenerator returns 0, 1, 2, ... wrapping at the requested bound.
+type sequentialTagGenerator struct {
+	next int64
+}
+
+func (g *sequentialTagGenerator) Int63n(n int64) int64 {
+	v := g.next % n
+	g.next++
+	return v
+}
+
+type constantTagGenerator int64
+
+func (g constantTagGenerator) Int63n(n int64) int64 {
+	return int64(g) % n
+}
+
+func TestTryAutoGenerateTag(t *testing.T) {
+	existing := set.NewUint64Set([]uint64{1, 2, 3})
+	tag, err := TryAutoGenerateTag(existing, "people", []types.NomsKind{types.StringKind}, "name", types.StringKind)
+	require.NoError(t, err)
+	assert.False(t, existing.Contains(tag))
+	assert.Equal(t, tag, AutoGenerateTag(existing, "people", []types.NomsKind{types.StringKind}, "name", types.StringKind))
+}
+
+func TestGenerateTagNearExhaustion(t *testing.T) {
+	const tagLimit = 128*128 + 1
+
+	// Half of the tag space is in use, so a sequential generator finds the first unused tag.
+	var used []uint64
+	for i := uint64(0); i < 128*128/2; i++ {
+		used = append(used, i)
+	}
+	existing := set.NewUint64Set(used)
+
+	tag, err := generateTag(existing, tagLimit, &sequentialTagGenerator{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(128*128/2), tag)
+
+	// A generator which only returns used tags gives up rather than looping forever.
+	_, err = generateTag(existing, tagLimit, constantTagGenerator(0))
+	assert.Equal(t, ErrTagSpaceExhausted, err)
+
+	// One more tag and the tag space can't grow past the limit.
+	existing.Add(tag)
+	_, err = generateTag(existing, tagLimit, &sequentialTagGenerator{})
+	assert.Equal(t, ErrTagSpaceExhausted, err)
+}
+
+func TestRegisterReservedTag(t *testing.T) {
+	tag := ReservedTagMin + 1<<40
+	RegisterReservedTag(tag, "testTag", "tag registered by TestRegisterReservedTag")
+	defer delete(reservedTags, tag)
+
+	assert.Contains(t, ListReservedTags(), ReservedTagInfo{tag, "testTag", "tag registered by TestRegisterReservedTag"})
+	assert.Panics(t, func() { RegisterReservedTag(tag, "dupTag", "duplicate") })
+	assert.Panics(t, func() { RegisterReservedTag(ReservedTagMin-1, "userTag", "below the reserved range") })
+}
+
+func TestTryAutoGenerateTagConcurrently(t *testing.T) {
+	// Each call seeds its own generator, so concurrent calls share no state and agree on the tag. Run with -race.
+	existing := set.NewUint64Set([]uint64{1, 2, 3})
+	expected, err := TryAutoGenerateTag(existing, "people", []types.NomsKind{types.StringKind}, "name", types.StringKind)
+	require.NoError(t, err)
+
+	const numGoroutines = 16
+	tags := make([]uint64, numGoroutines)
+	errs := make([]error, numGoroutines)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tags[i], errs[i] = TryAutoGenerateTag(existing, "people", []types.NomsKind{types.StringKind}, "name", types.StringKind)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < numGoroutines; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, expected, tags[i])
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeinfo
+
+import (
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/proto/query"
+
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+var numberTypeBits = map[query.Type]int{
+	sqltypes.Int8:    8,
+	sqltypes.Int16:   16,
+	sqltypes.Int24:   24,
+	sqltypes.Int32:   32,
+	sqltypes.Int64:   64,
+	sqltypes.Uint8:   8,
+	sqltypes.Uint16:  16,
+	sqltypes.Uint24:  24,
+	sqltypes.Uint32:  32,
+	sqltypes.Uint64:  64,
+	sqltypes.Float32: 32,
+	sqltypes.Float64: 64,
+}
+
+// IsWidening returns whether every value of the type |from| is also a value of the type |to|, so that a column may
+// be changed from |from| to |to| without losing data. Every type widens to itself, and the other widenings are:
+//   - a signed integer to a wider signed integer
+//   - an unsigned integer to a wider unsigned integer, or to a wider signed integer
+//   - an integer of at most 32 bits, or a float, to a 64 bit float
+//   - a string to a string of the same kind and collation with at least the same length, or a varchar to a text
+//   - a binary string to a binary string of the same kind with at least the same length, or a varbinary to a blob
+//   - a decimal to a decimal with at least as many digits both before and after the decimal point
+func IsWidening(from, to TypeInfo) bool {
+	if from.Equals(to) {
+		return true
+	}
+
+	switch fromTi := from.(type) {
+	case *intType:
+		fromBits := numberTypeBits[fromTi.sqlIntType.Type()]
+		switch toTi := to.(type) {
+		case *intType:
+			return fromBits < numberTypeBits[toTi.sqlIntType.Type()]
+		case *floatType:
+			return fromBits <= 32 && toTi.sqlFloatType.Type() == sqltypes.Float64
+		}
+	case *uintType:
+		fromBits := numberTypeBits[fromTi.sqlUintType.Type()]
+		switch toTi := to.(type) {
+		case *uintType:
+			return fromBits < numberTypeBits[toTi.sqlUintType.Type()]
+		case *intType:
+			return fromBits < numberTypeBits[toTi.sqlIntType.Type()]
+		case *floatType:
+			return fromBits <= 32 && toTi.sqlFloatType.Type() == sqltypes.Float64
+		}
+	case *floatType:
+		if toTi, ok := to.(*floatType); ok {
+			return toTi.sqlFloatType.Type() == sqltypes.Float64
+		}
+	case *varStringType:
+		if toTi, ok := to.(*varStringType); ok {
+			fromSt, toSt := fromTi.sqlStringType, toTi.sqlStringType
+			if fromSt.Collation() != toSt.Collation() || fromSt.MaxCharacterLength() > toSt.MaxCharacterLength() {
+				return false
+			}
+
+			return fromSt.Type() == toSt.Type() || (fromSt.Type() == sqltypes.VarChar && toSt.Type() == sqltypes.Text)
+		}
+	case *varBinaryType:
+		if toTi, ok := to.(*varBinaryType); ok {
+			fromBt, toBt := fromTi.sqlBinaryType, toTi.sqlBinaryType
+			if fromBt.MaxCharacterLength() > toBt.MaxCharacterLength() {
+				return false
+			}
+
+			return fromBt.Type() == toBt.Type() || (fromBt.Type() == sqltypes.VarBinary && toBt.Type() == sqltypes.Blob)
+		}
+	case *decimalType:
+		if toTi, ok := to.(*decimalType); ok {
+			fromDt, toDt := fromTi.sqlDecimalType, toTi.sqlDecimalType
+			return fromDt.Scale() <= toDt.Scale() && fromDt.Precision()-fromDt.Scale() <= toDt.Precision()-toDt.Scale()
+		}
+	}
+
+	return false
+}
+
+// WidenValue converts the value |v| of the type |from| to the type |to|, which must be a widening of |from| as
+// reported by IsWidening.
+func WidenValue(v types.Value, from, to TypeInfo) (types.Value, error) {
+	if from.NomsKind() == to.NomsKind() {
+		return v, nil
+	}
+
+	goVal, err := from.ConvertNomsValueToValue(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return to.ConvertValueToNomsValue(goVal)
+}
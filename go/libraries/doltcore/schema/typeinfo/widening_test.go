@@ -0,0 +1,83 @@
+// Copyright 2020 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeinfo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestIsWidening(t *testing.T) {
+	tests := []struct {
+		from     TypeInfo
+		to       TypeInfo
+		widening bool
+	}{
+		{Int32Type, Int32Type, true},
+		{Int32Type, Int64Type, true},
+		{Int8Type, Int24Type, true},
+		{Int64Type, Int32Type, false},
+		{Int32Type, Uint64Type, false},
+		{Uint32Type, Uint64Type, true},
+		{Uint32Type, Int64Type, true},
+		{Uint32Type, Int32Type, false},
+		{Uint64Type, Uint32Type, false},
+		{Int32Type, Float64Type, true},
+		{Int64Type, Float64Type, false},
+		{Uint24Type, Float64Type, true},
+		{Int32Type, Float32Type, false},
+		{Float32Type, Float64Type, true},
+		{Float64Type, Float32Type, false},
+		{Int64Type, StringDefaultType, false},
+		{generateVarStringType(t, 10, false), generateVarStringType(t, 20, false), true},
+		{generateVarStringType(t, 20, false), generateVarStringType(t, 10, false), false},
+		{generateVarStringType(t, 10, false), StringDefaultType, true},
+		{StringDefaultType, generateVarStringType(t, 10, false), false},
+		{generateVarStringType(t, 10, true), generateVarStringType(t, 20, false), false},
+		{generateVarBinaryType(t, 10, false), generateVarBinaryType(t, 20, false), true},
+		{generateVarBinaryType(t, 20, false), generateVarBinaryType(t, 10, false), false},
+		{generateVarBinaryType(t, 10, true), generateVarBinaryType(t, 20, false), false},
+		{generateVarBinaryType(t, 10, false), generateVarStringType(t, 10, false), false},
+		{generateDecimalType(t, 10, 2), generateDecimalType(t, 12, 4), true},
+		{generateDecimalType(t, 10, 2), generateDecimalType(t, 10, 4), false},
+		{generateDecimalType(t, 12, 4), generateDecimalType(t, 10, 2), false},
+		{Int32Type, generateDecimalType(t, 20, 0), false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v %v", test.from, test.to), func(t *testing.T) {
+			assert.Equal(t, test.widening, IsWidening(test.from, test.to))
+		})
+	}
+}
+
+func TestWidenValue(t *testing.T) {
+	v, err := WidenValue(types.Int(-7), Int32Type, Int64Type)
+	require.NoError(t, err)
+	assert.Equal(t, types.Int(-7), v)
+
+	v, err = WidenValue(types.Uint(7), Uint32Type, Int64Type)
+	require.NoError(t, err)
+	assert.Equal(t, types.Int(7), v)
+
+	v, err = WidenValue(types.Int(-7), Int32Type, Float64Type)
+	require.NoError(t, err)
+	assert.Equal(t, types.Float(-7), v)
+}
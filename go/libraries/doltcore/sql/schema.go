@@ -41,6 +41,8 @@ func FmtColWithNameAndType(indent, nameWidth, typeWidth int, colName, typeStr st
 		switch cnst.GetConstraintType() {
 		case schema.NotNullConstraintType:
 			colStr += " NOT NULL"
+		case schema.UniqueConstraintType:
+			colStr += " UNIQUE"
 		default:
 			panic("FmtColWithNameAndType doesn't know how to format constraint type: " + cnst.GetConstraintType())
 		}
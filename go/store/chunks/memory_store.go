@@ -107,11 +107,13 @@ func (ms *MemoryStorage) Update(current, last hash.Hash, novel map[hash.Hash]Chu
 	return true
 }
 
-// MemoryStoreView is an in-memory implementation of store.ChunkStore. Useful
-// mainly for tests.
+// MemoryStoreView is an in-memory implementation of store.ChunkStore. It is
+// ephemeral and intended for tests: nothing outlives the MemoryStorage backing
+// it.
 // The proper way to get one:
 // storage := &MemoryStorage{}
 // ms := storage.NewView()
+// or, when only a single view is needed, NewMemoryStore().
 type MemoryStoreView struct {
 	pending  map[hash.Hash]Chunk
 	rootHash hash.Hash
@@ -121,6 +123,13 @@ type MemoryStoreView struct {
 	storage *MemoryStorage
 }
 
+// NewMemoryStore returns a MemoryStoreView backed by its own, new
+// MemoryStorage. It is intended for tests which need a ChunkStore without
+// setting up a store on disk.
+func NewMemoryStore() *MemoryStoreView {
+	return (&MemoryStorage{}).NewView().(*MemoryStoreView)
+}
+
 func (ms *MemoryStoreView) Get(ctx context.Context, h hash.Hash) (Chunk, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
@@ -139,7 +148,11 @@ func (ms *MemoryStoreView) GetMany(ctx context.Context, hashes hash.HashSet, fou
 		}
 
 		if !c.IsEmpty() {
-			foundChunks <- &c
+			select {
+			case foundChunks <- &c:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 	}
 
@@ -206,6 +219,13 @@ func (ms *MemoryStoreView) Root(ctx context.Context) (hash.Hash, error) {
 func (ms *MemoryStoreView) Commit(ctx context.Context, current, last hash.Hash) (bool, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
+
+	if len(ms.pending) == 0 && current == last {
+		// nothing to write, so this is a Rebase
+		ms.rootHash = ms.storage.Root(ctx)
+		return true, nil
+	}
+
 	if last != ms.rootHash {
 		return false, nil
 	}
@@ -22,9 +22,15 @@
 package chunks
 
 import (
+	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/liquidata-inc/dolt/go/store/constants"
+	"github.com/liquidata-inc/dolt/go/store/hash"
 )
 
 func TestMemoryStoreTestSuite(t *testing.T) {
@@ -42,3 +48,45 @@ func (suite *MemoryStoreTestSuite) SetupTest() {
 func (suite *MemoryStoreTestSuite) TearDownTest() {
 	suite.Factory.Shutter()
 }
+
+func TestNewMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	assert.Equal(t, constants.NomsVersion, store.Version())
+
+	c := NewChunk([]byte("abc"))
+	require.NoError(t, store.Put(ctx, c))
+	absent, err := store.HasMany(ctx, hash.NewHashSet(c.Hash()))
+	require.NoError(t, err)
+	assert.Empty(t, absent)
+
+	success, err := store.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	assert.True(t, success)
+
+	// a second view of the same storage sees the commit, and a no-op commit rebases
+	other := store.storage.NewView()
+	d := NewChunk([]byte("def"))
+	require.NoError(t, other.Put(ctx, d))
+	success, err = other.Commit(ctx, d.Hash(), c.Hash())
+	require.NoError(t, err)
+	assert.True(t, success)
+
+	success, err = store.Commit(ctx, c.Hash(), c.Hash())
+	require.NoError(t, err)
+	assert.True(t, success)
+	root, err := store.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, d.Hash(), root)
+}
+
+func TestMemoryStoreGetManyCancelled(t *testing.T) {
+	store := NewMemoryStore()
+	c := NewChunk([]byte("abc"))
+	require.NoError(t, store.Put(context.Background(), c))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := store.GetMany(ctx, hash.NewHashSet(c.Hash()), make(chan *Chunk))
+	assert.Equal(t, context.Canceled, err)
+}
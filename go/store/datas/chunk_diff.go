@@ -0,0 +1,233 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datas
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+type heightHeap []uint64
+
+func (hh heightHeap) Len() int {
+	return len(hh)
+}
+
+func (hh heightHeap) Less(i, j int) bool {
+	return hh[i] > hh[j]
+}
+
+func (hh heightHeap) Swap(i, j int) {
+	hh[i], hh[j] = hh[j], hh[i]
+}
+
+func (hh *heightHeap) Push(x interface{}) {
+	*hh = append(*hh, x.(uint64))
+}
+
+func (hh *heightHeap) Pop() interface{} {
+	old := *hh
+	h := old[len(old)-1]
+	*hh = old[:len(old)-1]
+	return h
+}
+
+// diffFrontier holds the chunks which ChunkDiff has yet to visit, grouped by height. Each chunk is marked with whether
+// it is reachable from the from root.
+type diffFrontier struct {
+	heights heightHeap
+	levels  map[uint64]map[hash.Hash]bool
+	toCount int
+}
+
+func (df *diffFrontier) add(h hash.Hash, height uint64, fromReachable bool) {
+	level, ok := df.levels[height]
+
+	if !ok {
+		level = make(map[hash.Hash]bool)
+		df.levels[height] = level
+		heap.Push(&df.heights, height)
+	}
+
+	if prev, ok := level[h]; !ok {
+		level[h] = fromReachable
+
+		if !fromReachable {
+			df.toCount++
+		}
+	} else if fromReachable && !prev {
+		level[h] = true
+		df.toCount--
+	}
+}
+
+// popTallest removes and returns the tallest level of the frontier.
+func (df *diffFrontier) popTallest() map[hash.Hash]bool {
+	height := heap.Pop(&df.heights).(uint64)
+	level := df.levels[height]
+	delete(df.levels, height)
+
+	for _, fromReachable := range level {
+		if !fromReachable {
+			df.toCount--
+		}
+	}
+
+	return level
+}
+
+// chunkHeight returns the height of the chunk |c|, which is one more than the height of the tallest chunk it
+// references.
+func chunkHeight(c chunks.Chunk, nbf *types.NomsBinFormat) (uint64, error) {
+	var max uint64
+	err := types.WalkRefs(c, nbf, func(r types.Ref) error {
+		if r.Height() > max {
+			max = r.Height()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return max + 1, nil
+}
+
+// ChunkDiff sends to |ch| the address of every chunk in |cs| which is reachable from |toRoot| but not from |fromRoot|,
+// which is the set of chunks that must be transferred to a store that already has |fromRoot|. An empty |fromRoot|
+// sends every chunk reachable from |toRoot|.
+//
+// Both graphs are walked together from their tallest chunks down, one height at a time. A chunk can only be referenced
+// by taller chunks, so by the time a height is visited it is known whether each of its chunks is reachable from
+// |fromRoot|, and only the chunks of the heights not yet visited need to be held in memory. The walk stops as soon as
+// no chunk reachable only from |toRoot| remains, so the parts of |fromRoot|'s graph below that are never read.
+//
+// Addresses are sent in no particular order within a height. ChunkDiff returns ctx.Err() if |ctx| is cancelled, and
+// does not close |ch|.
+func ChunkDiff(ctx context.Context, cs chunks.ChunkStore, nbf *types.NomsBinFormat, fromRoot, toRoot hash.Hash, ch chan<- hash.Hash) error {
+	if toRoot.IsEmpty() || fromRoot == toRoot {
+		return nil
+	}
+
+	df := &diffFrontier{levels: make(map[uint64]map[hash.Hash]bool)}
+	for _, root := range []hash.Hash{toRoot, fromRoot} {
+		if root.IsEmpty() {
+			continue
+		}
+
+		c, err := cs.Get(ctx, root)
+
+		if err != nil {
+			return err
+		}
+
+		if c.IsEmpty() {
+			return fmt.Errorf("chunk diff: root %s not found", root.String())
+		}
+
+		height, err := chunkHeight(c, nbf)
+
+		if err != nil {
+			return err
+		}
+
+		df.add(root, height, root == fromRoot)
+	}
+
+	for df.toCount > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		level := df.popTallest()
+		err := visitDiffLevel(ctx, cs, nbf, df, level, ch)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visitDiffLevel reads the chunks of |level|, sends the addresses of those not reachable from the from root to |ch|
+// and adds the chunks they reference to |df|.
+func visitDiffLevel(ctx context.Context, cs chunks.ChunkStore, nbf *types.NomsBinFormat, df *diffFrontier, level map[hash.Hash]bool, ch chan<- hash.Hash) error {
+	getCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	hashes, remaining := make(hash.HashSet, len(level)), make(hash.HashSet, len(level))
+	for h := range level {
+		hashes.Insert(h)
+		remaining.Insert(h)
+	}
+
+	ae := atomicerr.New()
+	found := make(chan *chunks.Chunk, 32)
+	go func() {
+		defer close(found)
+		err := cs.GetMany(getCtx, hashes, found)
+		ae.SetIfError(err)
+	}()
+
+	drain := func() {
+		cancel()
+		for range found {
+		}
+	}
+
+	for c := range found {
+		h := c.Hash()
+		remaining.Remove(h)
+		fromReachable := level[h]
+
+		if !fromReachable {
+			select {
+			case ch <- h:
+			case <-ctx.Done():
+				drain()
+				return ctx.Err()
+			}
+		}
+
+		err := types.WalkRefs(*c, nbf, func(r types.Ref) error {
+			df.add(r.TargetHash(), r.Height(), fromReachable)
+			return nil
+		})
+
+		if err != nil {
+			drain()
+			return err
+		}
+	}
+
+	if err := ae.Get(); err != nil {
+		return err
+	}
+
+	for h := range remaining {
+		return fmt.Errorf("chunk diff: chunk %s not found", h.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestChunkDiff(t *testing.T) {
+	ctx := context.Background()
+	storage := &chunks.TestStorage{}
+	cs := storage.NewView()
+	vs := types.NewValueStore(cs)
+
+	write := func(v types.Value) types.Ref {
+		r, err := vs.WriteValue(ctx, v)
+		require.NoError(t, err)
+		return r
+	}
+
+	newList := func(vals ...types.Value) types.List {
+		l, err := types.NewList(ctx, vs, vals...)
+		require.NoError(t, err)
+		return l
+	}
+
+	a := write(types.String("a"))
+	b := write(types.String("b"))
+	shared := write(newList(a, b))
+	c := write(newList(a))
+	from := write(newList(shared))
+	to := write(newList(shared, c))
+
+	rt, err := vs.Root(ctx)
+	require.NoError(t, err)
+	ok, err := vs.Commit(ctx, rt, rt)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	diff := func(fromRoot, toRoot hash.Hash) hash.HashSet {
+		ch := make(chan hash.Hash, 16)
+		err := ChunkDiff(ctx, cs, vs.Format(), fromRoot, toRoot, ch)
+		require.NoError(t, err)
+		close(ch)
+
+		hs := make(hash.HashSet)
+		for h := range ch {
+			assert.False(t, hs.Has(h), "%s sent more than once", h.String())
+			hs.Insert(h)
+		}
+		return hs
+	}
+
+	assert.Equal(t, hash.NewHashSet(to.TargetHash(), c.TargetHash()), diff(from.TargetHash(), to.TargetHash()))
+	assert.Equal(t, hash.NewHashSet(from.TargetHash()), diff(to.TargetHash(), from.TargetHash()))
+	assert.Equal(t, hash.NewHashSet(), diff(to.TargetHash(), to.TargetHash()))
+
+	all := hash.NewHashSet(to.TargetHash(), shared.TargetHash(), c.TargetHash(), a.TargetHash(), b.TargetHash())
+	assert.Equal(t, all, diff(hash.Hash{}, to.TargetHash()))
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := ChunkDiff(ctx, cs, vs.Format(), hash.Hash{}, to.TargetHash(), make(chan hash.Hash))
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		missing := chunks.NewChunk([]byte("missing")).Hash()
+		err := ChunkDiff(ctx, cs, vs.Format(), missing, to.TargetHash(), make(chan hash.Hash, 16))
+		assert.Error(t, err)
+	})
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/liquidata-inc/dolt/go/store/nbs"
 
 	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
@@ -138,6 +139,10 @@ type Database interface {
 
 	Flush(ctx context.Context) error
 
+	// RefCounts returns the number of references to each chunk reachable from
+	// the current root. See the RefCounts function for how |sample| is used.
+	RefCounts(ctx context.Context, sample float64) (map[hash.Hash]uint32, error)
+
 	// chunkStore returns the ChunkStore used to read and write
 	// groups of values to the database efficiently. This interface is a low-
 	// level detail of the database that should infrequently be needed by
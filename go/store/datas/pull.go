@@ -155,6 +155,10 @@ func clone(ctx context.Context, srcTS, sinkTS nbs.TableFileStore, eventCh chan<-
 
 				err = sinkTS.WriteTableFile(ctx, tblFile.FileID(), tblFile.NumChunks(), rd, 0, nil)
 
+				if err == nil {
+					err = copyTableFileChecksum(srcTS, sinkTS, tblFile.FileID())
+				}
+
 				if err != nil {
 					if eventCh != nil {
 						eventCh <- TableFileEvent{DownloadFailed, []nbs.TableFile{tblFile}}
@@ -222,6 +226,52 @@ func clone(ctx context.Context, srcTS, sinkTS nbs.TableFileStore, eventCh chan<-
 	return sinkTS.SetRootChunk(ctx, root, hash.Hash{})
 }
 
+// copyTableFileChecksum carries the checksum |srcTS| records for the table file |fileID| over to its copy in |sinkTS|.
+// If |sinkTS| recorded its own checksum as the file was written, nbs.ErrTableFileChecksumMismatch is returned if the
+// two differ. Otherwise the source's checksum is recorded for the copy, if |sinkTS| can record one, after the copy is
+// checked against it. Nothing is checked when the source has no checksum for the file.
+func copyTableFileChecksum(srcTS, sinkTS nbs.TableFileStore, fileID string) error {
+	srcCS, ok := srcTS.(nbs.TableFileChecksummer)
+
+	if !ok {
+		return nil
+	}
+
+	expected, err := srcCS.TableFileChecksum(fileID)
+
+	if err == nbs.ErrNoTableFileChecksum || err == nbs.ErrTableFileChecksumsUnsupported {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if sinkCS, ok := sinkTS.(nbs.TableFileChecksummer); ok {
+		actual, err := sinkCS.TableFileChecksum(fileID)
+
+		if err == nil {
+			if actual != expected {
+				return nbs.ErrTableFileChecksumMismatch
+			}
+
+			return nil
+		} else if err != nbs.ErrNoTableFileChecksum && err != nbs.ErrTableFileChecksumsUnsupported {
+			return err
+		}
+	}
+
+	if sinkCR, ok := sinkTS.(nbs.TableFileChecksumRecorder); ok {
+		err = sinkCR.RecordTableFileChecksum(fileID, expected)
+
+		if err == nbs.ErrTableFileChecksumsUnsupported {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
 // Pull objects that descend from sourceRef from srcDB to sinkDB.
 func Pull(ctx context.Context, srcDB, sinkDB Database, sourceRef types.Ref, progressCh chan PullProgress) error {
 	return pull(ctx, srcDB, sinkDB, sourceRef, progressCh, defaultBatchSize)
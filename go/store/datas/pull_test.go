@@ -24,6 +24,7 @@ package datas
 import (
 	"bytes"
 	"context"
+	"hash/crc64"
 	"io"
 	"io/ioutil"
 	"reflect"
@@ -491,3 +492,99 @@ func TestClone(t *testing.T) {
 
 	assert.True(t, reflect.DeepEqual(src, dest))
 }
+
+// ChecksummingTableFileStore is a TestTableFileStore which records checksums of its table files, and which corrupts
+// the table files written to it if |corrupt| is set.
+type ChecksummingTableFileStore struct {
+	*TestTableFileStore
+	corrupt bool
+}
+
+func (ctfs *ChecksummingTableFileStore) WriteTableFile(ctx context.Context, fileId string, numChunks int, rd io.Reader, contentLength uint64, contentHash []byte) error {
+	data, err := ioutil.ReadAll(rd)
+
+	if err != nil {
+		return err
+	}
+
+	if ctfs.corrupt {
+		data[0] ^= 0xff
+	}
+
+	return ctfs.TestTableFileStore.WriteTableFile(ctx, fileId, numChunks, bytes.NewReader(data), contentLength, contentHash)
+}
+
+func (ctfs *ChecksummingTableFileStore) TableFileChecksum(fileId string) (uint64, error) {
+	tblFile, ok := ctfs.tableFiles[fileId]
+
+	if !ok {
+		return 0, nbs.ErrNoTableFileChecksum
+	}
+
+	return crc64.Checksum(tblFile.(*TestTableFile).data, crc64.MakeTable(crc64.ECMA)), nil
+}
+
+// RecordingTableFileStore is a TestTableFileStore which records the checksums given to it for its table files, and
+// which corrupts the table files written to it if |corrupt| is set.
+type RecordingTableFileStore struct {
+	*TestTableFileStore
+	checksums map[string]uint64
+	corrupt   bool
+}
+
+func (rtfs *RecordingTableFileStore) WriteTableFile(ctx context.Context, fileId string, numChunks int, rd io.Reader, contentLength uint64, contentHash []byte) error {
+	ctfs := &ChecksummingTableFileStore{TestTableFileStore: rtfs.TestTableFileStore, corrupt: rtfs.corrupt}
+	return ctfs.WriteTableFile(ctx, fileId, numChunks, rd, contentLength, contentHash)
+}
+
+func (rtfs *RecordingTableFileStore) RecordTableFileChecksum(fileId string, sum uint64) error {
+	actual, err := (&ChecksummingTableFileStore{TestTableFileStore: rtfs.TestTableFileStore}).TableFileChecksum(fileId)
+
+	if err != nil {
+		return err
+	}
+
+	if actual != sum {
+		return nbs.ErrTableFileChecksumMismatch
+	}
+
+	rtfs.checksums[fileId] = sum
+	return nil
+}
+
+func TestCloneChecksums(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(tableFiles map[string]nbs.TableFile) *TestTableFileStore {
+		return &TestTableFileStore{root: hash.Of([]byte("root")), tableFiles: tableFiles}
+	}
+
+	src := &ChecksummingTableFileStore{TestTableFileStore: newStore(map[string]nbs.TableFile{
+		"file1": &TestTableFile{fileID: "file1", numChunks: 1, data: []byte("Call me Ishmael.")},
+	})}
+
+	dest := &ChecksummingTableFileStore{TestTableFileStore: newStore(map[string]nbs.TableFile{})}
+	require.NoError(t, clone(ctx, src, dest, nil))
+	assert.Equal(t, src.tableFiles, dest.tableFiles)
+
+	// sinks which don't record checksums can't be checked
+	unchecked := newStore(map[string]nbs.TableFile{})
+	require.NoError(t, clone(ctx, src, unchecked, nil))
+
+	// sinks which can record a checksum are given the source's, once their copy matches it
+	recording := &RecordingTableFileStore{TestTableFileStore: newStore(map[string]nbs.TableFile{}), checksums: map[string]uint64{}}
+	require.NoError(t, clone(ctx, src, recording, nil))
+	expected, err := src.TableFileChecksum("file1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"file1": expected}, recording.checksums)
+
+	corruptingRecorder := &RecordingTableFileStore{
+		TestTableFileStore: newStore(map[string]nbs.TableFile{}),
+		checksums:          map[string]uint64{},
+		corrupt:            true,
+	}
+	assert.Equal(t, nbs.ErrTableFileChecksumMismatch, clone(ctx, src, corruptingRecorder, nil))
+	assert.Empty(t, corruptingRecorder.checksums)
+
+	corrupting := &ChecksummingTableFileStore{TestTableFileStore: newStore(map[string]nbs.TableFile{}), corrupt: true}
+	assert.Equal(t, nbs.ErrTableFileChecksumMismatch, clone(ctx, src, corrupting, nil))
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datas
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+var ErrInvalidSample = errors.New("sample must be greater than 0 and at most 1")
+
+func (db *database) RefCounts(ctx context.Context, sample float64) (map[hash.Hash]uint32, error) {
+	root, err := db.rt.Root(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return RefCounts(ctx, db.chunkStore(), db.Format(), root, sample)
+}
+
+// RefCounts returns the number of references to each chunk reachable from |root| in |cs|, counting every reference
+// held by a chunk to another. A chunk referenced by many others is shared by many values, so the counts show how
+// well the store deduplicates. The chunks are not checked for reachability from anything other than |root|, so this
+// is a diagnostic and must not be used to decide what can be collected.
+//
+// A |sample| of 1 walks every chunk. A smaller |sample| reads only that fraction of the chunks below |root|, chosen
+// by their addresses, and counts only the references held by the chunks read. The chunks below a chunk which is not
+// read are themselves only reached if another chunk read references them, so sampled counts are lower bounds, and
+// they fall off with the depth of the chunk. They are useful for finding the most referenced chunks, not for summing.
+func RefCounts(ctx context.Context, cs chunks.ChunkStore, nbf *types.NomsBinFormat, root hash.Hash, sample float64) (map[hash.Hash]uint32, error) {
+	if sample <= 0 || sample > 1 {
+		return nil, ErrInvalidSample
+	}
+
+	counts := make(map[hash.Hash]uint32)
+
+	if root.IsEmpty() {
+		return counts, nil
+	}
+
+	limit := sample * math.MaxUint32
+	level := hash.NewHashSet(root)
+	seen := hash.NewHashSet(root)
+
+	for len(level) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		next := make(hash.HashSet)
+		err := visitRefCountLevel(ctx, cs, nbf, level, func(r types.Ref) {
+			h := r.TargetHash()
+			counts[h]++
+
+			// A chunk of height 1 holds no references, so there is no need to read it.
+			if r.Height() > 1 && !seen.Has(h) {
+				seen.Insert(h)
+
+				if float64(binary.BigEndian.Uint32(h[:])) <= limit {
+					next.Insert(h)
+				}
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		level = next
+	}
+
+	return counts, nil
+}
+
+// visitRefCountLevel reads the chunks of |level| and calls |cb| for each reference they hold.
+func visitRefCountLevel(ctx context.Context, cs chunks.ChunkStore, nbf *types.NomsBinFormat, level hash.HashSet, cb func(r types.Ref)) error {
+	getCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	remaining := make(hash.HashSet, len(level))
+	for h := range level {
+		remaining.Insert(h)
+	}
+
+	ae := atomicerr.New()
+	found := make(chan *chunks.Chunk, 32)
+	go func() {
+		defer close(found)
+		err := cs.GetMany(getCtx, level, found)
+		ae.SetIfError(err)
+	}()
+
+	for c := range found {
+		remaining.Remove(c.Hash())
+		err := types.WalkRefs(*c, nbf, func(r types.Ref) error {
+			cb(r)
+			return nil
+		})
+
+		if err != nil {
+			cancel()
+			for range found {
+			}
+
+			return err
+		}
+	}
+
+	if err := ae.Get(); err != nil {
+		return err
+	}
+
+	for h := range remaining {
+		return fmt.Errorf("ref counts: chunk %s not found", h.String())
+	}
+
+	return nil
+}
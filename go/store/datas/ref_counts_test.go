@@ -0,0 +1,86 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestRefCounts(t *testing.T) {
+	ctx := context.Background()
+	storage := &chunks.TestStorage{}
+	db := NewDatabase(storage.NewView())
+	defer db.Close()
+
+	write := func(v types.Value) types.Ref {
+		r, err := db.WriteValue(ctx, v)
+		require.NoError(t, err)
+		return r
+	}
+
+	newList := func(vals ...types.Value) types.List {
+		l, err := types.NewList(ctx, db, vals...)
+		require.NoError(t, err)
+		return l
+	}
+
+	a := write(types.String("a"))
+	b := write(types.String("b"))
+	shared := write(newList(a, b))
+	c := write(newList(a, a))
+	top := write(newList(shared, c))
+	other := write(newList(shared))
+
+	ds, err := db.GetDataset(ctx, "ds")
+	require.NoError(t, err)
+	_, err = db.CommitValue(ctx, ds, newList(top, other))
+	require.NoError(t, err)
+
+	counts, err := db.RefCounts(ctx, 1)
+	require.NoError(t, err)
+
+	expected := map[hash.Hash]uint32{
+		a.TargetHash():      3,
+		b.TargetHash():      1,
+		shared.TargetHash(): 2,
+		c.TargetHash():      1,
+		top.TargetHash():    1,
+		other.TargetHash():  1,
+	}
+	for h, n := range expected {
+		assert.Equal(t, n, counts[h], h.String())
+	}
+
+	sampled, err := db.RefCounts(ctx, 0.5)
+	require.NoError(t, err)
+	for h, n := range sampled {
+		assert.True(t, n <= counts[h], h.String())
+	}
+
+	_, err = db.RefCounts(ctx, 0)
+	assert.Equal(t, ErrInvalidSample, err)
+
+	empty, err := RefCounts(ctx, storage.NewView(), types.Format_Default, hash.Hash{}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
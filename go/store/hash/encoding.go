@@ -0,0 +1,132 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hash
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+
+	"github.com/liquidata-inc/dolt/go/store/d"
+)
+
+// Encoding is a textual representation of a Hash. Base32Encoding is the representation used everywhere within
+// Noms; the others exist to read and write addresses produced by other tools.
+type Encoding int
+
+const (
+	// Base32Encoding is big-endian base32 with the alphabet {0-9,a-v}, as produced by Hash.String.
+	Base32Encoding Encoding = iota
+
+	// HexEncoding is lowercase hexadecimal.
+	HexEncoding
+
+	// StdBase32Encoding is unpadded base32 with the RFC 4648 alphabet {A-Z,2-7}.
+	StdBase32Encoding
+)
+
+var (
+	ErrUnknownEncoding    = errors.New("unknown hash encoding")
+	ErrInvalidHashLength  = errors.New("invalid hash length")
+	ErrInvalidHashCharset = errors.New("invalid character in hash")
+)
+
+var stdBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (enc Encoding) String() string {
+	switch enc {
+	case Base32Encoding:
+		return "base32"
+	case HexEncoding:
+		return "hex"
+	case StdBase32Encoding:
+		return "std-base32"
+	default:
+		return "unknown"
+	}
+}
+
+// stringLen returns the number of characters in a Hash encoded with |enc|.
+func (enc Encoding) stringLen() (int, error) {
+	switch enc {
+	case Base32Encoding, StdBase32Encoding:
+		return StringLen, nil
+	case HexEncoding:
+		return hex.EncodedLen(ByteLen), nil
+	default:
+		return 0, ErrUnknownEncoding
+	}
+}
+
+func (enc Encoding) encode(data []byte) (string, error) {
+	switch enc {
+	case Base32Encoding:
+		return encode(data), nil
+	case HexEncoding:
+		return hex.EncodeToString(data), nil
+	case StdBase32Encoding:
+		return stdBase32.EncodeToString(data), nil
+	default:
+		return "", ErrUnknownEncoding
+	}
+}
+
+func (enc Encoding) decode(s string) ([]byte, error) {
+	switch enc {
+	case Base32Encoding:
+		return encoding.DecodeString(s)
+	case HexEncoding:
+		return hex.DecodeString(s)
+	case StdBase32Encoding:
+		return stdBase32.DecodeString(s)
+	default:
+		return nil, ErrUnknownEncoding
+	}
+}
+
+// ParseWithEncoding parses |s| as a Hash encoded with |enc|. Unlike Parse, malformed input results in an error rather
+// than a panic.
+func ParseWithEncoding(s string, enc Encoding) (Hash, error) {
+	strLen, err := enc.stringLen()
+
+	if err != nil {
+		return emptyHash, err
+	}
+
+	if len(s) != strLen {
+		return emptyHash, ErrInvalidHashLength
+	}
+
+	data, err := enc.decode(s)
+
+	if err != nil || len(data) != ByteLen {
+		return emptyHash, ErrInvalidHashCharset
+	}
+
+	// The decoders accept some inputs, such as uppercase hex, which are not the canonical form. Rejecting anything
+	// that doesn't round trip keeps parsing strict.
+	if canonical, _ := enc.encode(data); canonical != s {
+		return emptyHash, ErrInvalidHashCharset
+	}
+
+	return New(data), nil
+}
+
+// StringWithEncoding returns a string representation of the hash using |enc|. It panics if |enc| is not a known
+// Encoding.
+func (h Hash) StringWithEncoding(enc Encoding) string {
+	s, err := enc.encode(h[:])
+	d.PanicIfError(err)
+	return s
+}
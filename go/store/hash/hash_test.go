@@ -22,6 +22,7 @@
 package hash
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -144,3 +145,40 @@ func TestGreater(t *testing.T) {
 	assert.False(r0.Greater(r2))
 	assert.True(r2.Greater(r0))
 }
+
+func TestParseWithEncoding(t *testing.T) {
+	h := Of([]byte("abc"))
+
+	for _, enc := range []Encoding{Base32Encoding, HexEncoding, StdBase32Encoding} {
+		t.Run(enc.String(), func(t *testing.T) {
+			s := h.StringWithEncoding(enc)
+			parsed, err := ParseWithEncoding(s, enc)
+			assert.NoError(t, err)
+			assert.Equal(t, h, parsed)
+
+			_, err = ParseWithEncoding(s[1:], enc)
+			assert.Equal(t, ErrInvalidHashLength, err)
+
+			_, err = ParseWithEncoding(s+s[:1], enc)
+			assert.Equal(t, ErrInvalidHashLength, err)
+
+			_, err = ParseWithEncoding("!"+s[1:], enc)
+			assert.Equal(t, ErrInvalidHashCharset, err)
+		})
+	}
+
+	assert.Equal(t, h.String(), h.StringWithEncoding(Base32Encoding))
+	assert.Equal(t, "0000000000000000000000000000000000000001", New(append(make([]byte, ByteLen-1), 1)).StringWithEncoding(HexEncoding))
+
+	_, err := ParseWithEncoding(strings.ToUpper(h.StringWithEncoding(HexEncoding)), HexEncoding)
+	assert.Equal(t, ErrInvalidHashCharset, err)
+
+	_, err = ParseWithEncoding("0000000000000000000000000000000w", Base32Encoding)
+	assert.Equal(t, ErrInvalidHashCharset, err)
+
+	_, err = ParseWithEncoding(h.String(), Encoding(-1))
+	assert.Equal(t, ErrUnknownEncoding, err)
+	assert.Panics(t, func() {
+		h.StringWithEncoding(Encoding(-1))
+	})
+}
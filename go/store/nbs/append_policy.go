@@ -0,0 +1,478 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+)
+
+// AppendPolicy controls whether a store appends the chunks of each full memtable to the table most recently written
+// from a memtable, rather than always writing a new table file. A flush heavy workload then adds tables to the
+// manifest, and so needs conjoining, far less often.
+//
+// Table files are immutable and named for their contents, so appending rewrites the recent table and the memtable
+// as a single new table file. Each append costs a write of the whole table so far, which MaxTableSize bounds. As with
+// any memtable, the rewrite happens in the background, and the memtable's chunks are read from memory until it is
+// done. The replaced table file was never committed to the manifest, and is deleted once no reads of it remain.
+// Appending only changes tables which are not yet committed: until Commit writes the manifest, their chunks are lost
+// by a crash either way, and committed tables are never rewritten.
+type AppendPolicy struct {
+	// MaxTableSize is the size of chunk data, in bytes, up to which memtables are appended to the most recent table.
+	// The size of each memtable's chunk data is taken to be the most it can compress to, so tables may hold less.
+	// Zero disables appending.
+	MaxTableSize uint64
+}
+
+// DefaultAppendPolicy writes each memtable to a new table file.
+var DefaultAppendPolicy = AppendPolicy{}
+
+// PrependAppending is like Prepend, but when the most recently prepended table was prepended by PrependAppending, and
+// it and |mt| together hold no more than |maxTableSize| bytes of chunk data, |mt| is appended to that table instead.
+func (ts tableSet) PrependAppending(ctx context.Context, mt *memTable, maxTableSize uint64, stats *Stats) tableSet {
+	if len(ts.novel) > 0 {
+		if acs, ok := ts.novel[0].(*appendingChunkSource); ok && acs.tryAppend(ctx, mt, ts, maxTableSize, stats) {
+			return ts
+		}
+	}
+
+	newTs := tableSet{
+		novel:    make(chunkSources, len(ts.novel)+1),
+		upstream: make(chunkSources, len(ts.upstream)),
+		p:        ts.p,
+		rl:       ts.rl,
+		access:   ts.access,
+	}
+	newTs.novel[0] = newAppendingChunkSource(ctx, mt, ts, stats)
+	copy(newTs.novel[1:], ts.novel)
+	copy(newTs.upstream, ts.upstream)
+	return newTs
+}
+
+// prependMemTable adds the store's memtable to its tables according to its AppendPolicy.
+func (nbs *NomsBlockStore) prependMemTable(ctx context.Context) {
+	if nbs.appendPolicy.MaxTableSize == 0 {
+		nbs.tables = nbs.tables.Prepend(ctx, nbs.mt, nbs.stats)
+	} else {
+		nbs.tables = nbs.tables.PrependAppending(ctx, nbs.mt, nbs.appendPolicy.MaxTableSize, nbs.stats)
+	}
+}
+
+// maxChunkDataLen returns the most chunk data the chunks of |mt| can take up in a table.
+func maxChunkDataLen(mt *memTable) uint64 {
+	var size uint64
+	for _, data := range mt.chunks {
+		size += uint64(snappy.MaxEncodedLen(len(data))) + checksumSize
+	}
+
+	return size
+}
+
+// appendedTable is a table of an appendingChunkSource, with the number of reads using it.
+type appendedTable struct {
+	chunkSource
+	reads   int
+	retired bool
+}
+
+// appendingChunkSource is a chunkSource which memtables are appended to, as PrependAppending does. Like a
+// persistingChunkSource, it persists memtables in the background, reading their chunks from memory in the meantime.
+// Each append rewrites the source's table and the memtable as a new table, which replaces the old one in place, so
+// that every tableSet holding the source sees it. A replaced table is deleted once the reads using it are done.
+type appendingChunkSource struct {
+	p  tablePersister
+	rl chan struct{}
+
+	// mu guards |table|, |pending| and the reads of each appendedTable. It is never held while waiting on anything.
+	mu      sync.Mutex
+	table   *appendedTable
+	pending []*memTable // appended, but not yet in |table|, newest first
+
+	// maxData bounds the chunk data of |table| and |pending| together. It is only used by tryAppend.
+	maxData uint64
+
+	// lastAppend is closed when the most recently started append is done. Each append waits for the one before it, so
+	// that appends happen one at a time, in the order they were started. It is only used by append, which callers
+	// serialize.
+	lastAppend chan struct{}
+	ae         *atomicerr.AtomicError
+	wg         sync.WaitGroup
+}
+
+func newAppendingChunkSource(ctx context.Context, mt *memTable, haver tableSet, stats *Stats) *appendingChunkSource {
+	acs := &appendingChunkSource{
+		p:     haver.p,
+		rl:    haver.rl,
+		table: &appendedTable{chunkSource: emptyChunkSource{}},
+		ae:    atomicerr.New(),
+	}
+	acs.append(ctx, mt, haver, stats)
+
+	return acs
+}
+
+// tryAppend appends |mt| to |acs| if their chunk data together is no more than |maxTableSize| bytes, returning
+// whether it did. |ts| holds |acs| as its most recent table.
+func (acs *appendingChunkSource) tryAppend(ctx context.Context, mt *memTable, ts tableSet, maxTableSize uint64, stats *Stats) bool {
+	if acs.ae.IsSet() || acs.maxData+maxChunkDataLen(mt) > maxTableSize {
+		return false
+	}
+
+	others := ts
+	others.novel = ts.novel[1:]
+	acs.append(ctx, mt, others, stats)
+
+	return true
+}
+
+// append adds |mt| to the chunks |acs| reads, and starts rewriting |acs|'s table with it, dropping the chunks already
+// in |others|, the rest of the tables of the store.
+func (acs *appendingChunkSource) append(ctx context.Context, mt *memTable, others tableSet, stats *Stats) {
+	acs.maxData += maxChunkDataLen(mt)
+
+	acs.mu.Lock()
+	acs.pending = append([]*memTable{mt}, acs.pending...)
+	acs.mu.Unlock()
+
+	prev, done := acs.lastAppend, make(chan struct{})
+	acs.lastAppend = done
+
+	acs.wg.Add(1)
+	go func() {
+		defer acs.wg.Done()
+		defer close(done)
+
+		if prev != nil {
+			<-prev
+		}
+
+		if acs.ae.IsSet() {
+			return
+		}
+
+		acs.rl <- struct{}{}
+		defer func() {
+			<-acs.rl
+		}()
+
+		// Only this append replaces |acs.table|, so it can be read without counting the read.
+		err := acs.rewrite(ctx, mt, others, acs.table.chunkSource, stats)
+		acs.ae.SetIfError(err)
+	}()
+}
+
+// rewrite persists |mt|, without the chunks in |others| or |table|, conjoins it with |table| and replaces |table|
+// with the result.
+func (acs *appendingChunkSource) rewrite(ctx context.Context, mt *memTable, others tableSet, table chunkSource, stats *Stats) error {
+	t1 := time.Now()
+	haver := others
+	haver.novel = append(chunkSources{table}, others.novel...)
+	src, err := acs.p.Persist(ctx, mt, haver, stats)
+
+	if err != nil {
+		return err
+	}
+
+	srcCnt, err := src.count()
+
+	if err != nil {
+		return err
+	}
+
+	tableCnt, err := table.count()
+
+	if err != nil {
+		return err
+	}
+
+	// an append which adds no chunks leaves |table| as it is, and nothing is rewritten when |table| is empty
+	appended := table
+	if srcCnt > 0 && tableCnt == 0 {
+		appended = src
+	} else if srcCnt > 0 {
+		appended, err = acs.p.ConjoinAll(ctx, chunkSources{table, src}, stats)
+
+		if err != nil {
+			return err
+		}
+
+		// |src| was never read by anything but the conjoin
+		acs.deleteTable(src)
+	}
+
+	if srcCnt > 0 {
+		stats.PersistLatency.SampleTimeSince(t1)
+	}
+
+	acs.mu.Lock()
+	replaced := acs.table
+	if srcCnt > 0 {
+		acs.table = &appendedTable{chunkSource: appended}
+		replaced.retired = true
+	}
+
+	for i, pending := range acs.pending {
+		if pending == mt {
+			acs.pending = append(acs.pending[:i:i], acs.pending[i+1:]...)
+			break
+		}
+	}
+
+	unread := replaced.retired && replaced.reads == 0
+	acs.mu.Unlock()
+
+	if unread {
+		acs.deleteTable(replaced.chunkSource)
+	}
+
+	return nil
+}
+
+// deleteTable deletes the table file of |cs|, if it has one. Deleting is best effort: a table which isn't deleted is
+// left on disk unreferenced, as conjoined tables are.
+func (acs *appendingChunkSource) deleteTable(cs chunkSource) {
+	fsPersister, ok := acs.p.(*fsTablePersister)
+
+	if !ok {
+		return
+	}
+
+	cnt, err := cs.count()
+
+	if err != nil || cnt == 0 {
+		return
+	}
+
+	name, err := cs.hash()
+
+	if err != nil {
+		return
+	}
+
+	_ = fsPersister.deleteTable(name)
+}
+
+// readers returns the readers of the chunks of |acs|, newest first. The returned table must be released when the
+// reads of it are done.
+func (acs *appendingChunkSource) readers() ([]chunkReader, *appendedTable) {
+	acs.mu.Lock()
+	defer acs.mu.Unlock()
+
+	acs.table.reads++
+	readers := make([]chunkReader, 0, len(acs.pending)+1)
+	for _, mt := range acs.pending {
+		readers = append(readers, mt)
+	}
+
+	return append(readers, acs.table.chunkSource), acs.table
+}
+
+// release ends a read of |table|, deleting it if it was replaced and this was the last read using it.
+func (acs *appendingChunkSource) release(table *appendedTable) {
+	acs.mu.Lock()
+	table.reads--
+	unread := table.retired && table.reads == 0
+	acs.mu.Unlock()
+
+	if unread {
+		acs.deleteTable(table.chunkSource)
+	}
+}
+
+func (acs *appendingChunkSource) has(h addr) (bool, error) {
+	readers, table := acs.readers()
+	defer acs.release(table)
+
+	for _, cr := range readers {
+		has, err := cr.has(h)
+
+		if err != nil || has {
+			return has, err
+		}
+	}
+
+	return false, nil
+}
+
+func (acs *appendingChunkSource) hasMany(addrs []hasRecord) (bool, error) {
+	readers, table := acs.readers()
+	defer acs.release(table)
+
+	for _, cr := range readers {
+		remaining, err := cr.hasMany(addrs)
+
+		if err != nil || !remaining {
+			return remaining, err
+		}
+	}
+
+	return true, nil
+}
+
+func (acs *appendingChunkSource) get(ctx context.Context, h addr, stats *Stats) ([]byte, error) {
+	readers, table := acs.readers()
+	defer acs.release(table)
+
+	for _, cr := range readers {
+		data, err := cr.get(ctx, h, stats)
+
+		if err != nil || data != nil {
+			return data, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (acs *appendingChunkSource) getMany(ctx context.Context, reqs []getRecord, foundChunks chan<- *chunks.Chunk, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
+	return acs.getManyReleasing(wg, func(cr chunkReader, readWg *sync.WaitGroup) bool {
+		return cr.getMany(ctx, reqs, foundChunks, readWg, ae, stats)
+	})
+}
+
+func (acs *appendingChunkSource) getManyCompressed(ctx context.Context, reqs []getRecord, foundCmpChunks chan<- CompressedChunk, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
+	return acs.getManyReleasing(wg, func(cr chunkReader, readWg *sync.WaitGroup) bool {
+		return cr.getManyCompressed(ctx, reqs, foundCmpChunks, readWg, ae, stats)
+	})
+}
+
+// getManyReleasing calls |getMany| with each of the readers of |acs| until no requests remain, and releases the
+// table read once the reads started on |readWg| are done, which |wg| waits for.
+func (acs *appendingChunkSource) getManyReleasing(wg *sync.WaitGroup, getMany func(cr chunkReader, readWg *sync.WaitGroup) bool) bool {
+	readers, table := acs.readers()
+	readWg := &sync.WaitGroup{}
+
+	remaining := true
+	for _, cr := range readers {
+		remaining = getMany(cr, readWg)
+
+		if !remaining {
+			break
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		readWg.Wait()
+		acs.release(table)
+	}()
+
+	return remaining
+}
+
+func (acs *appendingChunkSource) wait() error {
+	acs.wg.Wait()
+	return acs.ae.Get()
+}
+
+// appended waits for the appends to |acs| to be done, and returns its table, to be released when the reads of it are
+// done.
+func (acs *appendingChunkSource) appended() (*appendedTable, error) {
+	err := acs.wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, table := acs.readers()
+	return table, nil
+}
+
+func (acs *appendingChunkSource) count() (uint32, error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer acs.release(table)
+	return table.count()
+}
+
+func (acs *appendingChunkSource) uncompressedLen() (uint64, error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer acs.release(table)
+	return table.uncompressedLen()
+}
+
+func (acs *appendingChunkSource) hash() (addr, error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return addr{}, err
+	}
+
+	defer acs.release(table)
+	return table.hash()
+}
+
+func (acs *appendingChunkSource) index() (tableIndex, error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return tableIndex{}, err
+	}
+
+	defer acs.release(table)
+	return table.index()
+}
+
+// reader, unlike the other reads of |acs|, returns with the read still in progress. It is only called to conjoin
+// committed tables, which are never appended to.
+func (acs *appendingChunkSource) reader(ctx context.Context) (io.Reader, error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer acs.release(table)
+	return table.reader(ctx)
+}
+
+func (acs *appendingChunkSource) calcReads(reqs []getRecord, blockSize uint64) (reads int, remaining bool, err error) {
+	table, err := acs.appended()
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer acs.release(table)
+	return table.calcReads(reqs, blockSize)
+}
+
+func (acs *appendingChunkSource) extract(ctx context.Context, chunks chan<- extractRecord) error {
+	table, err := acs.appended()
+
+	if err != nil {
+		return err
+	}
+
+	defer acs.release(table)
+	return table.extract(ctx, chunks)
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// BatchedRootsMetaKey is the root metadata key under which a BatchCommitStore records the roots a batch committed
+// before its final root, when its policy asks for them. They are comma separated, oldest first.
+const BatchedRootsMetaKey = "batched_roots"
+
+// ErrBatchCommitConflict is returned when a BatchCommitStore flushes a batch and finds that the store's root was moved
+// by another writer since the batch began. The batch's roots are dropped, although the chunks written for them
+// remain in the store.
+var ErrBatchCommitConflict = errors.New("root moved while commits were batched")
+
+// BatchCommitPolicy controls how many commits a BatchCommitStore coalesces into each manifest update.
+type BatchCommitPolicy struct {
+	// Window is how long after the first commit of a batch the batch is flushed. Time is only checked by Commit, so a
+	// batch is never flushed while no commits are being made.
+	Window time.Duration
+
+	// MaxCommits is the number of commits after which a batch is flushed, regardless of Window. Zero means no limit.
+	MaxCommits int
+
+	// RecordRoots has each flush record the batch's intermediate roots in the final root's metadata, under
+	// BatchedRootsMetaKey.
+	RecordRoots bool
+}
+
+// BatchCommitStore is a NomsBlockStore whose Commits are coalesced, in batches, into a single manifest update, trading
+// commit latency for throughput during high volume ingestion. The final root of each batch wins.
+//
+// A commit to a BatchCommitStore succeeds or fails as if it were made to the store, and is visible right away through
+// the BatchCommitStore's Root. Until its batch is flushed, however, it is not in the manifest: other stores and
+// processes don't see it, and a crash loses it. Chunks written for a batch are persisted as the memtable fills, as
+// always, but are not reachable from any committed root until the flush. A batch's commits are made durable together
+// by Flush, Rebase or Close, or by the Commit that ends the batch's window. If another writer moved the store's root
+// during the batch, flushing it fails with ErrBatchCommitConflict.
+type BatchCommitStore struct {
+	*NomsBlockStore
+	policy BatchCommitPolicy
+
+	mu      sync.Mutex // protects the following batch state
+	pending bool
+	base    hash.Hash
+	root    hash.Hash
+	started time.Time
+	commits int
+	roots   []hash.Hash
+}
+
+// NewBatchCommitStore returns a BatchCommitStore which batches commits to |nbs| according to |policy|.
+func NewBatchCommitStore(nbs *NomsBlockStore, policy BatchCommitPolicy) *BatchCommitStore {
+	return &BatchCommitStore{NomsBlockStore: nbs, policy: policy}
+}
+
+// Root returns the root of the most recent commit, including one which hasn't been flushed yet.
+func (bcs *BatchCommitStore) Root(ctx context.Context) (hash.Hash, error) {
+	bcs.mu.Lock()
+	defer bcs.mu.Unlock()
+
+	if bcs.pending {
+		return bcs.root, nil
+	}
+
+	return bcs.NomsBlockStore.Root(ctx)
+}
+
+// Commit adds the move of the root from |last| to |current| to the current batch, starting a batch if there isn't
+// one, and flushes the batch if the policy says it is done. Commit fails without error if |last| is not the root. If
+// the flush fails, its error is returned, and the batch, including this commit, stays pending unless the error is
+// ErrBatchCommitConflict.
+func (bcs *BatchCommitStore) Commit(ctx context.Context, current, last hash.Hash) (bool, error) {
+	bcs.mu.Lock()
+	defer bcs.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if !bcs.pending {
+		root, err := bcs.NomsBlockStore.Root(ctx)
+
+		if err != nil {
+			return false, err
+		}
+
+		if root != last {
+			return false, nil
+		}
+
+		bcs.pending = true
+		bcs.base = root
+		bcs.started = time.Now()
+	} else if bcs.root != last {
+		return false, nil
+	} else if bcs.policy.RecordRoots {
+		bcs.roots = append(bcs.roots, bcs.root)
+	}
+
+	bcs.root = current
+	bcs.commits++
+
+	if bcs.policy.MaxCommits > 0 && bcs.commits >= bcs.policy.MaxCommits || time.Since(bcs.started) >= bcs.policy.Window {
+		err := bcs.flush(ctx)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Flush writes the pending batch, if any, to the manifest, along with any pending chunks.
+func (bcs *BatchCommitStore) Flush(ctx context.Context) error {
+	bcs.mu.Lock()
+	defer bcs.mu.Unlock()
+
+	if !bcs.pending {
+		return bcs.NomsBlockStore.Flush(ctx)
+	}
+
+	return bcs.flush(ctx)
+}
+
+// Rebase flushes the pending batch and then rebases the store.
+func (bcs *BatchCommitStore) Rebase(ctx context.Context) error {
+	err := bcs.Flush(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return bcs.NomsBlockStore.Rebase(ctx)
+}
+
+// Close flushes the pending batch and closes the store. The store is closed even if the flush fails.
+func (bcs *BatchCommitStore) Close() error {
+	bcs.mu.Lock()
+	var err error
+	if bcs.pending {
+		err = bcs.flush(context.Background())
+	}
+	bcs.mu.Unlock()
+
+	closeErr := bcs.NomsBlockStore.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// flush commits the batch, which must be pending, and ends it unless the commit returns an error. Callers must hold
+// bcs.mu.
+func (bcs *BatchCommitStore) flush(ctx context.Context) error {
+	var meta map[string]string
+	if len(bcs.roots) > 0 {
+		strs := make([]string, len(bcs.roots))
+		for i, h := range bcs.roots {
+			strs[i] = h.String()
+		}
+
+		meta = map[string]string{BatchedRootsMetaKey: strings.Join(strs, ",")}
+	}
+
+	success, err := bcs.NomsBlockStore.CommitWithMeta(ctx, bcs.root, bcs.base, meta)
+
+	if err != nil {
+		// the batch stays pending, so that it can be retried
+		return err
+	}
+
+	bcs.pending = false
+	bcs.commits = 0
+	bcs.roots = nil
+
+	if !success {
+		return ErrBatchCommitConflict
+	}
+
+	return nil
+}
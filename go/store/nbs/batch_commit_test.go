@@ -0,0 +1,214 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func makeBatchCommitTestDir(t require.TestingT) string {
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	return testDir
+}
+
+func manifestRoot(t *testing.T, dir string) hash.Hash {
+	_, contents, err := fileManifest{dir: dir}.ParseIfExists(context.Background(), &Stats{}, nil)
+	require.NoError(t, err)
+	return contents.root
+}
+
+func TestBatchCommitStore(t *testing.T) {
+	ctx := context.Background()
+	testDir := makeBatchCommitTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	bcs := NewBatchCommitStore(st, BatchCommitPolicy{Window: time.Hour, MaxCommits: 3, RecordRoots: true})
+
+	commitChunk := func(data string, last hash.Hash) hash.Hash {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, bcs.Put(ctx, c))
+		ok, err := bcs.Commit(ctx, c.Hash(), last)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return c.Hash()
+	}
+
+	h1 := commitChunk("one", hash.Hash{})
+	h2 := commitChunk("two", h1)
+
+	// batched commits are visible through the BatchCommitStore only
+	root, err := bcs.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, h2, root)
+	assert.Equal(t, hash.Hash{}, manifestRoot(t, testDir))
+
+	ok, err := bcs.Commit(ctx, h1, h1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the third commit fills the batch
+	h3 := commitChunk("three", h2)
+	assert.Equal(t, h3, manifestRoot(t, testDir))
+	meta, ok, err := st.RootMeta(ctx, h3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, h1.String()+","+h2.String(), meta[BatchedRootsMetaKey])
+
+	h4 := commitChunk("four", h3)
+	assert.Equal(t, h3, manifestRoot(t, testDir))
+	require.NoError(t, bcs.Flush(ctx))
+	assert.Equal(t, h4, manifestRoot(t, testDir))
+	_, ok, err = st.RootMeta(ctx, h4)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	meta, ok, err = st.RootMeta(ctx, h3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, h1.String()+","+h2.String(), meta[BatchedRootsMetaKey])
+
+	commitChunk("five", h4)
+	require.NoError(t, bcs.Close())
+
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.Close()
+	assertInputInStore([]byte("five"), manifestRoot(t, testDir), st, assert.New(t))
+}
+
+func TestBatchCommitStoreMaxCommits(t *testing.T) {
+	ctx := context.Background()
+	testDir := makeBatchCommitTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	bcs := NewBatchCommitStore(st, BatchCommitPolicy{Window: time.Hour, MaxCommits: 2})
+	defer bcs.Close()
+
+	last := hash.Hash{}
+	for i := 1; i <= 5; i++ {
+		c := chunks.NewChunk([]byte(strconv.Itoa(i)))
+		require.NoError(t, bcs.Put(ctx, c))
+		ok, err := bcs.Commit(ctx, c.Hash(), last)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// every second commit fills the batch, without the roots being recorded
+		if i%2 == 0 {
+			assert.Equal(t, c.Hash(), manifestRoot(t, testDir), "commit %d", i)
+		} else {
+			assert.Equal(t, last, manifestRoot(t, testDir), "commit %d", i)
+		}
+
+		last = c.Hash()
+	}
+}
+
+func TestBatchCommitStoreConflict(t *testing.T) {
+	ctx := context.Background()
+	testDir := makeBatchCommitTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	bcs := NewBatchCommitStore(st, BatchCommitPolicy{Window: time.Hour})
+	defer bcs.Close()
+
+	c := chunks.NewChunk([]byte("batched"))
+	require.NoError(t, bcs.Put(ctx, c))
+	ok, err := bcs.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	interloper, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer interloper.Close()
+	ic := chunks.NewChunk([]byte("interloper"))
+	require.NoError(t, interloper.Put(ctx, ic))
+	ok, err = interloper.Commit(ctx, ic.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	err = bcs.Flush(ctx)
+	assert.Equal(t, ErrBatchCommitConflict, err)
+	assert.Equal(t, ic.Hash(), manifestRoot(t, testDir))
+
+	root, err := bcs.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ic.Hash(), root)
+}
+
+func BenchmarkBatchCommitStore(b *testing.B) {
+	ctx := context.Background()
+
+	type committer interface {
+		Put(ctx context.Context, c chunks.Chunk) error
+		Commit(ctx context.Context, current, last hash.Hash) (bool, error)
+		Close() error
+	}
+
+	run := func(b *testing.B, open func(st *NomsBlockStore) committer) {
+		testDir := makeBatchCommitTestDir(b)
+		defer os.RemoveAll(testDir)
+
+		opts := DefaultLocalStoreOptions
+		opts.DurabilityPolicy = DurabilityPolicy{}
+		st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+		require.NoError(b, err)
+		cs := open(st)
+
+		b.ResetTimer()
+		var last hash.Hash
+		for i := 0; i < b.N; i++ {
+			c := chunks.NewChunk([]byte(strconv.Itoa(i)))
+			require.NoError(b, cs.Put(ctx, c))
+			ok, err := cs.Commit(ctx, c.Hash(), last)
+			require.NoError(b, err)
+			require.True(b, ok)
+			last = c.Hash()
+		}
+
+		require.NoError(b, cs.Close())
+	}
+
+	b.Run("per commit", func(b *testing.B) {
+		run(b, func(st *NomsBlockStore) committer {
+			return st
+		})
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		run(b, func(st *NomsBlockStore) committer {
+			return NewBatchCommitStore(st, BatchCommitPolicy{Window: 100 * time.Millisecond, MaxCommits: 100})
+		})
+	})
+}
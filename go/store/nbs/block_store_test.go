@@ -26,11 +26,14 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -38,7 +41,6 @@ import (
 	"github.com/liquidata-inc/dolt/go/libraries/utils/osutil"
 	"github.com/liquidata-inc/dolt/go/store/chunks"
 	"github.com/liquidata-inc/dolt/go/store/constants"
-	"github.com/liquidata-inc/dolt/go/store/d"
 	"github.com/liquidata-inc/dolt/go/store/hash"
 )
 
@@ -128,6 +130,49 @@ func (suite *BlockStoreSuite) TestChunkStorePut() {
 	}
 }
 
+func (suite *BlockStoreSuite) TestChunkStoreDedupHits() {
+	ctx := context.Background()
+	input := []byte("abc")
+	c := chunks.NewChunk(input)
+	suite.store.DetectDuplicatePuts(true)
+
+	err := suite.store.Put(ctx, c)
+	suite.NoError(err)
+	suite.Equal(uint64(0), suite.store.DedupHits())
+
+	// a duplicate of a pending chunk
+	err = suite.store.Put(ctx, chunks.NewChunk(input))
+	suite.NoError(err)
+	suite.Equal(uint64(1), suite.store.DedupHits())
+
+	success, err := suite.store.Commit(ctx, c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	// a duplicate of a committed chunk
+	err = suite.store.Put(ctx, chunks.NewChunk(input))
+	suite.NoError(err)
+	suite.Equal(uint64(2), suite.store.DedupHits())
+	assertInputInStore(input, c.Hash(), suite.store, suite.Assert())
+
+	if suite.putCountFn != nil {
+		suite.Equal(1, suite.putCountFn())
+	}
+
+	// without detection a duplicate is another put
+	suite.store.DetectDuplicatePuts(false)
+	err = suite.store.Put(ctx, chunks.NewChunk(input))
+	suite.NoError(err)
+	suite.Equal(uint64(2), suite.store.DedupHits())
+
+	if suite.putCountFn != nil {
+		suite.Equal(2, suite.putCountFn())
+	}
+
+	_, err = suite.store.Commit(ctx, c.Hash(), c.Hash())
+	suite.NoError(err)
+}
+
 func (suite *BlockStoreSuite) TestChunkStorePutMany() {
 	input1, input2 := []byte("abc"), []byte("def")
 	c1, c2 := chunks.NewChunk(input1), chunks.NewChunk(input2)
@@ -169,99 +214,1105 @@ func (suite *BlockStoreSuite) TestChunkStoreStatsSummary() {
 	suite.NotEqual("Unsupported", summary)
 }
 
-func (suite *BlockStoreSuite) TestChunkStorePutMoreThanMemTable() {
-	input1, input2 := make([]byte, testMemTableSize/2+1), make([]byte, testMemTableSize/2+1)
-	_, err := rand.Read(input1)
+func (suite *BlockStoreSuite) TestChunkStorePutMoreThanMemTable() {
+	input1, input2 := make([]byte, testMemTableSize/2+1), make([]byte, testMemTableSize/2+1)
+	_, err := rand.Read(input1)
+	suite.NoError(err)
+	_, err = rand.Read(input2)
+	suite.NoError(err)
+	c1, c2 := chunks.NewChunk(input1), chunks.NewChunk(input2)
+	err = suite.store.Put(context.Background(), c1)
+	suite.NoError(err)
+	err = suite.store.Put(context.Background(), c2)
+	suite.NoError(err)
+
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	success, err := suite.store.Commit(context.Background(), c1.Hash(), rt) // Commit writes
+	suite.NoError(err)
+	suite.True(success)
+
+	// And reading it via the API should work...
+	assertInputInStore(input1, c1.Hash(), suite.store, suite.Assert())
+	assertInputInStore(input2, c2.Hash(), suite.store, suite.Assert())
+	if suite.putCountFn != nil {
+		suite.Equal(2, suite.putCountFn())
+	}
+	specs, err := suite.store.tables.ToSpecs()
+	suite.NoError(err)
+	suite.Len(specs, 2)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetMany() {
+	inputs := [][]byte{make([]byte, testMemTableSize/2+1), make([]byte, testMemTableSize/2+1), []byte("abc")}
+	_, err := rand.Read(inputs[0])
+	suite.NoError(err)
+	_, err = rand.Read(inputs[1])
+	suite.NoError(err)
+	chnx := make([]chunks.Chunk, len(inputs))
+	for i, data := range inputs {
+		chnx[i] = chunks.NewChunk(data)
+		err = suite.store.Put(context.Background(), chnx[i])
+		suite.NoError(err)
+	}
+
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	_, err = suite.store.Commit(context.Background(), chnx[0].Hash(), rt) // Commit writes
+	suite.NoError(err)
+
+	hashes := make(hash.HashSlice, len(chnx))
+	for i, c := range chnx {
+		hashes[i] = c.Hash()
+	}
+
+	chunkChan := make(chan *chunks.Chunk, len(hashes))
+	err = suite.store.GetMany(context.Background(), hashes.HashSet(), chunkChan)
+	suite.NoError(err)
+	close(chunkChan)
+
+	found := make(hash.HashSlice, 0)
+	for c := range chunkChan {
+		found = append(found, c.Hash())
+	}
+
+	sort.Sort(found)
+	sort.Sort(hashes)
+	suite.True(found.Equals(hashes))
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetManyCounted() {
+	hashes := suite.putAndCommitRandomChunks(16, testMemTableSize/4)
+
+	chunkChan := make(chan *chunks.Chunk, len(hashes))
+	found, err := suite.store.GetManyCounted(context.Background(), hashes.HashSet(), chunkChan)
+	suite.NoError(err)
+	suite.Equal(uint32(len(hashes)), found)
+	suite.Len(chunkChan, len(hashes))
+
+	missing := hashes.HashSet()
+	missing.Insert(hash.Of([]byte("missing")))
+	chunkChan = make(chan *chunks.Chunk, len(missing))
+	found, err = suite.store.GetManyCounted(context.Background(), missing, chunkChan)
+	suite.NoError(err)
+	suite.Equal(uint32(len(hashes)), found)
+
+	// the consumer stops after receiving 3 chunks
+	ctx, cancel := context.WithCancel(context.Background())
+	chunkChan = make(chan *chunks.Chunk)
+	go func() {
+		for i := 0; i < 3; i++ {
+			<-chunkChan
+		}
+		cancel()
+	}()
+
+	found, err = suite.store.GetManyCounted(ctx, hashes.HashSet(), chunkChan)
+	suite.Equal(context.Canceled, err)
+	suite.Equal(uint32(3), found)
+}
+
+func (suite *BlockStoreSuite) putAndCommitRandomChunks(n, size int) hash.HashSlice {
+	hashes := make(hash.HashSlice, n)
+	for i := range hashes {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		suite.NoError(err)
+		c := chunks.NewChunk(data)
+		err = suite.store.Put(context.Background(), c)
+		suite.NoError(err)
+		hashes[i] = c.Hash()
+	}
+
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	_, err = suite.store.Commit(context.Background(), hashes[0], rt)
+	suite.NoError(err)
+
+	return hashes
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetManySlowConsumer() {
+	hashes := suite.putAndCommitRandomChunks(64, testMemTableSize/4)
+
+	chunkChan := make(chan *chunks.Chunk)
+	found := make(hash.HashSlice, 0, len(hashes))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for c := range chunkChan {
+			time.Sleep(time.Millisecond)
+			found = append(found, c.Hash())
+		}
+	}()
+
+	err := suite.store.GetMany(context.Background(), hashes.HashSet(), chunkChan)
+	suite.NoError(err)
+	close(chunkChan)
+	<-done
+
+	sort.Sort(found)
+	sort.Sort(hashes)
+	suite.True(found.Equals(hashes))
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetManyCancelledWhileBlocked() {
+	hashes := suite.putAndCommitRandomChunks(64, testMemTableSize/4)
+
+	// verifying chunks and reporting missing ones both relay chunks through a goroutine of their own
+	tests := []struct {
+		name          string
+		verify        bool
+		missingChunks MissingChunkPolicy
+	}{
+		{"default", false, ReturnEmptyForMissingChunks},
+		{"verify on read", true, ReturnEmptyForMissingChunks},
+		{"error for missing chunks", false, ReturnErrorForMissingChunks},
+		{"both", true, ReturnErrorForMissingChunks},
+	}
+
+	for _, test := range tests {
+		suite.Run(test.name, func() {
+			suite.store.VerifyOnRead(test.verify)
+			suite.store.missingChunks = test.missingChunks
+			defer func() {
+				suite.store.VerifyOnRead(false)
+				suite.store.missingChunks = DefaultMissingChunkPolicy
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			chunkChan := make(chan *chunks.Chunk)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- suite.store.GetMany(ctx, hashes.HashSet(), chunkChan)
+			}()
+
+			// Receive a single chunk, then stall the consumer and cancel.
+			<-chunkChan
+			cancel()
+
+			select {
+			case err := <-errCh:
+				suite.Equal(context.Canceled, err)
+			case <-time.After(10 * time.Second):
+				suite.Fail("GetMany did not return after its context was cancelled")
+			}
+		})
+	}
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreIterateChunksWithPrefix() {
+	committed := suite.putAndCommitRandomChunks(32, testMemTableSize/4)
+	pending := chunks.NewChunk([]byte("pending"))
+	err := suite.store.Put(context.Background(), pending)
+	suite.NoError(err)
+	all := append(committed, pending.Hash())
+
+	collect := func(prefix []byte) hash.HashSlice {
+		found := hash.HashSlice{}
+		err := suite.store.IterateChunksWithPrefix(context.Background(), prefix, func(h hash.Hash) error {
+			found = append(found, h)
+			return nil
+		})
+		suite.NoError(err)
+		sort.Sort(found)
+		return found
+	}
+	matching := func(prefix []byte) hash.HashSlice {
+		expected := hash.HashSlice{}
+		for _, h := range all {
+			if bytes.HasPrefix(h[:], prefix) {
+				expected = append(expected, h)
+			}
+		}
+		sort.Sort(expected)
+		return expected
+	}
+
+	suite.Len(collect(nil), len(all))
+
+	for _, h := range all[:4] {
+		for _, n := range []int{1, 8, 12, hash.ByteLen} {
+			prefix := h[:n]
+			suite.Equal(matching(prefix), collect(prefix))
+		}
+	}
+
+	suite.Empty(collect(make([]byte, hash.ByteLen+1)))
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = suite.store.IterateChunksWithPrefix(context.Background(), nil, func(h hash.Hash) error {
+		calls++
+		return errStop
+	})
+	suite.Equal(errStop, err)
+	suite.Equal(1, calls)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreIterateChunksPage() {
+	ctx := context.Background()
+	expected := suite.putAndCommitRandomChunks(10, 64)
+	expected = append(expected, suite.putAndCommitRandomChunks(10, 64)...)
+
+	pendingChunk := chunks.NewChunk([]byte("pending"))
+	suite.NoError(suite.store.Put(ctx, pendingChunk))
+	expected = append(expected, pendingChunk.Hash())
+	sort.Sort(expected)
+
+	_, _, err := suite.store.IterateChunksPage(ctx, Cursor{}, 0)
+	suite.Equal(ErrInvalidPageLimit, err)
+
+	var paged hash.HashSlice
+	var cursor Cursor
+	for pages := 0; !cursor.Done; pages++ {
+		suite.True(pages < 4, "too many pages")
+
+		var page []chunks.Chunk
+		page, cursor, err = suite.store.IterateChunksPage(ctx, cursor, 7)
+		suite.NoError(err)
+		suite.True(len(page) <= 7)
+
+		for _, c := range page {
+			paged = append(paged, c.Hash())
+		}
+	}
+
+	suite.Equal(expected, paged)
+
+	// a chunk added mid-iteration is only returned if its address is after the cursor
+	page, cursor, err := suite.store.IterateChunksPage(ctx, Cursor{}, 10)
+	suite.NoError(err)
+	suite.Len(page, 10)
+	suite.False(cursor.Done)
+
+	var added chunks.Chunk
+	for i := 0; ; i++ {
+		added = chunks.NewChunk([]byte{byte(i), byte(i >> 8)})
+		if h := added.Hash(); bytes.Compare(h[:], cursor.Last[:]) < 0 {
+			break
+		}
+	}
+	suite.NoError(suite.store.Put(ctx, added))
+
+	var rest []chunks.Chunk
+	for !cursor.Done {
+		page, cursor, err = suite.store.IterateChunksPage(ctx, cursor, 10)
+		suite.NoError(err)
+		rest = append(rest, page...)
+	}
+
+	suite.Len(rest, len(expected)-10)
+	for _, c := range rest {
+		suite.NotEqual(added.Hash(), c.Hash())
+	}
+
+	rt, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	_, err = suite.store.Commit(ctx, added.Hash(), rt)
+	suite.NoError(err)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreSeekChunk() {
+	ctx := context.Background()
+
+	_, ok, err := suite.store.SeekChunk(ctx, hash.Hash{})
+	suite.NoError(err)
+	suite.False(ok)
+
+	expected := suite.putAndCommitRandomChunks(10, 64)
+	expected = append(expected, suite.putAndCommitRandomChunks(10, 64)...)
+
+	pendingChunk := chunks.NewChunk([]byte("pending"))
+	suite.NoError(suite.store.Put(ctx, pendingChunk))
+	expected = append(expected, pendingChunk.Hash())
+	sort.Sort(expected)
+
+	// an address before every chunk seeks to the first
+	h, ok, err := suite.store.SeekChunk(ctx, hash.Hash{})
+	suite.NoError(err)
+	suite.True(ok)
+	suite.Equal(expected[0], h)
+
+	for i, e := range expected {
+		h, ok, err = suite.store.SeekChunk(ctx, e)
+		suite.NoError(err)
+		suite.True(ok)
+		suite.Equal(e, h)
+
+		after, _ := nextAddr(addr(e))
+		h, ok, err = suite.store.SeekChunk(ctx, hash.Hash(after))
+		suite.NoError(err)
+		if i == len(expected)-1 {
+			suite.False(ok)
+		} else {
+			suite.True(ok)
+			suite.Equal(expected[i+1], h)
+		}
+	}
+
+	var last hash.Hash
+	for i := range last {
+		last[i] = 0xff
+	}
+	_, ok, err = suite.store.SeekChunk(ctx, last)
+	suite.NoError(err)
+	suite.False(ok)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreCloseWithPendingWrites() {
+	c := chunks.NewChunk([]byte("abc"))
+	err := suite.store.Put(context.Background(), c)
+	suite.NoError(err)
+
+	err = suite.store.Close()
+	suite.Equal(ErrUncommittedChunks, err)
+
+	suite.putAndCommitRandomChunks(4, testMemTableSize/4)
+	err = suite.store.Close()
+	suite.NoError(err)
+
+	// enough data to force the memtable to be persisted as a novel table
+
+	for i := 0; i < 3; i++ {
+		data := make([]byte, testMemTableSize/2+1)
+		data[0] = byte(i)
+		err = suite.store.Put(context.Background(), chunks.NewChunk(data))
+		suite.NoError(err)
+	}
+	suite.True(suite.store.tables.Novel() > 0)
+	err = suite.store.Close()
+	suite.Equal(ErrUncommittedChunks, err)
+
+	err = suite.store.CloseDiscardingPending()
+	suite.NoError(err)
+	err = suite.store.Close()
+	suite.NoError(err)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreManifestLock() {
+	lock, err := suite.store.ManifestLock()
+	suite.NoError(err)
+	suite.Equal(hash.Hash{}, lock)
+
+	c := chunks.NewChunk([]byte("abc"))
+	err = suite.store.Put(context.Background(), c)
+	suite.NoError(err)
+	success, err := suite.store.Commit(context.Background(), c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	lock2, err := suite.store.ManifestLock()
+	suite.NoError(err)
+	suite.NotEqual(lock, lock2)
+
+	// A commit from another store moves the lock, which is observed after Rebase.
+	other, err := NewLocalStore(context.Background(), constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	d := chunks.NewChunk([]byte("def"))
+	err = other.Put(context.Background(), d)
+	suite.NoError(err)
+	success, err = other.Commit(context.Background(), d.Hash(), c.Hash())
+	suite.NoError(err)
+	suite.True(success)
+	otherLock, err := other.ManifestLock()
+	suite.NoError(err)
+
+	lock3, err := suite.store.ManifestLock()
+	suite.NoError(err)
+	suite.Equal(lock2, lock3)
+
+	err = suite.store.Rebase(context.Background())
+	suite.NoError(err)
+	lock3, err = suite.store.ManifestLock()
+	suite.NoError(err)
+	suite.Equal(otherLock, lock3)
+	suite.NotEqual(lock2, lock3)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreExtractChunks() {
+	committed := suite.putAndCommitRandomChunks(16, testMemTableSize/4)
+	pending := chunks.NewChunk([]byte("pending"))
+	err := suite.store.Put(context.Background(), pending)
+	suite.NoError(err)
+
+	chunkChan := make(chan *chunks.Chunk, len(committed)+1)
+	err = suite.store.ExtractChunks(context.Background(), chunkChan)
+	suite.NoError(err)
+	close(chunkChan)
+
+	var extracted hash.HashSlice
+	for c := range chunkChan {
+		extracted = append(extracted, c.Hash())
+	}
+	suite.Equal(append(committed, pending.Hash()), extracted)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreExtractChunksRange() {
+	committed := suite.putAndCommitRandomChunks(16, testMemTableSize/4)
+	pending := chunks.NewChunk([]byte("pending"))
+	err := suite.store.Put(context.Background(), pending)
+	suite.NoError(err)
+	all := hash.NewHashSet(append(committed, pending.Hash())...)
+
+	var mid hash.Hash
+	mid[0] = 0x80
+
+	extract := func(start, end hash.Hash) hash.HashSet {
+		chunkChan := make(chan *chunks.Chunk, len(all))
+		err := suite.store.ExtractChunksRange(context.Background(), start, end, chunkChan)
+		suite.NoError(err)
+		close(chunkChan)
+
+		extracted := make(hash.HashSet)
+		for c := range chunkChan {
+			suite.False(extracted.Has(c.Hash()))
+			extracted.Insert(c.Hash())
+		}
+		return extracted
+	}
+
+	low, high := extract(hash.Hash{}, mid), extract(mid, hash.Hash{})
+	for h := range low {
+		suite.True(h.Less(mid))
+	}
+
+	union := make(hash.HashSet)
+	for h := range high {
+		suite.False(h.Less(mid))
+		suite.False(low.Has(h))
+		union.Insert(h)
+	}
+	for h := range low {
+		union.Insert(h)
+	}
+	suite.Equal(all, union)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreExtractChunksCancelled() {
+	suite.putAndCommitRandomChunks(16, testMemTableSize/4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chunkChan := make(chan *chunks.Chunk)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- suite.store.ExtractChunks(ctx, chunkChan)
+	}()
+
+	<-chunkChan
+	cancel()
+
+	select {
+	case err := <-errCh:
+		suite.Equal(context.Canceled, err)
+	case <-time.After(10 * time.Second):
+		suite.Fail("ExtractChunks did not return after its context was cancelled")
+	}
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreHasMany() {
+	chnx := []chunks.Chunk{
+		chunks.NewChunk([]byte("abc")),
+		chunks.NewChunk([]byte("def")),
+	}
+	for _, c := range chnx {
+		err := suite.store.Put(context.Background(), c)
+		suite.NoError(err)
+	}
+
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	success, err := suite.store.Commit(context.Background(), chnx[0].Hash(), rt) // Commit writes
+	suite.NoError(err)
+	suite.True(success)
+	notPresent := chunks.NewChunk([]byte("ghi")).Hash()
+
+	hashes := hash.NewHashSet(chnx[0].Hash(), chnx[1].Hash(), notPresent)
+	absent, err := suite.store.HasMany(context.Background(), hashes)
+	suite.NoError(err)
+
+	suite.Len(absent, 1)
+	for _, c := range chnx {
+		suite.False(absent.Has(c.Hash()), "%s present in %v", c.Hash(), absent)
+	}
+	suite.True(absent.Has(notPresent))
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreCountPresent() {
+	committed := chunks.NewChunk([]byte("abc"))
+	pending := chunks.NewChunk([]byte("def"))
+	notPresent := chunks.NewChunk([]byte("ghi")).Hash()
+
+	err := suite.store.Put(context.Background(), committed)
+	suite.NoError(err)
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	success, err := suite.store.Commit(context.Background(), committed.Hash(), rt)
+	suite.NoError(err)
+	suite.True(success)
+	err = suite.store.Put(context.Background(), pending)
+	suite.NoError(err)
+
+	hashes := hash.NewHashSet(committed.Hash(), pending.Hash(), notPresent)
+	absent, err := suite.store.HasMany(context.Background(), hashes)
+	suite.NoError(err)
+	count, err := suite.store.CountPresent(context.Background(), hashes)
+	suite.NoError(err)
+	suite.Equal(uint32(len(hashes)-len(absent)), count)
+	suite.Equal(uint32(2), count)
+
+	count, err = suite.store.CountPresent(context.Background(), hash.HashSet{})
+	suite.NoError(err)
+	suite.Zero(count)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreLocateChunk() {
+	ctx := context.Background()
+	input := []byte("abc")
+	c := chunks.NewChunk(input)
+
+	locations, err := suite.store.LocateChunk(ctx, c.Hash())
+	suite.NoError(err)
+	suite.NotNil(locations)
+	suite.Empty(locations)
+
+	err = suite.store.Put(ctx, c)
+	suite.NoError(err)
+	locations, err = suite.store.LocateChunk(ctx, c.Hash())
+	suite.NoError(err)
+	suite.Equal([]string{memTableLocation}, locations)
+
+	rt, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	success, err := suite.store.Commit(ctx, c.Hash(), rt)
+	suite.NoError(err)
+	suite.True(success)
+
+	locations, err = suite.store.LocateChunk(ctx, c.Hash())
+	suite.NoError(err)
+	suite.Require().Len(locations, 1)
+	_, err = os.Stat(filepath.Join(suite.dir, locations[0]))
+	suite.NoError(err)
+
+	// a second table holding the same chunk
+	data, name, err := buildTable([][]byte{input, []byte("def")})
+	suite.NoError(err)
+	err = suite.store.WriteTableFile(ctx, name.String(), 2, bytes.NewReader(data), 0, nil)
+	suite.NoError(err)
+
+	locations, err = suite.store.LocateChunk(ctx, c.Hash())
+	suite.NoError(err)
+	suite.Len(locations, 2)
+	suite.Contains(locations, name.String())
+}
+
+func (suite *BlockStoreSuite) TestChunkStorePartition() {
+	committed := chunks.NewChunk([]byte("abc"))
+	pending := chunks.NewChunk([]byte("def"))
+	notPresent := chunks.NewChunk([]byte("ghi")).Hash()
+
+	err := suite.store.Put(context.Background(), committed)
+	suite.NoError(err)
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	success, err := suite.store.Commit(context.Background(), committed.Hash(), rt)
+	suite.NoError(err)
+	suite.True(success)
+	err = suite.store.Put(context.Background(), pending)
+	suite.NoError(err)
+
+	present, absent, err := suite.store.Partition(context.Background(), hash.NewHashSet(committed.Hash(), pending.Hash(), notPresent))
+	suite.NoError(err)
+	suite.Equal(hash.NewHashSet(committed.Hash(), pending.Hash()), present)
+	suite.Equal(hash.NewHashSet(notPresent), absent)
+
+	present, absent, err = suite.store.Partition(context.Background(), hash.HashSet{})
+	suite.NoError(err)
+	suite.Empty(present)
+	suite.Empty(absent)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreCommitWithMeta() {
+	c := chunks.NewChunk([]byte("abc"))
+	err := suite.store.Put(context.Background(), c)
+	suite.NoError(err)
+
+	rt, err := suite.store.Root(context.Background())
+	suite.NoError(err)
+	meta := map[string]string{"author": "bill", "message": "first"}
+	success, err := suite.store.CommitWithMeta(context.Background(), c.Hash(), rt, meta)
+	suite.NoError(err)
+	suite.True(success)
+
+	actual, ok, err := suite.store.RootMeta(context.Background(), c.Hash())
+	suite.NoError(err)
+	suite.True(ok)
+	suite.Equal(meta, actual)
+
+	_, ok, err = suite.store.RootMeta(context.Background(), rt)
+	suite.NoError(err)
+	suite.False(ok)
+
+	// The metadata is persisted along with the root.
+	other, err := NewLocalStore(context.Background(), constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	actual, ok, err = other.RootMeta(context.Background(), c.Hash())
+	suite.NoError(err)
+	suite.True(ok)
+	suite.Equal(meta, actual)
+
+	// Committing without metadata attaches none to the new root, but keeps the metadata of the earlier one.
+	c2 := chunks.NewChunk([]byte("def"))
+	err = suite.store.Put(context.Background(), c2)
+	suite.NoError(err)
+	success, err = suite.store.Commit(context.Background(), c2.Hash(), c.Hash())
+	suite.NoError(err)
+	suite.True(success)
+	_, ok, err = suite.store.RootMeta(context.Background(), c2.Hash())
+	suite.NoError(err)
+	suite.False(ok)
+	actual, ok, err = suite.store.RootMeta(context.Background(), c.Hash())
+	suite.NoError(err)
+	suite.True(ok)
+	suite.Equal(meta, actual)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreRootMetaOfEarlierRoots() {
+	ctx := context.Background()
+	root, err := suite.store.Root(ctx)
+	suite.NoError(err)
+
+	var roots []hash.Hash
+	var metas []map[string]string
+	for i, data := range []string{"abc", "def", "ghi"} {
+		c := chunks.NewChunk([]byte(data))
+		err = suite.store.Put(ctx, c)
+		suite.NoError(err)
+
+		meta := map[string]string{"message": data, "index": strconv.Itoa(i)}
+		success, err := suite.store.CommitWithMeta(ctx, c.Hash(), root, meta)
+		suite.NoError(err)
+		suite.True(success)
+
+		roots, metas, root = append(roots, c.Hash()), append(metas, meta), c.Hash()
+	}
+
+	// Committing an existing root again, as for a reset, keeps the metadata of every earlier root.
+	success, err := suite.store.ForceSetRoot(ctx, roots[1], root)
+	suite.NoError(err)
+	suite.True(success)
+
+	other, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	defer other.Close()
+
+	for _, st := range []*NomsBlockStore{suite.store, other} {
+		for i, r := range roots {
+			actual, ok, err := st.RootMeta(ctx, r)
+			suite.NoError(err)
+			suite.True(ok)
+			suite.Equal(metas[i], actual)
+		}
+
+		_, ok, err := st.RootMeta(ctx, hash.Of([]byte("unknown root")))
+		suite.NoError(err)
+		suite.False(ok)
+	}
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreFlush() {
+	ctx := context.Background()
+	input := []byte("abc")
+	c := chunks.NewChunk(input)
+	err := suite.store.Put(ctx, c)
+	suite.NoError(err)
+
+	root, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	err = suite.store.Flush(ctx)
+	suite.NoError(err)
+
+	// The root is unchanged, but the chunk is in a table file recorded in the manifest.
+	flushedRoot, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(root, flushedRoot)
+	assertInputInStore(input, c.Hash(), suite.store, suite.Assert())
+
+	other, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	assertInputInStore(input, c.Hash(), other, suite.Assert())
+	otherRoot, err := other.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(root, otherRoot)
+	suite.NoError(other.Close())
+
+	// Committing doesn't write the flushed chunk again.
+	_, sources, err := suite.store.Sources(ctx)
+	suite.NoError(err)
+	success, err := suite.store.Commit(ctx, c.Hash(), root)
+	suite.NoError(err)
+	suite.True(success)
+	_, committedSources, err := suite.store.Sources(ctx)
+	suite.NoError(err)
+	suite.Len(committedSources, len(sources))
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreDiskUsage() {
+	ctx := context.Background()
+
+	// each commit writes a table file
+	var root hash.Hash
+	for _, data := range []string{"abc", "defghi", "jklmnopqr"} {
+		c := chunks.NewChunk([]byte(data))
+		suite.NoError(suite.store.Put(ctx, c))
+		success, err := suite.store.Commit(ctx, c.Hash(), root)
+		suite.NoError(err)
+		suite.True(success)
+		root = c.Hash()
+	}
+
+	pending := chunks.NewChunk([]byte("pending"))
+	suite.NoError(suite.store.Put(ctx, pending))
+
+	usage, err := suite.store.DiskUsage(ctx)
+	suite.NoError(err)
+	suite.Equal(uint64(len(pending.Data())), usage[MemTableDiskUsageName])
+
+	_, tableFiles, err := suite.store.Sources(ctx)
+	suite.NoError(err)
+	suite.Len(tableFiles, 3)
+	suite.Len(usage, len(tableFiles)+1)
+
+	var total uint64
+	for _, tf := range tableFiles {
+		info, err := os.Stat(filepath.Join(suite.dir, tf.FileID()))
+		suite.NoError(err)
+		suite.Equal(uint64(info.Size()), usage[tf.FileID()])
+		total += usage[tf.FileID()]
+	}
+
+	physLen, err := suite.store.tables.physicalLen()
+	suite.NoError(err)
+	suite.Equal(physLen+3*footerSize, total)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreStorageSize() {
+	ctx := context.Background()
+
+	var root hash.Hash
+	var logical uint64
+	for i := 0; i < 3; i++ {
+		c := chunks.NewChunk(bytes.Repeat([]byte{byte(i)}, testMemTableSize/2))
+		suite.NoError(suite.store.Put(ctx, c))
+		success, err := suite.store.Commit(ctx, c.Hash(), root)
+		suite.NoError(err)
+		suite.True(success)
+		root = c.Hash()
+		logical += uint64(len(c.Data()))
+	}
+
+	pending := chunks.NewChunk(bytes.Repeat([]byte("pending"), 4))
+	suite.NoError(suite.store.Put(ctx, pending))
+
+	committedLogical, committedPhysical, err := suite.store.StorageSize(ctx, false)
+	suite.NoError(err)
+	suite.Equal(logical, committedLogical)
+	suite.True(committedPhysical < committedLogical, "physical %d, logical %d", committedPhysical, committedLogical)
+
+	usage, err := suite.store.DiskUsage(ctx)
+	suite.NoError(err)
+	var diskUsage uint64
+	for name, size := range usage {
+		if name != MemTableDiskUsageName {
+			diskUsage += size
+		}
+	}
+	suite.Equal(diskUsage, committedPhysical)
+
+	pendingLogical, pendingPhysical, err := suite.store.StorageSize(ctx, true)
+	suite.NoError(err)
+	suite.Equal(logical+uint64(len(pending.Data())), pendingLogical)
+	suite.Equal(committedPhysical, pendingPhysical)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreTableCompressionStats() {
+	ctx := context.Background()
+
+	random := make([]byte, 200)
+	_, err := rand.Read(random)
+	suite.NoError(err)
+	compressible, incompressible := chunks.NewChunk(bytes.Repeat([]byte("compressible"), 16)), chunks.NewChunk(random)
+
+	// each commit writes a table file
+	commit := func(c chunks.Chunk, last hash.Hash) map[string]CompressionStat {
+		suite.NoError(suite.store.Put(ctx, c))
+		success, err := suite.store.Commit(ctx, c.Hash(), last)
+		suite.NoError(err)
+		suite.True(success)
+
+		stats, err := suite.store.TableCompressionStats()
+		suite.NoError(err)
+		return stats
+	}
+
+	stats := commit(compressible, hash.Hash{})
+	suite.Len(stats, 1)
+	var compressibleStat CompressionStat
+	for _, stat := range stats {
+		compressibleStat = stat
+	}
+
+	suite.Equal(uint32(1), compressibleStat.ChunkCount)
+	suite.Equal(uint64(len(compressible.Data())), compressibleStat.LogicalBytes)
+	suite.True(compressibleStat.Ratio() > 4, "ratio %f", compressibleStat.Ratio())
+
+	stats = commit(incompressible, compressible.Hash())
+	suite.Len(stats, 2)
+	var incompressibleStat CompressionStat
+	for _, stat := range stats {
+		if stat != compressibleStat {
+			incompressibleStat = stat
+		}
+	}
+
+	suite.Equal(uint32(1), incompressibleStat.ChunkCount)
+	suite.Equal(uint64(len(incompressible.Data())), incompressibleStat.LogicalBytes)
+	suite.True(incompressibleStat.Ratio() < 1, "ratio %f", incompressibleStat.Ratio())
+
+	suite.NoError(suite.store.Close())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreHealthCheck() {
+	ctx := context.Background()
+	suite.NoError(suite.store.HealthCheck(ctx))
+
+	var root hash.Hash
+	for _, data := range []string{"abc", "def"} {
+		c := chunks.NewChunk([]byte(data))
+		suite.NoError(suite.store.Put(ctx, c))
+		success, err := suite.store.Commit(ctx, c.Hash(), root)
+		suite.NoError(err)
+		suite.True(success)
+		root = c.Hash()
+	}
+
+	suite.NoError(suite.store.HealthCheck(ctx))
+
+	_, tableFiles, err := suite.store.Sources(ctx)
+	suite.NoError(err)
+	suite.Len(tableFiles, 2)
+	suite.NoError(os.Remove(filepath.Join(suite.dir, tableFiles[0].FileID())))
+
+	err = suite.store.HealthCheck(ctx)
+	suite.Error(err)
+	suite.True(errors.Is(err, ErrNotFound))
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreChunkFilter() {
+	ctx := context.Background()
+
+	filter, err := suite.store.BuildChunkFilter(ctx)
+	suite.NoError(err)
+	suite.False(FilterContains(filter, hash.Of([]byte("abc"))))
+
+	var stored []hash.Hash
+	var root, last hash.Hash
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 10; j++ {
+			c := chunks.NewChunk([]byte(fmt.Sprintf("chunk %d %d", i, j)))
+			suite.NoError(suite.store.Put(ctx, c))
+			stored = append(stored, c.Hash())
+			root = c.Hash()
+		}
+
+		// the last batch is left pending
+		if i < 2 {
+			success, err := suite.store.Commit(ctx, root, last)
+			suite.NoError(err)
+			suite.True(success)
+			last = root
+		}
+	}
+
+	filter, err = suite.store.BuildChunkFilter(ctx)
+	suite.NoError(err)
+	for _, h := range stored {
+		suite.True(FilterContains(filter, h), "false negative for %s", h)
+	}
+
+	strict, err := suite.store.BuildChunkFilterWithFalsePositiveRate(ctx, 0.0001)
+	suite.NoError(err)
+	suite.True(len(strict) > len(filter))
+	for _, h := range stored {
+		suite.True(FilterContains(strict, h), "false negative for %s", h)
+	}
+
+	_, err = suite.store.BuildChunkFilterWithFalsePositiveRate(ctx, 1)
+	suite.Equal(ErrInvalidFalsePositiveRate, err)
+
+	suite.NoError(suite.store.CloseDiscardingPending())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreRebaseTo() {
+	ctx := context.Background()
+	c1, c2 := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def"))
+
+	err := suite.store.Put(ctx, c1)
+	suite.NoError(err)
+	success, err := suite.store.Commit(ctx, c1.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	err = suite.store.Put(ctx, c2)
+	suite.NoError(err)
+	success, err = suite.store.Commit(ctx, c2.Hash(), c1.Hash())
+	suite.NoError(err)
+	suite.True(success)
+
+	err = suite.store.RebaseTo(ctx, c1.Hash())
+	suite.NoError(err)
+	root, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(c1.Hash(), root)
+	assertInputInStore([]byte("def"), c2.Hash(), suite.store, suite.Assert())
+
+	// The live root is unchanged.
+	other, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	root, err = other.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(c2.Hash(), root)
+	suite.NoError(other.Close())
+
+	err = suite.store.RebaseTo(ctx, chunks.NewChunk([]byte("ghi")).Hash())
+	suite.Equal(ErrUnknownRoot, err)
+
+	// Committing from the historical root fails and moves the store back to the live root.
+	c3 := chunks.NewChunk([]byte("jkl"))
+	err = suite.store.Put(ctx, c3)
+	suite.NoError(err)
+	success, err = suite.store.Commit(ctx, c3.Hash(), c1.Hash())
+	suite.NoError(err)
+	suite.False(success)
+	root, err = suite.store.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(c2.Hash(), root)
+
+	err = suite.store.RebaseTo(ctx, c2.Hash())
 	suite.NoError(err)
-	_, err = rand.Read(input2)
+	success, err = suite.store.Commit(ctx, c3.Hash(), c2.Hash())
 	suite.NoError(err)
-	c1, c2 := chunks.NewChunk(input1), chunks.NewChunk(input2)
-	err = suite.store.Put(context.Background(), c1)
+	suite.True(success)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreForceSetRoot() {
+	ctx := context.Background()
+	c1, c2 := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def"))
+
+	err := suite.store.Put(ctx, c1)
 	suite.NoError(err)
-	err = suite.store.Put(context.Background(), c2)
+	success, err := suite.store.Commit(ctx, c1.Hash(), hash.Hash{})
 	suite.NoError(err)
+	suite.True(success)
 
-	rt, err := suite.store.Root(context.Background())
+	err = suite.store.Put(ctx, c2)
 	suite.NoError(err)
-	success, err := suite.store.Commit(context.Background(), c1.Hash(), rt) // Commit writes
+	success, err = suite.store.Commit(ctx, c2.Hash(), c1.Hash())
 	suite.NoError(err)
 	suite.True(success)
 
-	// And reading it via the API should work...
-	assertInputInStore(input1, c1.Hash(), suite.store, suite.Assert())
-	assertInputInStore(input2, c2.Hash(), suite.store, suite.Assert())
-	if suite.putCountFn != nil {
-		suite.Equal(2, suite.putCountFn())
-	}
-	specs, err := suite.store.tables.ToSpecs()
+	// A stale |last| fails.
+	success, err = suite.store.ForceSetRoot(ctx, c1.Hash(), hash.Hash{})
 	suite.NoError(err)
-	suite.Len(specs, 2)
-}
+	suite.False(success)
 
-func (suite *BlockStoreSuite) TestChunkStoreGetMany() {
-	inputs := [][]byte{make([]byte, testMemTableSize/2+1), make([]byte, testMemTableSize/2+1), []byte("abc")}
-	_, err := rand.Read(inputs[0])
+	success, err = suite.store.ForceSetRoot(ctx, chunks.NewChunk([]byte("ghi")).Hash(), c2.Hash())
+	suite.Equal(ErrUnknownRoot, err)
+	suite.False(success)
+
+	success, err = suite.store.ForceSetRoot(ctx, c1.Hash(), c2.Hash())
 	suite.NoError(err)
-	_, err = rand.Read(inputs[1])
+	suite.True(success)
+	root, err := suite.store.Root(ctx)
 	suite.NoError(err)
-	chnx := make([]chunks.Chunk, len(inputs))
-	for i, data := range inputs {
-		chnx[i] = chunks.NewChunk(data)
-		err = suite.store.Put(context.Background(), chnx[i])
-		suite.NoError(err)
-	}
+	suite.Equal(c1.Hash(), root)
 
-	rt, err := suite.store.Root(context.Background())
+	other, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
 	suite.NoError(err)
-	_, err = suite.store.Commit(context.Background(), chnx[0].Hash(), rt) // Commit writes
+	root, err = other.Root(ctx)
 	suite.NoError(err)
+	suite.Equal(c1.Hash(), root)
+	assertInputInStore([]byte("abc"), c1.Hash(), other, suite.Assert())
+	assertInputInStore([]byte("def"), c2.Hash(), other, suite.Assert())
+	suite.NoError(other.Close())
+}
 
-	hashes := make(hash.HashSlice, len(chnx))
-	for i, c := range chnx {
-		hashes[i] = c.Hash()
-	}
+func (suite *BlockStoreSuite) TestChunkStorePinRoot() {
+	ctx := context.Background()
+	c1, c2, c3 := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def")), chunks.NewChunk([]byte("ghi"))
 
-	chunkChan := make(chan *chunks.Chunk, len(hashes))
-	err = suite.store.GetMany(context.Background(), hashes.HashSet(), chunkChan)
+	err := suite.store.Put(ctx, c1)
 	suite.NoError(err)
-	close(chunkChan)
+	success, err := suite.store.Commit(ctx, c1.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
 
-	found := make(hash.HashSlice, 0)
-	for c := range chunkChan {
-		found = append(found, c.Hash())
-	}
+	err = suite.store.Put(ctx, c2)
+	suite.NoError(err)
+	success, err = suite.store.Commit(ctx, c2.Hash(), c1.Hash())
+	suite.NoError(err)
+	suite.True(success)
 
-	sort.Sort(found)
-	sort.Sort(hashes)
-	suite.True(found.Equals(hashes))
-}
+	err = suite.store.PinRoot(ctx, c1.Hash())
+	suite.NoError(err)
+	suite.Equal(hash.HashSlice{c1.Hash()}, suite.store.PinnedRoots())
 
-func (suite *BlockStoreSuite) TestChunkStoreHasMany() {
-	chnx := []chunks.Chunk{
-		chunks.NewChunk([]byte("abc")),
-		chunks.NewChunk([]byte("def")),
-	}
-	for _, c := range chnx {
-		err := suite.store.Put(context.Background(), c)
-		suite.NoError(err)
-	}
+	err = suite.store.PinRoot(ctx, c3.Hash())
+	suite.Equal(ErrUnknownRoot, err)
 
-	rt, err := suite.store.Root(context.Background())
+	// Pins survive later commits.
+	err = suite.store.Put(ctx, c3)
 	suite.NoError(err)
-	success, err := suite.store.Commit(context.Background(), chnx[0].Hash(), rt) // Commit writes
+	success, err = suite.store.Commit(ctx, c3.Hash(), c2.Hash())
 	suite.NoError(err)
 	suite.True(success)
-	notPresent := chunks.NewChunk([]byte("ghi")).Hash()
 
-	hashes := hash.NewHashSet(chnx[0].Hash(), chnx[1].Hash(), notPresent)
-	absent, err := suite.store.HasMany(context.Background(), hashes)
+	other, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
 	suite.NoError(err)
+	root, err := other.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(c3.Hash(), root)
+	suite.Equal(hash.HashSlice{c1.Hash()}, other.PinnedRoots())
+	suite.NoError(other.Close())
 
-	suite.Len(absent, 1)
-	for _, c := range chnx {
-		suite.False(absent.Has(c.Hash()), "%s present in %v", c.Hash(), absent)
-	}
-	suite.True(absent.Has(notPresent))
+	err = suite.store.UnpinRoot(ctx, c1.Hash())
+	suite.NoError(err)
+	err = suite.store.UnpinRoot(ctx, c1.Hash())
+	suite.NoError(err)
+
+	other, err = NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	suite.Empty(other.PinnedRoots())
+	root, err = other.Root(ctx)
+	suite.NoError(err)
+	suite.Equal(c3.Hash(), root)
+	suite.NoError(other.Close())
 }
 
 func (suite *BlockStoreSuite) TestChunkStoreFlushOptimisticLockFail() {
@@ -312,6 +1363,61 @@ func (suite *BlockStoreSuite) TestChunkStoreFlushOptimisticLockFail() {
 	suite.True(success)
 }
 
+func (suite *BlockStoreSuite) TestChunkStoreCommitReturningLock() {
+	ctx := context.Background()
+	readLock := func() hash.Hash {
+		_, contents, err := fileManifest{dir: suite.dir}.ParseIfExists(ctx, &Stats{}, nil)
+		suite.NoError(err)
+		return hash.Hash(contents.lock)
+	}
+
+	// chain commits, passing each root as the next |last|
+	var last hash.Hash
+	var locks []hash.Hash
+	for _, data := range []string{"abc", "def", "ghi"} {
+		c := chunks.NewChunk([]byte(data))
+		suite.NoError(suite.store.Put(ctx, c))
+		success, lock, err := suite.store.CommitReturningLock(ctx, c.Hash(), last)
+		suite.NoError(err)
+		suite.True(success)
+		suite.Equal(readLock(), lock)
+
+		storeLock, err := suite.store.ManifestLock()
+		suite.NoError(err)
+		suite.Equal(storeLock, lock)
+
+		locks = append(locks, lock)
+		last = c.Hash()
+	}
+
+	suite.NotEqual(locks[0], locks[1])
+	suite.NotEqual(locks[1], locks[2])
+
+	// a commit which loses to another writer returns the winner's lock
+	interloper, err := NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+	defer interloper.Close()
+	c := chunks.NewChunk([]byte("jkl"))
+	suite.NoError(interloper.Put(ctx, c))
+	success, err := interloper.Commit(ctx, c.Hash(), last)
+	suite.NoError(err)
+	suite.True(success)
+
+	c = chunks.NewChunk([]byte("mno"))
+	suite.NoError(suite.store.Put(ctx, c))
+	success, lock, err := suite.store.CommitReturningLock(ctx, c.Hash(), last)
+	suite.NoError(err)
+	suite.False(success)
+	suite.Equal(readLock(), lock)
+
+	root, err := suite.store.Root(ctx)
+	suite.NoError(err)
+	success, lock, err = suite.store.CommitReturningLock(ctx, c.Hash(), root)
+	suite.NoError(err)
+	suite.True(success)
+	suite.Equal(readLock(), lock)
+}
+
 func (suite *BlockStoreSuite) TestChunkStoreRebaseOnNoOpFlush() {
 	input1 := []byte("abc")
 	c1 := chunks.NewChunk(input1)
@@ -524,6 +1630,54 @@ func TestBlockStoreConjoinOnCommit(t *testing.T) {
 		assert.True(t, ok)
 		assertContainAll(t, smallTableStore, srcs...)
 	})
+
+	t.Run("ConjoinObserver", func(t *testing.T) {
+		fm := &fakeManifest{}
+		p := newFakeTablePersister()
+
+		srcs := makeTestSrcs(t, []uint32{1, 1, 3, 7}, p)
+		upstream, err := toSpecs(srcs)
+		assert.NoError(t, err)
+		fm.set(constants.NomsVersion, computeAddr([]byte{0xbe}), hash.Of([]byte{0xef}), upstream)
+		canned := makeCanned(upstream[:2], upstream[2:], p)
+		c := &fakeConjoiner{[]cannedConjoin{canned}}
+
+		smallTableStore, err := newNomsBlockStore(context.Background(), constants.FormatDefaultString, makeManifestManager(fm), p, c, testMemTableSize)
+		assert.NoError(t, err)
+
+		var events []ConjoinEvent
+		smallTableStore.SetConjoinObserver(func(event ConjoinEvent) {
+			events = append(events, event)
+		})
+
+		root, err := smallTableStore.Root(context.Background())
+		assert.NoError(t, err)
+		err = smallTableStore.Put(context.Background(), newChunk)
+		assert.NoError(t, err)
+		success, err := smallTableStore.Commit(context.Background(), newChunk.Hash(), root)
+		assert.NoError(t, err)
+		assert.True(t, success)
+
+		// the canned conjoin, then the decision not to conjoin again on the retried update
+		if assert.Len(t, events, 2) {
+			assert.True(t, events[0].Required)
+			assert.NoError(t, events[0].Err)
+			assert.Equal(t, tableSpecInfos(upstream), events[0].Before)
+			assert.Equal(t, tableSpecInfos(canned.specs), events[0].After)
+
+			assert.False(t, events[1].Required)
+			assert.Equal(t, tableSpecInfos(canned.specs), events[1].Before)
+			assert.Nil(t, events[1].After)
+		}
+
+		smallTableStore.SetConjoinObserver(nil)
+		err = smallTableStore.Put(context.Background(), chunks.NewChunk([]byte("unobserved")))
+		assert.NoError(t, err)
+		success, err = smallTableStore.Commit(context.Background(), newChunk.Hash(), newChunk.Hash())
+		assert.NoError(t, err)
+		assert.True(t, success)
+		assert.Len(t, events, 2)
+	})
 }
 
 type cannedConjoin struct {
@@ -531,6 +1685,82 @@ type cannedConjoin struct {
 	specs  []tableSpec // Must name tables that are already persisted
 }
 
+// cancellingPersister cancels a commit's context once the commit's table has been persisted, simulating a
+// cancellation which arrives between writing table files and updating the manifest.
+type cancellingPersister struct {
+	tablePersister
+	cancel context.CancelFunc
+}
+
+func (cp cancellingPersister) Persist(ctx context.Context, mt *memTable, haver chunkReader, stats *Stats) (chunkSource, error) {
+	cs, err := cp.tablePersister.Persist(ctx, mt, haver, stats)
+	cp.cancel()
+	return cs, err
+}
+
+func TestBlockStoreCommitCancelledBeforeManifestUpdate(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cacheOnce.Do(makeGlobalCaches)
+	p := cancellingPersister{newFSTablePersister(dir, FlatTableLayout, globalFDCache, nil, false, false), cancel}
+	store, err := newNomsBlockStore(context.Background(), constants.FormatDefaultString, makeManifestManager(fileManifest{dir: dir}), p, newInlineConjoiner(DefaultConjoinPolicy), testMemTableSize)
+	assert.NoError(err)
+
+	c := chunks.NewChunk([]byte("abc"))
+	err = store.Put(context.Background(), c)
+	assert.NoError(err)
+
+	success, err := store.Commit(ctx, c.Hash(), hash.Hash{})
+	assert.Equal(context.Canceled, err)
+	assert.False(success)
+
+	// The root is unchanged, both in this store and on disk, and the chunk is still pending.
+	root, err := store.Root(context.Background())
+	assert.NoError(err)
+	assert.Equal(hash.Hash{}, root)
+	exists, _, err := fileManifest{dir: dir}.ParseIfExists(context.Background(), &Stats{}, nil)
+	assert.NoError(err)
+	assert.False(exists)
+	assert.Equal(ErrUncommittedChunks, store.Close())
+
+	found, err := store.Get(context.Background(), c.Hash())
+	assert.NoError(err)
+	assert.Equal(c.Data(), found.Data())
+
+	// A later commit picks up the already persisted table.
+	success, err = store.Commit(context.Background(), c.Hash(), hash.Hash{})
+	assert.NoError(err)
+	assert.True(success)
+	assert.NoError(store.Close())
+
+	reopened, err := NewLocalStore(context.Background(), constants.FormatDefaultString, dir, testMemTableSize)
+	assert.NoError(err)
+	found, err = reopened.Get(context.Background(), c.Hash())
+	assert.NoError(err)
+	assert.Equal(c.Data(), found.Data())
+}
+
+func TestBlockStoreCommitCancelled(t *testing.T) {
+	_, _, store := makeStoreWithFakes(t)
+	c := chunks.NewChunk([]byte("abc"))
+	err := store.Put(context.Background(), c)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	success, err := store.Commit(ctx, c.Hash(), hash.Hash{})
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, success)
+
+	has, err := store.Has(context.Background(), c.Hash())
+	assert.NoError(t, err)
+	assert.True(t, has)
+}
+
 type fakeConjoiner struct {
 	canned []cannedConjoin
 }
@@ -543,7 +1773,10 @@ func (fc *fakeConjoiner) ConjoinRequired(ts tableSet) bool {
 }
 
 func (fc *fakeConjoiner) Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
-	d.PanicIfTrue(len(fc.canned) == 0)
+	if len(fc.canned) == 0 {
+		return manifestContents{}, errors.New("no canned conjoin")
+	}
+
 	canned := fc.canned[0]
 	fc.canned = fc.canned[1:]
 
@@ -551,7 +1784,7 @@ func (fc *fakeConjoiner) Conjoin(ctx context.Context, upstream manifestContents,
 		vers:  constants.NomsVersion,
 		root:  upstream.root,
 		specs: canned.specs,
-		lock:  generateLockHash(upstream.root, canned.specs),
+		lock:  generateLockHash(upstream.root, canned.specs, nil),
 	}
 
 	var err error
@@ -562,7 +1795,7 @@ func (fc *fakeConjoiner) Conjoin(ctx context.Context, upstream manifestContents,
 	}
 
 	if upstream.lock != newContents.lock {
-		return manifestContents{}, errors.New("lock failed")
+		return manifestContents{}, newStoreError(ErrManifestConflict, "lock failed")
 	}
 
 	return upstream, err
@@ -581,3 +1814,236 @@ func (suite *BlockStoreSuite) TestChunkStoreGetNonExisting() {
 	suite.NoError(err)
 	suite.True(c.IsEmpty())
 }
+
+func (suite *BlockStoreSuite) TestChunkStoreMissingChunkPolicy() {
+	ctx := context.Background()
+	c := chunks.NewChunk([]byte("abc"))
+	err := suite.store.Put(ctx, c)
+	suite.NoError(err)
+	success, err := suite.store.Commit(ctx, c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	missing := hash.Parse("11111111111111111111111111111111")
+	hashes := hash.NewHashSet(c.Hash(), missing)
+
+	// the default policy returns the empty chunk
+	found := make(chan *chunks.Chunk, 2)
+	err = suite.store.GetMany(ctx, hashes, found)
+	suite.NoError(err)
+	suite.Len(found, 1)
+
+	opts := DefaultLocalStoreOptions
+	opts.MissingChunkPolicy = ReturnErrorForMissingChunks
+	store, err := NewLocalStoreWithOptions(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize, opts)
+	suite.NoError(err)
+	defer store.Close()
+
+	got, err := store.Get(ctx, c.Hash())
+	suite.NoError(err)
+	suite.Equal(c.Data(), got.Data())
+
+	got, err = store.Get(ctx, missing)
+	suite.True(errors.Is(err, ErrChunkNotFound))
+	suite.True(errors.Is(err, ErrNotFound))
+	suite.True(got.IsEmpty())
+
+	found = make(chan *chunks.Chunk, 2)
+	err = store.GetMany(ctx, hashes, found)
+	suite.True(errors.Is(err, ErrChunkNotFound))
+	suite.Len(found, 1)
+	suite.Equal(c.Hash(), (<-found).Hash())
+
+	found = make(chan *chunks.Chunk, 1)
+	err = store.GetMany(ctx, hash.NewHashSet(c.Hash()), found)
+	suite.NoError(err)
+	suite.Len(found, 1)
+
+	count, err := store.GetManyCounted(ctx, hashes, make(chan *chunks.Chunk, 2))
+	suite.True(errors.Is(err, ErrChunkNotFound))
+	suite.Equal(uint32(1), count)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetRaw() {
+	ctx := context.Background()
+	c := chunks.NewChunk([]byte("abc"))
+	err := suite.store.Put(ctx, c)
+	suite.NoError(err)
+
+	assertRoundTrips := func() {
+		cmp, err := suite.store.GetRaw(ctx, c.Hash())
+		suite.NoError(err)
+		suite.Equal(c.Hash(), cmp.Hash())
+		suite.Equal(ChunkToCompressedChunk(c).FullCompressedChunk, cmp.FullCompressedChunk)
+
+		decoded, err := cmp.ToChunk()
+		suite.NoError(err)
+		suite.Equal(c.Data(), decoded.Data())
+
+		cp := cmp.Copy()
+		cmp.FullCompressedChunk[0] ^= 0xff
+		decoded, err = cp.ToChunk()
+		suite.NoError(err)
+		suite.Equal(c.Data(), decoded.Data())
+	}
+
+	// from the memtable, then from a table file
+	assertRoundTrips()
+	success, err := suite.store.Commit(ctx, c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+	assertRoundTrips()
+
+	cmp, err := suite.store.GetRaw(ctx, hash.Parse("11111111111111111111111111111111"))
+	suite.NoError(err)
+	suite.True(cmp.IsEmpty())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreGetManyOrdered() {
+	ctx := context.Background()
+	a, b := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def"))
+	suite.NoError(suite.store.Put(ctx, a))
+	suite.NoError(suite.store.Put(ctx, b))
+	success, err := suite.store.Commit(ctx, b.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	missing := hash.Parse("11111111111111111111111111111111")
+	got, err := suite.store.GetManyOrdered(ctx, hash.HashSlice{b.Hash(), missing, a.Hash(), b.Hash()})
+	suite.NoError(err)
+	suite.Require().Len(got, 4)
+	suite.Equal(b.Hash(), got[0].Hash())
+	suite.Nil(got[1])
+	suite.Equal(a.Hash(), got[2].Hash())
+	suite.Equal(b.Hash(), got[3].Hash())
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreHasContent() {
+	ctx := context.Background()
+	data := []byte("abc")
+	c := chunks.NewChunk(data)
+
+	has, h, err := suite.store.HasContent(ctx, data)
+	suite.NoError(err)
+	suite.False(has)
+	suite.Equal(c.Hash(), h)
+
+	suite.NoError(suite.store.Put(ctx, c))
+	has, h, err = suite.store.HasContent(ctx, data)
+	suite.NoError(err)
+	suite.True(has)
+	suite.Equal(c.Hash(), h)
+
+	success, err := suite.store.Commit(ctx, c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+	has, _, err = suite.store.HasContent(ctx, data)
+	suite.NoError(err)
+	suite.True(has)
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreValidateParallel() {
+	ctx := context.Background()
+	inputs := [][]byte{make([]byte, testMemTableSize/2+1), make([]byte, testMemTableSize/2+1), []byte("abc")}
+	var chnx []chunks.Chunk
+	for _, data := range inputs {
+		_, err := rand.Read(data)
+		suite.NoError(err)
+		c := chunks.NewChunk(data)
+		suite.NoError(suite.store.Put(ctx, c))
+		chnx = append(chnx, c)
+	}
+
+	success, err := suite.store.Commit(ctx, chnx[0].Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+	specs, err := suite.store.tables.ToSpecs()
+	suite.NoError(err)
+	suite.Require().Len(specs, 2)
+
+	_, err = suite.store.ValidateParallel(ctx, 0)
+	suite.Equal(ErrInvalidWorkerCount, err)
+
+	corrupt, err := suite.store.ValidateParallel(ctx, 2)
+	suite.NoError(err)
+	suite.Empty(corrupt)
+
+	// the first chunk's data starts each table file, so this corrupts whichever chunk comes first
+	locations, err := suite.store.LocateChunk(ctx, chnx[0].Hash())
+	suite.NoError(err)
+	suite.Require().Len(locations, 1)
+	suite.NoError(suite.store.Close())
+
+	path := filepath.Join(suite.dir, locations[0])
+	data, err := ioutil.ReadFile(path)
+	suite.NoError(err)
+	data[0] ^= 0xff
+	suite.NoError(ioutil.WriteFile(path, data, 0644))
+
+	suite.store, err = NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+
+	for _, workers := range []int{1, 4} {
+		corrupt, err = suite.store.ValidateParallel(ctx, workers)
+		suite.NoError(err)
+		suite.Require().Len(corrupt, 1)
+		suite.Contains([]hash.Hash{chnx[0].Hash(), chnx[1].Hash(), chnx[2].Hash()}, corrupt[0])
+	}
+}
+
+func (suite *BlockStoreSuite) TestChunkStoreVerifyOnRead() {
+	ctx := context.Background()
+	c := chunks.NewChunk([]byte("abc"))
+	other := chunks.NewChunk([]byte("def"))
+	suite.NoError(suite.store.Put(ctx, c))
+	suite.NoError(suite.store.Put(ctx, other))
+	success, err := suite.store.Commit(ctx, c.Hash(), hash.Hash{})
+	suite.NoError(err)
+	suite.True(success)
+
+	locations, err := suite.store.LocateChunk(ctx, c.Hash())
+	suite.NoError(err)
+	suite.Require().Len(locations, 1)
+	suite.NoError(suite.store.Close())
+
+	// replace the chunk's record with a well formed one for different data, which only a hash check detects
+	path := filepath.Join(suite.dir, locations[0])
+	data, err := ioutil.ReadFile(path)
+	suite.NoError(err)
+	record := ChunkToCompressedChunk(c).FullCompressedChunk
+	replacement := ChunkToCompressedChunk(chunks.NewChunk([]byte("abd"))).FullCompressedChunk
+	suite.Require().Equal(len(record), len(replacement))
+	idx := bytes.Index(data, record)
+	suite.Require().True(idx >= 0)
+	copy(data[idx:], replacement)
+	suite.NoError(ioutil.WriteFile(path, data, 0644))
+
+	suite.store, err = NewLocalStore(ctx, constants.FormatDefaultString, suite.dir, testMemTableSize)
+	suite.NoError(err)
+
+	_, err = suite.store.Get(ctx, c.Hash())
+	suite.NoError(err)
+
+	suite.store.VerifyOnRead(true)
+	_, err = suite.store.Get(ctx, c.Hash())
+	suite.True(errors.Is(err, ErrCorrupt), "%v", err)
+
+	found := make(chan *chunks.Chunk, 2)
+	err = suite.store.GetMany(ctx, hash.NewHashSet(c.Hash(), other.Hash()), found)
+	suite.True(errors.Is(err, ErrCorrupt), "%v", err)
+	close(found)
+	var hashes []hash.Hash
+	for fc := range found {
+		hashes = append(hashes, fc.Hash())
+	}
+	suite.Equal([]hash.Hash{other.Hash()}, hashes)
+
+	read, err := suite.store.Get(ctx, other.Hash())
+	suite.NoError(err)
+	suite.Equal(other.Data(), read.Data())
+}
+
+func (suite *BlockStoreSuite) TestChunkStorePutEmptyChunk() {
+	err := suite.store.Put(context.Background(), chunks.EmptyChunk)
+	suite.Equal(ErrEmptyChunk, err)
+}
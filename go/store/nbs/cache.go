@@ -60,7 +60,11 @@ type NomsBlockCache struct {
 
 // Insert stores c in the cache.
 func (nbc *NomsBlockCache) Insert(ctx context.Context, c chunks.Chunk) error {
-	success := nbc.chunks.addChunk(ctx, addr(c.Hash()), c.Data())
+	success, err := nbc.chunks.addChunk(ctx, addr(c.Hash()), c.Data())
+
+	if err != nil {
+		return err
+	}
 
 	if !success {
 		return errors.New("failed to add chunk")
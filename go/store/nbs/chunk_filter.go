@@ -0,0 +1,146 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// DefaultChunkFilterFalsePositiveRate is the false positive rate of the filters built by BuildChunkFilter.
+const DefaultChunkFilterFalsePositiveRate = 0.01
+
+var ErrInvalidFalsePositiveRate = errors.New("false positive rate must be between 0 and 1")
+
+// A chunk filter is a Bloom filter of chunk addresses, encoded as the number of bits set for each address as a byte,
+// followed by the filter's size in bits as a big endian uint64 and then its bits. Addresses are already uniformly
+// distributed, so the bits for an address are derived from the address itself by double hashing.
+const chunkFilterHeaderSize = 1 + uint64Size
+
+type chunkFilter struct {
+	k    uint8
+	bits []byte
+}
+
+// newChunkFilter returns an empty filter sized for |n| addresses with false positive rate |fpRate|.
+func newChunkFilter(n uint64, fpRate float64) chunkFilter {
+	if n == 0 {
+		n = 1
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	if k < 1 {
+		k = 1
+	} else if k > math.MaxUint8 {
+		k = math.MaxUint8
+	}
+
+	return chunkFilter{k: uint8(k), bits: make([]byte, (uint64(m)+7)/8)}
+}
+
+// bitIndexes calls |cb| with the index of each bit of the filter for |h|, stopping when it returns false.
+func (cf chunkFilter) bitIndexes(h hash.Hash, cb func(idx uint64) bool) {
+	m := uint64(len(cf.bits)) * 8
+	h1, h2 := binary.BigEndian.Uint64(h[:8]), binary.BigEndian.Uint64(h[8:16])|1
+	for i := uint64(0); i < uint64(cf.k); i++ {
+		if !cb((h1 + i*h2) % m) {
+			return
+		}
+	}
+}
+
+func (cf chunkFilter) add(h hash.Hash) {
+	cf.bitIndexes(h, func(idx uint64) bool {
+		cf.bits[idx/8] |= 1 << (idx % 8)
+		return true
+	})
+}
+
+func (cf chunkFilter) contains(h hash.Hash) bool {
+	found := true
+	cf.bitIndexes(h, func(idx uint64) bool {
+		found = cf.bits[idx/8]&(1<<(idx%8)) != 0
+		return found
+	})
+
+	return found
+}
+
+func (cf chunkFilter) encode() []byte {
+	buff := make([]byte, chunkFilterHeaderSize+len(cf.bits))
+	buff[0] = cf.k
+	binary.BigEndian.PutUint64(buff[1:], uint64(len(cf.bits))*8)
+	copy(buff[chunkFilterHeaderSize:], cf.bits)
+	return buff
+}
+
+// BuildChunkFilter returns a Bloom filter of the addresses of every chunk in the store, including pending chunks, with
+// a false positive rate of DefaultChunkFilterFalsePositiveRate. It is much smaller than the list of addresses, so a
+// remote can download it and probe it with FilterContains to find the chunks pushed to the store which it is
+// definitely missing. Only table indexes are read.
+func (nbs *NomsBlockStore) BuildChunkFilter(ctx context.Context) ([]byte, error) {
+	return nbs.BuildChunkFilterWithFalsePositiveRate(ctx, DefaultChunkFilterFalsePositiveRate)
+}
+
+// BuildChunkFilterWithFalsePositiveRate is like BuildChunkFilter, but the filter reports an address the store doesn't
+// have as present with probability |fpRate|, which must be between 0 and 1 exclusive. Halving the rate grows the
+// filter by about 1.44 bits per chunk. The rate is exceeded if chunks are added to the store while the filter is
+// built.
+func (nbs *NomsBlockStore) BuildChunkFilterWithFalsePositiveRate(ctx context.Context, fpRate float64) ([]byte, error) {
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, ErrInvalidFalsePositiveRate
+	}
+
+	count, err := nbs.Count()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cf := newChunkFilter(uint64(count), fpRate)
+	err = nbs.IterateChunksWithPrefix(ctx, nil, func(h hash.Hash) error {
+		cf.add(h)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cf.encode(), nil
+}
+
+// FilterContains returns whether the chunk filter |filter|, built by BuildChunkFilter, may contain |h|. A false
+// result means the store the filter was built from definitely did not have the chunk, while a true one must be
+// confirmed with a has-check. A malformed filter may contain any address.
+func FilterContains(filter []byte, h hash.Hash) bool {
+	if len(filter) < chunkFilterHeaderSize {
+		return true
+	}
+
+	cf := chunkFilter{k: filter[0], bits: filter[chunkFilterHeaderSize:]}
+	m := binary.BigEndian.Uint64(filter[1:])
+
+	if cf.k == 0 || m == 0 || m != uint64(len(cf.bits))*8 {
+		return true
+	}
+
+	return cf.contains(h)
+}
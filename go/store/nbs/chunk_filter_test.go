@@ -0,0 +1,61 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+func TestChunkFilterFalsePositiveRate(t *testing.T) {
+	const n = 10000
+
+	for _, fpRate := range []float64{0.1, DefaultChunkFilterFalsePositiveRate, 0.001} {
+		cf := newChunkFilter(n, fpRate)
+		for i := 0; i < n; i++ {
+			cf.add(hash.Of([]byte(strconv.Itoa(i))))
+		}
+
+		filter := cf.encode()
+		for i := 0; i < n; i++ {
+			assert.True(t, FilterContains(filter, hash.Of([]byte(strconv.Itoa(i)))))
+		}
+
+		var falsePositives int
+		for i := n; i < 2*n; i++ {
+			if FilterContains(filter, hash.Of([]byte(strconv.Itoa(i)))) {
+				falsePositives++
+			}
+		}
+
+		measured := float64(falsePositives) / n
+		assert.True(t, measured < 2*fpRate, "rate %f, measured %f", fpRate, measured)
+	}
+}
+
+func TestFilterContainsMalformed(t *testing.T) {
+	h := hash.Of([]byte("abc"))
+	assert.True(t, FilterContains(nil, h))
+	assert.True(t, FilterContains([]byte{1, 0, 0}, h))
+
+	// the size in bits doesn't match the bits present
+	filter := newChunkFilter(10, DefaultChunkFilterFalsePositiveRate).encode()
+	assert.True(t, FilterContains(filter[:len(filter)-1], h))
+	assert.False(t, FilterContains(filter, h))
+}
@@ -0,0 +1,222 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// ErrInvalidPageLimit is returned by IterateChunksPage when asked for pages of fewer than one chunk.
+var ErrInvalidPageLimit = errors.New("page limit must be positive")
+
+var errPageFull = errors.New("page full")
+
+// Cursor is a position in the address ordered iteration of IterateChunksPage. The zero Cursor is the start of the
+// store.
+type Cursor struct {
+	// Last is the address of the last chunk of the previous page. The next page starts after it.
+	Last hash.Hash
+
+	// Done is set on the Cursor returned with the last page.
+	Done bool
+}
+
+// IterateChunksPage returns up to |limit| chunks of the store in address order, starting after |cursor|, and the
+// Cursor to pass to get the next page. Pending chunks which have been Put but not yet committed are included. Only
+// table indexes are read to find the page, then only the page's chunks are read.
+//
+// A Cursor only records an address, so pages stay consistent as the store changes between calls: no chunk is
+// returned twice, and every chunk present for the whole iteration is returned once. A chunk added between pages is
+// returned by a later page if its address is after the cursor, and never otherwise. A chunk removed from the store by
+// a Rebase or RebaseTo before its page is read is not returned.
+func (nbs *NomsBlockStore) IterateChunksPage(ctx context.Context, cursor Cursor, limit int) ([]chunks.Chunk, Cursor, error) {
+	if limit <= 0 {
+		return nil, Cursor{}, ErrInvalidPageLimit
+	}
+
+	if cursor.Done {
+		return nil, cursor, nil
+	}
+
+	var start addr
+	if cursor != (Cursor{}) {
+		var ok bool
+		start, ok = nextAddr(addr(cursor.Last))
+
+		if !ok {
+			return nil, Cursor{Last: cursor.Last, Done: true}, nil
+		}
+	}
+
+	pending, tables := func() (hash.HashSet, tableSet) {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		pending := make(hash.HashSet)
+		if nbs.mt != nil {
+			for a := range nbs.mt.chunks {
+				if addrInRange(a, start, addr{}) {
+					pending.Insert(hash.Hash(a))
+				}
+			}
+		}
+
+		return pending, nbs.tables
+	}()
+
+	candidates := pending
+	for _, css := range []chunkSources{tables.novel, tables.upstream} {
+		for _, cs := range css {
+			if err := ctx.Err(); err != nil {
+				return nil, Cursor{}, err
+			}
+
+			index, err := cs.index()
+
+			if err != nil {
+				return nil, Cursor{}, err
+			}
+
+			// indexes are in prefix order, so a table's first |limit| addresses in the range, and any which share a
+			// prefix with the last of them, include its smallest |limit| addresses.
+			n := 0
+			var lastPrefix uint64
+			err = index.iterateRange(start, addr{}, func(a addr) error {
+				if n >= limit && a.Prefix() != lastPrefix {
+					return errPageFull
+				}
+
+				candidates.Insert(hash.Hash(a))
+				n++
+				lastPrefix = a.Prefix()
+				return nil
+			})
+
+			if err != nil && err != errPageFull {
+				return nil, Cursor{}, err
+			}
+		}
+	}
+
+	sorted := make(hash.HashSlice, 0, len(candidates))
+	for h := range candidates {
+		sorted = append(sorted, h)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	done := len(sorted) <= limit
+	if !done {
+		sorted = sorted[:limit]
+	}
+
+	if len(sorted) == 0 {
+		return nil, Cursor{Last: cursor.Last, Done: true}, nil
+	}
+
+	found := make(chan *chunks.Chunk, len(sorted))
+	err := nbs.GetMany(ctx, sorted.HashSet(), found)
+
+	if err != nil && !errors.Is(err, ErrChunkNotFound) {
+		return nil, Cursor{}, err
+	}
+
+	close(found)
+
+	byHash := make(map[hash.Hash]chunks.Chunk, len(sorted))
+	for c := range found {
+		byHash[c.Hash()] = *c
+	}
+
+	page := make([]chunks.Chunk, 0, len(sorted))
+	for _, h := range sorted {
+		if c, ok := byHash[h]; ok {
+			page = append(page, c)
+		}
+	}
+
+	return page, Cursor{Last: sorted[len(sorted)-1], Done: done}, nil
+}
+
+// SeekChunk returns the smallest address of a chunk in the store which is >= |h|, or false if there is none, so that
+// an iteration or a shard of the address space can start from any address. An |h| smaller than every address in the
+// store, such as the empty hash, returns the smallest address, and one larger than every address returns false.
+// Pending chunks which have been Put but not yet committed are included. Only table indexes are read.
+func (nbs *NomsBlockStore) SeekChunk(ctx context.Context, h hash.Hash) (hash.Hash, bool, error) {
+	start := addr(h)
+	var min addr
+	found := false
+	consider := func(a addr) {
+		if !found || bytes.Compare(a[:], min[:]) < 0 {
+			min, found = a, true
+		}
+	}
+
+	tables := func() tableSet {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		if nbs.mt != nil {
+			for a := range nbs.mt.chunks {
+				if addrInRange(a, start, addr{}) {
+					consider(a)
+				}
+			}
+		}
+
+		return nbs.tables
+	}()
+
+	for _, css := range []chunkSources{tables.novel, tables.upstream} {
+		for _, cs := range css {
+			if err := ctx.Err(); err != nil {
+				return hash.Hash{}, false, err
+			}
+
+			index, err := cs.index()
+
+			if err != nil {
+				return hash.Hash{}, false, err
+			}
+
+			if a, ok := index.seek(start); ok {
+				consider(a)
+			}
+		}
+	}
+
+	return hash.Hash(min), found, nil
+}
+
+// nextAddr returns the address after |a|, or false if |a| is the last address.
+func nextAddr(a addr) (addr, bool) {
+	for i := len(a) - 1; i >= 0; i-- {
+		a[i]++
+
+		if a[i] != 0 {
+			return a, true
+		}
+	}
+
+	return addr{}, false
+}
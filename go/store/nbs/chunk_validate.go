@@ -0,0 +1,131 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/golang/snappy"
+
+	"github.com/liquidata-inc/dolt/go/store/atomicerr"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// ErrInvalidWorkerCount is returned by ValidateParallel when it's asked to use fewer than one worker.
+var ErrInvalidWorkerCount = errors.New("validation needs at least one worker")
+
+// ValidateParallel reads every chunk in the store's table files and returns the addresses of those whose data is
+// corrupt: chunks which fail their checksum, can't be decompressed, or don't hash to their address. Chunks pending in
+// memory aren't checked. Table files are independent, so each is checked by one of |workers| goroutines, which reads
+// one chunk at a time to bound its memory. The reads are recorded in the store's Stats like any other.
+func (nbs *NomsBlockStore) ValidateParallel(ctx context.Context, workers int) ([]hash.Hash, error) {
+	if workers < 1 {
+		return nil, ErrInvalidWorkerCount
+	}
+
+	tables := func() tableSet {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+		return nbs.tables
+	}()
+
+	srcs := make(chan chunkSource, len(tables.novel)+len(tables.upstream))
+	for _, css := range []chunkSources{tables.novel, tables.upstream} {
+		for _, cs := range css {
+			srcs <- cs
+		}
+	}
+	close(srcs)
+
+	ae := atomicerr.New()
+	mu := &sync.Mutex{}
+	var corrupt hash.HashSlice
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cs := range srcs {
+				if ae.IsSet() {
+					return
+				}
+
+				bad, err := nbs.validateChunkSource(ctx, cs)
+
+				if ae.SetIfError(err) {
+					return
+				}
+
+				mu.Lock()
+				corrupt = append(corrupt, bad...)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := ae.Get(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(corrupt, func(i, j int) bool {
+		return bytes.Compare(corrupt[i][:], corrupt[j][:]) < 0
+	})
+
+	return corrupt, nil
+}
+
+// validateChunkSource returns the addresses of the corrupt chunks in |cs|, as described by ValidateParallel.
+func (nbs *NomsBlockStore) validateChunkSource(ctx context.Context, cs chunkSource) ([]hash.Hash, error) {
+	index, err := cs.index()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupt []hash.Hash
+	err = index.iterateRange(addr{}, addr{}, func(a addr) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := cs.get(ctx, a, nbs.stats)
+
+		if err == ErrChecksumMismatch || err == snappy.ErrCorrupt {
+			corrupt = append(corrupt, hash.Hash(a))
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if hash.Of(data) != hash.Hash(a) {
+			corrupt = append(corrupt, hash.Hash(a))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return corrupt, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import "time"
+
+// ConjoinEvent describes a store's decision whether to conjoin its tables before a manifest update, and the result
+// when it did.
+type ConjoinEvent struct {
+	// Before is the store's upstream tables when the decision was made.
+	Before []TableSpecInfo
+
+	// Required is whether the store's conjoiner decided to conjoin. The remaining fields are only set when it did.
+	Required bool
+
+	// After is the upstream tables after the conjoin. It is nil if the conjoin failed.
+	After []TableSpecInfo
+
+	// Duration is how long the conjoin took.
+	Duration time.Duration
+
+	// Err is the error the conjoin failed with, if any.
+	Err error
+}
+
+// ConjoinObserver is called with each ConjoinEvent of the store it is registered with. It is called synchronously
+// while the store is locked, so it must be quick, and must not call the store.
+type ConjoinObserver func(event ConjoinEvent)
+
+// SetConjoinObserver registers |obs| to be called for every conjoin decision the store makes during Commit and Flush,
+// replacing any observer registered before. A nil |obs| removes the observer. Decisions are only described to an
+// observer when one is registered.
+func (nbs *NomsBlockStore) SetConjoinObserver(obs ConjoinObserver) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.conjoinObserver = obs
+}
+
+func tableSpecInfos(specs []tableSpec) []TableSpecInfo {
+	infos := make([]TableSpecInfo, len(specs))
+	for i, spec := range specs {
+		infos[i] = spec
+	}
+
+	return infos
+}
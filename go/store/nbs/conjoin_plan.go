@@ -0,0 +1,92 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import "context"
+
+// ConjoinPlan describes the conjoin a store would perform on its next Commit, as computed by PlanConjoin.
+type ConjoinPlan struct {
+	// Required is whether the store's conjoiner would conjoin. The remaining fields are only set when it would.
+	Required bool
+
+	// Conjoinees are the upstream tables which would be conjoined into a single table, and Keepers are those which
+	// would be left as they are.
+	Conjoinees []TableSpecInfo
+	Keepers    []TableSpecInfo
+
+	// ChunkCount is the number of chunks in the conjoined table, and CompressedSize and UncompressedSize are the
+	// sizes of the chunk data it would hold, compressed as in the table file and uncompressed.
+	ChunkCount       uint32
+	CompressedSize   uint64
+	UncompressedSize uint64
+
+	// TableCount is the number of upstream tables there would be after the conjoin.
+	TableCount int
+}
+
+// PlanConjoin returns the conjoin the store would perform if it were committed now, without writing any table files
+// or changing the manifest. The decision is made by the store's conjoiner, and the tables are chosen by the same code
+// a conjoin uses. Tables written since the last Commit count toward the decision, as they do during Commit, but are
+// never conjoined, since a conjoin only involves tables in the manifest.
+func (nbs *NomsBlockStore) PlanConjoin(ctx context.Context) (ConjoinPlan, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return ConjoinPlan{}, err
+	}
+
+	if !nbs.c.ConjoinRequired(nbs.tables) || len(nbs.tables.upstream) < 2 {
+		return ConjoinPlan{}, nil
+	}
+
+	toConjoin, toKeep, err := chooseConjoinees(nbs.tables.upstream, conjoinMinTableSize(nbs.c))
+
+	if err != nil {
+		return ConjoinPlan{}, err
+	}
+
+	if len(toConjoin) == 0 {
+		return ConjoinPlan{}, nil
+	}
+
+	compaction, uncompressedSize, err := planConjoinSources(toConjoin)
+
+	if err != nil {
+		return ConjoinPlan{}, err
+	}
+
+	conjoinees, err := toSpecs(toConjoin)
+
+	if err != nil {
+		return ConjoinPlan{}, err
+	}
+
+	keepers, err := toSpecs(toKeep)
+
+	if err != nil {
+		return ConjoinPlan{}, err
+	}
+
+	return ConjoinPlan{
+		Required:         true,
+		Conjoinees:       tableSpecInfos(conjoinees),
+		Keepers:          tableSpecInfos(keepers),
+		ChunkCount:       compaction.chunkCount,
+		CompressedSize:   compaction.totalCompressedData,
+		UncompressedSize: uncompressedSize,
+		TableCount:       len(keepers) + 1,
+	}, nil
+}
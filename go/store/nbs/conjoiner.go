@@ -23,7 +23,6 @@ package nbs
 
 import (
 	"context"
-	"errors"
 	"sort"
 	"sync"
 	"time"
@@ -31,6 +30,9 @@ import (
 	"github.com/liquidata-inc/dolt/go/store/atomicerr"
 )
 
+// conjoiner decides when a store's tables are conjoined into fewer, larger ones, and does the conjoining. A
+// conjoiner implementation must be goroutine-safe. Conjoin is only called while the store holds the manifest's update
+// lock.
 type conjoiner interface {
 	// ConjoinRequired tells the caller whether or not it's time to request a
 	// Conjoin, based upon the contents of |ts| and the conjoiner
@@ -47,40 +49,130 @@ type conjoiner interface {
 	Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error)
 }
 
+// ConjoinPolicy controls when a store conjoins its tables into fewer, larger ones.
+type ConjoinPolicy struct {
+	// MaxTables is the number of tables a store may reference before it conjoins them.
+	MaxTables int
+
+	// MinTableSize is the uncompressed size in bytes below which a table counts toward MaxTables. Larger tables don't
+	// trigger a conjoin. If zero, every table counts.
+	MinTableSize uint64
+
+	// MinConjoinInterval is the least time between conjoins, however many tables the store references. Under a steady
+	// stream of commits this lets small tables accumulate between conjoins rather than conjoining on every commit. If
+	// zero, conjoins aren't rate limited.
+	MinConjoinInterval time.Duration
+}
+
+// DefaultConjoinPolicy is the ConjoinPolicy used by stores unless another is given at construction.
+var DefaultConjoinPolicy = ConjoinPolicy{MaxTables: defaultMaxTables}
+
 type inlineConjoiner struct {
-	maxTables int
+	policy ConjoinPolicy
+	last   *lastConjoin
+}
+
+// lastConjoin is the time of an inlineConjoiner's last successful conjoin.
+type lastConjoin struct {
+	mu  sync.Mutex
+	now func() time.Time
+	at  time.Time
+}
+
+func newInlineConjoiner(policy ConjoinPolicy) inlineConjoiner {
+	return inlineConjoiner{policy, &lastConjoin{now: time.Now}}
 }
 
 func (c inlineConjoiner) ConjoinRequired(ts tableSet) bool {
-	return ts.Size() > c.maxTables
+	if c.policy.MinConjoinInterval > 0 {
+		c.last.mu.Lock()
+		tooSoon := !c.last.at.IsZero() && c.last.now().Sub(c.last.at) < c.policy.MinConjoinInterval
+		c.last.mu.Unlock()
+
+		if tooSoon {
+			return false
+		}
+	}
+
+	if c.policy.MinTableSize == 0 {
+		return ts.Size() > c.policy.MaxTables
+	}
+
+	return countSmallTables(ts.novel, c.policy.MinTableSize)+countSmallTables(ts.upstream, c.policy.MinTableSize) > c.policy.MaxTables
+}
+
+// countSmallTables returns the number of |srcs| whose uncompressed size is less than |minSize|. A source whose size
+// can't be determined is counted.
+func countSmallTables(srcs chunkSources, minSize uint64) int {
+	cnt := 0
+	for _, src := range srcs {
+		size, err := src.uncompressedLen()
+
+		if err != nil || size < minSize {
+			cnt++
+		}
+	}
+
+	return cnt
 }
 
 func (c inlineConjoiner) Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
-	return conjoin(ctx, upstream, mm, p, stats)
+	contents, err := conjoin(ctx, upstream, mm, p, c.policy.MinTableSize, stats)
+
+	if err != nil {
+		return manifestContents{}, err
+	}
+
+	c.last.mu.Lock()
+	c.last.at = c.last.now()
+	c.last.mu.Unlock()
+
+	return contents, nil
+}
+
+// conjoinMinTableSize returns the MinTableSize of |c|'s policy, or zero if |c| has no ConjoinPolicy.
+func conjoinMinTableSize(c conjoiner) uint64 {
+	if ic, ok := c.(inlineConjoiner); ok {
+		return ic.policy.MinTableSize
+	}
+
+	return 0
 }
 
-func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
+// conjoin conjoins some of the tables in |upstream| and lands the result in |mm|. Tables whose uncompressed size is
+// at least |minTableSize| are never conjoined; if zero, any table may be. If there are fewer than two smaller tables,
+// |upstream| is returned unchanged.
+func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, minTableSize uint64, stats *Stats) (manifestContents, error) {
 	var conjoined tableSpec
 	var conjoinees, keepers []tableSpec
 
 	for {
 		if conjoinees == nil {
 			var err error
-			conjoined, conjoinees, keepers, err = conjoinTables(ctx, p, upstream.specs, stats)
+			conjoined, conjoinees, keepers, err = conjoinTables(ctx, p, upstream.specs, minTableSize, stats)
 
 			if err != nil {
 				return manifestContents{}, err
 			}
+
+			if len(conjoinees) == 0 {
+				return upstream, nil
+			}
 		}
 
 		specs := append(make([]tableSpec, 0, len(keepers)+1), conjoined)
 		specs = append(specs, keepers...)
 
 		newContents := manifestContents{
-			vers:  upstream.vers,
-			root:  upstream.root,
-			lock:  generateLockHash(upstream.root, specs),
-			specs: specs,
+			vers:     upstream.vers,
+			root:     upstream.root,
+			lock:     generateLockHash(upstream.root, specs, upstream.pinned),
+			specs:    specs,
+			meta:     upstream.meta,
+			metaHead: upstream.metaHead,
+			layout:   upstream.layout,
+			pinned:   upstream.pinned,
+			origin:   upstream.origin,
 		}
 
 		var err error
@@ -117,7 +209,7 @@ func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater,
 	}
 }
 
-func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec, stats *Stats) (conjoined tableSpec, conjoinees, keepers []tableSpec, err error) {
+func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec, minTableSize uint64, stats *Stats) (conjoined tableSpec, conjoinees, keepers []tableSpec, err error) {
 	// Open all the upstream tables concurrently
 	sources := make(chunkSources, len(upstream))
 
@@ -142,12 +234,16 @@ func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec,
 
 	t1 := time.Now()
 
-	toConjoin, toKeep, err := chooseConjoinees(sources)
+	toConjoin, toKeep, err := chooseConjoinees(sources, minTableSize)
 
 	if err != nil {
 		return tableSpec{}, nil, nil, err
 	}
 
+	if len(toConjoin) == 0 {
+		return tableSpec{}, nil, nil, nil
+	}
+
 	conjoinedSrc, err := p.ConjoinAll(ctx, toConjoin, stats)
 
 	if err != nil {
@@ -193,9 +289,27 @@ func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec,
 }
 
 // Current approach is to choose the smallest N tables which, when removed and replaced with the conjoinment, will leave the conjoinment as the smallest table.
-func chooseConjoinees(upstream chunkSources) (toConjoin, toKeep chunkSources, err error) {
-	sortedUpstream := make(chunkSources, len(upstream))
-	copy(sortedUpstream, upstream)
+// Tables whose uncompressed size is at least |minTableSize| are always kept, as are all the tables if there are fewer
+// than two smaller ones. If |minTableSize| is zero, every table is a candidate.
+func chooseConjoinees(upstream chunkSources, minTableSize uint64) (toConjoin, toKeep chunkSources, err error) {
+	sortedUpstream := make(chunkSources, 0, len(upstream))
+	var large chunkSources
+	for _, src := range upstream {
+		if minTableSize > 0 {
+			size, err := src.uncompressedLen()
+
+			if err == nil && size >= minTableSize {
+				large = append(large, src)
+				continue
+			}
+		}
+
+		sortedUpstream = append(sortedUpstream, src)
+	}
+
+	if len(sortedUpstream) < 2 {
+		return nil, append(sortedUpstream, large...), nil
+	}
 
 	csbac := chunkSourcesByAscendingCount{sortedUpstream, nil}
 	sort.Sort(csbac)
@@ -233,7 +347,7 @@ func chooseConjoinees(upstream chunkSources) (toConjoin, toKeep chunkSources, er
 		partition++
 	}
 
-	return sortedUpstream[:partition], sortedUpstream[partition:], nil
+	return sortedUpstream[:partition], append(sortedUpstream[partition:], large...), nil
 }
 
 func toSpecs(srcs chunkSources) ([]tableSpec, error) {
@@ -246,7 +360,7 @@ func toSpecs(srcs chunkSources) ([]tableSpec, error) {
 		}
 
 		if cnt <= 0 {
-			return nil, errors.New("invalid table spec has no sources")
+			return nil, newStoreError(ErrCorrupt, "invalid table spec has no sources")
 		}
 
 		h, err := src.hash()
@@ -146,7 +146,33 @@ func TestConjoin(t *testing.T) {
 			t.Run(c.name, func(t *testing.T) {
 				fm, p, upstream := setup(startLock, startRoot, c.precompact)
 
-				_, err := conjoin(context.Background(), upstream, fm, p, stats)
+				_, err := conjoin(context.Background(), upstream, fm, p, 0, stats)
+				assert.NoError(t, err)
+				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
+				assert.NoError(t, err)
+				assert.True(t, exists)
+				assert.Equal(t, c.postcompact, getSortedSizes(newUpstream.specs))
+				assertContainAll(t, p, upstream.specs, newUpstream.specs)
+			})
+		}
+	})
+
+	t.Run("MinTableSize", func(t *testing.T) {
+		// Each chunk is 4 bytes uncompressed, so with a MinTableSize of 12 tables of 3 or more chunks are kept
+		tc := []struct {
+			name        string
+			precompact  []uint32
+			postcompact []uint32
+		}{
+			{"small only", []uint32{2, 2, 3, 10}, []uint32{3, 4, 10}},
+			{"all large", []uint32{5, 5, 5}, []uint32{5, 5, 5}},
+			{"one small", []uint32{1, 5, 5}, []uint32{1, 5, 5}},
+		}
+		for _, c := range tc {
+			t.Run(c.name, func(t *testing.T) {
+				fm, p, upstream := setup(startLock, startRoot, c.precompact)
+
+				_, err := conjoin(context.Background(), upstream, fm, p, 12, stats)
 				assert.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				assert.NoError(t, err)
@@ -176,7 +202,7 @@ func TestConjoin(t *testing.T) {
 					specs := append([]tableSpec{}, upstream.specs...)
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, append(specs, newTable))
 				}}
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, 0, stats)
 				assert.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				assert.NoError(t, err)
@@ -196,7 +222,7 @@ func TestConjoin(t *testing.T) {
 				u := updatePreemptManifest{fm, func() {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, upstream.specs[1:])
 				}}
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, 0, stats)
 				assert.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				assert.NoError(t, err)
@@ -218,3 +244,25 @@ func (u updatePreemptManifest) Update(ctx context.Context, lastLock addr, newCon
 	}
 	return u.manifest.Update(ctx, lastLock, newContents, stats, writeHook)
 }
+
+func TestConjoinPolicy(t *testing.T) {
+	// each chunk is 4 bytes uncompressed, so the tables are 4, 4 and 40 bytes
+	ts := tableSet{upstream: makeTestSrcs(t, []uint32{1, 1, 10}, newFakeTablePersister())}
+
+	tests := []struct {
+		policy   ConjoinPolicy
+		required bool
+	}{
+		{ConjoinPolicy{MaxTables: 2}, true},
+		{ConjoinPolicy{MaxTables: 3}, false},
+		{ConjoinPolicy{MaxTables: 2, MinTableSize: 20}, false},
+		{ConjoinPolicy{MaxTables: 1, MinTableSize: 20}, true},
+		{ConjoinPolicy{MaxTables: 2, MinTableSize: 41}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.required, newInlineConjoiner(test.policy).ConjoinRequired(ts), "%+v", test.policy)
+	}
+
+	assert.False(t, newInlineConjoiner(DefaultConjoinPolicy).ConjoinRequired(ts))
+}
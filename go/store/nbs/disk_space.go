@@ -0,0 +1,91 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLowDiskSpace is returned by Flush and Commit when a store's DiskSpaceMonitor finds less space available than its
+// threshold. Nothing has been written when it is returned, so the store is unchanged and the write can be retried once
+// space is freed.
+var ErrLowDiskSpace = errors.New("low disk space")
+
+var errDiskSpaceUnsupported = errors.New("available disk space is not supported on this platform")
+
+// DiskSpaceMonitor checks the space available in a local store's directory before Flush or Commit writes a table file,
+// so that a store running out of space fails with ErrLowDiskSpace rather than with a write failing part way through.
+// The check is skipped on platforms where the available space can't be read.
+type DiskSpaceMonitor struct {
+	// MinAvailable is the number of bytes which must be available in the store's directory for a write to go ahead.
+	MinAvailable uint64
+
+	// Warn, if set, is called when less than MinAvailable bytes are available, and the write goes ahead rather than
+	// failing with ErrLowDiskSpace. It is called synchronously, so it must be quick, and must not call the store.
+	Warn func(dir string, available uint64)
+
+	// availableSpace returns the number of bytes available in a directory. It is nil for the file system's actual
+	// available space, and is replaced by tests.
+	availableSpace func(dir string) (uint64, error)
+}
+
+func (m *DiskSpaceMonitor) check(dir string) error {
+	availableSpace := m.availableSpace
+	if availableSpace == nil {
+		availableSpace = availableDiskSpace
+	}
+
+	available, err := availableSpace(dir)
+
+	if err == errDiskSpaceUnsupported {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if available >= m.MinAvailable {
+		return nil
+	}
+
+	if m.Warn != nil {
+		m.Warn(dir, available)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d bytes available in %s, below %d", ErrLowDiskSpace, available, dir, m.MinAvailable)
+}
+
+// SetDiskSpaceMonitor registers |m| to check the space available before each Flush and Commit which writes a table
+// file, replacing any monitor registered before. A nil |m| removes the monitor. Only local stores are checked.
+func (nbs *NomsBlockStore) SetDiskSpaceMonitor(m *DiskSpaceMonitor) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.diskSpaceMonitor = m
+}
+
+func (nbs *NomsBlockStore) checkDiskSpace() error {
+	nbs.mu.RLock()
+	m := nbs.diskSpaceMonitor
+	nbs.mu.RUnlock()
+
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if m == nil || !ok {
+		return nil
+	}
+
+	return m.check(fsPersister.dir)
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+// DurabilityPolicy controls which of a local store's writes are fsynced. Table files and the manifest are always
+// written to temporary files and renamed into place, so a crash of the process never loses a committed root or
+// corrupts the store. The policy only matters when the machine crashes, or loses power, before the operating system
+// has written its caches to disk.
+type DurabilityPolicy struct {
+	// FsyncTables fsyncs each table file, and the directory it is renamed into, as the table is persisted. Tables are
+	// persisted as memtables fill, and by Commit before it writes the manifest.
+	FsyncTables bool
+
+	// FsyncManifest fsyncs the manifest, and the store's directory, when Commit writes it. Without it, a successful
+	// Commit may be rolled back to an earlier root by a machine crash. Without FsyncTables, a manifest which was
+	// fsynced may reference table files which never reached the disk, so a store written that way should be synced
+	// by other means, such as sync(1), before it is relied upon.
+	FsyncManifest bool
+}
+
+// DefaultDurabilityPolicy fsyncs both table files and the manifest, so that a successful Commit survives a machine
+// crash.
+var DefaultDurabilityPolicy = DurabilityPolicy{FsyncTables: true, FsyncManifest: true}
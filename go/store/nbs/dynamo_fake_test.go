@@ -41,8 +41,8 @@ type fakeDDB struct {
 }
 
 type record struct {
-	lock, root  []byte
-	vers, specs string
+	lock, root, metaHead       []byte
+	nbsVers, vers, specs, meta string
 }
 
 func makeFakeDDB(t *testing.T) *fakeDDB {
@@ -76,13 +76,19 @@ func (m *fakeDDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInp
 		item[dbAttr] = &dynamodb.AttributeValue{S: key}
 		switch e := e.(type) {
 		case record:
-			item[nbsVersAttr] = &dynamodb.AttributeValue{S: aws.String(StorageVersion)}
+			item[nbsVersAttr] = &dynamodb.AttributeValue{S: aws.String(e.nbsVers)}
 			item[versAttr] = &dynamodb.AttributeValue{S: aws.String(e.vers)}
 			item[rootAttr] = &dynamodb.AttributeValue{B: e.root}
 			item[lockAttr] = &dynamodb.AttributeValue{B: e.lock}
 			if e.specs != "" {
 				item[tableSpecsAttr] = &dynamodb.AttributeValue{S: aws.String(e.specs)}
 			}
+			if e.meta != "" {
+				item[rootMetaAttr] = &dynamodb.AttributeValue{S: aws.String(e.meta)}
+			}
+			if e.metaHead != nil {
+				item[metaHeadAttr] = &dynamodb.AttributeValue{B: e.metaHead}
+			}
 		case []byte:
 			item[dataAttr] = &dynamodb.AttributeValue{B: e}
 		}
@@ -92,7 +98,7 @@ func (m *fakeDDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInp
 }
 
 func (m *fakeDDB) putRecord(k string, l, r []byte, v string, s string) {
-	m.data[k] = record{l, r, v, s}
+	m.data[k] = record{l, r, nil, StorageVersion, v, s, ""}
 }
 
 func (m *fakeDDB) putData(k string, d []byte) {
@@ -112,7 +118,7 @@ func (m *fakeDDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInp
 
 	assert.NotNil(m.t, input.Item[nbsVersAttr], "%s should have been present", nbsVersAttr)
 	assert.NotNil(m.t, input.Item[nbsVersAttr].S, "nbsVers should have been a String: %+v", input.Item[nbsVersAttr])
-	assert.Equal(m.t, StorageVersion, *input.Item[nbsVersAttr].S)
+	nbsVers := *input.Item[nbsVersAttr].S
 
 	assert.NotNil(m.t, input.Item[versAttr], "%s should have been present", versAttr)
 	assert.NotNil(m.t, input.Item[versAttr].S, "nbsVers should have been a String: %+v", input.Item[versAttr])
@@ -132,6 +138,18 @@ func (m *fakeDDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInp
 		specs = *attr.S
 	}
 
+	meta := ""
+	if attr, present := input.Item[rootMetaAttr]; present {
+		assert.NotNil(m.t, attr.S, "meta should have been a String: %+v", input.Item[rootMetaAttr])
+		meta = *attr.S
+	}
+
+	var metaHead []byte
+	if attr, present := input.Item[metaHeadAttr]; present {
+		assert.NotNil(m.t, attr.B, "metaHead should have been a blob: %+v", input.Item[metaHeadAttr])
+		metaHead = attr.B
+	}
+
 	mustNotExist := *(input.ConditionExpression) == valueNotExistsOrEqualsExpression
 	current, present := m.data[key]
 
@@ -141,7 +159,7 @@ func (m *fakeDDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInp
 		return nil, mockAWSError("ConditionalCheckFailedException")
 	}
 
-	m.putRecord(key, lock, root, constants.NomsVersion, specs)
+	m.data[key] = record{lock, root, metaHead, nbsVers, constants.NomsVersion, specs, meta}
 	atomic.AddInt64(&m.numPuts, 1)
 
 	return &dynamodb.PutItemOutput{}, nil
@@ -23,7 +23,6 @@ package nbs
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -44,6 +43,9 @@ const (
 	versAttr       = "vers"
 	nbsVersAttr    = "nbsVers"
 	tableSpecsAttr = "specs"
+	rootMetaAttr   = "meta"
+	pinnedAttr     = "pinned"
+	metaHeadAttr   = "metaHead"
 )
 
 var (
@@ -108,6 +110,30 @@ func (dm dynamoManifest) ParseIfExists(ctx context.Context, stats *Stats, readHo
 				return false, manifestContents{}, ErrCorruptManifest
 			}
 		}
+		if metaVal := result.Item[rootMetaAttr]; metaVal != nil && metaVal.S != nil {
+			contents.meta, err = decodeRootMeta(*metaVal.S)
+
+			if err != nil {
+				return false, manifestContents{}, err
+			}
+		}
+		if metaHeadVal := result.Item[metaHeadAttr]; metaHeadVal != nil && metaHeadVal.B != nil {
+			if len(metaHeadVal.B) != addrSize {
+				return false, manifestContents{}, ErrCorruptManifest
+			}
+
+			copy(contents.metaHead[:], metaHeadVal.B)
+		}
+		if pinnedVal := result.Item[pinnedAttr]; pinnedVal != nil && pinnedVal.S != nil {
+			contents.pinned, err = decodePinnedRoots(*pinnedVal.S)
+
+			if err != nil {
+				return false, manifestContents{}, err
+			}
+		}
+		if !contents.validStorageVersion(*result.Item[nbsVersAttr].S) {
+			return false, manifestContents{}, ErrCorruptManifest
+		}
 	}
 
 	return exists, contents, nil
@@ -115,14 +141,33 @@ func (dm dynamoManifest) ParseIfExists(ctx context.Context, stats *Stats, readHo
 
 func validateManifest(item map[string]*dynamodb.AttributeValue) (valid, hasSpecs bool) {
 	if item[nbsVersAttr] != nil && item[nbsVersAttr].S != nil &&
-		StorageVersion == *item[nbsVersAttr].S &&
+		(StorageVersion == *item[nbsVersAttr].S || ExtendedStorageVersion == *item[nbsVersAttr].S) &&
 		item[versAttr] != nil && item[versAttr].S != nil &&
 		item[lockAttr] != nil && item[lockAttr].B != nil &&
 		item[rootAttr] != nil && item[rootAttr].B != nil {
-		if len(item) == 6 && item[tableSpecsAttr] != nil && item[tableSpecsAttr].S != nil {
+		expectedLen := 5
+		if item[rootMetaAttr] != nil {
+			if item[rootMetaAttr].S == nil {
+				return false, false
+			}
+			expectedLen++
+		}
+		if item[pinnedAttr] != nil {
+			if item[pinnedAttr].S == nil {
+				return false, false
+			}
+			expectedLen++
+		}
+		if item[metaHeadAttr] != nil {
+			if item[metaHeadAttr].B == nil {
+				return false, false
+			}
+			expectedLen++
+		}
+		if len(item) == expectedLen+1 && item[tableSpecsAttr] != nil && item[tableSpecsAttr].S != nil {
 			return true, true
 		}
-		return len(item) == 5, false
+		return len(item) == expectedLen, false
 	}
 	return false, false
 }
@@ -135,7 +180,7 @@ func (dm dynamoManifest) Update(ctx context.Context, lastLock addr, newContents
 		TableName: aws.String(dm.table),
 		Item: map[string]*dynamodb.AttributeValue{
 			dbAttr:      {S: aws.String(dm.db)},
-			nbsVersAttr: {S: aws.String(StorageVersion)},
+			nbsVersAttr: {S: aws.String(newContents.storageVersion())},
 			versAttr:    {S: aws.String(newContents.vers)},
 			rootAttr:    {B: newContents.root[:]},
 			lockAttr:    {B: newContents.lock[:]},
@@ -146,6 +191,23 @@ func (dm dynamoManifest) Update(ctx context.Context, lastLock addr, newContents
 		formatSpecs(newContents.specs, tableInfo)
 		putArgs.Item[tableSpecsAttr] = &dynamodb.AttributeValue{S: aws.String(strings.Join(tableInfo, ":"))}
 	}
+	encodedMeta, err := encodeRootMeta(newContents.meta)
+
+	if err != nil {
+		return manifestContents{}, err
+	}
+
+	if encodedMeta != "" {
+		putArgs.Item[rootMetaAttr] = &dynamodb.AttributeValue{S: aws.String(encodedMeta)}
+	}
+
+	if newContents.metaHead != (addr{}) {
+		putArgs.Item[metaHeadAttr] = &dynamodb.AttributeValue{B: newContents.metaHead[:]}
+	}
+
+	if len(newContents.pinned) > 0 {
+		putArgs.Item[pinnedAttr] = &dynamodb.AttributeValue{S: aws.String(encodePinnedRoots(newContents.pinned))}
+	}
 
 	expr := valueEqualsExpression
 	if lastLock == (addr{}) {
@@ -168,11 +230,11 @@ func (dm dynamoManifest) Update(ctx context.Context, lastLock addr, newContents
 			}
 
 			if !exists {
-				return manifestContents{}, errors.New("manifest not found")
+				return manifestContents{}, newStoreError(ErrNotFound, "manifest not found")
 			}
 
 			if upstream.vers != newContents.vers {
-				return manifestContents{}, errors.New("version mismatch")
+				return manifestContents{}, newStoreError(ErrManifestConflict, "version mismatch")
 			}
 
 			return upstream, nil
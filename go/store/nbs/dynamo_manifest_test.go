@@ -71,7 +71,7 @@ func TestDynamoManifestParseIfExists(t *testing.T) {
 }
 
 func makeContents(lock, root string, specs []tableSpec) manifestContents {
-	return manifestContents{constants.NomsVersion, computeAddr([]byte(lock)), hash.Of([]byte(root)), specs}
+	return manifestContents{constants.NomsVersion, computeAddr([]byte(lock)), hash.Of([]byte(root)), specs, nil, addr{}, FlatTableLayout, nil, storeOrigin{}}
 }
 
 func TestDynamoManifestUpdateWontClobberOldVersion(t *testing.T) {
@@ -182,3 +182,39 @@ func TestDynamoManifestUpdateEmpty(t *testing.T) {
 	assert.True(upstream.root.IsEmpty())
 	assert.Empty(upstream.specs)
 }
+
+func TestDynamoManifestUpdateWithRootMeta(t *testing.T) {
+	assert := assert.New(t)
+	mm, ddb := makeDynamoManifestFake(t)
+	stats := &Stats{}
+
+	contents := makeContents("locker", "nuroot", []tableSpec{{computeAddr([]byte("a")), 3}})
+	contents.meta = map[string]string{"author": "bill", "message": "a:b"}
+	upstream, err := mm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+	assert.Equal(contents.meta, upstream.meta)
+	assert.Equal(ExtendedStorageVersion, ddb.data[db].(record).nbsVers)
+
+	exists, upstream, err := mm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents.root, upstream.root)
+	assert.Equal(contents.specs, upstream.specs)
+	assert.Equal(contents.meta, upstream.meta)
+}
+
+func TestDynamoManifestUpdateWithRootMetaHead(t *testing.T) {
+	assert := assert.New(t)
+	mm, _ := makeDynamoManifestFake(t)
+	stats := &Stats{}
+
+	contents := makeContents("locker", "nuroot", []tableSpec{{computeAddr([]byte("a")), 3}})
+	contents.metaHead = computeAddr([]byte("root meta record"))
+	_, err := mm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err := mm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents.metaHead, upstream.metaHead)
+}
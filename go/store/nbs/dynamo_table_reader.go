@@ -53,6 +53,11 @@ func (t tableNotInDynamoErr) Error() string {
 	return fmt.Sprintf("NBS table %s not present in DynamoDB table %s", t.nbs, t.dynamo)
 }
 
+// Is reports whether |target| is ErrNotFound, so that a missing table is handled like any other missing store data.
+func (t tableNotInDynamoErr) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 func (dtra *dynamoTableReaderAt) ReadAtWithStats(ctx context.Context, p []byte, off int64, stats *Stats) (n int, err error) {
 	data, err := dtra.ddb.ReadTable(ctx, dtra.h, stats)
 
@@ -115,7 +120,7 @@ func (dts *ddbTableStore) readTable(ctx context.Context, name addr) (data []byte
 		} else if len(result.Item) == 0 {
 			return nil, tableNotInDynamoErr{name.String(), dts.table}
 		} else if result.Item[dataAttr] == nil || result.Item[dataAttr].B == nil {
-			return nil, fmt.Errorf("NBS table %s in DynamoDB table %s is malformed", name, dts.table)
+			return nil, &StoreError{ErrCorrupt, fmt.Errorf("NBS table %s in DynamoDB table %s is malformed", name, dts.table)}
 		}
 		return result.Item[dataAttr].B, nil
 	}
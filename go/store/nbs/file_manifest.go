@@ -39,15 +39,32 @@ import (
 const (
 	manifestFileName = "manifest"
 	lockFileName     = "LOCK"
+
+	tableLayoutFieldPrefix  = "layout="
+	pinnedRootsFieldPrefix  = "pinned="
+	storeOriginFieldPrefix  = "created="
+	rootMetaHeadFieldPrefix = "metahead="
 )
 
 // fileManifest provides access to a NomsBlockStore manifest stored on disk in |dir|. The format
 // is currently human readable:
 //
-// |-- String --|-- String --|-------- String --------|-------- String --------|-- String --|- String --|...|-- String --|- String --|
-// | nbs version:Noms version:Base32-encoded lock hash:Base32-encoded root hash:table 1 hash:table 1 cnt:...:table N hash:table N cnt|
+// |-- String --|-- String --|-------- String --------|-------- String --------|-- String --|- String --|...|-- String --|- String --|------ String ------|
+// | nbs version:Noms version:Base32-encoded lock hash:Base32-encoded root hash:table 1 hash:table 1 cnt:...:table N hash:table N cnt:encoded root meta|
+//
+// The trailing root meta field is optional. Manifests written for roots committed without metadata omit it, which
+// leaves an even number of fields. Stores which have committed metadata follow it with "metahead=<Base32-encoded hash
+// of the newest root meta record>". Stores which recorded their creation follow that with
+// "created=<creation time in unix nanoseconds>,<memtable size>", and stores with pinned roots follow that with
+// "pinned=<comma separated root hashes>",
+// and stores using a TableLayout other than FlatTableLayout append one more field, "layout=<layout name>", after all
+// others. Manifests with any of these optional fields have the nbs version ExtendedStorageVersion rather than
+// StorageVersion.
 type fileManifest struct {
 	dir string
+
+	// fsync makes each manifest durable when it is renamed into place. See DurabilityPolicy.FsyncManifest.
+	fsync bool
 }
 
 func newLock(dir string) *fslock.Lock {
@@ -182,11 +199,66 @@ func parseManifest(r io.Reader) (manifestContents, error) {
 	}
 
 	slices := strings.Split(string(manifest), ":")
-	if len(slices) < 4 || len(slices)%2 == 1 {
+	if len(slices) < 4 {
 		return manifestContents{}, ErrCorruptManifest
 	}
 
-	if StorageVersion != string(slices[0]) {
+	layout := FlatTableLayout
+	if last := slices[len(slices)-1]; strings.HasPrefix(last, tableLayoutFieldPrefix) {
+		layout, err = parseTableLayout(strings.TrimPrefix(last, tableLayoutFieldPrefix))
+
+		if err != nil {
+			return manifestContents{}, err
+		}
+
+		slices = slices[:len(slices)-1]
+	}
+
+	var pinned []hash.Hash
+	if last := slices[len(slices)-1]; strings.HasPrefix(last, pinnedRootsFieldPrefix) {
+		pinned, err = decodePinnedRoots(strings.TrimPrefix(last, pinnedRootsFieldPrefix))
+
+		if err != nil {
+			return manifestContents{}, err
+		}
+
+		slices = slices[:len(slices)-1]
+	}
+
+	var origin storeOrigin
+	if last := slices[len(slices)-1]; strings.HasPrefix(last, storeOriginFieldPrefix) {
+		origin, err = decodeStoreOrigin(strings.TrimPrefix(last, storeOriginFieldPrefix))
+
+		if err != nil {
+			return manifestContents{}, err
+		}
+
+		slices = slices[:len(slices)-1]
+	}
+
+	var metaHead addr
+	if last := slices[len(slices)-1]; strings.HasPrefix(last, rootMetaHeadFieldPrefix) {
+		metaHead, err = parseAddr([]byte(strings.TrimPrefix(last, rootMetaHeadFieldPrefix)))
+
+		if err != nil {
+			return manifestContents{}, ErrCorruptManifest
+		}
+
+		slices = slices[:len(slices)-1]
+	}
+
+	var meta map[string]string
+	if len(slices)%2 == 1 {
+		meta, err = decodeRootMeta(slices[len(slices)-1])
+
+		if err != nil {
+			return manifestContents{}, err
+		}
+
+		slices = slices[:len(slices)-1]
+	}
+
+	if slices[0] != StorageVersion && slices[0] != ExtendedStorageVersion {
 		return manifestContents{}, errors.New("invalid storage version")
 	}
 
@@ -202,12 +274,23 @@ func parseManifest(r io.Reader) (manifestContents, error) {
 		return manifestContents{}, err
 	}
 
-	return manifestContents{
-		vers:  slices[1],
-		lock:  ad,
-		root:  hash.Parse(slices[3]),
-		specs: specs,
-	}, nil
+	contents := manifestContents{
+		vers:     slices[1],
+		lock:     ad,
+		root:     hash.Parse(slices[3]),
+		specs:    specs,
+		meta:     meta,
+		metaHead: metaHead,
+		layout:   layout,
+		pinned:   pinned,
+		origin:   origin,
+	}
+
+	if !contents.validStorageVersion(slices[0]) {
+		return manifestContents{}, ErrCorruptManifest
+	}
+
+	return contents, nil
 }
 
 func (fm fileManifest) Update(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (mc manifestContents, err error) {
@@ -240,6 +323,14 @@ func (fm fileManifest) Update(ctx context.Context, lastLock addr, newContents ma
 			return "", ferr
 		}
 
+		if fm.fsync {
+			ferr = temp.Sync()
+
+			if ferr != nil {
+				return "", ferr
+			}
+		}
+
 		return temp.Name(), nil
 	}()
 
@@ -294,7 +385,7 @@ func (fm fileManifest) Update(ctx context.Context, lastLock addr, newContents ma
 			}
 
 			if newContents.vers != upstream.vers {
-				return manifestContents{}, errors.New("Update cannot change manifest version")
+				return manifestContents{}, newStoreError(ErrManifestConflict, "Update cannot change manifest version")
 			}
 
 			return upstream, nil
@@ -303,7 +394,7 @@ func (fm fileManifest) Update(ctx context.Context, lastLock addr, newContents ma
 		}
 
 		if lastLock != (addr{}) {
-			return manifestContents{}, errors.New("new manifest created with non 0 lock")
+			return manifestContents{}, newStoreError(ErrManifestConflict, "new manifest created with non 0 lock")
 		}
 
 		return manifestContents{}, nil
@@ -323,15 +414,50 @@ func (fm fileManifest) Update(ctx context.Context, lastLock addr, newContents ma
 		return manifestContents{}, err
 	}
 
+	if fm.fsync {
+		err = syncDir(fm.dir)
+
+		if err != nil {
+			return manifestContents{}, err
+		}
+	}
+
 	return newContents, nil
 }
 
 func writeManifest(temp io.Writer, contents manifestContents) error {
 	strs := make([]string, 2*len(contents.specs)+4)
-	strs[0], strs[1], strs[2], strs[3] = StorageVersion, contents.vers, contents.lock.String(), contents.root.String()
+	strs[0], strs[1], strs[2], strs[3] = contents.storageVersion(), contents.vers, contents.lock.String(), contents.root.String()
 	tableInfo := strs[4:]
 	formatSpecs(contents.specs, tableInfo)
-	_, err := io.WriteString(temp, strings.Join(strs, ":"))
+
+	encodedMeta, err := encodeRootMeta(contents.meta)
+
+	if err != nil {
+		return err
+	}
+
+	if encodedMeta != "" {
+		strs = append(strs, encodedMeta)
+	}
+
+	if contents.metaHead != (addr{}) {
+		strs = append(strs, rootMetaHeadFieldPrefix+contents.metaHead.String())
+	}
+
+	if !contents.origin.created.IsZero() {
+		strs = append(strs, storeOriginFieldPrefix+encodeStoreOrigin(contents.origin))
+	}
+
+	if len(contents.pinned) > 0 {
+		strs = append(strs, pinnedRootsFieldPrefix+encodePinnedRoots(contents.pinned))
+	}
+
+	if contents.layout != FlatTableLayout {
+		strs = append(strs, tableLayoutFieldPrefix+contents.layout.String())
+	}
+
+	_, err = io.WriteString(temp, strings.Join(strs, ":"))
 
 	return err
 }
@@ -28,10 +28,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/liquidata-inc/dolt/go/store/constants"
 	"github.com/liquidata-inc/dolt/go/store/hash"
@@ -133,7 +136,7 @@ func TestFileManifestUpdateEmpty(t *testing.T) {
 	assert.True(upstream.root.IsEmpty())
 	assert.Empty(upstream.specs)
 
-	fm2 := fileManifest{fm.dir} // Open existent, but empty manifest
+	fm2 := fileManifest{dir: fm.dir} // Open existent, but empty manifest
 	exists, upstream, err := fm2.ParseIfExists(context.Background(), stats, nil)
 	assert.NoError(err)
 	assert.True(exists)
@@ -202,6 +205,74 @@ func TestFileManifestUpdate(t *testing.T) {
 	assert.Equal([]tableSpec{{tableName, 1}}, upstream.specs)
 }
 
+func TestFileManifestRootMeta(t *testing.T) {
+	assert := assert.New(t)
+	fm := makeFileManifestTempDir(t)
+	defer os.RemoveAll(fm.dir)
+	stats := &Stats{}
+
+	contents := manifestContents{
+		vers:     constants.NomsVersion,
+		lock:     computeAddr([]byte("locker")),
+		root:     hash.Of([]byte("new root")),
+		specs:    []tableSpec{{computeAddr([]byte("a")), 3}},
+		meta:     map[string]string{"author": "bill", "message": "contains: separators"},
+		metaHead: computeAddr([]byte("root meta record")),
+	}
+	_, err := fm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err := fm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents.root, upstream.root)
+	assert.Equal(contents.specs, upstream.specs)
+	assert.Equal(contents.meta, upstream.meta)
+	assert.Equal(contents.metaHead, upstream.metaHead)
+	assert.Equal(ExtendedStorageVersion, readManifestStorageVersion(t, fm.dir))
+
+	// A root committed without metadata drops the previous root's metadata, but not the record of earlier roots.
+	contents2 := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("locker 2")), root: hash.Of([]byte("new root 2")), metaHead: contents.metaHead}
+	_, err = fm.Update(context.Background(), contents.lock, contents2, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err = fm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents2.root, upstream.root)
+	assert.Nil(upstream.meta)
+	assert.Equal(contents.metaHead, upstream.metaHead)
+	assert.Equal(ExtendedStorageVersion, readManifestStorageVersion(t, fm.dir))
+
+	// A store which never committed metadata keeps writing the original format.
+	contents3 := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("locker 3")), root: hash.Of([]byte("new root 3"))}
+	_, err = fm.Update(context.Background(), contents2.lock, contents3, stats, nil)
+	assert.NoError(err)
+	assert.Equal(StorageVersion, readManifestStorageVersion(t, fm.dir))
+}
+
+// readManifestStorageVersion returns the nbs version the manifest in |dir| was written with.
+func readManifestStorageVersion(t *testing.T, dir string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, manifestFileName))
+	require.NoError(t, err)
+	return strings.SplitN(string(b), ":", 2)[0]
+}
+
+func TestParseManifestCorruptRootMeta(t *testing.T) {
+	lock := computeAddr([]byte("locker"))
+	root := hash.Of([]byte("root"))
+	_, err := parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), "!!not-meta!!"}, ":")))
+	assert.Equal(t, ErrCorruptManifest, err)
+
+	// Root metadata is only valid in manifests with the extended storage version.
+	meta, err := encodeRootMeta(map[string]string{"author": "bill"})
+	require.NoError(t, err)
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), meta}, ":")))
+	assert.Equal(t, ErrCorruptManifest, err)
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{ExtendedStorageVersion, constants.NomsVersion, lock.String(), root.String(), meta}, ":")))
+	assert.NoError(t, err)
+}
+
 // tryClobberManifest simulates another process trying to access dir/manifestFileName concurrently. To avoid deadlock, it does a non-blocking lock of dir/lockFileName. If it can get the lock, it clobbers the manifest.
 func tryClobberManifest(dir, contents string) ([]byte, error) {
 	return runClobber(dir, contents)
@@ -225,3 +296,126 @@ func runClobber(dir, contents string) ([]byte, error) {
 	c := exec.Command("go", "run", clobber, mkPath(lockFileName), mkPath(manifestFileName), contents)
 	return c.CombinedOutput()
 }
+
+func TestFileManifestTableLayout(t *testing.T) {
+	assert := assert.New(t)
+	fm := makeFileManifestTempDir(t)
+	defer os.RemoveAll(fm.dir)
+	stats := &Stats{}
+
+	contents := manifestContents{
+		vers:   constants.NomsVersion,
+		lock:   computeAddr([]byte("locker")),
+		root:   hash.Of([]byte("new root")),
+		specs:  []tableSpec{{computeAddr([]byte("a")), 3}},
+		meta:   map[string]string{"author": "bill"},
+		layout: ShardedTableLayout,
+	}
+	_, err := fm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err := fm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents.specs, upstream.specs)
+	assert.Equal(contents.meta, upstream.meta)
+	assert.Equal(ShardedTableLayout, upstream.layout)
+
+	lock := computeAddr([]byte("locker"))
+	root := hash.Of([]byte("root"))
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), tableLayoutFieldPrefix + "bogus"}, ":")))
+	assert.Equal(ErrUnknownTableLayout, err)
+
+	// The layout alone is enough to need the extended storage version.
+	contents2 := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("locker 2")), root: hash.Of([]byte("new root 2")), layout: ShardedTableLayout}
+	_, err = fm.Update(context.Background(), contents.lock, contents2, stats, nil)
+	assert.NoError(err)
+	assert.Equal(ExtendedStorageVersion, readManifestStorageVersion(t, fm.dir))
+
+	layout := tableLayoutFieldPrefix + ShardedTableLayout.String()
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), layout}, ":")))
+	assert.Equal(ErrCorruptManifest, err)
+}
+
+func TestFileManifestPinnedRoots(t *testing.T) {
+	assert := assert.New(t)
+	fm := makeFileManifestTempDir(t)
+	defer os.RemoveAll(fm.dir)
+	stats := &Stats{}
+
+	pinned := hash.HashSlice{hash.Of([]byte("pinned a")), hash.Of([]byte("pinned b"))}
+	sort.Sort(pinned)
+	contents := manifestContents{
+		vers:   constants.NomsVersion,
+		lock:   computeAddr([]byte("locker")),
+		root:   hash.Of([]byte("new root")),
+		specs:  []tableSpec{{computeAddr([]byte("a")), 3}},
+		meta:   map[string]string{"author": "bill"},
+		layout: ShardedTableLayout,
+		pinned: pinned,
+	}
+	_, err := fm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err := fm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.Equal(contents.specs, upstream.specs)
+	assert.Equal(contents.meta, upstream.meta)
+	assert.Equal(ShardedTableLayout, upstream.layout)
+	assert.Equal([]hash.Hash(pinned), upstream.pinned)
+
+	lock := computeAddr([]byte("locker"))
+	root := hash.Of([]byte("root"))
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), pinnedRootsFieldPrefix + "bogus"}, ":")))
+	assert.Equal(ErrCorruptManifest, err)
+
+	// Pinned roots alone are enough to need the extended storage version.
+	contents2 := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("locker 2")), root: hash.Of([]byte("new root 2")), pinned: pinned}
+	_, err = fm.Update(context.Background(), contents.lock, contents2, stats, nil)
+	assert.NoError(err)
+	assert.Equal(ExtendedStorageVersion, readManifestStorageVersion(t, fm.dir))
+
+	pinnedField := pinnedRootsFieldPrefix + encodePinnedRoots(pinned)
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), pinnedField}, ":")))
+	assert.Equal(ErrCorruptManifest, err)
+}
+
+func TestFileManifestStoreOrigin(t *testing.T) {
+	assert := assert.New(t)
+	fm := makeFileManifestTempDir(t)
+	defer os.RemoveAll(fm.dir)
+	stats := &Stats{}
+
+	origin := storeOrigin{time.Unix(0, 1583280000123456789), 1 << 20}
+	contents := manifestContents{
+		vers:   constants.NomsVersion,
+		lock:   computeAddr([]byte("locker")),
+		root:   hash.Of([]byte("new root")),
+		specs:  []tableSpec{{computeAddr([]byte("a")), 3}},
+		meta:   map[string]string{"author": "bill"},
+		pinned: []hash.Hash{hash.Of([]byte("pinned"))},
+		origin: origin,
+	}
+	_, err := fm.Update(context.Background(), addr{}, contents, stats, nil)
+	assert.NoError(err)
+
+	exists, upstream, err := fm.ParseIfExists(context.Background(), stats, nil)
+	assert.NoError(err)
+	assert.True(exists)
+	assert.True(origin.created.Equal(upstream.origin.created))
+	assert.Equal(origin.memTableSize, upstream.origin.memTableSize)
+	assert.Equal(contents.meta, upstream.meta)
+	assert.Equal(contents.pinned, upstream.pinned)
+
+	lock := computeAddr([]byte("locker"))
+	root := hash.Of([]byte("root"))
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), storeOriginFieldPrefix + "bogus"}, ":")))
+	assert.Equal(ErrCorruptManifest, err)
+
+	originField := storeOriginFieldPrefix + encodeStoreOrigin(origin)
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{StorageVersion, constants.NomsVersion, lock.String(), root.String(), originField}, ":")))
+	assert.Equal(ErrCorruptManifest, err)
+	_, err = parseManifest(strings.NewReader(strings.Join([]string{ExtendedStorageVersion, constants.NomsVersion, lock.String(), root.String(), originField}, ":")))
+	assert.NoError(err)
+}
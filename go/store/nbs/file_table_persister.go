@@ -24,7 +24,7 @@ package nbs
 import (
 	"bytes"
 	"context"
-	"errors"
+	"hash/crc64"
 	"io"
 	"io/ioutil"
 	"os"
@@ -35,19 +35,79 @@ import (
 
 const tempTablePrefix = "nbs_table_"
 
-func newFSTablePersister(dir string, fc *fdCache, indexCache *indexCache) tablePersister {
+func newFSTablePersister(dir string, layout TableLayout, fc *fdCache, indexCache *indexCache, fsync, checksums bool) tablePersister {
 	d.PanicIfTrue(fc == nil)
-	return &fsTablePersister{dir, fc, indexCache}
+	return &fsTablePersister{dir, layout, fc, indexCache, fsync, checksums}
 }
 
 type fsTablePersister struct {
 	dir        string
+	layout     TableLayout
 	fc         *fdCache
 	indexCache *indexCache
+
+	// fsync makes each table file durable before it is renamed into place. See DurabilityPolicy.FsyncTables.
+	fsync bool
+
+	// checksums records a checksum of each table file as it is written. See LocalStoreOptions.TableChecksums.
+	checksums bool
 }
 
 func (ftp *fsTablePersister) Open(ctx context.Context, name addr, chunkCount uint32, stats *Stats) (chunkSource, error) {
-	return newMmapTableReader(ftp.dir, name, chunkCount, ftp.indexCache, ftp.fc)
+	return newMmapTableReader(ftp.layout.tableDir(ftp.dir, name.String()), name, chunkCount, ftp.indexCache, ftp.fc)
+}
+
+// deleteTable removes the table file |name|, and its checksum if it has one. It must only be called for tables which
+// no manifest references and which nothing is reading.
+func (ftp *fsTablePersister) deleteTable(name addr) error {
+	path := ftp.layout.tablePath(ftp.dir, name.String())
+	err := os.Remove(path)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path + tableChecksumExt)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// moveIntoPlace renames the temp file |tempName| to the path of the table named |name|, creating its directory if
+// the layout requires one.
+func (ftp *fsTablePersister) moveIntoPlace(tempName string, name addr) error {
+	tableDir := ftp.layout.tableDir(ftp.dir, name.String())
+
+	if tableDir != ftp.dir {
+		err := os.MkdirAll(tableDir, os.ModePerm)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err := os.Rename(tempName, filepath.Join(tableDir, name.String()))
+
+	if err != nil {
+		return err
+	}
+
+	if ftp.fsync {
+		return syncDir(tableDir)
+	}
+
+	return nil
+}
+
+func (ftp *fsTablePersister) syncTemp(temp *os.File) error {
+	if ftp.fsync {
+		return temp.Sync()
+	}
+
+	return nil
 }
 
 func (ftp *fsTablePersister) Persist(ctx context.Context, mt *memTable, haver chunkReader, stats *Stats) (chunkSource, error) {
@@ -87,6 +147,12 @@ func (ftp *fsTablePersister) persistTable(ctx context.Context, name addr, data [
 			return "", ferr
 		}
 
+		ferr = ftp.syncTemp(temp)
+
+		if ferr != nil {
+			return "", ferr
+		}
+
 		index, ferr := parseTableIndex(data)
 
 		if ferr != nil {
@@ -112,24 +178,42 @@ func (ftp *fsTablePersister) persistTable(ctx context.Context, name addr, data [
 		return nil, err
 	}
 
-	newName := filepath.Join(ftp.dir, name.String())
 	err = ftp.fc.ShrinkCache()
 
 	if err != nil {
 		return nil, err
 	}
 
-	err = os.Rename(tempName, newName)
+	err = ftp.moveIntoPlace(tempName, name)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if ftp.checksums {
+		err = ftp.writeChecksum(name, crc64.Checksum(data, crc64Table))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ftp.Open(ctx, name, chunkCount, stats)
 }
 
+// defaultConjoinMaxMem is the write buffer size ConjoinAll passes to ConjoinAllBounded.
+const defaultConjoinMaxMem = 1 << 22
+
 func (ftp *fsTablePersister) ConjoinAll(ctx context.Context, sources chunkSources, stats *Stats) (chunkSource, error) {
-	plan, err := planConjoin(sources, stats)
+	return ftp.ConjoinAllBounded(ctx, sources, defaultConjoinMaxMem, stats)
+}
+
+// ConjoinAllBounded conjoins all chunks in |sources| into a single, new chunkSource, as ConjoinAll does, while
+// buffering at most |maxMem| bytes of the new table in memory. The merged index is written as it is computed, by
+// merging the sources' indexes, rather than being built in memory first. The sources' own indexes are still read into
+// memory, as they are for any read of the sources.
+func (ftp *fsTablePersister) ConjoinAllBounded(ctx context.Context, sources chunkSources, maxMem uint64, stats *Stats) (chunkSource, error) {
+	plan, totalUncompressedData, err := planConjoinSources(sources)
 
 	if err != nil {
 		return emptyChunkSource{}, err
@@ -139,7 +223,8 @@ func (ftp *fsTablePersister) ConjoinAll(ctx context.Context, sources chunkSource
 		return emptyChunkSource{}, nil
 	}
 
-	name := nameFromSuffixes(plan.suffixes())
+	var name addr
+	checksum := newTableChecksum()
 	tempName, err := func() (tempName string, ferr error) {
 		var temp *os.File
 		temp, ferr = ioutil.TempFile(ftp.dir, tempTablePrefix)
@@ -156,42 +241,23 @@ func (ftp *fsTablePersister) ConjoinAll(ctx context.Context, sources chunkSource
 			}
 		}()
 
-		for _, sws := range plan.sources.sws {
-			var r io.Reader
-			r, ferr = sws.source.reader(ctx)
-
-			if ferr != nil {
-				return "", ferr
-			}
-
-			n, ferr := io.CopyN(temp, r, int64(sws.dataLen))
-
-			if ferr != nil {
-				return "", ferr
-			}
-
-			if uint64(n) != sws.dataLen {
-				return "", errors.New("failed to copy all data")
-			}
+		var w io.Writer = temp
+		if ftp.checksums {
+			w = io.MultiWriter(temp, checksum)
 		}
 
-		_, ferr = temp.Write(plan.mergedIndex)
+		name, ferr = writeConjoinedTable(ctx, w, plan, totalUncompressedData, maxMem)
 
 		if ferr != nil {
 			return "", ferr
 		}
 
-		var index tableIndex
-		index, ferr = parseTableIndex(plan.mergedIndex)
+		ferr = ftp.syncTemp(temp)
 
 		if ferr != nil {
 			return "", ferr
 		}
 
-		if ftp.indexCache != nil {
-			ftp.indexCache.put(name, index)
-		}
-
 		return temp.Name(), nil
 	}()
 
@@ -199,11 +265,21 @@ func (ftp *fsTablePersister) ConjoinAll(ctx context.Context, sources chunkSource
 		return nil, err
 	}
 
-	err = os.Rename(tempName, filepath.Join(ftp.dir, name.String()))
+	stats.BytesPerConjoin.Sample(plan.totalCompressedData + indexSize(plan.chunkCount) + footerSize)
+
+	err = ftp.moveIntoPlace(tempName, name)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if ftp.checksums {
+		err = ftp.writeChecksum(name, checksum.Sum64())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ftp.Open(ctx, name, plan.chunkCount, stats)
 }
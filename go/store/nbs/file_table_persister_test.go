@@ -25,6 +25,7 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"hash/crc64"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -42,7 +43,7 @@ func TestFSTableCacheOnOpen(t *testing.T) {
 	cacheSize := 2
 	fc := newFDCache(cacheSize)
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 
 	// Create some tables manually, load them into the cache
 	func() {
@@ -118,7 +119,7 @@ func TestFSTablePersisterPersist(t *testing.T) {
 	defer os.RemoveAll(dir)
 	fc := newFDCache(defaultMaxTables)
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 
 	src, err := persistTableData(fts, testChunks...)
 	assert.NoError(err)
@@ -156,7 +157,7 @@ func TestFSTablePersisterPersistNoData(t *testing.T) {
 	defer os.RemoveAll(dir)
 	fc := newFDCache(defaultMaxTables)
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 
 	src, err := fts.Persist(context.Background(), mt, existingTable, &Stats{})
 	assert.NoError(err)
@@ -171,7 +172,7 @@ func TestFSTablePersisterCacheOnPersist(t *testing.T) {
 	dir := makeTempDir(t)
 	fc := newFDCache(1)
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 	defer os.RemoveAll(dir)
 
 	var name addr
@@ -207,7 +208,7 @@ func TestFSTablePersisterConjoinAll(t *testing.T) {
 	defer os.RemoveAll(dir)
 	fc := newFDCache(len(sources))
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 
 	for i, c := range testChunks {
 		randChunk := make([]byte, (i+1)*13)
@@ -242,7 +243,7 @@ func TestFSTablePersisterConjoinAllDups(t *testing.T) {
 	defer os.RemoveAll(dir)
 	fc := newFDCache(defaultMaxTables)
 	defer fc.Drop()
-	fts := newFSTablePersister(dir, fc, nil)
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false)
 
 	reps := 3
 	sources := make(chunkSources, reps)
@@ -270,3 +271,117 @@ func TestFSTablePersisterConjoinAllDups(t *testing.T) {
 		assert.EqualValues(reps*len(testChunks), mustUint32(tr.count()))
 	}
 }
+
+func TestFSTablePersisterConjoinAllBounded(t *testing.T) {
+	assert := assert.New(t)
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	fc := newFDCache(defaultMaxTables)
+	defer fc.Drop()
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false).(*fsTablePersister)
+
+	// Every chunk is in two of the sources.
+	var allChunks [][]byte
+	sources := make(chunkSources, 6)
+	for i := range sources {
+		mt := newMemTable(1 << 16)
+		if i%2 == 1 {
+			for _, c := range allChunks[len(allChunks)-50:] {
+				mt.addChunk(computeAddr(c), c)
+			}
+		} else {
+			for j := 0; j < 50; j++ {
+				c := make([]byte, j+1)
+				_, err := rand.Read(c)
+				assert.NoError(err)
+				allChunks = append(allChunks, c)
+				mt.addChunk(computeAddr(c), c)
+			}
+		}
+
+		var err error
+		sources[i], err = fts.Persist(context.Background(), mt, nil, &Stats{})
+		assert.NoError(err)
+	}
+
+	plan, err := planConjoin(sources, &Stats{})
+	assert.NoError(err)
+
+	// A one byte buffer is flushed on every write.
+	src, err := fts.ConjoinAllBounded(context.Background(), sources, 1, &Stats{})
+	assert.NoError(err)
+	assert.Equal(nameFromSuffixes(plan.suffixes()), mustAddr(src.hash()))
+
+	buff, err := ioutil.ReadFile(filepath.Join(dir, mustAddr(src.hash()).String()))
+	assert.NoError(err)
+	ti, err := parseTableIndex(buff)
+	assert.NoError(err)
+	tr := newTableReader(ti, tableReaderAtFromBytes(buff), fileBlockSize)
+	assert.EqualValues(2*len(allChunks), mustUint32(tr.count()))
+
+	for _, c := range allChunks {
+		data, err := tr.get(context.Background(), computeAddr(c), &Stats{})
+		assert.NoError(err)
+		assert.Equal(c, data)
+	}
+}
+
+func TestFSTablePersisterTableFileChecksum(t *testing.T) {
+	assert := assert.New(t)
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	fc := newFDCache(defaultMaxTables)
+	defer fc.Drop()
+	fts := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, true).(*fsTablePersister)
+
+	sources := make(chunkSources, 2)
+	for i := range sources {
+		var err error
+		sources[i], err = persistTableData(fts, []byte(fmt.Sprintf("chunk %d", i)))
+		assert.NoError(err)
+	}
+
+	conjoined, err := fts.ConjoinAll(context.Background(), sources, &Stats{})
+	assert.NoError(err)
+
+	for _, src := range append(sources, conjoined) {
+		name := mustAddr(src.hash()).String()
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(err)
+
+		sum, err := fts.TableFileChecksum(name)
+		assert.NoError(err)
+		assert.Equal(crc64.Checksum(data, crc64Table), sum)
+		assert.NoError(fts.ValidateTableFile(name))
+	}
+
+	name := mustAddr(conjoined.hash()).String()
+	path := filepath.Join(dir, name)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+
+	// truncated in transfer
+	assert.NoError(ioutil.WriteFile(path, data[:len(data)-1], 0666))
+	assert.Equal(ErrTableFileChecksumMismatch, fts.ValidateTableFile(name))
+
+	// corrupted in transfer
+	corrupted := append([]byte{}, data...)
+	corrupted[0] ^= 0xff
+	assert.NoError(ioutil.WriteFile(path, corrupted, 0666))
+	assert.Equal(ErrTableFileChecksumMismatch, fts.ValidateTableFile(name))
+
+	// table files written without a checksum can't be checked
+	assert.NoError(os.Remove(path + tableChecksumExt))
+	_, err = fts.TableFileChecksum(name)
+	assert.Equal(ErrNoTableFileChecksum, err)
+	assert.Equal(ErrNoTableFileChecksum, fts.ValidateTableFile(name))
+
+	// and are all that a persister without checksums writes
+	unchecked := newFSTablePersister(dir, FlatTableLayout, fc, nil, false, false).(*fsTablePersister)
+	src, err := persistTableData(unchecked, []byte("unchecked chunk"))
+	assert.NoError(err)
+	name = mustAddr(src.hash()).String()
+	_, err = os.Stat(filepath.Join(dir, name) + tableChecksumExt)
+	assert.True(os.IsNotExist(err))
+	assert.Equal(ErrNoTableFileChecksum, unchecked.ValidateTableFile(name))
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly is returned by the methods of a follower store which would write to it.
+var ErrReadOnly = errors.New("store is read-only")
+
+var ErrInvalidPollInterval = errors.New("poll interval must be positive")
+
+// follower rebases a store onto the latest contents of its manifest every poll interval, until stopped.
+type follower struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// NewFollowerStore opens a read-only store on a manifest and table files written by other stores, such as those of a
+// primary sharing an object store bucket with its read replicas. Every |pollInterval| the store rebases onto the
+// manifest's latest contents, so that its reads follow the writer's commits. Between polls, reads see the root and
+// tables of the last successful rebase. Methods which would write to the store return ErrReadOnly. The manifest must
+// already exist. Close stops the polling.
+func NewFollowerStore(ctx context.Context, m Manifest, p TablePersister, pollInterval time.Duration) (*NomsBlockStore, error) {
+	if pollInterval <= 0 {
+		return nil, ErrInvalidPollInterval
+	}
+
+	cacheOnce.Do(makeGlobalCaches)
+	nbs, err := newNomsBlockStore(ctx, "", makeManifestManager(manifestAdapter{m}), tablePersisterAdapter{p, globalIndexCache}, newInlineConjoiner(DefaultConjoinPolicy), 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if nbs.upstream.vers == "" {
+		return nil, newStoreError(ErrNotFound, "no manifest to follow")
+	}
+
+	nbs.follower = &follower{stop: make(chan struct{}), done: make(chan struct{})}
+	go nbs.follow(pollInterval)
+
+	return nbs, nil
+}
+
+func (nbs *NomsBlockStore) follow(pollInterval time.Duration) {
+	defer close(nbs.follower.done)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-nbs.follower.stop:
+			return
+		case <-ticker.C:
+			err := nbs.Rebase(context.Background())
+
+			nbs.follower.mu.Lock()
+			nbs.follower.lastErr = err
+			nbs.follower.mu.Unlock()
+		}
+	}
+}
+
+// FollowErr returns the error of the most recent rebase of a follower store, or nil if it succeeded. A failed rebase
+// is retried at the next poll. It is always nil for other stores.
+func (nbs *NomsBlockStore) FollowErr() error {
+	if nbs.follower == nil {
+		return nil
+	}
+
+	nbs.follower.mu.Lock()
+	defer nbs.follower.mu.Unlock()
+	return nbs.follower.lastErr
+}
+
+// stopFollowing stops the polling of a follower store, and waits for a rebase in progress to finish.
+func (nbs *NomsBlockStore) stopFollowing() {
+	if nbs.follower == nil {
+		return
+	}
+
+	nbs.follower.stopOnce.Do(func() {
+		close(nbs.follower.stop)
+	})
+	<-nbs.follower.done
+}
+
+func (nbs *NomsBlockStore) checkWritable() error {
+	if nbs.follower != nil {
+		return ErrReadOnly
+	}
+
+	return nil
+}
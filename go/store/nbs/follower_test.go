@@ -0,0 +1,105 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestFollowerStore(t *testing.T) {
+	ctx := context.Background()
+	m := &testManifest{name: "TestFollowerStore"}
+	p := newTestTablePersister()
+
+	newFollower := func(pollInterval time.Duration) (*NomsBlockStore, error) {
+		return NewFollowerStore(ctx, m, p, pollInterval)
+	}
+
+	writer, err := NewBlockStore(ctx, types.Format_Default.VersionString(), m, p, nil, defaultMemTableSize)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	_, err = newFollower(time.Millisecond)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	c1 := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, writer.Put(ctx, c1))
+	ok, err := writer.Commit(ctx, c1.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = newFollower(0)
+	assert.Equal(t, ErrInvalidPollInterval, err)
+
+	follower, err := newFollower(time.Millisecond)
+	require.NoError(t, err)
+	defer follower.Close()
+
+	assert.Equal(t, types.Format_Default.VersionString(), follower.Version())
+	root, err := follower.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c1.Hash(), root)
+
+	read, err := follower.Get(ctx, c1.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, c1.Data(), read.Data())
+
+	c2 := chunks.NewChunk([]byte("def"))
+	require.NoError(t, writer.Put(ctx, c2))
+	ok, err = writer.Commit(ctx, c2.Hash(), c1.Hash())
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for root != c2.Hash() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		root, err = follower.Root(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, c2.Hash(), root)
+	assert.NoError(t, follower.FollowErr())
+
+	read, err = follower.Get(ctx, c2.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, c2.Data(), read.Data())
+
+	c3 := chunks.NewChunk([]byte("ghi"))
+	assert.Equal(t, ErrReadOnly, follower.Put(ctx, c3))
+	_, err = follower.Commit(ctx, c1.Hash(), c2.Hash())
+	assert.Equal(t, ErrReadOnly, err)
+	_, err = follower.ForceSetRoot(ctx, c1.Hash(), c2.Hash())
+	assert.Equal(t, ErrReadOnly, err)
+	assert.Equal(t, ErrReadOnly, follower.PinRoot(ctx, c1.Hash()))
+	assert.False(t, follower.SupportedOperations().CanWrite)
+
+	tx := follower.Begin()
+	require.NoError(t, tx.Put(ctx, c3))
+	_, err = tx.Commit(ctx, c3.Hash())
+	assert.Equal(t, ErrReadOnly, err)
+
+	root, err = writer.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c2.Hash(), root)
+}
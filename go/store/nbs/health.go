@@ -0,0 +1,158 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// HealthCheck checks that the store's manifest can be read, that each table file it lists exists and has the number
+// of chunks the manifest records for it, and that the manifest's root chunk is in one of them. Only the footers of
+// local table files are read, so that it is cheap enough for a readiness probe however large the store is. Problems
+// are returned as StoreErrors of kind ErrNotFound or ErrCorrupt. A store whose manifest hasn't been written yet is
+// healthy.
+func (nbs *NomsBlockStore) HealthCheck(ctx context.Context) error {
+	exists, contents, err := nbs.mm.m.ParseIfExists(ctx, &Stats{}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	upstream := func() chunkSources {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		return nbs.tables.upstream
+	}()
+
+	fsPersister, isLocal := nbs.p.(*fsTablePersister)
+	hasRoot := contents.root.IsEmpty()
+	for _, spec := range contents.specs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if isLocal {
+			err = fsPersister.checkTableFooter(spec)
+
+			if err != nil {
+				return err
+			}
+
+			if hasRoot {
+				continue
+			}
+		}
+
+		src, err := findOrOpenTable(ctx, nbs.p, upstream, spec)
+
+		if err != nil {
+			return err
+		}
+
+		if !isLocal {
+			cnt, err := src.count()
+
+			if err != nil {
+				return err
+			}
+
+			if cnt != spec.chunkCount {
+				return &StoreError{ErrCorrupt, fmt.Errorf("table file %s has %d chunks, but the manifest lists %d", spec.name, cnt, spec.chunkCount)}
+			}
+		}
+
+		if !hasRoot {
+			hasRoot, err = src.has(addr(contents.root))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !hasRoot {
+		return &StoreError{ErrNotFound, fmt.Errorf("root chunk %s is missing", contents.root)}
+	}
+
+	return nil
+}
+
+// findOrOpenTable returns the table of |srcs| named by |spec|, opening it with |p| if there is none.
+func findOrOpenTable(ctx context.Context, p tablePersister, srcs chunkSources, spec tableSpec) (chunkSource, error) {
+	for _, src := range srcs {
+		h, err := src.hash()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if h == spec.name {
+			return src, nil
+		}
+	}
+
+	return p.Open(ctx, spec.name, spec.chunkCount, &Stats{})
+}
+
+// checkTableFooter checks that the table file named by |spec| exists and that its footer records the chunk count of
+// |spec|.
+func (ftp *fsTablePersister) checkTableFooter(spec tableSpec) error {
+	f, err := os.Open(ftp.layout.tablePath(ftp.dir, spec.name.String()))
+
+	if os.IsNotExist(err) {
+		return &StoreError{ErrNotFound, fmt.Errorf("table file %s is missing", spec.name)}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() < footerSize {
+		return &StoreError{ErrCorrupt, fmt.Errorf("table file %s is too short to have a footer", spec.name)}
+	}
+
+	footer := make([]byte, footerSize)
+	_, err = f.ReadAt(footer, info.Size()-footerSize)
+
+	if err != nil {
+		return err
+	}
+
+	if string(footer[uint32Size+uint64Size:]) != magicNumber {
+		return &StoreError{ErrCorrupt, fmt.Errorf("table file %s has an invalid footer", spec.name)}
+	}
+
+	if cnt := binary.BigEndian.Uint32(footer); cnt != spec.chunkCount {
+		return &StoreError{ErrCorrupt, fmt.Errorf("table file %s has %d chunks, but the manifest lists %d", spec.name, cnt, spec.chunkCount)}
+	}
+
+	return nil
+}
@@ -24,8 +24,11 @@ package nbs
 import (
 	"context"
 	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,7 +36,7 @@ import (
 	"github.com/liquidata-inc/dolt/go/store/hash"
 )
 
-var ErrCorruptManifest = errors.New("corrupt manifest")
+var ErrCorruptManifest = newStoreError(ErrCorrupt, "corrupt manifest")
 
 type manifest interface {
 	// Name returns a stable, unique identifier for the store this manifest describes.
@@ -90,6 +93,36 @@ type manifestContents struct {
 	lock  addr
 	root  hash.Hash
 	specs []tableSpec
+
+	// meta is the optional key/value metadata attached to |root| by the commit
+	// that set it. It is nil when the root was committed without metadata.
+	meta map[string]string
+
+	// metaHead is the address of the newest root meta record, from which the
+	// metadata of earlier roots can be found. It is the zero addr for stores
+	// which have never committed metadata.
+	metaHead addr
+
+	// layout is the TableLayout used by a local store for its table files. Only
+	// fileManifest persists it.
+	layout TableLayout
+
+	// pinned holds the roots pinned by NomsBlockStore.PinRoot, sorted. It is
+	// nil when there are none.
+	pinned []hash.Hash
+
+	// origin describes the creation of the store, for stores which record it.
+	// It is written with the store's first manifest and never changes. Only
+	// fileManifest and blobstoreManifest persist it.
+	origin storeOrigin
+}
+
+// storeOrigin records when a store was created, and the memtable size of the
+// store which created it. It is the zero value for stores whose manifests
+// predate it.
+type storeOrigin struct {
+	created      time.Time
+	memTableSize uint64
 }
 
 func (mc manifestContents) GetVersion() string {
@@ -112,11 +145,31 @@ func (mc manifestContents) GetTableSpecInfo(i int) TableSpecInfo {
 	return mc.specs[i]
 }
 
+// storageVersion returns the storage version of the manifest which records
+// |mc|: ExtendedStorageVersion if any optional field is set, StorageVersion
+// otherwise.
+func (mc manifestContents) storageVersion() string {
+	if len(mc.meta) > 0 || mc.metaHead != (addr{}) || mc.layout != FlatTableLayout || len(mc.pinned) > 0 || !mc.origin.created.IsZero() {
+		return ExtendedStorageVersion
+	}
+
+	return StorageVersion
+}
+
+// validStorageVersion returns whether |vers| is a storage version a manifest
+// recording |mc| could have been written with.
+func (mc manifestContents) validStorageVersion(vers string) bool {
+	return vers == ExtendedStorageVersion || vers == mc.storageVersion()
+}
+
 func (mc manifestContents) size() (size uint64) {
 	size += uint64(len(mc.vers)) + addrSize + hash.ByteLen
 	for _, sp := range mc.specs {
 		size += uint64(len(sp.name)) + uint32Size // for sp.chunkCount
 	}
+	for k, v := range mc.meta {
+		size += uint64(len(k) + len(v))
+	}
 	return
 }
 
@@ -339,18 +392,120 @@ func formatSpecs(specs []tableSpec, tableInfo []string) {
 	}
 }
 
-// generateLockHash returns a hash of root and the names of all the tables in
-// specs, which should be included in all persisted manifests. When a client
-// attempts to update a manifest, it must check the lock hash in the currently
-// persisted manifest against the lock hash it saw last time it loaded the
-// contents of a manifest. If they do not match, the client must not update
-// the persisted manifest.
-func generateLockHash(root hash.Hash, specs []tableSpec) (lock addr) {
+// encodeRootMeta serializes |meta| into a string which contains no manifest
+// field separators. A nil or empty |meta| encodes to the empty string.
+func encodeRootMeta(meta map[string]string) (string, error) {
+	if len(meta) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(meta)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeRootMeta is the inverse of encodeRootMeta.
+func decodeRootMeta(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, ErrCorruptManifest
+	}
+
+	var meta map[string]string
+	err = json.Unmarshal(data, &meta)
+
+	if err != nil {
+		return nil, ErrCorruptManifest
+	}
+
+	return meta, nil
+}
+
+// encodePinnedRoots serializes |pinned| into a string which contains no
+// manifest field separators.
+func encodePinnedRoots(pinned []hash.Hash) string {
+	strs := make([]string, len(pinned))
+	for i, h := range pinned {
+		strs[i] = h.String()
+	}
+
+	return strings.Join(strs, ",")
+}
+
+// encodeStoreOrigin serializes |origin| into a string which contains no
+// manifest field separators.
+func encodeStoreOrigin(origin storeOrigin) string {
+	return strconv.FormatInt(origin.created.UnixNano(), 10) + "," + strconv.FormatUint(origin.memTableSize, 10)
+}
+
+// decodeStoreOrigin is the inverse of encodeStoreOrigin.
+func decodeStoreOrigin(s string) (storeOrigin, error) {
+	strs := strings.Split(s, ",")
+
+	if len(strs) != 2 {
+		return storeOrigin{}, ErrCorruptManifest
+	}
+
+	created, err := strconv.ParseInt(strs[0], 10, 64)
+
+	if err != nil {
+		return storeOrigin{}, ErrCorruptManifest
+	}
+
+	memTableSize, err := strconv.ParseUint(strs[1], 10, 64)
+
+	if err != nil {
+		return storeOrigin{}, ErrCorruptManifest
+	}
+
+	return storeOrigin{time.Unix(0, created), memTableSize}, nil
+}
+
+// decodePinnedRoots is the inverse of encodePinnedRoots.
+func decodePinnedRoots(s string) ([]hash.Hash, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	strs := strings.Split(s, ",")
+	pinned := make([]hash.Hash, len(strs))
+	for i, str := range strs {
+		h, ok := hash.MaybeParse(str)
+
+		if !ok {
+			return nil, ErrCorruptManifest
+		}
+
+		pinned[i] = h
+	}
+
+	return pinned, nil
+}
+
+// generateLockHash returns a hash of root, the names of all the tables in
+// specs and the pinned roots, which should be included in all persisted
+// manifests. When a client attempts to update a manifest, it must check the
+// lock hash in the currently persisted manifest against the lock hash it saw
+// last time it loaded the contents of a manifest. If they do not match, the
+// client must not update the persisted manifest.
+func generateLockHash(root hash.Hash, specs []tableSpec, pinned []hash.Hash) (lock addr) {
 	blockHash := sha512.New()
 	blockHash.Write(root[:])
 	for _, spec := range specs {
 		blockHash.Write(spec.name[:])
 	}
+	for _, h := range pinned {
+		blockHash.Write(h[:])
+	}
 	var h []byte
 	h = blockHash.Sum(h) // Appends hash to h
 	copy(lock[:], h)
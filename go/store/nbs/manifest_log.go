@@ -0,0 +1,300 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// ErrNoManifestLog is returned by ReadManifestLog when the store has no ManifestLog.
+var ErrNoManifestLog = errors.New("store has no manifest log")
+
+// ErrCorruptManifestLog is returned when a ManifestLog entry can't be parsed.
+var ErrCorruptManifestLog = newStoreError(ErrCorrupt, "corrupt manifest log")
+
+// ManifestLogEntry describes one update of a store's manifest.
+type ManifestLogEntry struct {
+	Root      hash.Hash
+	Lock      hash.Hash
+	Specs     []TableSpecInfo
+	Timestamp time.Time
+}
+
+// ManifestLog records every update a store makes to its manifest, so that a follower can replay the sequence of roots
+// rather than only seeing the latest one.
+type ManifestLog interface {
+	// Append records |entry| durably.
+	Append(entry ManifestLogEntry) error
+
+	// Read returns the entries with timestamps no earlier than |since|, in the order they were appended.
+	Read(since time.Time) ([]ManifestLogEntry, error)
+}
+
+type fileManifestLog struct {
+	mu   sync.Mutex
+	path string
+
+	// complete is true once the file is known to end with a complete line.
+	complete bool
+}
+
+// NewFileManifestLog returns a ManifestLog which appends entries to the file at |path|, creating it if needed. Each
+// entry is a line which is synced to disk before Append returns. A partial line left by a crash is ignored by Read,
+// and is truncated by the next Append before it writes its entry.
+func NewFileManifestLog(path string) ManifestLog {
+	return &fileManifestLog{path: path}
+}
+
+func (fl *fileManifestLog) Append(entry ManifestLogEntry) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	strs := make([]string, 2*len(entry.Specs)+3)
+	strs[0], strs[1], strs[2] = strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Lock.String(), entry.Root.String()
+	for i, spec := range entry.Specs {
+		strs[3+2*i] = spec.GetName()
+		strs[4+2*i] = strconv.FormatUint(uint64(spec.GetChunkCount()), 10)
+	}
+
+	f, err := os.OpenFile(fl.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if !fl.complete {
+		err = truncatePartialLine(f)
+	}
+
+	if err == nil {
+		_, err = io.WriteString(f, strings.Join(strs, ":")+"\n")
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	closeErr := f.Close()
+
+	if err != nil {
+		// a failed write may have left a partial line
+		fl.complete = false
+		return err
+	}
+
+	fl.complete = true
+	return closeErr
+}
+
+// truncatePartialLine truncates |f| after its last newline, dropping a line which was never fully appended.
+func truncatePartialLine(f *os.File) error {
+	fi, err := f.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	end := fi.Size()
+	buff := make([]byte, 4096)
+	for end > 0 {
+		n := int64(len(buff))
+		if n > end {
+			n = end
+		}
+
+		_, err := f.ReadAt(buff[:n], end-n)
+
+		if err != nil {
+			return err
+		}
+
+		if i := bytes.LastIndexByte(buff[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+
+		end -= n
+	}
+
+	if end == fi.Size() {
+		return nil
+	}
+
+	return f.Truncate(end)
+}
+
+func (fl *fileManifestLog) Read(since time.Time) ([]ManifestLogEntry, error) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	f, err := os.Open(fl.path)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var entries []ManifestLogEntry
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+
+		if err == io.EOF {
+			// an incomplete last line was never fully appended
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry, err := parseManifestLogEntry(strings.TrimSuffix(line, "\n"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !entry.Timestamp.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+}
+
+func parseManifestLogEntry(line string) (ManifestLogEntry, error) {
+	slices := strings.Split(line, ":")
+	if len(slices) < 3 || len(slices)%2 == 0 {
+		return ManifestLogEntry{}, ErrCorruptManifestLog
+	}
+
+	ts, err := strconv.ParseInt(slices[0], 10, 64)
+
+	if err != nil {
+		return ManifestLogEntry{}, ErrCorruptManifestLog
+	}
+
+	lock, ok := hash.MaybeParse(slices[1])
+
+	if !ok {
+		return ManifestLogEntry{}, ErrCorruptManifestLog
+	}
+
+	root, ok := hash.MaybeParse(slices[2])
+
+	if !ok {
+		return ManifestLogEntry{}, ErrCorruptManifestLog
+	}
+
+	specs, err := parseSpecs(slices[3:])
+
+	if err != nil {
+		return ManifestLogEntry{}, ErrCorruptManifestLog
+	}
+
+	return ManifestLogEntry{Root: root, Lock: lock, Specs: tableSpecInfos(specs), Timestamp: time.Unix(0, ts)}, nil
+}
+
+// SetManifestLog makes the store append an entry to |log| for every manifest update of a Commit or a conjoin which
+// succeeds. An entry is appended after the manifest is updated, so a failed append doesn't fail the update; its error
+// is returned by ManifestLogErr instead. A nil |log| stops logging.
+func (nbs *NomsBlockStore) SetManifestLog(log ManifestLog) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.manifestLog = log
+	nbs.manifestLogErr = nil
+}
+
+// ManifestLogErr returns the error of the most recent append to the store's ManifestLog, or nil if it succeeded. An
+// update whose append failed is missing from the log.
+func (nbs *NomsBlockStore) ManifestLogErr() error {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return nbs.manifestLogErr
+}
+
+// ReadManifestLog returns the entries of the store's ManifestLog with timestamps no earlier than |since|.
+func (nbs *NomsBlockStore) ReadManifestLog(since time.Time) ([]ManifestLogEntry, error) {
+	nbs.mu.RLock()
+	log := nbs.manifestLog
+	nbs.mu.RUnlock()
+
+	if log == nil {
+		return nil, ErrNoManifestLog
+	}
+
+	return log.Read(since)
+}
+
+// AllKnownRoots returns the roots the store's manifest has recorded, oldest first, ending with the current root. When
+// the store has a ManifestLog these are the roots of its entries, with a root repeated only when the manifest returned
+// to it after recording another, as when a commit is undone. Without one only the current root is known. The empty
+// root of a store which has never been committed to is left out.
+func (nbs *NomsBlockStore) AllKnownRoots(ctx context.Context) ([]hash.Hash, error) {
+	nbs.mu.RLock()
+	log, current := nbs.manifestLog, nbs.upstream.root
+	nbs.mu.RUnlock()
+
+	var roots []hash.Hash
+	add := func(root hash.Hash) {
+		if !root.IsEmpty() && (len(roots) == 0 || roots[len(roots)-1] != root) {
+			roots = append(roots, root)
+		}
+	}
+
+	if log != nil {
+		entries, err := log.Read(time.Time{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			add(entry.Root)
+		}
+	}
+
+	add(current)
+	return roots, nil
+}
+
+// appendManifestLog appends an entry for |contents|, which the manifest has already recorded, to the store's
+// ManifestLog, and records the error of the append for ManifestLogErr. The caller must hold nbs.mu.
+func (nbs *NomsBlockStore) appendManifestLog(contents manifestContents) {
+	if nbs.manifestLog == nil {
+		return
+	}
+
+	nbs.manifestLogErr = nbs.manifestLog.Append(ManifestLogEntry{
+		Root:      contents.root,
+		Lock:      hash.Hash(contents.lock),
+		Specs:     tableSpecInfos(contents.specs),
+		Timestamp: time.Now(),
+	})
+}
@@ -144,7 +144,12 @@ func (mt *memTable) getMany(ctx context.Context, reqs []getRecord, foundChunks c
 		data := mt.chunks[*r.a]
 		if data != nil {
 			c := chunks.NewChunkWithHash(hash.Hash(*r.a), data)
-			foundChunks <- &c
+			select {
+			case foundChunks <- &c:
+			case <-ctx.Done():
+				ae.SetIfError(ctx.Err())
+				return false
+			}
 		} else {
 			remaining = true
 		}
@@ -159,7 +164,12 @@ func (mt *memTable) getManyCompressed(ctx context.Context, reqs []getRecord, fou
 		data := mt.chunks[*r.a]
 		if data != nil {
 			c := chunks.NewChunkWithHash(hash.Hash(*r.a), data)
-			foundCmpChunks <- ChunkToCompressedChunk(c)
+			select {
+			case foundCmpChunks <- ChunkToCompressedChunk(c):
+			case <-ctx.Done():
+				ae.SetIfError(ctx.Err())
+				return false
+			}
 		} else {
 			remaining = true
 		}
@@ -170,7 +180,11 @@ func (mt *memTable) getManyCompressed(ctx context.Context, reqs []getRecord, fou
 
 func (mt *memTable) extract(ctx context.Context, chunks chan<- extractRecord) error {
 	for _, hrec := range mt.order {
-		chunks <- extractRecord{a: *hrec.a, data: mt.chunks[*hrec.a], err: nil}
+		select {
+		case chunks <- extractRecord{a: *hrec.a, data: mt.chunks[*hrec.a], err: nil}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	return nil
@@ -0,0 +1,52 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// ErrChunkNotFound is returned by Get and GetMany for absent chunks when a store's MissingChunkPolicy is
+// ReturnErrorForMissingChunks. The error is a StoreError of kind ErrNotFound.
+var ErrChunkNotFound = errors.New("chunk not found")
+
+// MissingChunkPolicy controls what Get and GetMany do when asked for a chunk which is not in the store.
+type MissingChunkPolicy int
+
+const (
+	// ReturnEmptyForMissingChunks has Get return chunks.EmptyChunk for an absent chunk, and GetMany skip it, without
+	// an error. Callers must check for the empty chunk themselves.
+	ReturnEmptyForMissingChunks MissingChunkPolicy = iota
+
+	// ReturnErrorForMissingChunks has Get return ErrChunkNotFound for an absent chunk. GetMany still sends every
+	// chunk which is present, and then returns ErrChunkNotFound if any were absent.
+	ReturnErrorForMissingChunks
+)
+
+// DefaultMissingChunkPolicy returns empty chunks, as stores always have.
+const DefaultMissingChunkPolicy = ReturnEmptyForMissingChunks
+
+func newChunkNotFoundError(missing hash.HashSet) *StoreError {
+	if len(missing) == 1 {
+		for h := range missing {
+			return &StoreError{ErrNotFound, fmt.Errorf("%w: %s", ErrChunkNotFound, h.String())}
+		}
+	}
+
+	return &StoreError{ErrNotFound, fmt.Errorf("%w: %d chunks missing", ErrChunkNotFound, len(missing))}
+}
@@ -98,7 +98,7 @@ func newMmapTableReader(dir string, h addr, chunkCount uint32, indexCache *index
 
 			if fi.Size() < 0 {
 				// Size returns the number of bytes for regular files and is system dependant for others (Some of which can be negative).
-				err = fmt.Errorf("%s has invalid size: %d", path, fi.Size())
+				err = &StoreError{ErrCorrupt, fmt.Errorf("%s has invalid size: %d", path, fi.Size())}
 				return
 			}
 
@@ -46,6 +46,16 @@ func (nbsMW *NBSMetricWrapper) Sources(ctx context.Context) (hash.Hash, []TableF
 	return nbsMW.nbs.Sources(ctx)
 }
 
+// TableFileChecksum returns the checksum recorded for the table file |fileId|
+func (nbsMW *NBSMetricWrapper) TableFileChecksum(fileId string) (uint64, error) {
+	return nbsMW.nbs.TableFileChecksum(fileId)
+}
+
+// RecordTableFileChecksum records |sum| as the checksum of the table file |fileId| after checking the file against it
+func (nbsMW *NBSMetricWrapper) RecordTableFileChecksum(fileId string, sum uint64) error {
+	return nbsMW.nbs.RecordTableFileChecksum(fileId, sum)
+}
+
 // WriteTableFile will read a table file from the provided reader and write it to the TableFileStore
 func (nbsMW *NBSMetricWrapper) WriteTableFile(ctx context.Context, fileId string, numChunks int, rd io.Reader, contentLength uint64, contentHash []byte) error {
 	return nbsMW.nbs.WriteTableFile(ctx, fileId, numChunks, rd, contentLength, contentHash)
@@ -0,0 +1,126 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nbs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// rootMetaRecordPrefix begins the chunk data of every root meta record, so that a record is never mistaken for, or
+// has the same address as, a chunk of Noms data.
+const rootMetaRecordPrefix = "nbs-root-meta:"
+
+var ErrCorruptRootMeta = newStoreError(ErrCorrupt, "corrupt root meta record")
+
+var errRootMetaTooLarge = errors.New("root meta is too large for the memtable")
+
+// rootMetaRecord is the metadata CommitWithMeta attached to a root. Records are stored as chunks, written with the
+// chunks of the commit which attaches them, so that the metadata of a root outlives its turn as the store's current
+// root. Each record refers to the record of the previous root committed with metadata, and the manifest refers to the
+// newest, so that the records form a chain from the newest root to the oldest.
+type rootMetaRecord struct {
+	Root string            `json:"root"`
+	Prev string            `json:"prev,omitempty"`
+	Meta map[string]string `json:"meta"`
+}
+
+func encodeRootMetaRecord(root hash.Hash, meta map[string]string, prev addr) ([]byte, error) {
+	rec := rootMetaRecord{Root: root.String(), Meta: meta}
+	if prev != (addr{}) {
+		rec.Prev = prev.String()
+	}
+
+	data, err := json.Marshal(rec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(rootMetaRecordPrefix), data...), nil
+}
+
+func decodeRootMetaRecord(data []byte) (root hash.Hash, meta map[string]string, prev addr, err error) {
+	if len(data) < len(rootMetaRecordPrefix) || string(data[:len(rootMetaRecordPrefix)]) != rootMetaRecordPrefix {
+		return hash.Hash{}, nil, addr{}, ErrCorruptRootMeta
+	}
+
+	var rec rootMetaRecord
+	err = json.Unmarshal(data[len(rootMetaRecordPrefix):], &rec)
+
+	if err != nil {
+		return hash.Hash{}, nil, addr{}, ErrCorruptRootMeta
+	}
+
+	root, ok := hash.MaybeParse(rec.Root)
+
+	if !ok {
+		return hash.Hash{}, nil, addr{}, ErrCorruptRootMeta
+	}
+
+	if rec.Prev != "" {
+		prev, err = parseAddr([]byte(rec.Prev))
+
+		if err != nil {
+			return hash.Hash{}, nil, addr{}, ErrCorruptRootMeta
+		}
+	}
+
+	return root, rec.Meta, prev, nil
+}
+
+// addRootMetaRecord adds the record of |meta| being attached to |root| to the store's pending chunks, unless it is
+// already in the store, and returns the address of the newest root meta record once the commit of |root| lands.
+// Recommitting the current root with the same metadata adds no record. The caller must hold nbs.mu.
+func (nbs *NomsBlockStore) addRootMetaRecord(ctx context.Context, root hash.Hash, meta map[string]string) (addr, error) {
+	if meta == nil || (root == nbs.upstream.root && reflect.DeepEqual(meta, nbs.upstream.meta)) {
+		return nbs.upstream.metaHead, nil
+	}
+
+	data, err := encodeRootMetaRecord(root, meta, nbs.upstream.metaHead)
+
+	if err != nil {
+		return addr{}, err
+	}
+
+	a := computeAddr(data)
+	has, err := nbs.tables.has(a)
+
+	if err != nil {
+		return addr{}, err
+	}
+
+	if has {
+		return a, nil
+	}
+
+	if nbs.mt == nil {
+		nbs.mt = newMemTable(nbs.mtSize)
+	}
+
+	if !nbs.mt.addChunk(a, data) {
+		nbs.prependMemTable(ctx)
+		nbs.mt = newMemTable(nbs.mtSize)
+
+		if !nbs.mt.addChunk(a, data) {
+			return addr{}, errRootMetaTooLarge
+		}
+	}
+
+	return a, nil
+}
@@ -89,6 +89,47 @@ func TestChunkStoreRebase(t *testing.T) {
 	assertDataInStore(chunks, store, assert)
 }
 
+func TestChunkStoreForceRootRefresh(t *testing.T) {
+	assert := assert.New(t)
+	fm, p, store := makeStoreWithFakes(t)
+	defer store.Close()
+
+	newRoot, chunks, err := interloperWrite(fm, p, []byte("new root"), []byte("hello2"), []byte("goodbye2"))
+	assert.NoError(err)
+
+	// Root is served from memory and doesn't see the interloper
+	h, err := store.Root(context.Background())
+	assert.NoError(err)
+	assert.Equal(hash.Hash{}, h)
+
+	h, err = store.ForceRootRefresh(context.Background())
+	assert.NoError(err)
+	assert.Equal(newRoot, h)
+	h, err = store.Root(context.Background())
+	assert.NoError(err)
+	assert.Equal(newRoot, h)
+	assertDataInStore(chunks, store, assert)
+
+	// an unchanged manifest leaves the tables as they were
+	tables := store.tables
+	h, err = store.ForceRootRefresh(context.Background())
+	assert.NoError(err)
+	assert.Equal(newRoot, h)
+	assert.Equal(tables, store.tables)
+
+	// a local commit is visible immediately
+	committed := hash.Of([]byte("committed root"))
+	success, err := store.Commit(context.Background(), committed, newRoot)
+	assert.NoError(err)
+	assert.True(success)
+	h, err = store.Root(context.Background())
+	assert.NoError(err)
+	assert.Equal(committed, h)
+	h, err = store.ForceRootRefresh(context.Background())
+	assert.NoError(err)
+	assert.Equal(committed, h)
+}
+
 func TestChunkStoreCommit(t *testing.T) {
 	assert := assert.New(t)
 	_, _, store := makeStoreWithFakes(t)
@@ -162,7 +203,7 @@ func TestChunkStoreManifestFirstWriteByOtherProcess(t *testing.T) {
 	newRoot, chunks, err := interloperWrite(fm, p, []byte("new root"), []byte("hello2"), []byte("goodbye2"), []byte("badbye2"))
 	assert.NoError(err)
 
-	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, inlineConjoiner{defaultMaxTables}, defaultMemTableSize)
+	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, newInlineConjoiner(DefaultConjoinPolicy), defaultMemTableSize)
 	assert.NoError(err)
 	defer store.Close()
 
@@ -197,7 +238,7 @@ func TestChunkStoreManifestPreemptiveOptimisticLockFail(t *testing.T) {
 	fm := &fakeManifest{}
 	mm := manifestManager{fm, newManifestCache(defaultManifestCacheSize), newManifestLocks()}
 	p := newFakeTablePersister()
-	c := inlineConjoiner{defaultMaxTables}
+	c := newInlineConjoiner(DefaultConjoinPolicy)
 
 	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, c, defaultMemTableSize)
 	assert.NoError(err)
@@ -240,7 +281,7 @@ func TestChunkStoreCommitLocksOutFetch(t *testing.T) {
 	upm := &updatePreemptManifest{manifest: fm}
 	mm := manifestManager{upm, newManifestCache(defaultManifestCacheSize), newManifestLocks()}
 	p := newFakeTablePersister()
-	c := inlineConjoiner{defaultMaxTables}
+	c := newInlineConjoiner(DefaultConjoinPolicy)
 
 	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, c, defaultMemTableSize)
 	assert.NoError(err)
@@ -281,7 +322,7 @@ func TestChunkStoreSerializeCommits(t *testing.T) {
 	mc := newManifestCache(defaultManifestCacheSize)
 	l := newManifestLocks()
 	p := newFakeTablePersister()
-	c := inlineConjoiner{defaultMaxTables}
+	c := newInlineConjoiner(DefaultConjoinPolicy)
 
 	store, err := newNomsBlockStore(context.Background(), constants.Format718String, manifestManager{upm, mc, l}, p, c, defaultMemTableSize)
 	assert.NoError(err)
@@ -338,7 +379,7 @@ func makeStoreWithFakes(t *testing.T) (fm *fakeManifest, p tablePersister, store
 	fm = &fakeManifest{}
 	mm := manifestManager{fm, newManifestCache(0), newManifestLocks()}
 	p = newFakeTablePersister()
-	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, inlineConjoiner{defaultMaxTables}, 0)
+	store, err := newNomsBlockStore(context.Background(), constants.Format718String, mm, p, newInlineConjoiner(DefaultConjoinPolicy), 0)
 	assert.NoError(t, err)
 	return
 }
@@ -405,7 +446,7 @@ func (fm *fakeManifest) Update(ctx context.Context, lastLock addr, newContents m
 	fm.mu.Lock()
 	defer fm.mu.Unlock()
 	if fm.contents.lock == lastLock {
-		fm.contents = manifestContents{newContents.vers, newContents.lock, newContents.root, nil}
+		fm.contents = manifestContents{newContents.vers, newContents.lock, newContents.root, nil, newContents.meta, newContents.metaHead, newContents.layout, newContents.pinned, newContents.origin}
 		fm.contents.specs = make([]tableSpec, len(newContents.specs))
 		copy(fm.contents.specs, newContents.specs)
 	}
@@ -413,7 +454,7 @@ func (fm *fakeManifest) Update(ctx context.Context, lastLock addr, newContents m
 }
 
 func (fm *fakeManifest) set(version string, lock addr, root hash.Hash, specs []tableSpec) {
-	fm.contents = manifestContents{version, lock, root, specs}
+	fm.contents = manifestContents{version, lock, root, specs, nil, addr{}, FlatTableLayout, nil, storeOrigin{}}
 }
 
 func newFakeTableSet() tableSet {
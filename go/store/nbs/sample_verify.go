@@ -0,0 +1,131 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// SampleVerifyError is returned by SampleVerify when sampled chunks are missing from the store or their data
+// doesn't hash to their address.
+type SampleVerifyError struct {
+	// Failed holds the addresses of the sampled chunks which failed verification, in sorted order.
+	Failed hash.HashSlice
+}
+
+func (e *SampleVerifyError) Error() string {
+	return fmt.Sprintf("%d sampled chunks failed verification", len(e.Failed))
+}
+
+// SampleVerify reads a random sample of |hashes|, each address being chosen with probability |sampleRate|, and
+// checks that every sampled chunk is present and hashes to its address. This is a cheaper check than reading every
+// chunk, such as before trusting a manifest received from a remote. If any sample fails, false is returned along with
+// a *SampleVerifyError listing the failures.
+func (nbs *NomsBlockStore) SampleVerify(ctx context.Context, hashes hash.HashSet, sampleRate float64) (bool, error) {
+	return nbs.SampleVerifyWithSeed(ctx, hashes, sampleRate, time.Now().UnixNano())
+}
+
+// SampleVerifyWithSeed is like SampleVerify, but samples deterministically using |seed|, so that the same hashes,
+// rate and seed always sample the same chunks.
+func (nbs *NomsBlockStore) SampleVerifyWithSeed(ctx context.Context, hashes hash.HashSet, sampleRate float64, seed int64) (bool, error) {
+	sample := sampleHashes(hashes, sampleRate, rand.New(rand.NewSource(seed)))
+
+	if len(sample) == 0 {
+		return true, nil
+	}
+
+	unverified := make(hash.HashSet, len(sample))
+	for h := range sample {
+		unverified.Insert(h)
+	}
+
+	var failed hash.HashSlice
+	found := make(chan *chunks.Chunk, len(sample))
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for c := range found {
+			unverified.Remove(c.Hash())
+
+			if hash.Of(c.Data()) != c.Hash() {
+				failed = append(failed, c.Hash())
+			}
+		}
+	}()
+
+	err := nbs.GetMany(ctx, sample, found)
+	close(found)
+	wg.Wait()
+
+	if err != nil && !errors.Is(err, ErrCorrupt) {
+		return false, err
+	}
+
+	// A corrupt chunk fails the whole GetMany, so the chunks it didn't deliver are verified one at a time to find
+	// out which are at fault. After a successful GetMany, the undelivered chunks are missing.
+	for h := range unverified {
+		if err == nil {
+			failed = append(failed, h)
+			continue
+		}
+
+		c, getErr := nbs.Get(ctx, h)
+
+		if getErr != nil && !errors.Is(getErr, ErrCorrupt) {
+			return false, getErr
+		}
+
+		if getErr != nil || c.IsEmpty() || hash.Of(c.Data()) != h {
+			failed = append(failed, h)
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Sort(failed)
+		return false, &SampleVerifyError{failed}
+	}
+
+	return true, nil
+}
+
+// sampleHashes chooses each of |hashes| with probability |rate|. The hashes are considered in sorted order so that
+// the choice depends only on |rnd|, not on map iteration order.
+func sampleHashes(hashes hash.HashSet, rate float64, rnd *rand.Rand) hash.HashSet {
+	sorted := make(hash.HashSlice, 0, len(hashes))
+	for h := range hashes {
+		sorted = append(sorted, h)
+	}
+
+	sort.Sort(sorted)
+
+	sample := hash.HashSet{}
+	for _, h := range sorted {
+		if rnd.Float64() < rate {
+			sample.Insert(h)
+		}
+	}
+
+	return sample
+}
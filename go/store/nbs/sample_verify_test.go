@@ -0,0 +1,115 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/constants"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+func TestSampleHashesDeterministic(t *testing.T) {
+	hashes := hash.HashSet{}
+	for i := 0; i < 100; i++ {
+		hashes.Insert(hash.Of([]byte{byte(i)}))
+	}
+
+	sample := sampleHashes(hashes, 0.5, rand.New(rand.NewSource(42)))
+	assert.Equal(t, sample, sampleHashes(hashes, 0.5, rand.New(rand.NewSource(42))))
+	assert.True(t, len(sample) > 0 && len(sample) < len(hashes))
+
+	assert.Empty(t, sampleHashes(hashes, 0, rand.New(rand.NewSource(42))))
+	assert.Equal(t, hashes, sampleHashes(hashes, 1, rand.New(rand.NewSource(42))))
+}
+
+func TestSampleVerify(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewLocalStore(ctx, constants.FormatDefaultString, dir, testMemTableSize)
+	require.NoError(t, err)
+
+	// each chunk is committed to its own table file
+	var chnx []chunks.Chunk
+	for _, data := range []string{"abc", "def", "ghi"} {
+		c := chunks.NewChunk([]byte(data))
+		chnx = append(chnx, c)
+		require.NoError(t, store.Put(ctx, c))
+		root, err := store.Root(ctx)
+		require.NoError(t, err)
+		success, err := store.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, success)
+	}
+
+	hashes := hash.HashSet{}
+	for _, c := range chnx {
+		hashes.Insert(c.Hash())
+	}
+
+	ok, err := store.SampleVerify(ctx, hashes, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	missing := hash.Of([]byte("missing"))
+	hashes.Insert(missing)
+
+	ok, err = store.SampleVerifyWithSeed(ctx, hashes, 0, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.SampleVerifyWithSeed(ctx, hashes, 1, 1)
+	assert.False(t, ok)
+	var verifyErr *SampleVerifyError
+	require.True(t, errors.As(err, &verifyErr))
+	assert.Equal(t, hash.HashSlice{missing}, verifyErr.Failed)
+
+	// corrupt the table file holding the first chunk
+	locations, err := store.LocateChunk(ctx, chnx[0].Hash())
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	require.NoError(t, store.Close())
+
+	path := filepath.Join(dir, locations[0])
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	data[0] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	store, err = NewLocalStore(ctx, constants.FormatDefaultString, dir, testMemTableSize)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ok, err = store.SampleVerifyWithSeed(ctx, hashes, 1, 1)
+	assert.False(t, ok)
+	require.True(t, errors.As(err, &verifyErr))
+	expected := hash.HashSlice{chnx[0].Hash(), missing}
+	sort.Sort(expected)
+	assert.Equal(t, expected, verifyErr.Failed)
+}
@@ -68,6 +68,10 @@ type Stats struct {
 
 	ReadManifestLatency  metrics.Histogram
 	WriteManifestLatency metrics.Histogram
+
+	// FragmentationRatio is the store's FragmentationRatio when the stats were taken by NomsBlockStore.Stats, or zero
+	// if it couldn't be computed.
+	FragmentationRatio float64
 }
 
 func NewStats() *Stats {
@@ -128,6 +132,7 @@ ChunksPerConjoin:                 %s
 TablesPerConjoin:                 %s
 ReadManifestLatency:              %s
 WriteManifestLatency:             %s
+FragmentationRatio:               %.2f
 `,
 		s.OpenLatency,
 		s.CommitLatency,
@@ -167,5 +172,6 @@ WriteManifestLatency:             %s
 		s.ChunksPerConjoin,
 		s.TablesPerConjoin,
 		s.ReadManifestLatency,
-		s.WriteManifestLatency)
+		s.WriteManifestLatency,
+		s.FragmentationRatio)
 }
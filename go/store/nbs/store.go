@@ -22,6 +22,7 @@
 package nbs
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -30,6 +31,7 @@ import (
 	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -43,6 +45,9 @@ import (
 )
 
 var ErrFetchFailure = errors.New("fetch failed")
+var ErrUncommittedChunks = errors.New("store closed with uncommitted chunks")
+var ErrEmptyChunk = errors.New("NBS blocks cannot be zero length")
+var ErrUnknownRoot = errors.New("root is not in any table in the manifest")
 
 // The root of a Noms Chunk Store is stored in a 'manifest', along with the
 // names of the tables that hold all the chunks in the store. The number of
@@ -52,6 +57,12 @@ const (
 	// StorageVersion is the version of the on-disk Noms Chunks Store data format.
 	StorageVersion = "4"
 
+	// ExtendedStorageVersion is the version of manifests which record any of the
+	// optional manifest fields, such as root metadata. Manifests without them are
+	// still written with StorageVersion, so that they stay readable by binaries
+	// which predate the optional fields.
+	ExtendedStorageVersion = "5"
+
 	defaultMemTableSize uint64 = (1 << 20) * 128 // 128MB
 	defaultMaxTables           = 256
 
@@ -86,8 +97,23 @@ type NomsBlockStore struct {
 	tables   tableSet
 	upstream manifestContents
 
-	mtSize   uint64
-	putCount uint64
+	mtSize        uint64
+	putCount      uint64
+	appendPolicy  AppendPolicy
+	missingChunks MissingChunkPolicy
+
+	conjoinObserver  ConjoinObserver
+	manifestLog      ManifestLog
+	manifestLogErr   error
+	diskSpaceMonitor *DiskSpaceMonitor
+
+	detectDuplicatePuts bool
+	dedupHits           uint64
+
+	verifyOnRead bool
+
+	// follower is set for stores opened with NewFollowerStore, which are read-only
+	follower *follower
 
 	stats *Stats
 }
@@ -182,6 +208,10 @@ func (nbs *NomsBlockStore) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash
 }
 
 func (nbs *NomsBlockStore) UpdateManifest(ctx context.Context, updates map[hash.Hash]uint32) (mi ManifestInfo, err error) {
+	if err := nbs.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	nbs.mm.LockForUpdate()
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
@@ -202,7 +232,7 @@ func (nbs *NomsBlockStore) UpdateManifest(ctx context.Context, updates map[hash.
 	if err != nil {
 		return manifestContents{}, err
 	} else if !ok {
-		contents = manifestContents{vers: nbs.upstream.vers}
+		contents = manifestContents{vers: nbs.upstream.vers, layout: nbs.upstream.layout, origin: nbs.upstream.origin}
 	}
 
 	currSpecs := make(map[addr]bool)
@@ -257,7 +287,7 @@ func NewAWSStore(ctx context.Context, nbfVerStr string, table, ns, bucket string
 		ns,
 	}
 	mm := makeManifestManager(newDynamoManifest(table, ns, ddb))
-	return newNomsBlockStore(ctx, nbfVerStr, mm, p, inlineConjoiner{defaultMaxTables}, memTableSize)
+	return newNomsBlockStore(ctx, nbfVerStr, mm, p, newInlineConjoiner(DefaultConjoinPolicy), memTableSize)
 }
 
 // NewGCSStore returns an nbs implementation backed by a GCSBlobstore
@@ -269,10 +299,56 @@ func NewGCSStore(ctx context.Context, nbfVerStr string, bucketName, path string,
 	mm := makeManifestManager(blobstoreManifest{"manifest", bs})
 
 	p := &blobstorePersister{bs, s3BlockSize, globalIndexCache}
-	return newNomsBlockStore(ctx, nbfVerStr, mm, p, inlineConjoiner{defaultMaxTables}, memTableSize)
+	return newNomsBlockStore(ctx, nbfVerStr, mm, p, newInlineConjoiner(DefaultConjoinPolicy), memTableSize)
 }
 
 func NewLocalStore(ctx context.Context, nbfVerStr string, dir string, memTableSize uint64) (*NomsBlockStore, error) {
+	return NewLocalStoreWithOptions(ctx, nbfVerStr, dir, memTableSize, DefaultLocalStoreOptions)
+}
+
+// LocalStoreOptions configures a local store opened by NewLocalStoreWithOptions. Callers should start from
+// DefaultLocalStoreOptions and change only the fields they need, since the zero value of some policies differs from
+// their default.
+type LocalStoreOptions struct {
+	// Layout is how a new store places its table files, and is recorded in the manifest. An existing store always
+	// uses the layout recorded in its manifest, regardless of Layout.
+	Layout TableLayout
+
+	// ConjoinPolicy controls when the store conjoins its tables. A bulk import, for example, can defer conjoining by
+	// raising MaxTables and then reopen the store with the default policy to compact once at the end.
+	ConjoinPolicy ConjoinPolicy
+
+	// DurabilityPolicy controls which of the store's writes are fsynced.
+	DurabilityPolicy DurabilityPolicy
+
+	// AppendPolicy controls when full memtables are appended to recent tables rather than written as new ones.
+	AppendPolicy AppendPolicy
+
+	// MissingChunkPolicy controls what Get and GetMany do when asked for a chunk which is not in the store.
+	MissingChunkPolicy MissingChunkPolicy
+
+	// TableChecksums records a CRC-64 of each table file the store writes, in a file alongside it named for the table
+	// file with the extension ".crc64". ValidateTableFiles checks table files against them, and Clone checks the table
+	// files it copies between two such stores.
+	TableChecksums bool
+
+	// StoreInfo records, for a new store, when it was created and its memTableSize in its manifest, for StoreInfo to
+	// report. Manifests recording them have the ExtendedStorageVersion, so such a store can't be read by binaries
+	// which predate it.
+	StoreInfo bool
+}
+
+// DefaultLocalStoreOptions are the LocalStoreOptions used by NewLocalStore.
+var DefaultLocalStoreOptions = LocalStoreOptions{
+	Layout:             FlatTableLayout,
+	ConjoinPolicy:      DefaultConjoinPolicy,
+	DurabilityPolicy:   DefaultDurabilityPolicy,
+	AppendPolicy:       DefaultAppendPolicy,
+	MissingChunkPolicy: DefaultMissingChunkPolicy,
+}
+
+// NewLocalStoreWithOptions opens the local store in |dir|, configured by |opts|.
+func NewLocalStoreWithOptions(ctx context.Context, nbfVerStr string, dir string, memTableSize uint64, opts LocalStoreOptions) (*NomsBlockStore, error) {
 	cacheOnce.Do(makeGlobalCaches)
 	err := checkDir(dir)
 
@@ -280,14 +356,41 @@ func NewLocalStore(ctx context.Context, nbfVerStr string, dir string, memTableSi
 		return nil, err
 	}
 
-	mm := makeManifestManager(fileManifest{dir})
-	p := newFSTablePersister(dir, globalFDCache, globalIndexCache)
-	nbs, err := newNomsBlockStore(ctx, nbfVerStr, mm, p, inlineConjoiner{defaultMaxTables}, memTableSize)
+	fm := fileManifest{dir: dir, fsync: opts.DurabilityPolicy.FsyncManifest}
+	exists, contents, err := fm.ParseIfExists(ctx, &Stats{}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	layout := opts.Layout
+	if exists {
+		layout = contents.layout
+	}
+
+	mm := makeManifestManager(fm)
+	p := newFSTablePersister(dir, layout, globalFDCache, globalIndexCache, opts.DurabilityPolicy.FsyncTables, opts.TableChecksums)
+	nbs, err := newNomsBlockStore(ctx, nbfVerStr, mm, p, newInlineConjoiner(opts.ConjoinPolicy), memTableSize)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if nbs.upstream.layout != layout {
+		if exists {
+			return nil, errors.New("table layout changed while opening store")
+		}
+
+		nbs.upstream.layout = layout
+	}
+
+	if opts.StoreInfo && nbs.upstream.lock == (addr{}) {
+		nbs.upstream.origin = storeOrigin{time.Now().Round(0), nbs.mtSize}
+	}
+
+	nbs.appendPolicy = opts.AppendPolicy
+	nbs.missingChunks = opts.MissingChunkPolicy
+
 	return nbs, nil
 }
 
@@ -341,9 +444,33 @@ func newNomsBlockStore(ctx context.Context, nbfVerStr string, mm manifestManager
 }
 
 func (nbs *NomsBlockStore) Put(ctx context.Context, c chunks.Chunk) error {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
+	if len(c.Data()) == 0 {
+		return ErrEmptyChunk
+	}
+
 	t1 := time.Now()
 	a := addr(c.Hash())
-	success := nbs.addChunk(ctx, a, c.Data())
+	dup, err := nbs.isDuplicatePut(a)
+
+	if err != nil {
+		return err
+	}
+
+	if dup {
+		atomic.AddUint64(&nbs.dedupHits, 1)
+		nbs.stats.PutLatency.SampleTimeSince(t1)
+		return nil
+	}
+
+	success, err := nbs.addChunk(ctx, a, c.Data())
+
+	if err != nil {
+		return err
+	}
 
 	if !success {
 		return errors.New("failed to add chunk")
@@ -356,18 +483,67 @@ func (nbs *NomsBlockStore) Put(ctx context.Context, c chunks.Chunk) error {
 	return nil
 }
 
-func (nbs *NomsBlockStore) addChunk(ctx context.Context, h addr, data []byte) bool {
+// DetectDuplicatePuts makes Put check whether each chunk it's given is already in the store, pending or committed. A
+// chunk which is already present is not added again, and is counted by DedupHits instead of as a new put. Detection
+// costs a lookup in the indexes of the store's tables on every Put, so it's off by default.
+func (nbs *NomsBlockStore) DetectDuplicatePuts(enabled bool) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.detectDuplicatePuts = enabled
+}
+
+// VerifyOnRead makes Get and GetMany recompute the hash of each chunk they read, and return an ErrCorrupt error for
+// a chunk whose data doesn't match the address it's stored under. This catches corruption which still decodes, but
+// hashing every chunk read is expensive, so it's off by default.
+func (nbs *NomsBlockStore) VerifyOnRead(enabled bool) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.verifyOnRead = enabled
+}
+
+func verifyChunk(h hash.Hash, data []byte) error {
+	if actual := hash.Of(data); actual != h {
+		return &StoreError{ErrCorrupt, fmt.Errorf("chunk %s has data which hashes to %s", h.String(), actual.String())}
+	}
+
+	return nil
+}
+
+// DedupHits returns the number of Puts of chunks which were already in the store while DetectDuplicatePuts was
+// enabled.
+func (nbs *NomsBlockStore) DedupHits() uint64 {
+	return atomic.LoadUint64(&nbs.dedupHits)
+}
+
+func (nbs *NomsBlockStore) isDuplicatePut(h addr) (bool, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	if !nbs.detectDuplicatePuts {
+		return false, nil
+	}
+
+	if nbs.mt != nil {
+		if has, err := nbs.mt.has(h); err != nil || has {
+			return has, err
+		}
+	}
+
+	return nbs.tables.has(h)
+}
+
+func (nbs *NomsBlockStore) addChunk(ctx context.Context, h addr, data []byte) (bool, error) {
 	nbs.mu.Lock()
 	defer nbs.mu.Unlock()
 	if nbs.mt == nil {
 		nbs.mt = newMemTable(nbs.mtSize)
 	}
 	if !nbs.mt.addChunk(h, data) {
-		nbs.tables = nbs.tables.Prepend(ctx, nbs.mt, nbs.stats)
+		nbs.prependMemTable(ctx)
 		nbs.mt = newMemTable(nbs.mtSize)
-		return nbs.mt.addChunk(h, data)
+		return nbs.mt.addChunk(h, data), nil
 	}
-	return true
+	return true, nil
 }
 
 func (nbs *NomsBlockStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk, error) {
@@ -378,10 +554,12 @@ func (nbs *NomsBlockStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk,
 	}()
 
 	a := addr(h)
+	var verify bool
 	data, tables, err := func() ([]byte, chunkReader, error) {
 		var data []byte
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+		verify = nbs.verifyOnRead
 		if nbs.mt != nil {
 			var err error
 			data, err = nbs.mt.get(ctx, a, nbs.stats)
@@ -397,35 +575,248 @@ func (nbs *NomsBlockStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk,
 		return chunks.EmptyChunk, err
 	}
 
+	if data == nil {
+		data, err = tables.get(ctx, a, nbs.stats)
+
+		if err != nil {
+			return chunks.EmptyChunk, err
+		}
+	}
+
 	if data != nil {
+		if verify {
+			err = verifyChunk(h, data)
+
+			if err != nil {
+				return chunks.EmptyChunk, err
+			}
+		}
+
 		return chunks.NewChunkWithHash(h, data), nil
 	}
 
-	data, err = tables.get(ctx, a, nbs.stats)
+	if nbs.missingChunks == ReturnErrorForMissingChunks {
+		return chunks.EmptyChunk, newChunkNotFoundError(hash.NewHashSet(h))
+	}
+
+	return chunks.EmptyChunk, nil
+}
+
+// GetMany sends every chunk in |hashes| which is present in the store to |foundChunks|. GetMany does not buffer
+// decoded chunks on behalf of the consumer: each table being read has a small, fixed number of readers, each of which
+// holds a single read buffer and blocks until its current chunk is received. A slow consumer slows the readers down
+// rather than growing memory. If |ctx| is cancelled while a send is blocked, GetMany returns ctx.Err(). Absent chunks
+// are handled according to the store's MissingChunkPolicy.
+func (nbs *NomsBlockStore) GetMany(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+	if nbs.missingChunks == ReturnErrorForMissingChunks {
+		return nbs.getManyOrNotFound(ctx, hashes, foundChunks)
+	}
+
+	return nbs.getMany(ctx, hashes, foundChunks)
+}
+
+// GetManyOrdered returns the chunks with |hashes| in a slice parallel to |hashes|. The chunks are read in parallel as
+// with GetMany. A missing chunk is nil, unless the store's MissingChunkPolicy says to return an error.
+func (nbs *NomsBlockStore) GetManyOrdered(ctx context.Context, hashes hash.HashSlice) ([]*chunks.Chunk, error) {
+	byHash := make(map[hash.Hash]*chunks.Chunk, len(hashes))
+	found := make(chan *chunks.Chunk)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for c := range found {
+			byHash[c.Hash()] = c
+		}
+	}()
+
+	err := nbs.GetMany(ctx, hashes.HashSet(), found)
+	close(found)
+	<-done
 
 	if err != nil {
-		return chunks.EmptyChunk, err
+		return nil, err
 	}
 
-	if data != nil {
-		return chunks.NewChunkWithHash(h, data), nil
+	ordered := make([]*chunks.Chunk, len(hashes))
+	for i, h := range hashes {
+		ordered[i] = byHash[h]
 	}
 
-	return chunks.EmptyChunk, nil
+	return ordered, nil
 }
 
-func (nbs *NomsBlockStore) GetMany(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+func (nbs *NomsBlockStore) getMany(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+	nbs.mu.RLock()
+	verify := nbs.verifyOnRead
+	nbs.mu.RUnlock()
+
+	if verify {
+		return nbs.getManyVerified(ctx, hashes, foundChunks)
+	}
+
 	return nbs.getManyWithFunc(ctx, hashes, func(ctx context.Context, cr chunkReader, reqs []getRecord, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
 		return cr.getMany(ctx, reqs, foundChunks, wg, ae, nbs.stats)
 	})
 }
 
+// relayChunk sends |c| to |foundChunks|, returning false instead if |ctx| is cancelled first. The goroutines which
+// relay chunks from GetMany's readers to its caller use it, so that they keep draining the readers rather than blocking
+// once the caller has stopped receiving.
+func relayChunk(ctx context.Context, foundChunks chan<- *chunks.Chunk, c *chunks.Chunk) bool {
+	select {
+	case foundChunks <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// getManyVerified is like getMany, but checks the hash of each chunk read. Chunks which don't match their addresses
+// aren't sent, and the first of them is returned as an error.
+func (nbs *NomsBlockStore) getManyVerified(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+	read := make(chan *chunks.Chunk)
+	done := make(chan struct{})
+
+	var verifyErr error
+	var dropped bool
+	go func() {
+		defer close(done)
+		for c := range read {
+			if err := verifyChunk(c.Hash(), c.Data()); err != nil {
+				if verifyErr == nil {
+					verifyErr = err
+				}
+				continue
+			}
+
+			if !relayChunk(ctx, foundChunks, c) {
+				dropped = true
+			}
+		}
+	}()
+
+	err := nbs.getManyWithFunc(ctx, hashes, func(ctx context.Context, cr chunkReader, reqs []getRecord, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
+		return cr.getMany(ctx, reqs, read, wg, ae, nbs.stats)
+	})
+	close(read)
+	<-done
+
+	if err != nil {
+		return err
+	}
+
+	if dropped {
+		return ctx.Err()
+	}
+
+	return verifyErr
+}
+
+// getManyOrNotFound is like getMany, but returns ErrChunkNotFound once the chunks found have been sent if any of
+// |hashes| are absent.
+func (nbs *NomsBlockStore) getManyOrNotFound(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+	missing := make(hash.HashSet, len(hashes))
+	for h := range hashes {
+		missing.Insert(h)
+	}
+
+	found := make(chan *chunks.Chunk)
+	done := make(chan struct{})
+	var dropped bool
+	go func() {
+		defer close(done)
+		for c := range found {
+			missing.Remove(c.Hash())
+
+			if !relayChunk(ctx, foundChunks, c) {
+				dropped = true
+			}
+		}
+	}()
+
+	err := nbs.getMany(ctx, hashes, found)
+	close(found)
+	<-done
+
+	if err != nil {
+		return err
+	}
+
+	if dropped {
+		return ctx.Err()
+	}
+
+	if len(missing) != 0 {
+		return newChunkNotFoundError(missing)
+	}
+
+	return nil
+}
+
+// GetManyCounted is like GetMany, but also returns the number of chunks sent to |foundChunks|, so that callers can
+// tell whether every chunk was found without counting them. If |ctx| is cancelled, GetManyCounted returns the number
+// of chunks sent before the cancellation and ctx.Err().
+func (nbs *NomsBlockStore) GetManyCounted(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) (uint32, error) {
+	counted := make(chan *chunks.Chunk)
+	done := make(chan struct{})
+
+	var found uint32
+	var dropped bool
+	go func() {
+		defer close(done)
+		for c := range counted {
+			if relayChunk(ctx, foundChunks, c) {
+				found++
+			} else {
+				dropped = true
+			}
+		}
+	}()
+
+	err := nbs.GetMany(ctx, hashes, counted)
+	close(counted)
+	<-done
+
+	if err == nil && dropped {
+		err = ctx.Err()
+	}
+
+	return found, err
+}
+
 func (nbs *NomsBlockStore) GetManyCompressed(ctx context.Context, hashes hash.HashSet, foundCmpChunks chan<- CompressedChunk) error {
 	return nbs.getManyWithFunc(ctx, hashes, func(ctx context.Context, cr chunkReader, reqs []getRecord, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
 		return cr.getManyCompressed(ctx, reqs, foundCmpChunks, wg, ae, nbs.stats)
 	})
 }
 
+// GetRaw returns the chunk with hash |h| as it is stored, still compressed, so that a caller which writes it
+// somewhere else, as with CmpChunkTableWriter, can skip decoding and re-encoding it. ToChunk decodes it.
+//
+// The returned bytes may alias memory owned by the store. They must not be modified, and are only valid until the
+// store is next mutated by Put, Commit, Rebase or Close. A caller which keeps the chunk any longer must keep a Copy
+// instead. A missing chunk is returned as an empty CompressedChunk, unless the store's MissingChunkPolicy says to
+// return an error.
+func (nbs *NomsBlockStore) GetRaw(ctx context.Context, h hash.Hash) (CompressedChunk, error) {
+	found := make(chan CompressedChunk, 1)
+	err := nbs.GetManyCompressed(ctx, hash.NewHashSet(h), found)
+
+	if err != nil {
+		return CompressedChunk{}, err
+	}
+
+	select {
+	case cmp := <-found:
+		return cmp, nil
+	default:
+	}
+
+	if nbs.missingChunks == ReturnErrorForMissingChunks {
+		return CompressedChunk{}, newChunkNotFoundError(hash.NewHashSet(h))
+	}
+
+	return CompressedChunk{}, nil
+}
+
 func (nbs *NomsBlockStore) getManyWithFunc(
 	ctx context.Context,
 	hashes hash.HashSet,
@@ -501,117 +892,322 @@ func (nbs *NomsBlockStore) CalcReads(hashes hash.HashSet, blockSize uint64) (rea
 	}
 
 	if remaining {
-		return 0, false, errors.New("failed to find all chunks")
+		return 0, false, newStoreError(ErrNotFound, "failed to find all chunks")
 	}
 
 	return
 }
 
-func (nbs *NomsBlockStore) Count() (uint32, error) {
-	count, tables, err := func() (count uint32, tables chunkReader, err error) {
+// ExtractChunks sends every chunk in the store to |ch|, in the order the chunks were written. Chunks which have been
+// Put but not yet committed are sent last. ExtractChunks stops and returns the error if a table can't be read, or
+// ctx.Err() if |ctx| is cancelled. When it returns an error, the chunks already sent to |ch| are an incomplete set.
+// ExtractChunks does not close |ch|.
+func (nbs *NomsBlockStore) ExtractChunks(ctx context.Context, ch chan<- *chunks.Chunk) error {
+	pending, tables := func() ([]extractRecord, tableSet) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+
+		var pending []extractRecord
 		if nbs.mt != nil {
-			count, err = nbs.mt.count()
+			pending = make([]extractRecord, 0, len(nbs.mt.order))
+			for _, hrec := range nbs.mt.order {
+				pending = append(pending, extractRecord{a: *hrec.a, data: nbs.mt.chunks[*hrec.a]})
+			}
 		}
 
-		if err != nil {
-			return 0, nil, err
+		return pending, nbs.tables
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var extractErr error
+	recs := make(chan extractRecord, 32)
+	go func() {
+		defer close(recs)
+		extractErr = tables.extract(ctx, recs)
+
+		if extractErr != nil {
+			return
 		}
 
-		return count, nbs.tables, nil
+		for _, rec := range pending {
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				extractErr = ctx.Err()
+				return
+			}
+		}
 	}()
 
-	if err != nil {
-		return 0, err
-	}
+	for rec := range recs {
+		c := chunks.NewChunkWithHash(hash.Hash(rec.a), rec.data)
 
-	tablesCount, err := tables.count()
+		select {
+		case ch <- &c:
+		case <-ctx.Done():
+			cancel()
+			for range recs {
+			}
 
-	if err != nil {
-		return 0, err
+			return ctx.Err()
+		}
 	}
 
-	return count + tablesCount, nil
+	return extractErr
 }
 
-func (nbs *NomsBlockStore) Has(ctx context.Context, h hash.Hash) (bool, error) {
-	t1 := time.Now()
-	defer func() {
-		nbs.stats.HasLatency.SampleTimeSince(t1)
-		nbs.stats.AddressesPerHas.Sample(1)
-	}()
-
-	a := addr(h)
-	has, tables, err := func() (bool, chunkReader, error) {
+// ExtractChunksRange sends every chunk in the store whose address is in the range [|start|, |end|) to |ch|, in no
+// particular order, so that independent ranges can be exported in parallel. An empty |end| is the end of the address
+// space, so the ranges [hash.Hash{}, |mid|) and [|mid|, hash.Hash{}) together cover every chunk. Pending chunks which
+// have been Put but not yet committed are included. ExtractChunksRange does not close |ch|.
+func (nbs *NomsBlockStore) ExtractChunksRange(ctx context.Context, start, end hash.Hash, ch chan<- *chunks.Chunk) error {
+	startAddr, endAddr := addr(start), addr(end)
+	hashes, tables := func() (hash.HashSet, tableSet) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
 
+		hashes := make(hash.HashSet)
 		if nbs.mt != nil {
-			has, err := nbs.mt.has(a)
-
-			if err != nil {
-				return false, nil, err
+			for a := range nbs.mt.chunks {
+				if addrInRange(a, startAddr, endAddr) {
+					hashes.Insert(hash.Hash(a))
+				}
 			}
-
-			return has, nbs.tables, nil
 		}
 
-		return false, nbs.tables, nil
+		return hashes, nbs.tables
 	}()
 
-	if err != nil {
-		return false, err
-	}
+	for _, css := range []chunkSources{tables.novel, tables.upstream} {
+		for _, cs := range css {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 
-	if !has {
-		has, err = tables.has(a)
+			index, err := cs.index()
 
-		if err != nil {
-			return false, err
+			if err != nil {
+				return err
+			}
+
+			err = index.iterateRange(startAddr, endAddr, func(a addr) error {
+				hashes.Insert(hash.Hash(a))
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	return has, nil
+	return nbs.GetMany(ctx, hashes, ch)
 }
 
-func (nbs *NomsBlockStore) HasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
-	t1 := time.Now()
-
-	reqs := toHasRecords(hashes)
-
-	tables, remaining, err := func() (tables chunkReader, remaining bool, err error) {
+// IterateChunksWithPrefix calls |cb| with the address of every chunk in the store whose 20 byte address begins with
+// the bytes in |prefix|. An empty |prefix| matches every chunk, and a |prefix| longer than 20 bytes matches none.
+// Pending chunks which have been Put but not yet committed are included. Only table indexes are read, never chunk
+// data. Each matching address is visited once, in no particular order. If |cb| returns an error iteration stops and
+// that error is returned.
+func (nbs *NomsBlockStore) IterateChunksWithPrefix(ctx context.Context, prefix []byte, cb func(h hash.Hash) error) error {
+	pending, tables := func() ([]addr, tableSet) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
-		tables = nbs.tables
 
-		remaining = true
+		var pending []addr
 		if nbs.mt != nil {
-			remaining, err = nbs.mt.hasMany(reqs)
-
-			if err != nil {
-				return nil, false, err
+			for a := range nbs.mt.chunks {
+				if bytes.HasPrefix(a[:], prefix) {
+					pending = append(pending, a)
+				}
 			}
 		}
 
-		return tables, remaining, nil
+		return pending, nbs.tables
 	}()
 
-	if err != nil {
-		return nil, err
-	}
+	seen := make(hash.HashSet)
+	visit := func(a addr) error {
+		h := hash.Hash(a)
+		if seen.Has(h) {
+			return nil
+		}
 
-	if remaining {
-		_, err := tables.hasMany(reqs)
+		seen.Insert(h)
+		return cb(h)
+	}
 
-		if err != nil {
-			return nil, err
+	for _, a := range pending {
+		if err := visit(a); err != nil {
+			return err
 		}
 	}
 
-	if len(hashes) > 0 {
+	for _, css := range []chunkSources{tables.novel, tables.upstream} {
+		for _, cs := range css {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			index, err := cs.index()
+
+			if err != nil {
+				return err
+			}
+
+			err = index.iterateWithPrefix(prefix, visit)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (nbs *NomsBlockStore) Count() (uint32, error) {
+	count, tables, err := func() (count uint32, tables chunkReader, err error) {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+		if nbs.mt != nil {
+			count, err = nbs.mt.count()
+		}
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return count, nbs.tables, nil
+	}()
+
+	if err != nil {
+		return 0, err
+	}
+
+	tablesCount, err := tables.count()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count + tablesCount, nil
+}
+
+func (nbs *NomsBlockStore) Has(ctx context.Context, h hash.Hash) (bool, error) {
+	t1 := time.Now()
+	defer func() {
 		nbs.stats.HasLatency.SampleTimeSince(t1)
-		nbs.stats.AddressesPerHas.SampleLen(len(reqs))
+		nbs.stats.AddressesPerHas.Sample(1)
+	}()
+
+	a := addr(h)
+	has, tables, err := func() (bool, chunkReader, error) {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		if nbs.mt != nil {
+			has, err := nbs.mt.has(a)
+
+			if err != nil {
+				return false, nil, err
+			}
+
+			return has, nbs.tables, nil
+		}
+
+		return false, nbs.tables, nil
+	}()
+
+	if err != nil {
+		return false, err
+	}
+
+	if !has {
+		has, err = tables.has(a)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return has, nil
+}
+
+// HasContent returns whether the store has the chunk whose data is |data|, along with that chunk's hash, so that a
+// caller holding raw bytes needn't hash them itself.
+func (nbs *NomsBlockStore) HasContent(ctx context.Context, data []byte) (bool, hash.Hash, error) {
+	h := chunks.NewChunk(data).Hash()
+	has, err := nbs.Has(ctx, h)
+
+	if err != nil {
+		return false, hash.Hash{}, err
+	}
+
+	return has, h, nil
+}
+
+// memTableLocation is the name LocateChunk gives to the store's in-memory table of pending chunks.
+const memTableLocation = "memtable"
+
+// LocateChunk returns the names of the table files containing the chunk |h|, along with memTableLocation if the
+// chunk is pending in memory. A chunk may be found in several tables until they are conjoined. If the chunk is
+// absent, the returned slice is empty. This is intended as a diagnostic aid.
+func (nbs *NomsBlockStore) LocateChunk(ctx context.Context, h hash.Hash) ([]string, error) {
+	a := addr(h)
+	locations := []string{}
+
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	if nbs.mt != nil {
+		has, err := nbs.mt.has(a)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if has {
+			locations = append(locations, memTableLocation)
+		}
+	}
+
+	for _, srcs := range []chunkSources{nbs.tables.novel, nbs.tables.upstream} {
+		for _, src := range srcs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			has, err := src.has(a)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !has {
+				continue
+			}
+
+			name, err := src.hash()
+
+			if err != nil {
+				return nil, err
+			}
+
+			locations = append(locations, name.String())
+		}
+	}
+
+	return locations, nil
+}
+
+func (nbs *NomsBlockStore) HasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
+	reqs, err := nbs.hasManyRecords(hashes)
+
+	if err != nil {
+		return nil, err
 	}
 
 	absent := hash.HashSet{}
@@ -623,6 +1219,85 @@ func (nbs *NomsBlockStore) HasMany(ctx context.Context, hashes hash.HashSet) (ha
 	return absent, nil
 }
 
+// Partition splits |hashes| into the chunks which are present in the store and those which are absent, in a single
+// scan of the store.
+func (nbs *NomsBlockStore) Partition(ctx context.Context, hashes hash.HashSet) (present, absent hash.HashSet, err error) {
+	reqs, err := nbs.hasManyRecords(hashes)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present, absent = hash.HashSet{}, hash.HashSet{}
+	for _, r := range reqs {
+		if r.has {
+			present.Insert(hash.New(r.a[:]))
+		} else {
+			absent.Insert(hash.New(r.a[:]))
+		}
+	}
+	return present, absent, nil
+}
+
+// CountPresent returns the number of chunks in |hashes| which are present in the store, without collecting them.
+func (nbs *NomsBlockStore) CountPresent(ctx context.Context, hashes hash.HashSet) (uint32, error) {
+	reqs, err := nbs.hasManyRecords(hashes)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint32
+	for _, r := range reqs {
+		if r.has {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (nbs *NomsBlockStore) hasManyRecords(hashes hash.HashSet) ([]hasRecord, error) {
+	t1 := time.Now()
+
+	reqs := toHasRecords(hashes)
+
+	tables, remaining, err := func() (tables chunkReader, remaining bool, err error) {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+		tables = nbs.tables
+
+		remaining = true
+		if nbs.mt != nil {
+			remaining, err = nbs.mt.hasMany(reqs)
+
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		return tables, remaining, nil
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if remaining {
+		_, err := tables.hasMany(reqs)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(hashes) > 0 {
+		nbs.stats.HasLatency.SampleTimeSince(t1)
+		nbs.stats.AddressesPerHas.SampleLen(len(reqs))
+	}
+
+	return reqs, nil
+}
+
 func toHasRecords(hashes hash.HashSet) []hasRecord {
 	reqs := make([]hasRecord, len(hashes))
 	idx := 0
@@ -663,30 +1338,218 @@ func (nbs *NomsBlockStore) Rebase(ctx context.Context) error {
 	return nil
 }
 
+// RebaseTo rebases the store's tables like Rebase, but makes |root| the store's root rather than the manifest's
+// current root, so that reads reflect a historical state without changing the live root. |root| must be the manifest's
+// root or be in one of the tables the manifest references, or ErrUnknownRoot is returned. A Commit from a root other
+// than the manifest's fails, as it would from any stale root, and leaves the store at the manifest's root.
+func (nbs *NomsBlockStore) RebaseTo(ctx context.Context, root hash.Hash) error {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	exists, contents, err := nbs.mm.Fetch(ctx, nbs.stats)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if root.IsEmpty() {
+			return nil
+		}
+
+		return ErrUnknownRoot
+	}
+
+	newTables, err := nbs.tables.Rebase(ctx, contents.specs, nbs.stats)
+
+	if err != nil {
+		return err
+	}
+
+	if root != contents.root {
+		has, err := tableSet{upstream: newTables.upstream}.has(addr(root))
+
+		if err != nil {
+			return err
+		}
+
+		if !has {
+			return ErrUnknownRoot
+		}
+
+		// The lock no longer matches the manifest's, so that a Commit from this root fails rather than replacing
+		// the manifest's root.
+		contents.root = root
+		contents.lock = generateLockHash(root, contents.specs, contents.pinned)
+		contents.meta = nil
+	}
+
+	nbs.upstream = contents
+	nbs.tables = newTables
+
+	return nil
+}
+
+// Root returns the root as of the most recent Commit, Rebase or ForceRootRefresh. It is served from memory and never
+// reads the manifest, so it doesn't reflect commits made through other stores until one of those is called.
 func (nbs *NomsBlockStore) Root(ctx context.Context) (hash.Hash, error) {
 	nbs.mu.RLock()
 	defer nbs.mu.RUnlock()
 	return nbs.upstream.root, nil
 }
 
+// ForceRootRefresh reads the manifest and returns its current root, picking up commits made through other stores or
+// processes. Unlike Rebase, the store's tables are only rebased when the manifest has changed since it was last read.
+func (nbs *NomsBlockStore) ForceRootRefresh(ctx context.Context) (hash.Hash, error) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	exists, contents, err := nbs.mm.Fetch(ctx, nbs.stats)
+
+	if err != nil {
+		return hash.Hash{}, err
+	}
+
+	if exists && contents.lock != nbs.upstream.lock {
+		newTables, err := nbs.tables.Rebase(ctx, contents.specs, nbs.stats)
+
+		if err != nil {
+			return hash.Hash{}, err
+		}
+
+		nbs.upstream = contents
+		nbs.tables = newTables
+	}
+
+	return nbs.upstream.root, nil
+}
+
+// ManifestLock returns the lock hash of the manifest as of the time the store was opened or the most recent call to
+// Rebase or Commit. The lock changes on every successful manifest update, including updates which leave the root
+// unchanged, so callers coordinating commits externally can compare locks to detect intervening writes.
+func (nbs *NomsBlockStore) ManifestLock() (hash.Hash, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return hash.Hash(nbs.upstream.lock), nil
+}
+
+// StoreInfo describes the creation and format of a store, as recorded in its manifest.
+type StoreInfo struct {
+	// Created is when the store was created. For a store which hasn't been committed to, it is when the store was
+	// opened. It is only recorded for local stores created with LocalStoreOptions.StoreInfo, and is the zero time for
+	// every other store.
+	Created time.Time
+
+	// MemTableSize is the memtable size of the store which created the store. It is 0 when Created is the zero time.
+	MemTableSize uint64
+
+	// FormatVersion is the version of the Noms binary format of the store's data.
+	FormatVersion string
+
+	// StorageVersion is the version of the format of the store's manifest and table files: ExtendedStorageVersion if
+	// the manifest records any optional fields, such as Created, and StorageVersion otherwise.
+	StorageVersion string
+}
+
+// StoreInfo returns the creation and format of the store. Other than for a store which hasn't been committed to, its
+// creation and format versions are fixed when the store's first manifest is written.
+func (nbs *NomsBlockStore) StoreInfo() StoreInfo {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return StoreInfo{
+		Created:        nbs.upstream.origin.created,
+		MemTableSize:   nbs.upstream.origin.memTableSize,
+		FormatVersion:  nbs.upstream.vers,
+		StorageVersion: nbs.upstream.storageVersion(),
+	}
+}
+
+func (nbs *NomsBlockStore) upstreamLock() addr {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return nbs.upstream.lock
+}
+
 func (nbs *NomsBlockStore) Commit(ctx context.Context, current, last hash.Hash) (success bool, err error) {
+	success, _, err = nbs.commit(ctx, current, last, nil)
+	return success, err
+}
+
+// CommitReturningLock is like Commit, but also returns the manifest lock as of the end of the commit, as ManifestLock
+// would. After a successful commit it is the lock written by the commit, so a caller committing repeatedly can pass
+// |current| as the next |last| and compare locks to detect intervening writes without reading the store again. After
+// a failed commit it is the lock of the latest manifest the store has read, which is that of the winning write when
+// another writer moved the root.
+func (nbs *NomsBlockStore) CommitReturningLock(ctx context.Context, current, last hash.Hash) (success bool, newLock hash.Hash, err error) {
+	success, lock, err := nbs.commit(ctx, current, last, nil)
+	return success, hash.Hash(lock), err
+}
+
+// CommitWithMeta behaves like Commit, but additionally attaches |meta| to |current| in the manifest. The metadata is
+// written in the same manifest update as the root, so it is visible if and only if the root change succeeded. The
+// metadata for a root can be read back with RootMeta.
+func (nbs *NomsBlockStore) CommitWithMeta(ctx context.Context, current, last hash.Hash, meta map[string]string) (success bool, err error) {
+	if len(meta) == 0 {
+		meta = nil
+	}
+
+	success, _, err = nbs.commit(ctx, current, last, meta)
+	return success, err
+}
+
+// ForceSetRoot moves the store's root from |last| to |root|, which must already be in the store, as for a hard reset to
+// an earlier root. Unlike Commit, which is used after writing the chunks of a new root, it doesn't expect anything to
+// be written. It is still a compare-and-set on |last|, and returns false if the root has moved. An unknown |root|
+// returns ErrUnknownRoot, and an empty |root| is allowed, resetting the store to having no root.
+func (nbs *NomsBlockStore) ForceSetRoot(ctx context.Context, root, last hash.Hash) (bool, error) {
+	if !root.IsEmpty() {
+		has, err := nbs.Has(ctx, root)
+
+		if err != nil {
+			return false, err
+		}
+
+		if !has {
+			return false, ErrUnknownRoot
+		}
+	}
+
+	success, _, err := nbs.commit(ctx, root, last, nil)
+	return success, err
+}
+
+// commit persists pending chunks and moves the manifest's root from |last| to |current|. If |ctx| is cancelled before
+// the manifest is updated, commit returns ctx.Err() and the root is unchanged. Any table files written before the
+// cancellation are not referenced by the manifest; they remain pending in this store and are picked up by the next
+// commit.
+func (nbs *NomsBlockStore) commit(ctx context.Context, current, last hash.Hash, meta map[string]string) (success bool, lock addr, err error) {
 	t1 := time.Now()
 	defer nbs.stats.CommitLatency.SampleTimeSince(t1)
 
+	if err := ctx.Err(); err != nil {
+		return false, addr{}, err
+	}
+
+	if err := nbs.checkWritable(); err != nil {
+		return false, addr{}, err
+	}
+
 	anyPossiblyNovelChunks := func() bool {
 		nbs.mu.Lock()
 		defer nbs.mu.Unlock()
 		return nbs.mt != nil || nbs.tables.Novel() > 0
 	}
 
-	if !anyPossiblyNovelChunks() && current == last {
+	if !anyPossiblyNovelChunks() && current == last && meta == nil {
 		err := nbs.Rebase(ctx)
 
 		if err != nil {
-			return false, err
+			return false, addr{}, err
 		}
 
-		return true, nil
+		return true, nbs.upstreamLock(), nil
+	}
+
+	if err := nbs.checkDiskSpace(); err != nil {
+		return false, addr{}, err
 	}
 
 	err = func() error {
@@ -707,7 +1570,7 @@ func (nbs *NomsBlockStore) Commit(ctx context.Context, current, last hash.Hash)
 			}
 
 			if cnt > preflushChunkCount {
-				nbs.tables = nbs.tables.Prepend(ctx, nbs.mt, nbs.stats)
+				nbs.prependMemTable(ctx)
 				nbs.mt = nil
 			}
 		}
@@ -716,46 +1579,332 @@ func (nbs *NomsBlockStore) Commit(ctx context.Context, current, last hash.Hash)
 	}()
 
 	if err != nil {
-		return false, err
+		return false, addr{}, err
+	}
+
+	nbs.mm.LockForUpdate()
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, addr{}, err
+		}
+
+		if err := nbs.updateManifest(ctx, current, last, meta); err == nil {
+			return true, nbs.upstreamLock(), nil
+		} else if err == errOptimisticLockFailedRoot || err == errLastRootMismatch {
+			return false, nbs.upstreamLock(), nil
+		} else if err != errOptimisticLockFailedTables {
+			return false, addr{}, err
+		}
+
+		// I guess this thing infinitely retries without backoff in the case off errOptimisticLockFailedTables
+	}
+}
+
+// Flush persists pending chunks to a table file and adds it to the manifest without changing the store's root, so that
+// a long series of writes can be made durable without holding them all in memory until the next Commit. Flushed
+// chunks are readable immediately, by this store and by any store later opened on the same manifest, and are not
+// written again by the next Commit.
+func (nbs *NomsBlockStore) Flush(ctx context.Context) (err error) {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
+	anyPossiblyNovelChunks := func() bool {
+		nbs.mu.Lock()
+		defer nbs.mu.Unlock()
+		return nbs.mt != nil || nbs.tables.Novel() > 0
+	}
+
+	if !anyPossiblyNovelChunks() {
+		return nil
+	}
+
+	if err := nbs.checkDiskSpace(); err != nil {
+		return err
+	}
+
+	nbs.mm.LockForUpdate()
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		root, meta := func() (hash.Hash, map[string]string) {
+			nbs.mu.RLock()
+			defer nbs.mu.RUnlock()
+			return nbs.upstream.root, nbs.upstream.meta
+		}()
+
+		err := nbs.updateManifest(ctx, root, root, meta)
+
+		if err == nil {
+			return nil
+		} else if err != errOptimisticLockFailedRoot && err != errLastRootMismatch && err != errOptimisticLockFailedTables {
+			return err
+		}
 	}
+}
+
+var (
+	errLastRootMismatch           = fmt.Errorf("last does not match nbs.Root()")
+	errOptimisticLockFailedRoot   = fmt.Errorf("root moved")
+	errOptimisticLockFailedTables = fmt.Errorf("tables changed")
+)
+
+func (nbs *NomsBlockStore) updateManifest(ctx context.Context, current, last hash.Hash, meta map[string]string) error {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	if nbs.upstream.root != last {
+		return errLastRootMismatch
+	}
+
+	handleOptimisticLockFailure := func(upstream manifestContents) error {
+		newTables, err := nbs.tables.Rebase(ctx, upstream.specs, nbs.stats)
+		if err != nil {
+			return err
+		}
+
+		nbs.upstream = upstream
+		nbs.tables = newTables
+
+		if last != upstream.root {
+			return errOptimisticLockFailedRoot
+		}
+
+		return errOptimisticLockFailedTables
+	}
+
+	if cached, doomed := nbs.mm.updateWillFail(nbs.upstream.lock); doomed {
+		// Pre-emptive optimistic lock failure. Someone else in-process moved to the root, the set of tables, or both out from under us.
+		return handleOptimisticLockFailure(cached)
+	}
+
+	metaHead, err := nbs.addRootMetaRecord(ctx, current, meta)
+
+	if err != nil {
+		return err
+	}
+
+	if nbs.mt != nil {
+		cnt, err := nbs.mt.count()
+
+		if err != nil {
+			return err
+		}
+
+		if cnt > 0 {
+			nbs.prependMemTable(ctx)
+			nbs.mt = nil
+		}
+	}
+
+	conjoinRequired := nbs.c.ConjoinRequired(nbs.tables)
+
+	var event ConjoinEvent
+	if nbs.conjoinObserver != nil {
+		event = ConjoinEvent{Before: tableSpecInfos(nbs.upstream.specs), Required: conjoinRequired}
+
+		if !conjoinRequired {
+			nbs.conjoinObserver(event)
+		}
+	}
+
+	if conjoinRequired {
+		t1 := time.Now()
+		newUpstream, err := nbs.c.Conjoin(ctx, nbs.upstream, nbs.mm, nbs.p, nbs.stats)
+
+		if nbs.conjoinObserver != nil {
+			event.Duration = time.Since(t1)
+			event.Err = err
+
+			if err == nil {
+				event.After = tableSpecInfos(newUpstream.specs)
+			}
+
+			nbs.conjoinObserver(event)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		newTables, err := nbs.tables.Rebase(ctx, newUpstream.specs, nbs.stats)
+
+		if err != nil {
+			return err
+		}
+
+		nbs.upstream = newUpstream
+		nbs.tables = newTables
+
+		nbs.appendManifestLog(newUpstream)
+
+		return errOptimisticLockFailedTables
+	}
+
+	specs, err := nbs.tables.ToSpecs()
+
+	if err != nil {
+		return err
+	}
+
+	// Last chance to cancel. Past this point the manifest may be written.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	newContents := manifestContents{
+		vers:     nbs.upstream.vers,
+		root:     current,
+		lock:     generateLockHash(current, specs, nbs.upstream.pinned),
+		specs:    specs,
+		meta:     meta,
+		metaHead: metaHead,
+		layout:   nbs.upstream.layout,
+		pinned:   nbs.upstream.pinned,
+		origin:   nbs.upstream.origin,
+	}
+
+	upstream, err := nbs.mm.Update(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
+	if err != nil {
+		return err
+	}
+
+	if newContents.lock != upstream.lock {
+		// Optimistic lock failure. Someone else moved to the root, the set of tables, or both out from under us.
+		return handleOptimisticLockFailure(upstream)
+	}
+
+	newTables, err := nbs.tables.Flatten()
+
+	if err != nil {
+		return nil
+	}
+
+	nbs.upstream = newContents
+	nbs.tables = newTables
+
+	nbs.appendManifestLog(newContents)
+
+	return nil
+}
+
+// PinRoot adds |root| to the set of pinned roots recorded in the manifest. Pinned roots, along with the current root,
+// are the roots from which a garbage collector must mark reachable chunks, so that the chunks of roots which are not
+// current, such as those of other branches or stashes, are never collected. |root| must be in one of the store's
+// tables, or ErrUnknownRoot is returned.
+func (nbs *NomsBlockStore) PinRoot(ctx context.Context, root hash.Hash) error {
+	has, err := nbs.Has(ctx, root)
+
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return ErrUnknownRoot
+	}
+
+	return nbs.updatePinnedRoots(ctx, func(pinned hash.HashSet) {
+		pinned.Insert(root)
+	})
+}
+
+// UnpinRoot removes |root| from the set of pinned roots recorded in the manifest. It is not an error to unpin a root
+// which isn't pinned.
+func (nbs *NomsBlockStore) UnpinRoot(ctx context.Context, root hash.Hash) error {
+	return nbs.updatePinnedRoots(ctx, func(pinned hash.HashSet) {
+		pinned.Remove(root)
+	})
+}
+
+// PinnedRoots returns the roots pinned by PinRoot, sorted, as of the most recent read of the manifest.
+func (nbs *NomsBlockStore) PinnedRoots() hash.HashSlice {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return append(hash.HashSlice(nil), nbs.upstream.pinned...)
+}
+
+func (nbs *NomsBlockStore) updatePinnedRoots(ctx context.Context, edit func(pinned hash.HashSet)) (err error) {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
+	nbs.mm.LockForUpdate()
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ok, contents, err := nbs.mm.Fetch(ctx, nbs.stats)
+
+		if err != nil {
+			return err
+		} else if !ok {
+			contents = manifestContents{vers: nbs.upstream.vers, layout: nbs.upstream.layout, origin: nbs.upstream.origin}
+		}
 
-	nbs.mm.LockForUpdate()
-	defer func() {
-		unlockErr := nbs.mm.UnlockForUpdate()
+		pinned := hash.NewHashSet(contents.pinned...)
+		edit(pinned)
 
-		if err == nil {
-			err = unlockErr
+		var newPinned hash.HashSlice
+		for h := range pinned {
+			newPinned = append(newPinned, h)
 		}
-	}()
 
-	for {
-		if err := nbs.updateManifest(ctx, current, last); err == nil {
-			return true, nil
-		} else if err == errOptimisticLockFailedRoot || err == errLastRootMismatch {
-			return false, nil
-		} else if err != errOptimisticLockFailedTables {
-			return false, err
+		sort.Sort(newPinned)
+
+		if newPinned.Equals(contents.pinned) {
+			return nil
 		}
 
-		// I guess this thing infinitely retries without backoff in the case off errOptimisticLockFailedTables
-	}
-}
+		newContents := contents
+		newContents.pinned = newPinned
+		newContents.lock = generateLockHash(contents.root, contents.specs, newPinned)
 
-var (
-	errLastRootMismatch           = fmt.Errorf("last does not match nbs.Root()")
-	errOptimisticLockFailedRoot   = fmt.Errorf("root moved")
-	errOptimisticLockFailedTables = fmt.Errorf("tables changed")
-)
+		upstream, err := nbs.mm.Update(ctx, contents.lock, newContents, nbs.stats, nil)
 
-func (nbs *NomsBlockStore) updateManifest(ctx context.Context, current, last hash.Hash) error {
-	nbs.mu.Lock()
-	defer nbs.mu.Unlock()
-	if nbs.upstream.root != last {
-		return errLastRootMismatch
-	}
+		if err != nil {
+			return err
+		}
+
+		if upstream.lock != newContents.lock {
+			// Someone else updated the manifest since it was fetched
+			continue
+		}
 
-	handleOptimisticLockFailure := func(upstream manifestContents) error {
 		newTables, err := nbs.tables.Rebase(ctx, upstream.specs, nbs.stats)
+
 		if err != nil {
 			return err
 		}
@@ -763,96 +1912,116 @@ func (nbs *NomsBlockStore) updateManifest(ctx context.Context, current, last has
 		nbs.upstream = upstream
 		nbs.tables = newTables
 
-		if last != upstream.root {
-			return errOptimisticLockFailedRoot
-		}
-
-		return errOptimisticLockFailedTables
-	}
-
-	if cached, doomed := nbs.mm.updateWillFail(nbs.upstream.lock); doomed {
-		// Pre-emptive optimistic lock failure. Someone else in-process moved to the root, the set of tables, or both out from under us.
-		return handleOptimisticLockFailure(cached)
+		return nil
 	}
+}
 
-	if nbs.mt != nil {
-		cnt, err := nbs.mt.count()
+// RootMeta returns the metadata attached to |root| by CommitWithMeta. The metadata of the current root is kept in the
+// manifest, and that of every root committed with metadata is kept in a chain of root meta records, so the metadata of
+// earlier roots can be read as well. The returned bool is false if |root| was never committed with metadata. A garbage
+// collector must keep the chain of records reachable from the manifest's metaHead.
+func (nbs *NomsBlockStore) RootMeta(ctx context.Context, root hash.Hash) (map[string]string, bool, error) {
+	nbs.mu.RLock()
+	current, currentMeta, metaHead := nbs.upstream.root, nbs.upstream.meta, nbs.upstream.metaHead
+	nbs.mu.RUnlock()
 
-		if err != nil {
-			return err
+	if current == root && currentMeta != nil {
+		meta := make(map[string]string, len(currentMeta))
+		for k, v := range currentMeta {
+			meta[k] = v
 		}
 
-		if cnt > 0 {
-			nbs.tables = nbs.tables.Prepend(ctx, nbs.mt, nbs.stats)
-			nbs.mt = nil
-		}
+		return meta, true, nil
 	}
 
-	if nbs.c.ConjoinRequired(nbs.tables) {
-		var err error
-		newUpstream, err := nbs.c.Conjoin(ctx, nbs.upstream, nbs.mm, nbs.p, nbs.stats)
+	for next := metaHead; next != (addr{}); {
+		c, err := nbs.Get(ctx, hash.Hash(next))
 
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 
-		newTables, err := nbs.tables.Rebase(ctx, newUpstream.specs, nbs.stats)
+		if c.IsEmpty() {
+			return nil, false, ErrCorruptRootMeta
+		}
+
+		r, meta, prev, err := decodeRootMetaRecord(c.Data())
 
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 
-		nbs.upstream = newUpstream
-		nbs.tables = newTables
+		if r == root {
+			return meta, true, nil
+		}
 
-		return errOptimisticLockFailedTables
+		next = prev
 	}
 
-	specs, err := nbs.tables.ToSpecs()
+	return nil, false, nil
+}
 
-	if err != nil {
-		return err
-	}
+func (nbs *NomsBlockStore) Version() string {
+	return nbs.upstream.vers
+}
 
-	newContents := manifestContents{
-		vers:  nbs.upstream.vers,
-		root:  current,
-		lock:  generateLockHash(current, specs),
-		specs: specs,
-	}
+// Close returns ErrUncommittedChunks if chunks have been Put since the last Commit, leaving the store open so that
+// the caller can commit them. Use CloseDiscardingPending to close regardless.
+func (nbs *NomsBlockStore) Close() error {
+	err := nbs.checkCommitted()
 
-	upstream, err := nbs.mm.Update(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
 	if err != nil {
 		return err
 	}
 
-	if newContents.lock != upstream.lock {
-		// Optimistic lock failure. Someone else moved to the root, the set of tables, or both out from under us.
-		return handleOptimisticLockFailure(upstream)
-	}
+	nbs.stopFollowing()
+	return nil
+}
 
-	newTables, err := nbs.tables.Flatten()
+// checkCommitted returns ErrUncommittedChunks if chunks have been Put since the last Commit.
+func (nbs *NomsBlockStore) checkCommitted() error {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
 
-	if err != nil {
-		return nil
+	if nbs.tables.Novel() > 0 {
+		return ErrUncommittedChunks
 	}
 
-	nbs.upstream = newContents
-	nbs.tables = newTables
+	if nbs.mt != nil {
+		cnt, err := nbs.mt.count()
+
+		if err != nil {
+			return err
+		}
+
+		if cnt > 0 {
+			return ErrUncommittedChunks
+		}
+	}
 
 	return nil
 }
 
-func (nbs *NomsBlockStore) Version() string {
-	return nbs.upstream.vers
-}
+// CloseDiscardingPending closes the store, dropping any chunks which have been Put but not committed.
+func (nbs *NomsBlockStore) CloseDiscardingPending() error {
+	nbs.stopFollowing()
 
-func (nbs *NomsBlockStore) Close() (err error) {
-	return
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+
+	nbs.mt = nil
+	nbs.tables.novel = nil
+
+	return nil
 }
 
 func (nbs *NomsBlockStore) Stats() interface{} {
-	return *nbs.stats
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	stats := *nbs.stats
+	stats.FragmentationRatio, _ = nbs.fragmentationRatio(context.Background())
+	return stats
 }
 
 func (nbs *NomsBlockStore) StatsSummary() string {
@@ -887,7 +2056,8 @@ func (tfi NomsBlockStoreTableFileInfo) Open() (io.ReadCloser, error) {
 // NomsBlockStoreTableFile is an implementation of TableFile that is in a NomsBlockStore on the machine.
 type NomsBlockStoreTableFile struct {
 	NomsBlockStoreTableFileInfo
-	dir string
+	dir    string
+	layout TableLayout
 }
 
 // FileID gets the id of the file
@@ -902,7 +2072,7 @@ func (tf NomsBlockStoreTableFile) NumChunks() int {
 
 // Open returns an io.ReadCloser which can be used to read the bytes of a table file.
 func (tf NomsBlockStoreTableFile) Open() (io.ReadCloser, error) {
-	path := filepath.Join(tf.dir, tf.FileID())
+	path := tf.layout.tablePath(tf.dir, tf.FileID())
 	f, err := os.Open(path)
 
 	if err != nil {
@@ -939,6 +2109,7 @@ func (nbs *NomsBlockStore) Sources(ctx context.Context) (hash.Hash, []TableFile,
 			tf := NomsBlockStoreTableFile{
 				NomsBlockStoreTableFileInfo: NomsBlockStoreTableFileInfo{info: info},
 				dir:                         fsPersister.dir,
+				layout:                      fsPersister.layout,
 			}
 			tableFiles = append(tableFiles, tf)
 		} else {
@@ -949,25 +2120,272 @@ func (nbs *NomsBlockStore) Sources(ctx context.Context) (hash.Hash, []TableFile,
 	return contents.GetRoot(), tableFiles, nil
 }
 
+// MemTableDiskUsageName is the entry in the result of DiskUsage which holds the size of the data buffered in memory.
+const MemTableDiskUsageName = "memtable"
+
+var ErrDiskUsageUnsupported = errors.New("disk usage is only available for local stores")
+
+// DiskUsage returns the size on disk of each of the store's table files, including those not yet committed to the
+// manifest, keyed by table file name. The size of the data buffered in the store's memtable is included under
+// MemTableDiskUsageName, so that the sizes sum to the total held by the store.
+func (nbs *NomsBlockStore) DiskUsage(ctx context.Context) (map[string]uint64, error) {
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if !ok {
+		return nil, ErrDiskUsageUnsupported
+	}
+
+	specs, memTableSize, err := func() ([]tableSpec, uint64, error) {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		specs, err := nbs.tables.ToSpecs()
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if nbs.mt == nil {
+			return specs, 0, nil
+		}
+
+		return specs, nbs.mt.totalData, nil
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]uint64{MemTableDiskUsageName: memTableSize}
+	for _, spec := range specs {
+		name := spec.name.String()
+		info, err := os.Stat(fsPersister.layout.tablePath(fsPersister.dir, name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		usage[name] = uint64(info.Size())
+	}
+
+	return usage, nil
+}
+
+// StorageSize returns the logical size of the store's data, the sum of the uncompressed lengths of its chunks, and its
+// physical size, the sum of the sizes of its table files on disk. Only the table files in the manifest are counted
+// unless |includePending| is set, in which case table files written since the last Commit are counted too, as are the
+// chunks in the memtable, which add to the logical size only.
+func (nbs *NomsBlockStore) StorageSize(ctx context.Context, includePending bool) (logical, physical uint64, err error) {
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if !ok {
+		return 0, 0, ErrDiskUsageUnsupported
+	}
+
+	sources := func() chunkSources {
+		nbs.mu.RLock()
+		defer nbs.mu.RUnlock()
+
+		if !includePending {
+			return nbs.tables.upstream
+		}
+
+		if nbs.mt != nil {
+			logical += nbs.mt.totalData
+		}
+
+		sources := make(chunkSources, 0, len(nbs.tables.novel)+len(nbs.tables.upstream))
+		sources = append(sources, nbs.tables.novel...)
+		return append(sources, nbs.tables.upstream...)
+	}()
+
+	for _, src := range sources {
+		cnt, err := src.count()
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if cnt == 0 {
+			continue
+		}
+
+		uncmpLen, err := src.uncompressedLen()
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		h, err := src.hash()
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		info, err := os.Stat(fsPersister.layout.tablePath(fsPersister.dir, h.String()))
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		logical += uncmpLen
+		physical += uint64(info.Size())
+	}
+
+	return logical, physical, nil
+}
+
+// CompressionStat describes how well the chunks of a table file compress.
+type CompressionStat struct {
+	ChunkCount uint32
+
+	// LogicalBytes is the sum of the uncompressed lengths of the table's chunks, and CompressedBytes the sum of the
+	// lengths of their compressed records, checksums included.
+	LogicalBytes    uint64
+	CompressedBytes uint64
+}
+
+// Ratio returns LogicalBytes divided by CompressedBytes, which is less than 1 for data that doesn't compress.
+func (cs CompressionStat) Ratio() float64 {
+	if cs.CompressedBytes == 0 {
+		return 0
+	}
+
+	return float64(cs.LogicalBytes) / float64(cs.CompressedBytes)
+}
+
+// TableCompressionStats returns the CompressionStat of each of the store's non-empty table files, including those not
+// yet committed to the manifest, keyed by table file name. The sizes are those recorded in the tables' indexes and
+// footers, so no chunk data is read.
+func (nbs *NomsBlockStore) TableCompressionStats() (map[string]CompressionStat, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	stats := make(map[string]CompressionStat)
+	for _, css := range []chunkSources{nbs.tables.novel, nbs.tables.upstream} {
+		for _, src := range css {
+			index, err := src.index()
+
+			if err != nil {
+				return nil, err
+			}
+
+			if index.chunkCount == 0 {
+				continue
+			}
+
+			h, err := src.hash()
+
+			if err != nil {
+				return nil, err
+			}
+
+			stat := CompressionStat{ChunkCount: index.chunkCount, LogicalBytes: index.totalUncompressedData}
+			for _, l := range index.lengths {
+				stat.CompressedBytes += uint64(l)
+			}
+
+			stats[h.String()] = stat
+		}
+	}
+
+	return stats, nil
+}
+
+// FragmentationRatio returns the number of tables in the manifest divided by the number an optimally conjoined store
+// would have, so that it is 1 for a store which gains nothing from conjoining and grows as tables accumulate. When the
+// store's ConjoinPolicy has a MinTableSize, tables at least that large are left alone by an optimal conjoin and all
+// the smaller ones conjoin into a single table. Otherwise an optimal store has a single table. An empty store has a
+// ratio of 1.
+func (nbs *NomsBlockStore) FragmentationRatio(ctx context.Context) (float64, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	return nbs.fragmentationRatio(ctx)
+}
+
+func (nbs *NomsBlockStore) fragmentationRatio(ctx context.Context) (float64, error) {
+	minTableSize := conjoinMinTableSize(nbs.c)
+
+	var tables, optimal int
+	var hasSmall bool
+	for _, src := range nbs.tables.upstream {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		cnt, err := src.count()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if cnt == 0 {
+			continue
+		}
+
+		tables++
+
+		if minTableSize == 0 {
+			hasSmall = true
+			continue
+		}
+
+		size, err := src.uncompressedLen()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if size < minTableSize {
+			hasSmall = true
+		} else {
+			optimal++
+		}
+	}
+
+	if hasSmall {
+		optimal++
+	}
+
+	if optimal == 0 {
+		return 1, nil
+	}
+
+	return float64(tables) / float64(optimal), nil
+}
+
 func (nbs *NomsBlockStore) SupportedOperations() TableFileStoreOps {
 	_, canwrite := nbs.p.(*fsTablePersister)
 	return TableFileStoreOps{
 		CanRead:  true,
-		CanWrite: canwrite,
+		CanWrite: canwrite && nbs.follower == nil,
 	}
 }
 
 // WriteTableFile will read a table file from the provided reader and write it to the TableFileStore
 func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, numChunks int, rd io.Reader, contentLength uint64, contentHash []byte) error {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
 	fsPersister, ok := nbs.p.(*fsTablePersister)
 
 	if !ok {
 		return errors.New("Not implemented")
 	}
 
-	path := filepath.Join(fsPersister.dir, fileId)
+	tableDir := fsPersister.layout.tableDir(fsPersister.dir, fileId)
+	err := os.MkdirAll(tableDir, os.ModePerm)
+
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(tableDir, fileId)
+	checksum := newTableChecksum()
 
-	err := func() (err error) {
+	err = func() (err error) {
 		var f *os.File
 		f, err = os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
 
@@ -983,7 +2401,12 @@ func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, nu
 			}
 		}()
 
-		_, err = io.Copy(f, rd)
+		var w io.Writer = f
+		if fsPersister.checksums {
+			w = io.MultiWriter(f, checksum)
+		}
+
+		_, err = io.Copy(w, rd)
 
 		return err
 	}()
@@ -998,6 +2421,14 @@ func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, nu
 		return errors.New("invalid base32 encoded hash: " + fileId)
 	}
 
+	if fsPersister.checksums {
+		err = fsPersister.writeChecksum(addr(fileIdHash), checksum.Sum64())
+
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err = nbs.UpdateManifest(ctx, map[hash.Hash]uint32{fileIdHash: uint32(numChunks)})
 
 	return err
@@ -1006,7 +2437,7 @@ func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, nu
 // SetRootChunk changes the root chunk hash from the previous value to the new root.
 func (nbs *NomsBlockStore) SetRootChunk(ctx context.Context, root, previous hash.Hash) error {
 	for {
-		err := nbs.updateManifest(ctx, root, previous)
+		err := nbs.updateManifest(ctx, root, previous, nil)
 
 		if err == nil {
 			return nil
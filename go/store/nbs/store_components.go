@@ -0,0 +1,352 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// Manifest records the root and tables of a store opened by NewBlockStore or NewFollowerStore. It is the extension
+// point for keeping the manifest in a backend this package has no built in support for. Implementations must be
+// goroutine-safe, and must be safe to use from every process sharing the store.
+type Manifest interface {
+	// Name returns a stable, unique identifier for the store this manifest describes. Stores in the same process
+	// with the same Name share a manifest cache and locks, so it must differ between manifests which don't describe
+	// the same store.
+	Name() string
+
+	// ParseIfExists returns the contents of the manifest. If no manifest has been written yet, |exists| is false and
+	// |contents| is undefined. If |readHook| is non-nil, it must be invoked while the implementation guarantees
+	// exclusive access to the manifest.
+	ParseIfExists(ctx context.Context, stats *Stats, readHook func() error) (exists bool, contents ManifestContents, err error)
+
+	// Update replaces the contents of the manifest with |newContents| if and only if the Lock of its current contents
+	// is |lastLock|, atomically with respect to every other Update and ParseIfExists of the manifest, from any
+	// process. A manifest which doesn't exist yet has the zero Lock. Whether or not the manifest was updated, Update
+	// returns its contents afterwards; callers tell the difference by comparing their Locks. If |writeHook| is
+	// non-nil, it must be invoked while the implementation guarantees exclusive access to the manifest.
+	Update(ctx context.Context, lastLock hash.Hash, newContents ManifestContents, stats *Stats, writeHook func() error) (ManifestContents, error)
+}
+
+// ManifestContents is everything a Manifest must record for a store. A Manifest must return the contents it was last
+// updated with exactly, and needn't interpret them.
+type ManifestContents struct {
+	// NomsVersion is the version of the Noms binary format of the store's data.
+	NomsVersion string
+
+	// Lock identifies the contents. It changes with every Update.
+	Lock hash.Hash
+
+	// Root is the store's root hash.
+	Root hash.Hash
+
+	// Specs are the tables holding every chunk in the store.
+	Specs []TableSpec
+
+	// Meta is the key/value metadata attached to Root by the commit that set it, or nil if there is none.
+	Meta map[string]string
+
+	// MetaHead is the address of the chunk recording the metadata of the newest root committed with metadata, from
+	// which the metadata of earlier roots can be found. It is the zero hash if no root has been committed with
+	// metadata.
+	MetaHead hash.Hash
+
+	// Pinned are the roots pinned by NomsBlockStore.PinRoot, or nil if there are none.
+	Pinned []hash.Hash
+}
+
+// TableSpec names one of the tables listed in a manifest, and gives the number of chunks it holds.
+type TableSpec struct {
+	Name       hash.Hash
+	ChunkCount uint32
+}
+
+func (ts TableSpec) GetName() string {
+	return ts.Name.String()
+}
+
+func (ts TableSpec) GetChunkCount() uint32 {
+	return ts.ChunkCount
+}
+
+// TablePersister stores the table files of a store opened by NewBlockStore or NewFollowerStore. It is the extension
+// point for keeping table files in a backend this package has no built in support for. Implementations must be
+// goroutine-safe.
+type TablePersister interface {
+	// Persist durably writes the complete table file |data|, named |name| and holding |chunkCount| chunks. A table
+	// file is immutable once written, and its name is derived from its contents, so persisting a table which already
+	// exists may be a no-op. Persist must not return until a table file it has written can be opened by any process
+	// sharing the store, since the store lists it in the manifest next.
+	Persist(ctx context.Context, name hash.Hash, data []byte, chunkCount uint32, stats *Stats) error
+
+	// Open returns a reader of the table file |name|, holding |chunkCount| chunks, which was written by Persist.
+	Open(ctx context.Context, name hash.Hash, chunkCount uint32, stats *Stats) (TableFileReader, error)
+}
+
+// TableFileReader reads a table file opened by a TablePersister. Implementations must be goroutine-safe.
+type TableFileReader interface {
+	// ReadAtWithStats reads len(p) bytes of the table file starting at |off| into |p|, with the semantics of
+	// io.ReaderAt.
+	ReadAtWithStats(ctx context.Context, p []byte, off int64, stats *Stats) (n int, err error)
+
+	// Size returns the size of the table file in bytes.
+	Size() uint64
+}
+
+// Conjoiner decides when a store opened by NewBlockStore conjoins its tables into fewer, larger ones. The store itself
+// chooses the tables to conjoin and conjoins them through its TablePersister.
+type Conjoiner interface {
+	// ConjoinRequired returns whether the store should conjoin the tables described by |tables| before committing.
+	// |tables| includes tables the commit is about to add to the manifest. It's called on every Commit, with the
+	// store's manifest update lock held, and must be goroutine-safe.
+	ConjoinRequired(tables []TableSpecInfo) bool
+}
+
+var errShortTableIndex = errors.New("failed to read table index")
+
+// NewBlockStore opens a store whose manifest and table files are kept by |m| and |p|, for backends other than those
+// with their own constructors in this package. |c| decides when the store conjoins its tables; if it is nil, the store
+// conjoins according to DefaultConjoinPolicy. A |memTableSize| of zero uses the default size.
+func NewBlockStore(ctx context.Context, nbfVerStr string, m Manifest, p TablePersister, c Conjoiner, memTableSize uint64) (*NomsBlockStore, error) {
+	cacheOnce.Do(makeGlobalCaches)
+
+	var cj conjoiner = newInlineConjoiner(DefaultConjoinPolicy)
+	if c != nil {
+		cj = conjoinerAdapter{c}
+	}
+
+	return newNomsBlockStore(ctx, nbfVerStr, makeManifestManager(manifestAdapter{m}), tablePersisterAdapter{p, globalIndexCache}, cj, memTableSize)
+}
+
+// manifestAdapter adapts a Manifest to the manifest interface used by NomsBlockStore.
+type manifestAdapter struct {
+	m Manifest
+}
+
+func (ma manifestAdapter) Name() string {
+	return ma.m.Name()
+}
+
+func (ma manifestAdapter) ParseIfExists(ctx context.Context, stats *Stats, readHook func() error) (bool, manifestContents, error) {
+	exists, contents, err := ma.m.ParseIfExists(ctx, stats, readHook)
+
+	if err != nil || !exists {
+		return exists, manifestContents{}, err
+	}
+
+	return true, contents.internal(), nil
+}
+
+func (ma manifestAdapter) Update(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error) {
+	contents, err := ma.m.Update(ctx, hash.Hash(lastLock), exportManifestContents(newContents), stats, writeHook)
+
+	if err != nil {
+		return manifestContents{}, err
+	}
+
+	return contents.internal(), nil
+}
+
+func exportManifestContents(mc manifestContents) ManifestContents {
+	var specs []TableSpec
+	if len(mc.specs) > 0 {
+		specs = make([]TableSpec, len(mc.specs))
+		for i, spec := range mc.specs {
+			specs[i] = TableSpec{hash.Hash(spec.name), spec.chunkCount}
+		}
+	}
+
+	return ManifestContents{
+		NomsVersion: mc.vers,
+		Lock:        hash.Hash(mc.lock),
+		Root:        mc.root,
+		Specs:       specs,
+		Meta:        mc.meta,
+		MetaHead:    hash.Hash(mc.metaHead),
+		Pinned:      mc.pinned,
+	}
+}
+
+func (mc ManifestContents) internal() manifestContents {
+	var specs []tableSpec
+	if len(mc.Specs) > 0 {
+		specs = make([]tableSpec, len(mc.Specs))
+		for i, spec := range mc.Specs {
+			specs[i] = tableSpec{addr(spec.Name), spec.ChunkCount}
+		}
+	}
+
+	return manifestContents{
+		vers:     mc.NomsVersion,
+		lock:     addr(mc.Lock),
+		root:     mc.Root,
+		specs:    specs,
+		meta:     mc.Meta,
+		metaHead: addr(mc.MetaHead),
+		pinned:   mc.Pinned,
+	}
+}
+
+// tablePersisterAdapter adapts a TablePersister to the tablePersister interface used by NomsBlockStore. Conjoined
+// tables are built in memory and then persisted like any other.
+type tablePersisterAdapter struct {
+	p          TablePersister
+	indexCache *indexCache
+}
+
+func (tpa tablePersisterAdapter) Persist(ctx context.Context, mt *memTable, haver chunkReader, stats *Stats) (chunkSource, error) {
+	name, data, chunkCount, err := mt.write(haver, stats)
+
+	if err != nil {
+		return emptyChunkSource{}, err
+	}
+
+	if chunkCount == 0 {
+		return emptyChunkSource{}, nil
+	}
+
+	return tpa.persist(ctx, name, data, chunkCount, stats)
+}
+
+func (tpa tablePersisterAdapter) ConjoinAll(ctx context.Context, sources chunkSources, stats *Stats) (chunkSource, error) {
+	plan, totalUncompressedData, err := planConjoinSources(sources)
+
+	if err != nil {
+		return emptyChunkSource{}, err
+	}
+
+	if plan.chunkCount == 0 {
+		return emptyChunkSource{}, nil
+	}
+
+	buff := &bytes.Buffer{}
+	name, err := writeConjoinedTable(ctx, buff, plan, totalUncompressedData, defaultConjoinMaxMem)
+
+	if err != nil {
+		return emptyChunkSource{}, err
+	}
+
+	stats.BytesPerConjoin.Sample(uint64(buff.Len()))
+
+	return tpa.persist(ctx, name, buff.Bytes(), plan.chunkCount, stats)
+}
+
+func (tpa tablePersisterAdapter) persist(ctx context.Context, name addr, data []byte, chunkCount uint32, stats *Stats) (chunkSource, error) {
+	err := tpa.p.Persist(ctx, hash.Hash(name), data, chunkCount, stats)
+
+	if err != nil {
+		return emptyChunkSource{}, err
+	}
+
+	r, err := tpa.p.Open(ctx, hash.Hash(name), chunkCount, stats)
+
+	if err != nil {
+		return emptyChunkSource{}, err
+	}
+
+	return newReaderFromIndexData(tpa.indexCache, data, name, r, s3BlockSize)
+}
+
+func (tpa tablePersisterAdapter) Open(ctx context.Context, name addr, chunkCount uint32, stats *Stats) (cs chunkSource, err error) {
+	r, err := tpa.p.Open(ctx, hash.Hash(name), chunkCount, stats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tpa.indexCache != nil {
+		tpa.indexCache.lockEntry(name)
+		defer func() {
+			unlockErr := tpa.indexCache.unlockEntry(name)
+
+			if err == nil {
+				err = unlockErr
+			}
+		}()
+
+		if index, found := tpa.indexCache.get(name); found {
+			return &chunkSourceAdapter{newTableReader(index, r, s3BlockSize), name}, nil
+		}
+	}
+
+	size := indexSize(chunkCount) + footerSize
+	fileSize := r.Size()
+
+	if fileSize < size {
+		return nil, errShortTableIndex
+	}
+
+	indexBytes := make([]byte, size)
+	n, err := r.ReadAtWithStats(ctx, indexBytes, int64(fileSize-size), stats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(n) != size {
+		return nil, errShortTableIndex
+	}
+
+	index, err := parseTableIndex(indexBytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tpa.indexCache != nil {
+		tpa.indexCache.put(name, index)
+	}
+
+	return &chunkSourceAdapter{newTableReader(index, r, s3BlockSize), name}, nil
+}
+
+// conjoinerAdapter adapts a Conjoiner to the conjoiner interface used by NomsBlockStore.
+type conjoinerAdapter struct {
+	c Conjoiner
+}
+
+func (ca conjoinerAdapter) ConjoinRequired(ts tableSet) bool {
+	return ca.c.ConjoinRequired(append(tableSpecInfosOf(ts.novel), tableSpecInfosOf(ts.upstream)...))
+}
+
+func (ca conjoinerAdapter) Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
+	return conjoin(ctx, upstream, mm, p, 0, stats)
+}
+
+// tableSpecInfosOf describes the tables of |srcs| which hold any chunks. Sources which can't be described are left
+// out.
+func tableSpecInfosOf(srcs chunkSources) []TableSpecInfo {
+	infos := make([]TableSpecInfo, 0, len(srcs))
+	for _, src := range srcs {
+		cnt, err := src.count()
+
+		if err != nil || cnt == 0 {
+			continue
+		}
+
+		h, err := src.hash()
+
+		if err != nil {
+			continue
+		}
+
+		infos = append(infos, TableSpec{hash.Hash(h), cnt})
+	}
+
+	return infos
+}
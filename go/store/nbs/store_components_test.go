@@ -0,0 +1,172 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+// testManifest is an in-memory Manifest.
+type testManifest struct {
+	name     string
+	mu       sync.Mutex
+	exists   bool
+	contents ManifestContents
+}
+
+func (tm *testManifest) Name() string { return tm.name }
+
+func (tm *testManifest) ParseIfExists(ctx context.Context, stats *Stats, readHook func() error) (bool, ManifestContents, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if readHook != nil {
+		if err := readHook(); err != nil {
+			return false, ManifestContents{}, err
+		}
+	}
+
+	return tm.exists, tm.contents, nil
+}
+
+func (tm *testManifest) Update(ctx context.Context, lastLock hash.Hash, newContents ManifestContents, stats *Stats, writeHook func() error) (ManifestContents, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if writeHook != nil {
+		if err := writeHook(); err != nil {
+			return ManifestContents{}, err
+		}
+	}
+
+	if tm.contents.Lock == lastLock {
+		tm.contents = newContents
+		tm.contents.Specs = append([]TableSpec(nil), newContents.Specs...)
+		tm.exists = true
+	}
+
+	return tm.contents, nil
+}
+
+// testTablePersister is an in-memory TablePersister.
+type testTablePersister struct {
+	mu     sync.Mutex
+	tables map[hash.Hash][]byte
+}
+
+func newTestTablePersister() *testTablePersister {
+	return &testTablePersister{tables: map[hash.Hash][]byte{}}
+}
+
+func (tp *testTablePersister) Persist(ctx context.Context, name hash.Hash, data []byte, chunkCount uint32, stats *Stats) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	tp.tables[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (tp *testTablePersister) Open(ctx context.Context, name hash.Hash, chunkCount uint32, stats *Stats) (TableFileReader, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	data, ok := tp.tables[name]
+
+	if !ok {
+		return nil, errors.New("no such table file")
+	}
+
+	return bytesTableFileReader{bytes.NewReader(data)}, nil
+}
+
+type bytesTableFileReader struct {
+	r *bytes.Reader
+}
+
+func (r bytesTableFileReader) ReadAtWithStats(ctx context.Context, p []byte, off int64, stats *Stats) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+func (r bytesTableFileReader) Size() uint64 {
+	return uint64(r.r.Size())
+}
+
+// countConjoiner requires a conjoin when there are more than max tables.
+type countConjoiner struct {
+	max int
+}
+
+func (c countConjoiner) ConjoinRequired(tables []TableSpecInfo) bool {
+	return len(tables) > c.max
+}
+
+func TestNewBlockStore(t *testing.T) {
+	ctx := context.Background()
+	m := &testManifest{name: "TestNewBlockStore"}
+	p := newTestTablePersister()
+
+	st, err := NewBlockStore(ctx, types.Format_Default.VersionString(), m, p, countConjoiner{2}, 0)
+	require.NoError(t, err)
+	defer st.Close()
+
+	var root hash.Hash
+	var written []chunks.Chunk
+	for i := 0; i < 4; i++ {
+		c := chunks.NewChunk([]byte{byte(i), 0xff})
+		require.NoError(t, st.Put(ctx, c))
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+		root = c.Hash()
+		written = append(written, c)
+	}
+
+	// the conjoiner keeps the manifest to at most 2 tables, but every chunk is still in one of them
+	_, contents, err := m.ParseIfExists(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, root, contents.Root)
+	assert.Equal(t, types.Format_Default.VersionString(), contents.NomsVersion)
+	assert.True(t, len(contents.Specs) <= 2, "%d tables", len(contents.Specs))
+	var chunkCount uint32
+	for _, spec := range contents.Specs {
+		chunkCount += spec.ChunkCount
+	}
+	assert.Equal(t, uint32(len(written)), chunkCount)
+
+	// a second store on the same components sees everything the first wrote
+	reopened, err := NewBlockStore(ctx, types.Format_Default.VersionString(), m, p, nil, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	reopenedRoot, err := reopened.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, reopenedRoot)
+
+	for _, c := range written {
+		read, err := reopened.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), read.Data())
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import "errors"
+
+// These are the kinds of StoreError. Use errors.Is to test whether an error returned by the store is of a given kind.
+var (
+	// ErrCorrupt indicates that data read from the store, such as a manifest, table file or chunk, is malformed.
+	ErrCorrupt = errors.New("corrupt store data")
+
+	// ErrManifestConflict indicates that the manifest was changed by another writer in a way that an update can't be
+	// reconciled with.
+	ErrManifestConflict = errors.New("manifest conflict")
+
+	// ErrNotFound indicates that a chunk, table file or manifest which was expected to exist is missing.
+	ErrNotFound = errors.New("not found")
+)
+
+// StoreError is returned for failures that a caller can expect and recover from, as opposed to violations of the
+// store's internal invariants, which panic. Kind is one of ErrCorrupt, ErrManifestConflict or ErrNotFound.
+type StoreError struct {
+	Kind error
+	Err  error
+}
+
+func newStoreError(kind error, msg string) *StoreError {
+	return &StoreError{kind, errors.New(msg)}
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether |target| is the kind of this error.
+func (e *StoreError) Is(target error) bool {
+	return target == e.Kind
+}
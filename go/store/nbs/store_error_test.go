@@ -0,0 +1,200 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/constants"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+func TestStoreErrorKinds(t *testing.T) {
+	assert.True(t, errors.Is(ErrCorruptManifest, ErrCorrupt))
+	assert.True(t, errors.Is(ErrInvalidTableFile, ErrCorrupt))
+	assert.True(t, errors.Is(ErrChecksumMismatch, ErrCorrupt))
+	assert.False(t, errors.Is(ErrCorruptManifest, ErrNotFound))
+	assert.True(t, errors.Is(tableNotInDynamoErr{"nbs", "dynamo"}, ErrNotFound))
+
+	var se *StoreError
+	require.True(t, errors.As(ErrCorruptManifest, &se))
+	assert.Equal(t, ErrCorrupt, se.Kind)
+	assert.Equal(t, "corrupt manifest", se.Error())
+}
+
+func TestNewCompressedChunkChecksumMismatch(t *testing.T) {
+	cc := ChunkToCompressedChunk(chunks.NewChunk([]byte("abc")))
+	buff := append([]byte(nil), cc.FullCompressedChunk...)
+	buff[0] ^= 0xff
+
+	_, err := NewCompressedChunk(cc.H, buff)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestFileManifestUpdateConflict(t *testing.T) {
+	fm := makeFileManifestTempDir(t)
+	defer os.RemoveAll(fm.dir)
+
+	// updating from a lock when no manifest exists means another writer removed it
+	contents := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("locker")), root: hash.Of([]byte("root"))}
+	_, err := fm.Update(context.Background(), computeAddr([]byte("previous")), contents, &Stats{}, nil)
+	assert.True(t, errors.Is(err, ErrManifestConflict))
+}
+
+func TestLocalStoreCorruptTableFile(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewLocalStore(ctx, constants.FormatDefaultString, dir, testMemTableSize)
+	require.NoError(t, err)
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, store.Put(ctx, c))
+	success, err := store.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, success)
+	require.NoError(t, store.Close())
+
+	// corrupt the chunk data at the start of every table file
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, info := range infos {
+		if info.Name() == manifestFileName || info.Name() == lockFileName {
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		data[0] ^= 0xff
+		require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	}
+
+	store, err = NewLocalStore(ctx, constants.FormatDefaultString, dir, testMemTableSize)
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Get(ctx, c.Hash())
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestLocalStoreValidateTableFiles(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultLocalStoreOptions
+	opts.TableChecksums = true
+	store, err := NewLocalStoreWithOptions(ctx, constants.FormatDefaultString, dir, testMemTableSize, opts)
+	require.NoError(t, err)
+	defer store.Close()
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, store.Put(ctx, c))
+	success, err := store.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, success)
+	unchecked, err := store.ValidateTableFiles(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, unchecked)
+
+	_, tableFiles, err := store.Sources(ctx)
+	require.NoError(t, err)
+	require.Len(t, tableFiles, 1)
+	fileID := tableFiles[0].FileID()
+	path := filepath.Join(dir, fileID)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	// A table file written by WriteTableFile gets a checksum too, while a store without checksums records none.
+	for _, checksums := range []bool{true, false} {
+		func() {
+			sinkDir, err := ioutil.TempDir("", "")
+			require.NoError(t, err)
+			defer os.RemoveAll(sinkDir)
+
+			var sink *NomsBlockStore
+			if checksums {
+				sink, err = NewLocalStoreWithOptions(ctx, constants.FormatDefaultString, sinkDir, testMemTableSize, opts)
+			} else {
+				sink, err = NewLocalStore(ctx, constants.FormatDefaultString, sinkDir, testMemTableSize)
+			}
+			require.NoError(t, err)
+			defer sink.Close()
+
+			err = sink.WriteTableFile(ctx, fileID, tableFiles[0].NumChunks(), bytes.NewReader(data), uint64(len(data)), nil)
+			require.NoError(t, err)
+
+			unchecked, err := sink.ValidateTableFiles(ctx)
+			require.NoError(t, err)
+			if checksums {
+				expected, err := store.TableFileChecksum(fileID)
+				require.NoError(t, err)
+				actual, err := sink.TableFileChecksum(fileID)
+				require.NoError(t, err)
+				assert.Equal(t, expected, actual)
+				assert.Empty(t, unchecked)
+			} else {
+				_, err = sink.TableFileChecksum(fileID)
+				assert.Equal(t, ErrNoTableFileChecksum, err)
+				assert.Equal(t, []string{fileID}, unchecked)
+			}
+		}()
+	}
+
+	// A store without checksums keeps the checksum recorded for a table file copied into it, once the copy matches.
+	func() {
+		sinkDir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		defer os.RemoveAll(sinkDir)
+
+		sink, err := NewLocalStore(ctx, constants.FormatDefaultString, sinkDir, testMemTableSize)
+		require.NoError(t, err)
+		defer sink.Close()
+
+		err = sink.WriteTableFile(ctx, fileID, tableFiles[0].NumChunks(), bytes.NewReader(data), uint64(len(data)), nil)
+		require.NoError(t, err)
+
+		expected, err := store.TableFileChecksum(fileID)
+		require.NoError(t, err)
+		assert.Equal(t, ErrTableFileChecksumMismatch, sink.RecordTableFileChecksum(fileID, expected+1))
+		_, err = sink.TableFileChecksum(fileID)
+		assert.Equal(t, ErrNoTableFileChecksum, err)
+
+		require.NoError(t, sink.RecordTableFileChecksum(fileID, expected))
+		actual, err := sink.TableFileChecksum(fileID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		unchecked, err := sink.ValidateTableFiles(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, unchecked)
+	}()
+
+	require.NoError(t, ioutil.WriteFile(path, data[:len(data)/2], 0644))
+
+	_, err = store.ValidateTableFiles(ctx)
+	assert.Equal(t, ErrTableFileChecksumMismatch, err)
+}
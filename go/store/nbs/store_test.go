@@ -17,16 +17,22 @@ package nbs
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
 	"github.com/liquidata-inc/dolt/go/store/types"
 )
 
@@ -77,3 +83,859 @@ func TestNBSAsTableFileStore(t *testing.T) {
 		assert.Equal(t, expected, data)
 	}
 }
+
+func TestLocalStoreShardedTableLayout(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	opts := DefaultLocalStoreOptions
+	opts.Layout = ShardedTableLayout
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+
+	c := chunks.NewChunk([]byte("sharded"))
+	err = st.Put(ctx, c)
+	require.NoError(t, err)
+	ok, err := st.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	data, addr, err := buildTable([][]byte{[]byte("written")})
+	require.NoError(t, err)
+	err = st.WriteTableFile(ctx, addr.String(), 1, bytes.NewReader(data), 0, nil)
+	require.NoError(t, err)
+
+	_, sources, err := st.Sources(ctx)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+
+	for _, src := range sources {
+		fileID := src.FileID()
+		_, err := os.Stat(filepath.Join(testDir, fileID[:tableShardPrefixLen], fileID))
+		assert.NoError(t, err)
+		_, err = os.Stat(filepath.Join(testDir, fileID))
+		assert.True(t, os.IsNotExist(err))
+
+		rd, err := src.Open()
+		require.NoError(t, err)
+		require.NoError(t, rd.Close())
+	}
+
+	require.NoError(t, st.Close())
+
+	// Reopening with the default layout uses the layout recorded in the manifest.
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.Close()
+
+	found, err := st.Get(ctx, c.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, c.Data(), found.Data())
+
+	found, err = st.Get(ctx, chunks.NewChunk([]byte("written")).Hash())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("written"), found.Data())
+}
+
+func TestLocalStoreWithConjoinPolicy(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	numTables := func() int {
+		_, contents, err := fileManifest{dir: testDir}.ParseIfExists(ctx, &Stats{}, nil)
+		require.NoError(t, err)
+		return len(contents.specs)
+	}
+
+	commitChunk := func(st *NomsBlockStore, data string) {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	// a permissive policy defers conjoins, as during a bulk import
+	opts := DefaultLocalStoreOptions
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 100}
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		commitChunk(st, strconv.Itoa(i))
+	}
+	require.NoError(t, st.Close())
+	assert.Equal(t, 4, numTables())
+
+	// a strict policy conjoins on the next commit
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 2}
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	commitChunk(st, "after import")
+	require.NoError(t, st.Close())
+	assert.True(t, numTables() <= 2, "%d tables", numTables())
+}
+
+func TestPlanConjoin(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	commitChunks := func(st *NomsBlockStore, data ...string) {
+		var last chunks.Chunk
+		for _, d := range data {
+			last = chunks.NewChunk([]byte(d))
+			require.NoError(t, st.Put(ctx, last))
+		}
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, last.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	opts := DefaultLocalStoreOptions
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 100}
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	commitChunks(st, "a", "b", "c", "d", "e", "f")
+	commitChunks(st, "g")
+	commitChunks(st, "h")
+
+	plan, err := st.PlanConjoin(ctx)
+	require.NoError(t, err)
+	assert.False(t, plan.Required)
+	require.NoError(t, st.Close())
+
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 2}
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	defer st.Close()
+
+	plan, err = st.PlanConjoin(ctx)
+	require.NoError(t, err)
+	require.True(t, plan.Required)
+	assert.Len(t, plan.Conjoinees, 2)
+	require.Len(t, plan.Keepers, 1)
+	assert.Equal(t, uint32(6), plan.Keepers[0].GetChunkCount())
+	assert.Equal(t, uint32(2), plan.ChunkCount)
+	assert.Equal(t, uint64(2), plan.UncompressedSize)
+	assert.Equal(t, 2, plan.TableCount)
+
+	// planning leaves the manifest alone
+	_, contents, err := fileManifest{dir: testDir}.ParseIfExists(ctx, &Stats{}, nil)
+	require.NoError(t, err)
+	assert.Len(t, contents.specs, 3)
+
+	var events []ConjoinEvent
+	st.SetConjoinObserver(func(e ConjoinEvent) {
+		events = append(events, e)
+	})
+	commitChunks(st, "i")
+
+	require.NotEmpty(t, events)
+	conjoined := events[0]
+	require.NoError(t, conjoined.Err)
+	require.True(t, conjoined.Required)
+	assert.ElementsMatch(t, append(plan.Conjoinees, plan.Keepers...), conjoined.Before)
+	require.Len(t, conjoined.After, plan.TableCount)
+	assert.Equal(t, plan.ChunkCount, conjoined.After[0].GetChunkCount())
+	assert.Equal(t, plan.Keepers, conjoined.After[1:])
+}
+
+func TestFragmentationRatio(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	commitChunk := func(st *NomsBlockStore, data string) {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	opts := DefaultLocalStoreOptions
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 100}
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	ratio, err := st.FragmentationRatio(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, ratio)
+
+	for i := 0; i < 6; i++ {
+		commitChunk(st, strconv.Itoa(i))
+	}
+
+	fragmented, err := st.FragmentationRatio(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, fragmented)
+	assert.Equal(t, fragmented, st.Stats().(Stats).FragmentationRatio)
+	require.NoError(t, st.Close())
+
+	// tables this large are already optimal
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 100, MinTableSize: 1}
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	ratio, err = st.FragmentationRatio(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, ratio)
+	require.NoError(t, st.Close())
+
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 2}
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	commitChunk(st, "conjoin")
+	ratio, err = st.FragmentationRatio(ctx)
+	require.NoError(t, err)
+	assert.True(t, ratio < fragmented, "ratio %f", ratio)
+	require.NoError(t, st.Close())
+}
+
+func TestLocalStoreWithDurabilityPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policies := []DurabilityPolicy{
+		DefaultDurabilityPolicy,
+		{FsyncManifest: true},
+		{},
+	}
+
+	for _, policy := range policies {
+		t.Run(fmt.Sprintf("%+v", policy), func(t *testing.T) {
+			testDir := filepath.Join(os.TempDir(), uuid.New().String())
+			err := os.MkdirAll(testDir, os.ModePerm)
+			require.NoError(t, err)
+			defer os.RemoveAll(testDir)
+
+			opts := DefaultLocalStoreOptions
+			opts.DurabilityPolicy = policy
+			st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+			require.NoError(t, err)
+			c := chunks.NewChunk([]byte("durable"))
+			require.NoError(t, st.Put(ctx, c))
+			ok, err := st.Commit(ctx, c.Hash(), hash.Hash{})
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.NoError(t, st.Close())
+
+			st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+			require.NoError(t, err)
+			defer st.Close()
+			root, err := st.Root(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, c.Hash(), root)
+			has, err := st.Has(ctx, c.Hash())
+			require.NoError(t, err)
+			assert.True(t, has)
+		})
+	}
+}
+
+func BenchmarkLocalStoreDurabilityPolicy(b *testing.B) {
+	ctx := context.Background()
+
+	policies := []DurabilityPolicy{
+		DefaultDurabilityPolicy,
+		{FsyncManifest: true},
+		{},
+	}
+
+	for _, policy := range policies {
+		b.Run(fmt.Sprintf("%+v", policy), func(b *testing.B) {
+			testDir := filepath.Join(os.TempDir(), uuid.New().String())
+			err := os.MkdirAll(testDir, os.ModePerm)
+			require.NoError(b, err)
+			defer os.RemoveAll(testDir)
+
+			opts := DefaultLocalStoreOptions
+			opts.DurabilityPolicy = policy
+			st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+			require.NoError(b, err)
+			defer st.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c := chunks.NewChunk([]byte(strconv.Itoa(i)))
+				err := st.Put(ctx, c)
+				require.NoError(b, err)
+				root, err := st.Root(ctx)
+				require.NoError(b, err)
+				ok, err := st.Commit(ctx, c.Hash(), root)
+				require.NoError(b, err)
+				require.True(b, ok)
+			}
+		})
+	}
+}
+
+func putRandomChunks(t require.TestingT, st *NomsBlockStore, n, size int) hash.HashSlice {
+	hashes := make(hash.HashSlice, n)
+	for i := range hashes {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+		c := chunks.NewChunk(data)
+		require.NoError(t, st.Put(context.Background(), c))
+		hashes[i] = c.Hash()
+	}
+
+	return hashes
+}
+
+func TestLocalStoreWithAppendPolicy(t *testing.T) {
+	ctx := context.Background()
+	const memTableSize = 1 << 8
+
+	numTables := func(policy AppendPolicy) int {
+		testDir := filepath.Join(os.TempDir(), uuid.New().String())
+		err := os.MkdirAll(testDir, os.ModePerm)
+		require.NoError(t, err)
+		defer os.RemoveAll(testDir)
+
+		opts := DefaultLocalStoreOptions
+		opts.AppendPolicy = policy
+		st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, memTableSize, opts)
+		require.NoError(t, err)
+		hashes := putRandomChunks(t, st, 64, memTableSize/4)
+		ok, err := st.Commit(ctx, hashes[0], hash.Hash{})
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, st.Close())
+
+		st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, memTableSize)
+		require.NoError(t, err)
+		defer st.Close()
+		absent, err := st.HasMany(ctx, hash.NewHashSet(hashes...))
+		require.NoError(t, err)
+		assert.Empty(t, absent)
+
+		for _, h := range hashes {
+			c, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, h, c.Hash())
+		}
+
+		_, tableFiles, err := st.Sources(ctx)
+		require.NoError(t, err)
+
+		// the tables replaced by appends are deleted
+		infos, err := ioutil.ReadDir(testDir)
+		require.NoError(t, err)
+		var onDisk []string
+		for _, info := range infos {
+			if name := info.Name(); len(name) == 32 && ValidateAddr(name) {
+				onDisk = append(onDisk, info.Name())
+			}
+		}
+
+		var inManifest []string
+		for _, tf := range tableFiles {
+			inManifest = append(inManifest, tf.FileID())
+		}
+
+		assert.ElementsMatch(t, inManifest, onDisk)
+		return len(tableFiles)
+	}
+
+	unbounded := numTables(DefaultAppendPolicy)
+	assert.True(t, unbounded >= 16, "%d tables", unbounded)
+	assert.Equal(t, 1, numTables(AppendPolicy{MaxTableSize: 1 << 20}))
+
+	// table data is larger than memtable data, so fewer than 4 memtables fit each table
+	bounded := numTables(AppendPolicy{MaxTableSize: 4 * memTableSize})
+	assert.True(t, bounded > 4 && bounded < unbounded, "%d tables", bounded)
+}
+
+func BenchmarkLocalStoreAppendPolicy(b *testing.B) {
+	ctx := context.Background()
+	const memTableSize = 1 << 12
+
+	policies := []AppendPolicy{
+		DefaultAppendPolicy,
+		{MaxTableSize: 1 << 16},
+		{MaxTableSize: 1 << 20},
+	}
+
+	for _, policy := range policies {
+		b.Run(fmt.Sprintf("%+v", policy), func(b *testing.B) {
+			testDir := filepath.Join(os.TempDir(), uuid.New().String())
+			err := os.MkdirAll(testDir, os.ModePerm)
+			require.NoError(b, err)
+			defer os.RemoveAll(testDir)
+
+			opts := DefaultLocalStoreOptions
+			opts.AppendPolicy = policy
+			st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, memTableSize, opts)
+			require.NoError(b, err)
+			defer st.Close()
+
+			b.ResetTimer()
+			hashes := putRandomChunks(b, st, b.N, 256)
+			ok, err := st.Commit(ctx, hashes[0], hash.Hash{})
+			require.NoError(b, err)
+			require.True(b, ok)
+			b.StopTimer()
+
+			_, tableFiles, err := st.Sources(ctx)
+			require.NoError(b, err)
+			b.ReportMetric(float64(len(tableFiles)), "tables")
+		})
+	}
+}
+
+func TestTableAccessStats(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	opts := DefaultLocalStoreOptions
+	opts.ConjoinPolicy = ConjoinPolicy{MaxTables: 100}
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	var cs []chunks.Chunk
+	for _, data := range []string{"a", "b", "c"} {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+		cs = append(cs, c)
+	}
+	require.NoError(t, st.Close())
+
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	defer st.Close()
+	assert.Nil(t, st.TableAccessStats())
+
+	st.EnableTableAccessTracking(time.Second)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	st.tables.access.now = func() time.Time { return now }
+
+	_, contents, err := fileManifest{dir: testDir}.ParseIfExists(ctx, &Stats{}, nil)
+	require.NoError(t, err)
+	tableNames := make(map[string]bool)
+	for _, spec := range contents.specs {
+		tableNames[spec.name.String()] = true
+	}
+
+	_, err = st.Get(ctx, cs[0].Hash())
+	require.NoError(t, err)
+	accessed := st.TableAccessStats()
+	require.Len(t, accessed, 1)
+	var tableA string
+	for name, at := range accessed {
+		tableA = name
+		assert.Equal(t, start, at)
+	}
+	assert.True(t, tableNames[tableA])
+
+	// a read of another table is recorded without touching the first
+	now = start.Add(time.Minute)
+	_, err = st.Get(ctx, cs[1].Hash())
+	require.NoError(t, err)
+	accessed = st.TableAccessStats()
+	require.Len(t, accessed, 2)
+	assert.Equal(t, start, accessed[tableA])
+
+	// reads within the same interval leave the time alone, and GetMany reads are recorded too
+	now = start.Add(2*time.Minute + 500*time.Millisecond)
+	found := make(chan *chunks.Chunk, 1)
+	err = st.GetMany(ctx, hash.NewHashSet(cs[0].Hash()), found)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	now = start.Add(2*time.Minute + 900*time.Millisecond)
+	_, err = st.Get(ctx, cs[0].Hash())
+	require.NoError(t, err)
+	accessed = st.TableAccessStats()
+	assert.Equal(t, start.Add(2*time.Minute), accessed[tableA])
+
+	st.ResetTableAccessStats()
+	assert.Empty(t, st.TableAccessStats())
+}
+
+func TestManifestLog(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.Close()
+
+	_, err = st.ReadManifestLog(time.Time{})
+	assert.Equal(t, ErrNoManifestLog, err)
+
+	st.SetManifestLog(NewFileManifestLog(filepath.Join(testDir, "manifest.log")))
+	var roots []hash.Hash
+	for _, data := range []string{"a", "b", "c"} {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+		roots = append(roots, c.Hash())
+	}
+
+	entries, err := st.ReadManifestLog(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.Equal(t, roots[i], entry.Root)
+		assert.Len(t, entry.Specs, i+1)
+	}
+
+	assert.Equal(t, hash.Hash(st.upstream.lock), entries[2].Lock)
+	assert.Equal(t, tableSpecInfos(st.upstream.specs), entries[2].Specs)
+
+	entries, err = st.ReadManifestLog(entries[1].Timestamp)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, roots[1:], []hash.Hash{entries[0].Root, entries[1].Root})
+}
+
+type failingManifestLog struct {
+	err error
+}
+
+func (fl failingManifestLog) Append(entry ManifestLogEntry) error {
+	return fl.err
+}
+
+func (fl failingManifestLog) Read(since time.Time) ([]ManifestLogEntry, error) {
+	return nil, fl.err
+}
+
+func TestManifestLogErr(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.Close()
+
+	// the manifest is updated before the entry is appended, so a failed append doesn't fail the commit
+	appendErr := errors.New("append failed")
+	st.SetManifestLog(failingManifestLog{appendErr})
+	c := chunks.NewChunk([]byte("a"))
+	require.NoError(t, st.Put(ctx, c))
+	ok, err := st.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, appendErr, st.ManifestLogErr())
+
+	root, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c.Hash(), root)
+
+	st.SetManifestLog(NewFileManifestLog(filepath.Join(testDir, "manifest.log")))
+	assert.NoError(t, st.ManifestLogErr())
+	c2 := chunks.NewChunk([]byte("b"))
+	require.NoError(t, st.Put(ctx, c2))
+	ok, err = st.Commit(ctx, c2.Hash(), c.Hash())
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NoError(t, st.ManifestLogErr())
+}
+
+func TestFileManifestLogPartialLine(t *testing.T) {
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "manifest.log")
+	entry := func(data string) ManifestLogEntry {
+		return ManifestLogEntry{Root: hash.Of([]byte(data)), Lock: hash.Of([]byte("lock " + data)), Timestamp: time.Now()}
+	}
+	entryRoots := func(entries []ManifestLogEntry) (roots []hash.Hash) {
+		for _, entry := range entries {
+			roots = append(roots, entry.Root)
+		}
+		return roots
+	}
+
+	require.NoError(t, NewFileManifestLog(path).Append(entry("a")))
+
+	// a crash left a partial line after the first entry
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("12345:partial")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	log := NewFileManifestLog(path)
+	entries, err := log.Read(time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []hash.Hash{hash.Of([]byte("a"))}, entryRoots(entries))
+
+	require.NoError(t, log.Append(entry("b")))
+	require.NoError(t, log.Append(entry("c")))
+	entries, err = log.Read(time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []hash.Hash{hash.Of([]byte("a")), hash.Of([]byte("b")), hash.Of([]byte("c"))}, entryRoots(entries))
+}
+
+func TestAllKnownRoots(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.Close()
+
+	roots, err := st.AllKnownRoots(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, roots)
+
+	commit := func(data string) hash.Hash {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return c.Hash()
+	}
+
+	// without a log only the current root is known
+	a := commit("a")
+	roots, err = st.AllKnownRoots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []hash.Hash{a}, roots)
+
+	st.SetManifestLog(NewFileManifestLog(filepath.Join(testDir, "manifest.log")))
+	b := commit("b")
+	c := commit("c")
+
+	roots, err = st.AllKnownRoots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []hash.Hash{b, c}, roots)
+
+	// undoing the last commit returns to b, which is recorded again
+	ok, err := st.Commit(ctx, b, c)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	roots, err = st.AllKnownRoots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []hash.Hash{b, c, b}, roots)
+}
+
+func TestMinConjoinInterval(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	policy := ConjoinPolicy{MaxTables: 2, MinConjoinInterval: time.Minute}
+	opts := DefaultLocalStoreOptions
+	opts.ConjoinPolicy = policy
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize, opts)
+	require.NoError(t, err)
+	defer st.Close()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.c.(inlineConjoiner).last.now = func() time.Time { return now }
+
+	var conjoins int
+	st.SetConjoinObserver(func(event ConjoinEvent) {
+		if event.Required {
+			conjoins++
+		}
+	})
+
+	commit := func(data string) {
+		c := chunks.NewChunk([]byte(data))
+		require.NoError(t, st.Put(ctx, c))
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+		ok, err := st.Commit(ctx, c.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	// the first conjoin isn't rate limited, but later ones wait for the interval to pass
+	for i := 0; i < 10; i++ {
+		commit(fmt.Sprintf("chunk %d", i))
+		now = now.Add(time.Second)
+	}
+
+	assert.Equal(t, 1, conjoins)
+	assert.True(t, st.tables.Size() > policy.MaxTables)
+
+	now = now.Add(time.Minute)
+	commit("after the interval")
+	assert.Equal(t, 2, conjoins)
+	assert.True(t, st.tables.Size() <= policy.MaxTables)
+}
+
+func TestDiskSpaceMonitor(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+	defer st.CloseDiscardingPending()
+
+	available := uint64(1 << 20)
+	m := &DiskSpaceMonitor{
+		MinAvailable: 1 << 10,
+		availableSpace: func(dir string) (uint64, error) {
+			assert.Equal(t, testDir, dir)
+			return available, nil
+		},
+	}
+	st.SetDiskSpaceMonitor(m)
+
+	c1, c2 := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def"))
+	require.NoError(t, st.Put(ctx, c1))
+	ok, err := st.Commit(ctx, c1.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Below the threshold, nothing is written and the root is unchanged.
+	available = 1 << 9
+	require.NoError(t, st.Put(ctx, c2))
+	_, err = st.Commit(ctx, c2.Hash(), c1.Hash())
+	assert.True(t, errors.Is(err, ErrLowDiskSpace))
+	err = st.Flush(ctx)
+	assert.True(t, errors.Is(err, ErrLowDiskSpace))
+
+	root, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c1.Hash(), root)
+
+	var warned uint64
+	m.Warn = func(dir string, available uint64) {
+		warned = available
+	}
+	ok, err = st.Commit(ctx, c2.Hash(), c1.Hash())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<9), warned)
+
+	st.SetDiskSpaceMonitor(nil)
+	c3 := chunks.NewChunk([]byte("ghi"))
+	require.NoError(t, st.Put(ctx, c3))
+	ok, err = st.Commit(ctx, c3.Hash(), c2.Hash())
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStoreInfo(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	before := time.Now()
+	opts := DefaultLocalStoreOptions
+	opts.StoreInfo = true
+	st, err := NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, 1<<20, opts)
+	require.NoError(t, err)
+
+	info := st.StoreInfo()
+	assert.False(t, info.Created.Before(before.Round(0)))
+	assert.Equal(t, uint64(1<<20), info.MemTableSize)
+	assert.Equal(t, types.Format_Default.VersionString(), info.FormatVersion)
+	assert.Equal(t, ExtendedStorageVersion, info.StorageVersion)
+
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, st.Put(ctx, c))
+	ok, err := st.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, st.Close())
+
+	// The store is reopened with a different memtable size, but keeps the one it was created with.
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, 1<<21)
+	require.NoError(t, err)
+	defer st.Close()
+
+	reopened := st.StoreInfo()
+	assert.True(t, info.Created.Equal(reopened.Created))
+	assert.Equal(t, info.MemTableSize, reopened.MemTableSize)
+	assert.Equal(t, info.FormatVersion, reopened.FormatVersion)
+	assert.Equal(t, ExtendedStorageVersion, reopened.StorageVersion)
+}
+
+func TestStoreInfoNotRecorded(t *testing.T) {
+	ctx := context.Background()
+	testDir := filepath.Join(os.TempDir(), uuid.New().String())
+
+	err := os.MkdirAll(testDir, os.ModePerm)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, 1<<20)
+	require.NoError(t, err)
+
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, st.Put(ctx, c))
+	ok, err := st.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, st.Close())
+
+	// Asking for the store info when reopening an existing store doesn't record it.
+	opts := DefaultLocalStoreOptions
+	opts.StoreInfo = true
+	st, err = NewLocalStoreWithOptions(ctx, types.Format_Default.VersionString(), testDir, 1<<20, opts)
+	require.NoError(t, err)
+	defer st.Close()
+
+	info := st.StoreInfo()
+	assert.True(t, info.Created.IsZero())
+	assert.Equal(t, uint64(0), info.MemTableSize)
+	assert.Equal(t, types.Format_Default.VersionString(), info.FormatVersion)
+	assert.Equal(t, StorageVersion, info.StorageVersion)
+}
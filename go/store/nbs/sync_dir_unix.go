@@ -0,0 +1,38 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin dragonfly freebsd linux openbsd solaris netbsd
+
+package nbs
+
+import "os"
+
+// syncDir fsyncs the directory |dir|, making the renames of files into it durable.
+func syncDir(dir string) (err error) {
+	f, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		closeErr := f.Close()
+
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	return f.Sync()
+}
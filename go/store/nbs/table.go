@@ -81,6 +81,8 @@ import (
 
      - Each Length is the length of a Chunk Record in this Table.
      - Length M must correspond to Chunk Record M for 0 <= M <= N
+     - Offsets are computed by summing Lengths as 64-bit integers, so only each Chunk Record is limited to 4GB, not the
+       Table as a whole.
 
    Suffixes:
    +------------------+------------------+-----+--------------------+
@@ -302,3 +304,19 @@ type TableFileStore interface {
 	// Returns a description of the support TableFile operations. Some stores only support reading table files, not writing.
 	SupportedOperations() TableFileStoreOps
 }
+
+// TableFileChecksummer is implemented by TableFileStores which may record a checksum of each of their table files,
+// including those written by WriteTableFile, so that table files copied between them can be checked.
+type TableFileChecksummer interface {
+	// TableFileChecksum returns the checksum recorded for the table file |fileId|, ErrNoTableFileChecksum if it has
+	// none, or ErrTableFileChecksumsUnsupported if the store never records them.
+	TableFileChecksum(fileId string) (uint64, error)
+}
+
+// TableFileChecksumRecorder is implemented by TableFileStores which can record a checksum for a table file copied into
+// them, so that the copy keeps the checksum the source recorded for it.
+type TableFileChecksumRecorder interface {
+	// RecordTableFileChecksum checks the table file |fileId| against |sum|, returning ErrTableFileChecksumMismatch if
+	// they differ, and records |sum| as its checksum otherwise.
+	RecordTableFileChecksum(fileId string, sum uint64) error
+}
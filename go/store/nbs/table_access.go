@@ -0,0 +1,117 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"sync"
+	"time"
+)
+
+// tableAccessTracker records the most recent read of each upstream table of a store. Timestamps are truncated to
+// |granularity|, so repeated reads of a table within the same interval only take the lock to compare them.
+type tableAccessTracker struct {
+	mu          sync.Mutex
+	granularity time.Duration
+	now         func() time.Time
+	accessed    map[addr]time.Time
+}
+
+func newTableAccessTracker(granularity time.Duration) *tableAccessTracker {
+	return &tableAccessTracker{granularity: granularity, now: time.Now, accessed: make(map[addr]time.Time)}
+}
+
+// record notes a read of |cs|. It is a no-op on a nil tracker, which is how a tableSet which isn't tracking reads
+// holds one.
+func (tat *tableAccessTracker) record(cs chunkSource) {
+	if tat == nil {
+		return
+	}
+
+	h, err := cs.hash()
+
+	if err != nil {
+		// tracking is best effort, and the read itself succeeded
+		return
+	}
+
+	t := tat.now().Truncate(tat.granularity)
+
+	tat.mu.Lock()
+	defer tat.mu.Unlock()
+
+	if tat.accessed[h] != t {
+		tat.accessed[h] = t
+	}
+}
+
+func (tat *tableAccessTracker) snapshot() map[string]time.Time {
+	tat.mu.Lock()
+	defer tat.mu.Unlock()
+
+	accessed := make(map[string]time.Time, len(tat.accessed))
+	for h, t := range tat.accessed {
+		accessed[h.String()] = t
+	}
+
+	return accessed
+}
+
+func (tat *tableAccessTracker) reset() {
+	tat.mu.Lock()
+	defer tat.mu.Unlock()
+	tat.accessed = make(map[addr]time.Time)
+}
+
+// EnableTableAccessTracking starts recording the most recent read of each table file in the store's manifest, for
+// TableAccessStats. Times are truncated to |granularity|, which keeps the cost of a read to a comparison for tables
+// read often. Reads of tables written since the last Commit aren't recorded. Enabling tracking again changes the
+// granularity and keeps the times recorded so far.
+func (nbs *NomsBlockStore) EnableTableAccessTracking(granularity time.Duration) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+
+	if nbs.tables.access == nil {
+		nbs.tables.access = newTableAccessTracker(granularity)
+		return
+	}
+
+	nbs.tables.access.mu.Lock()
+	defer nbs.tables.access.mu.Unlock()
+	nbs.tables.access.granularity = granularity
+}
+
+// TableAccessStats returns the time of the most recent read of each table file read since tracking was enabled or
+// last reset, keyed by table file name. Tables which haven't been read are absent. It returns nil if tracking isn't
+// enabled.
+func (nbs *NomsBlockStore) TableAccessStats() map[string]time.Time {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	if nbs.tables.access == nil {
+		return nil
+	}
+
+	return nbs.tables.access.snapshot()
+}
+
+// ResetTableAccessStats forgets every read recorded so far. Tracking stays enabled.
+func (nbs *NomsBlockStore) ResetTableAccessStats() {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	if nbs.tables.access != nil {
+		nbs.tables.access.reset()
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc64"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	nomshash "github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// tableChecksumExt is the extension of the file, stored alongside each table file written by a fsTablePersister with
+// checksums, which holds the CRC-64 of the whole table file.
+//
+// The checksum is kept in this sidecar rather than in the table footer so that table files stay byte for byte what
+// every other reader of the format, including older clients and s3/remote stores, expects, and so that tables written
+// before checksums existed need no rewrite. The cost is that the sidecar must travel with its table: deleteTable
+// removes it, and a clone records the source's checksum on the sink with RecordTableFileChecksum, which validates the
+// copied table file against it.
+const tableChecksumExt = ".crc64"
+
+const tableChecksumSize = uint64Size
+
+var ErrNoTableFileChecksum = errors.New("table file has no checksum")
+var ErrTableFileChecksumMismatch = errors.New("table file does not match its checksum")
+var ErrTableFileChecksumsUnsupported = errors.New("table file checksums are only supported by local stores")
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+func newTableChecksum() hash.Hash64 {
+	return crc64.New(crc64Table)
+}
+
+func (ftp *fsTablePersister) checksumPath(name string) string {
+	return ftp.layout.tablePath(ftp.dir, name) + tableChecksumExt
+}
+
+// writeChecksum records |sum| as the checksum of the table file |name|, which must already be in place.
+func (ftp *fsTablePersister) writeChecksum(name addr, sum uint64) error {
+	buff := make([]byte, tableChecksumSize)
+	binary.BigEndian.PutUint64(buff, sum)
+
+	tempName, err := func() (tempName string, ferr error) {
+		var temp *os.File
+		temp, ferr = ioutil.TempFile(ftp.dir, tempTablePrefix)
+
+		if ferr != nil {
+			return "", ferr
+		}
+
+		defer func() {
+			closeErr := temp.Close()
+
+			if ferr == nil {
+				ferr = closeErr
+			}
+		}()
+
+		_, ferr = temp.Write(buff)
+
+		if ferr != nil {
+			return "", ferr
+		}
+
+		ferr = ftp.syncTemp(temp)
+
+		if ferr != nil {
+			return "", ferr
+		}
+
+		return temp.Name(), nil
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	path := ftp.checksumPath(name.String())
+	err = os.Rename(tempName, path)
+
+	if err != nil {
+		return err
+	}
+
+	if ftp.fsync {
+		return syncDir(filepath.Dir(path))
+	}
+
+	return nil
+}
+
+// TableFileChecksum returns the checksum recorded for the table file |name| when it was written.
+// ErrNoTableFileChecksum is returned for table files written without one, such as those written by stores without
+// table checksums.
+func (ftp *fsTablePersister) TableFileChecksum(name string) (uint64, error) {
+	buff, err := ioutil.ReadFile(ftp.checksumPath(name))
+
+	if os.IsNotExist(err) {
+		return 0, ErrNoTableFileChecksum
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(buff) != tableChecksumSize {
+		return 0, ErrTableFileChecksumMismatch
+	}
+
+	return binary.BigEndian.Uint64(buff), nil
+}
+
+// ValidateTableFile checks the contents of the table file |name| against its recorded checksum, returning
+// ErrTableFileChecksumMismatch if they differ, and ErrNoTableFileChecksum if it has no checksum to check against.
+func (ftp *fsTablePersister) ValidateTableFile(name string) error {
+	expected, err := ftp.TableFileChecksum(name)
+
+	if err != nil {
+		return err
+	}
+
+	actual, err := ftp.computeChecksum(name)
+
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return ErrTableFileChecksumMismatch
+	}
+
+	return nil
+}
+
+// RecordTableFileChecksum checks the contents of the table file |name| against |sum|, the checksum recorded for it
+// by the store it was copied from, and records |sum| as its checksum if they match. It returns
+// ErrTableFileChecksumMismatch, recording nothing, if they differ.
+func (ftp *fsTablePersister) RecordTableFileChecksum(name string, sum uint64) error {
+	a, ok := nomshash.MaybeParse(name)
+
+	if !ok {
+		return errors.New("invalid base32 encoded hash: " + name)
+	}
+
+	actual, err := ftp.computeChecksum(name)
+
+	if err != nil {
+		return err
+	}
+
+	if actual != sum {
+		return ErrTableFileChecksumMismatch
+	}
+
+	return ftp.writeChecksum(addr(a), sum)
+}
+
+// computeChecksum returns the checksum of the current contents of the table file |name|.
+func (ftp *fsTablePersister) computeChecksum(name string) (uint64, error) {
+	f, err := os.Open(ftp.layout.tablePath(ftp.dir, name))
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	tc := newTableChecksum()
+	_, err = io.Copy(tc, f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return tc.Sum64(), nil
+}
+
+// TableFileChecksum returns the checksum recorded for the table file |name|. Only local stores opened with
+// LocalStoreOptions.TableChecksums record checksums.
+func (nbs *NomsBlockStore) TableFileChecksum(name string) (uint64, error) {
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if !ok {
+		return 0, ErrTableFileChecksumsUnsupported
+	}
+
+	return fsPersister.TableFileChecksum(name)
+}
+
+// RecordTableFileChecksum records |sum|, the checksum of the table file |name| in the store it was copied from, as
+// its checksum in this store, after checking the copy against it. It is recorded whether or not the store was opened
+// with LocalStoreOptions.TableChecksums, so that a copied table file keeps its checksum.
+func (nbs *NomsBlockStore) RecordTableFileChecksum(name string, sum uint64) error {
+	if err := nbs.checkWritable(); err != nil {
+		return err
+	}
+
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if !ok {
+		return ErrTableFileChecksumsUnsupported
+	}
+
+	return fsPersister.RecordTableFileChecksum(name, sum)
+}
+
+// ValidateTableFiles checks each of the store's table files against its recorded checksum, so that a table file
+// which was truncated or corrupted, as when copied between machines, is caught before its chunks are read. The IDs of
+// the table files which have no checksum, such as those written before the store recorded checksums, are returned, as
+// they can't be checked.
+func (nbs *NomsBlockStore) ValidateTableFiles(ctx context.Context) (unchecked []string, err error) {
+	fsPersister, ok := nbs.p.(*fsTablePersister)
+
+	if !ok {
+		return nil, ErrTableFileChecksumsUnsupported
+	}
+
+	_, tableFiles, err := nbs.Sources(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tf := range tableFiles {
+		err = fsPersister.ValidateTableFile(tf.FileID())
+
+		if err == ErrNoTableFileChecksum {
+			unchecked = append(unchecked, tf.FileID())
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return unchecked, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// TableLayout controls where a local store places table files within its directory.
+type TableLayout int
+
+const (
+	// FlatTableLayout places every table file directly in the store's directory.
+	FlatTableLayout TableLayout = iota
+
+	// ShardedTableLayout places each table file in a subdirectory named after the first |tableShardPrefixLen|
+	// characters of the table file's name.
+	ShardedTableLayout
+)
+
+const tableShardPrefixLen = 2
+
+var ErrUnknownTableLayout = errors.New("unknown table layout")
+
+func (tl TableLayout) String() string {
+	switch tl {
+	case FlatTableLayout:
+		return "flat"
+	case ShardedTableLayout:
+		return "sharded"
+	default:
+		return "unknown"
+	}
+}
+
+func parseTableLayout(s string) (TableLayout, error) {
+	switch s {
+	case "flat":
+		return FlatTableLayout, nil
+	case "sharded":
+		return ShardedTableLayout, nil
+	default:
+		return FlatTableLayout, ErrUnknownTableLayout
+	}
+}
+
+// tableDir returns the directory within |dir| which holds the table file named |fileID|.
+func (tl TableLayout) tableDir(dir, fileID string) string {
+	if tl == ShardedTableLayout && len(fileID) > tableShardPrefixLen {
+		return filepath.Join(dir, fileID[:tableShardPrefixLen])
+	}
+
+	return dir
+}
+
+// tablePath returns the path of the table file named |fileID| within |dir|.
+func (tl TableLayout) tablePath(dir, fileID string) string {
+	return filepath.Join(tl.tableDir(dir, fileID), fileID)
+}
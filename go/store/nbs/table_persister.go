@@ -22,12 +22,15 @@
 package nbs
 
 import (
+	"bufio"
 	"bytes"
+	"container/heap"
 	"context"
 	"crypto/sha512"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"sync"
 
@@ -209,21 +212,22 @@ func (cp compactionPlan) suffixes() []byte {
 	return cp.mergedIndex[suffixesStart : suffixesStart+uint64(cp.chunkCount)*addrSuffixSize]
 }
 
-func planConjoin(sources chunkSources, stats *Stats) (plan compactionPlan, err error) {
-	var totalUncompressedData uint64
+// planConjoinSources orders |sources| as they are laid out in the table conjoining them, without building the merged
+// index.
+func planConjoinSources(sources chunkSources) (plan compactionPlan, totalUncompressedData uint64, err error) {
 	for _, src := range sources {
 		var uncmp uint64
 		uncmp, err = src.uncompressedLen()
 
 		if err != nil {
-			return compactionPlan{}, err
+			return compactionPlan{}, 0, err
 		}
 
 		totalUncompressedData += uncmp
 		index, err := src.index()
 
 		if err != nil {
-			return compactionPlan{}, err
+			return compactionPlan{}, 0, err
 		}
 
 		plan.chunkCount += index.chunkCount
@@ -236,7 +240,17 @@ func planConjoin(sources chunkSources, stats *Stats) (plan compactionPlan, err e
 	sort.Sort(plan.sources)
 
 	if plan.sources.err != nil {
-		return compactionPlan{}, plan.sources.err
+		return compactionPlan{}, 0, plan.sources.err
+	}
+
+	return plan, totalUncompressedData, nil
+}
+
+func planConjoin(sources chunkSources, stats *Stats) (plan compactionPlan, err error) {
+	plan, totalUncompressedData, err := planConjoinSources(sources)
+
+	if err != nil {
+		return compactionPlan{}, err
 	}
 
 	lengthsPos := lengthsOffset(plan.chunkCount)
@@ -301,6 +315,149 @@ func planConjoin(sources chunkSources, stats *Stats) (plan compactionPlan, err e
 	return plan, nil
 }
 
+// prefixCursor walks the prefix tuples of one source of a conjoin, in prefix order.
+type prefixCursor struct {
+	index         tableIndex
+	pos           int
+	ordinalOffset uint32
+}
+
+func (pc *prefixCursor) prefix() uint64 {
+	return pc.index.prefixes[pc.pos]
+}
+
+func (pc *prefixCursor) ordinal() uint32 {
+	return pc.ordinalOffset + pc.index.ordinals[pc.pos]
+}
+
+type prefixCursorHeap []*prefixCursor
+
+func (h prefixCursorHeap) Len() int {
+	return len(h)
+}
+
+func (h prefixCursorHeap) Less(i, j int) bool {
+	if h[i].prefix() != h[j].prefix() {
+		return h[i].prefix() < h[j].prefix()
+	}
+
+	return h[i].ordinal() < h[j].ordinal()
+}
+
+func (h prefixCursorHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *prefixCursorHeap) Push(x interface{}) {
+	*h = append(*h, x.(*prefixCursor))
+}
+
+func (h *prefixCursorHeap) Pop() interface{} {
+	old := *h
+	pc := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return pc
+}
+
+// writeConjoinedTable writes the table conjoining the sources of |plan|, which was made by planConjoinSources, to |w|
+// and returns its name. Unlike planConjoin, it doesn't build the merged index in memory. The prefix tuples of the
+// sources' indexes, which are each already sorted, are merged as they are written, and everything is written through
+// a buffer of at most |maxMem| bytes.
+func writeConjoinedTable(ctx context.Context, w io.Writer, plan compactionPlan, totalUncompressedData, maxMem uint64) (name addr, err error) {
+	bw := bufio.NewWriterSize(w, int(maxMem))
+
+	for _, sws := range plan.sources.sws {
+		if err := ctx.Err(); err != nil {
+			return addr{}, err
+		}
+
+		r, err := sws.source.reader(ctx)
+
+		if err != nil {
+			return addr{}, err
+		}
+
+		n, err := io.CopyN(bw, r, int64(sws.dataLen))
+
+		if err != nil {
+			return addr{}, err
+		}
+
+		if uint64(n) != sws.dataLen {
+			return addr{}, errors.New("failed to copy all data")
+		}
+	}
+
+	indexes := make([]tableIndex, len(plan.sources.sws))
+	cursors := make(prefixCursorHeap, 0, len(plan.sources.sws))
+	var ordinalOffset uint32
+	for i, sws := range plan.sources.sws {
+		indexes[i], err = sws.source.index()
+
+		if err != nil {
+			return addr{}, err
+		}
+
+		if indexes[i].chunkCount > 0 {
+			cursors = append(cursors, &prefixCursor{index: indexes[i], ordinalOffset: ordinalOffset})
+		}
+
+		ordinalOffset += indexes[i].chunkCount
+	}
+
+	heap.Init(&cursors)
+
+	var tuple [prefixTupleSize]byte
+	for len(cursors) > 0 {
+		pc := cursors[0]
+		binary.BigEndian.PutUint64(tuple[:], pc.prefix())
+		binary.BigEndian.PutUint32(tuple[addrPrefixSize:], pc.ordinal())
+
+		if _, err := bw.Write(tuple[:]); err != nil {
+			return addr{}, err
+		}
+
+		pc.pos++
+		if pc.pos < len(pc.index.prefixes) {
+			heap.Fix(&cursors, 0)
+		} else {
+			heap.Pop(&cursors)
+		}
+	}
+
+	var length [lengthSize]byte
+	for _, index := range indexes {
+		for _, l := range index.lengths {
+			binary.BigEndian.PutUint32(length[:], l)
+
+			if _, err := bw.Write(length[:]); err != nil {
+				return addr{}, err
+			}
+		}
+	}
+
+	sha := sha512.New()
+	for _, index := range indexes {
+		if _, err := io.MultiWriter(bw, sha).Write(index.suffixes); err != nil {
+			return addr{}, err
+		}
+	}
+
+	footer := make([]byte, footerSize)
+	writeFooter(footer, plan.chunkCount, totalUncompressedData)
+
+	if _, err := bw.Write(footer); err != nil {
+		return addr{}, err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return addr{}, err
+	}
+
+	copy(name[:], sha.Sum(nil))
+	return name, nil
+}
+
 func nameFromSuffixes(suffixes []byte) (name addr) {
 	sha := sha512.New()
 	sha.Write(suffixes)
@@ -27,6 +27,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"math/bits"
 	"sort"
 	"sync"
 
@@ -57,7 +58,7 @@ func NewCompressedChunk(h hash.Hash, buff []byte) (CompressedChunk, error) {
 	compressedData := buff[:dataLen]
 
 	if chksum != crc(compressedData) {
-		return CompressedChunk{}, errors.New("checksum error")
+		return CompressedChunk{}, ErrChecksumMismatch
 	}
 
 	return CompressedChunk{H: h, FullCompressedChunk: buff, CompressedData: compressedData}, nil
@@ -74,6 +75,14 @@ func (cmp CompressedChunk) ToChunk() (chunks.Chunk, error) {
 	return chunks.NewChunkWithHash(cmp.H, data), nil
 }
 
+// Copy returns a CompressedChunk which holds its own copy of the compressed data, so it stays valid after the buffer
+// |cmp| was read into is reused.
+func (cmp CompressedChunk) Copy() CompressedChunk {
+	full := make([]byte, len(cmp.FullCompressedChunk))
+	copy(full, cmp.FullCompressedChunk)
+	return CompressedChunk{H: cmp.H, FullCompressedChunk: full, CompressedData: full[:len(cmp.CompressedData)]}
+}
+
 func ChunkToCompressedChunk(chunk chunks.Chunk) CompressedChunk {
 	compressed := snappy.Encode(nil, chunk.Data())
 	length := len(compressed)
@@ -99,7 +108,10 @@ func init() {
 }
 
 // ErrInvalidTableFile is an error returned when a table file is corrupt or invalid.
-var ErrInvalidTableFile = errors.New("invalid or corrupt table file")
+var ErrInvalidTableFile = newStoreError(ErrCorrupt, "invalid or corrupt table file")
+
+// ErrChecksumMismatch is an error returned when a chunk's data doesn't match its checksum.
+var ErrChecksumMismatch = newStoreError(ErrCorrupt, "checksum error")
 
 type tableIndex struct {
 	chunkCount            uint32
@@ -239,6 +251,119 @@ func (ti tableIndex) prefixIdx(prefix uint64) (idx uint32) {
 	return
 }
 
+// prefixIdxFrom is like prefixIdx, but only searches the positions from |start| on.
+func (ti tableIndex) prefixIdxFrom(start uint32, prefix uint64) (idx uint32) {
+	idx, j := start, ti.chunkCount
+	for idx < j {
+		h := idx + (j-idx)/2
+		if ti.prefixes[h] < prefix {
+			idx = h + 1
+		} else {
+			j = h
+		}
+	}
+
+	return
+}
+
+// iterateWithPrefix calls |cb| with every address in the index which begins with the bytes in |prefix|, in address
+// order. Only the index is consulted.
+func (ti tableIndex) iterateWithPrefix(prefix []byte, cb func(a addr) error) error {
+	if len(prefix) > addrSize {
+		return nil
+	}
+
+	var lo [addrPrefixSize]byte
+	copy(lo[:], prefix)
+	start := binary.BigEndian.Uint64(lo[:])
+
+	for idx := ti.prefixIdx(start); idx < ti.chunkCount; idx++ {
+		var a addr
+		binary.BigEndian.PutUint64(a[:], ti.prefixes[idx])
+
+		n := len(prefix)
+		if n > addrPrefixSize {
+			n = addrPrefixSize
+		}
+
+		if !bytes.Equal(a[:n], prefix[:n]) {
+			break
+		}
+
+		li := uint64(ti.prefixIdxToOrdinal(idx)) * addrSuffixSize
+		copy(a[addrPrefixSize:], ti.suffixes[li:li+addrSuffixSize])
+
+		if !bytes.HasPrefix(a[:], prefix) {
+			continue
+		}
+
+		if err := cb(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// iterateRange calls |cb| with every address in the index in the range [|start|, |end|), in prefix order. An empty
+// |end| is the end of the address space. Only the index is consulted.
+func (ti tableIndex) iterateRange(start, end addr, cb func(a addr) error) error {
+	for idx := ti.prefixIdx(start.Prefix()); idx < ti.chunkCount; idx++ {
+		if end != (addr{}) && ti.prefixes[idx] > end.Prefix() {
+			break
+		}
+
+		var a addr
+		binary.BigEndian.PutUint64(a[:], ti.prefixes[idx])
+		li := uint64(ti.prefixIdxToOrdinal(idx)) * addrSuffixSize
+		copy(a[addrPrefixSize:], ti.suffixes[li:li+addrSuffixSize])
+
+		if !addrInRange(a, start, end) {
+			continue
+		}
+
+		if err := cb(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seek returns the smallest address in the index which is >= |start|, or false if there is none. Only the index is
+// consulted.
+func (ti tableIndex) seek(start addr) (addr, bool) {
+	var min addr
+	found := false
+	for idx := ti.prefixIdx(start.Prefix()); idx < ti.chunkCount; idx++ {
+		// addresses are in prefix order, so the smallest has the first prefix of any address >= |start|
+		if found && ti.prefixes[idx] != min.Prefix() {
+			break
+		}
+
+		var a addr
+		binary.BigEndian.PutUint64(a[:], ti.prefixes[idx])
+		li := uint64(ti.prefixIdxToOrdinal(idx)) * addrSuffixSize
+		copy(a[addrPrefixSize:], ti.suffixes[li:li+addrSuffixSize])
+
+		if bytes.Compare(a[:], start[:]) < 0 {
+			continue
+		}
+
+		if !found || bytes.Compare(a[:], min[:]) < 0 {
+			min, found = a, true
+		}
+	}
+
+	return min, found
+}
+
+// addrInRange returns whether |a| is in the range [|start|, |end|), where an empty |end| is the end of the address
+// space.
+func addrInRange(a, start, end addr) bool {
+	return bytes.Compare(a[:], start[:]) >= 0 && (end == (addr{}) || bytes.Compare(a[:], end[:]) < 0)
+}
+
 // Return true IFF the suffix at insertion order |ordinal| matches the address |a|.
 func (ti tableIndex) ordinalSuffixMatches(ordinal uint32, h addr) bool {
 	li := uint64(ordinal) * addrSuffixSize
@@ -268,8 +393,18 @@ func newTableReader(index tableIndex, r tableReaderAt, blockSize uint64) tableRe
 
 // Scan across (logically) two ordered slices of address prefixes.
 func (tr tableReader) hasMany(addrs []hasRecord) (bool, error) {
-	// TODO: Use findInIndex if (tr.chunkCount - len(addrs)*Log2(tr.chunkCount)) > (tr.chunkCount - len(addrs))
+	return tr.scanHasMany(addrs, seekIndexForHas(len(addrs), tr.chunkCount))
+}
+
+// seekIndexForHas reports whether a hasMany of |n| addresses against a table of |chunkCount| chunks should binary
+// search the index for each address, rather than step through every prefix in the index.
+func seekIndexForHas(n int, chunkCount uint32) bool {
+	return uint64(n)*uint64(bits.Len32(chunkCount)) < uint64(chunkCount)
+}
 
+// scanHasMany marks each of the sorted |addrs| found in the table in a single pass over the index. When |seek| is
+// set, the pass binary searches ahead to each address's prefix.
+func (tr tableReader) scanHasMany(addrs []hasRecord, seek bool) (bool, error) {
 	filterIdx := uint32(0)
 	filterLen := uint32(len(tr.prefixes))
 
@@ -279,8 +414,12 @@ func (tr tableReader) hasMany(addrs []hasRecord) (bool, error) {
 			continue
 		}
 
-		for filterIdx < filterLen && addr.prefix > tr.prefixes[filterIdx] {
-			filterIdx++
+		if seek {
+			filterIdx = tr.prefixIdxFrom(filterIdx, addr.prefix)
+		} else {
+			for filterIdx < filterLen && addr.prefix > tr.prefixes[filterIdx] {
+				filterIdx++
+			}
 		}
 
 		if filterIdx >= filterLen {
@@ -400,8 +539,12 @@ func (tr tableReader) readCompressedAtOffsets(
 	stats *Stats,
 ) error {
 	return tr.readAtOffsetsWithCB(ctx, readStart, readEnd, reqs, offsets, stats, func(cmp CompressedChunk) error {
-		foundCmpChunks <- cmp
-		return nil
+		select {
+		case foundCmpChunks <- cmp:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	})
 }
 
@@ -420,8 +563,12 @@ func (tr tableReader) readAtOffsets(
 			return err
 		}
 
-		foundChunks <- &chk
-		return nil
+		select {
+		case foundChunks <- &chk:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	})
 }
 
@@ -530,6 +677,20 @@ func (tr tableReader) getManyAtOffsets(
 	})
 }
 
+const (
+	// ioParallelism is the number of goroutines per table which read batches of chunk records during a getMany.
+	ioParallelism = 4
+
+	// maxGetManyReadSize caps the size of a single physical read issued by a getMany. Adjacent chunk records are
+	// coalesced into one read until it would exceed this size.
+	maxGetManyReadSize = 1 << 24
+)
+
+// getManyAtOffsetsWithReadFunc reads the chunk records in |offsetRecords| using |ioParallelism| goroutines. Each
+// goroutine owns at most one read buffer of up to |maxGetManyReadSize| bytes, and decodes and sends one chunk at a
+// time, blocking until the consumer accepts it. A slow consumer therefore stalls the readers instead of causing
+// decoded chunks to accumulate in memory. If |ctx| is cancelled while a reader is blocked on a send, the read is
+// abandoned and ctx.Err() is recorded in |ae|.
 func (tr tableReader) getManyAtOffsetsWithReadFunc(
 	ctx context.Context,
 	reqs []getRecord,
@@ -575,7 +736,7 @@ func (tr tableReader) getManyAtOffsetsWithReadFunc(
 				continue
 			}
 
-			if newReadEnd, canRead := canReadAhead(rec, tr.lengths[rec.ordinal], readStart, readEnd, tr.blockSize); canRead {
+			if newReadEnd, canRead := canReadAhead(rec, tr.lengths[rec.ordinal], readStart, readEnd, tr.blockSize); canRead && newReadEnd-readStart <= maxGetManyReadSize {
 				batch = append(batch, rec)
 				readEnd = newReadEnd
 				i++
@@ -599,8 +760,6 @@ func (tr tableReader) getManyAtOffsetsWithReadFunc(
 		}
 	}
 
-	ioParallelism := 4
-
 	batchCh := make(chan readBatch, 128)
 	go func() {
 		defer close(batchCh)
@@ -754,8 +913,12 @@ func (tr tableReader) extract(ctx context.Context, chunks chan<- extractRecord)
 			return err
 		}
 
-		chunks <- extractRecord{a: hashes[i], data: chnk.Data()}
-		return nil
+		select {
+		case chunks <- extractRecord{a: hashes[i], data: chnk.Data()}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	for i := uint32(0); i < tr.chunkCount; i++ {
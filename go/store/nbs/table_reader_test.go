@@ -15,7 +15,16 @@
 package nbs
 
 import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
 )
 
 func TestCompressedChunkIsEmpty(t *testing.T) {
@@ -26,3 +35,65 @@ func TestCompressedChunkIsEmpty(t *testing.T) {
 		t.Fatal("CompressedChunk{}.IsEmpty() should equal true.")
 	}
 }
+
+// recordAtReaderAt serves |record| at |off| and zeros everywhere else.
+type recordAtReaderAt struct {
+	record []byte
+	off    int64
+}
+
+func (r recordAtReaderAt) ReadAtWithStats(ctx context.Context, p []byte, off int64, stats *Stats) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	if off <= r.off && r.off < off+int64(len(p)) {
+		copy(p[r.off-off:], r.record)
+	}
+
+	return len(p), nil
+}
+
+func TestTableReaderOffsetsBeyond32Bits(t *testing.T) {
+	// two records of nearly 4GB each precede a small one, putting it past the 32-bit boundary
+	c := chunks.NewChunk([]byte("far away"))
+	record := ChunkToCompressedChunk(c).FullCompressedChunk
+	lengths := []uint32{math.MaxUint32 - 15, math.MaxUint32 - 15, uint32(len(record))}
+	addrs := []addr{computeAddr([]byte("first")), computeAddr([]byte("second")), addr(c.Hash())}
+
+	// build the index as a table writer would, with the prefix tuples sorted
+	ordinals := []int{0, 1, 2}
+	sort.Slice(ordinals, func(i, j int) bool {
+		return addrs[ordinals[i]].Prefix() < addrs[ordinals[j]].Prefix()
+	})
+
+	var buff []byte
+	for _, o := range ordinals {
+		tuple := make([]byte, prefixTupleSize)
+		binary.BigEndian.PutUint64(tuple, addrs[o].Prefix())
+		binary.BigEndian.PutUint32(tuple[addrPrefixSize:], uint32(o))
+		buff = append(buff, tuple...)
+	}
+	for _, l := range lengths {
+		buff = append(buff, make([]byte, lengthSize)...)
+		binary.BigEndian.PutUint32(buff[len(buff)-lengthSize:], l)
+	}
+	for _, a := range addrs {
+		buff = append(buff, a[addrPrefixSize:]...)
+	}
+	footer := make([]byte, footerSize)
+	writeFooter(footer, uint32(len(lengths)), 0)
+	buff = append(buff, footer...)
+
+	index, err := parseTableIndex(buff)
+	require.NoError(t, err)
+
+	farOffset := uint64(lengths[0]) + uint64(lengths[1])
+	require.True(t, farOffset > math.MaxUint32)
+	assert.Equal(t, []uint64{0, uint64(lengths[0]), farOffset}, index.offsets)
+
+	tr := newTableReader(index, recordAtReaderAt{record, int64(farOffset)}, fileBlockSize)
+	data, err := tr.get(context.Background(), addr(c.Hash()), &Stats{})
+	require.NoError(t, err)
+	assert.Equal(t, c.Data(), data)
+}
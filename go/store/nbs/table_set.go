@@ -22,8 +22,9 @@
 package nbs
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -43,6 +44,7 @@ type tableSet struct {
 	novel, upstream chunkSources
 	p               tablePersister
 	rl              chan struct{}
+	access          *tableAccessTracker
 }
 
 func (ts tableSet) has(h addr) (bool, error) {
@@ -103,7 +105,7 @@ func (ts tableSet) hasMany(addrs []hasRecord) (bool, error) {
 }
 
 func (ts tableSet) get(ctx context.Context, h addr, stats *Stats) ([]byte, error) {
-	f := func(css chunkSources) ([]byte, error) {
+	f := func(css chunkSources, access *tableAccessTracker) ([]byte, error) {
 		for _, haver := range css {
 			data, err := haver.get(ctx, h, stats)
 
@@ -112,6 +114,7 @@ func (ts tableSet) get(ctx context.Context, h addr, stats *Stats) ([]byte, error
 			}
 
 			if data != nil {
+				access.record(haver)
 				return data, nil
 			}
 		}
@@ -119,7 +122,7 @@ func (ts tableSet) get(ctx context.Context, h addr, stats *Stats) ([]byte, error
 		return nil, nil
 	}
 
-	data, err := f(ts.novel)
+	data, err := f(ts.novel, nil)
 
 	if err != nil {
 		return nil, err
@@ -129,11 +132,11 @@ func (ts tableSet) get(ctx context.Context, h addr, stats *Stats) ([]byte, error
 		return data, nil
 	}
 
-	return f(ts.upstream)
+	return f(ts.upstream, ts.access)
 }
 
 func (ts tableSet) getMany(ctx context.Context, reqs []getRecord, foundChunks chan<- *chunks.Chunk, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
-	f := func(css chunkSources) bool {
+	f := func(css chunkSources, access *tableAccessTracker) bool {
 		for _, haver := range css {
 			if ae.IsSet() {
 				return false
@@ -142,6 +145,10 @@ func (ts tableSet) getMany(ctx context.Context, reqs []getRecord, foundChunks ch
 			if rp, ok := haver.(chunkReadPlanner); ok {
 				offsets, remaining := rp.findOffsets(reqs)
 
+				if len(offsets) > 0 {
+					access.record(haver)
+				}
+
 				rp.getManyAtOffsets(ctx, reqs, offsets, foundChunks, wg, ae, stats)
 
 				if !remaining {
@@ -161,11 +168,11 @@ func (ts tableSet) getMany(ctx context.Context, reqs []getRecord, foundChunks ch
 		return true
 	}
 
-	return f(ts.novel) && f(ts.upstream)
+	return f(ts.novel, nil) && f(ts.upstream, ts.access)
 }
 
 func (ts tableSet) getManyCompressed(ctx context.Context, reqs []getRecord, foundCmpChunks chan<- CompressedChunk, wg *sync.WaitGroup, ae *atomicerr.AtomicError, stats *Stats) bool {
-	f := func(css chunkSources) bool {
+	f := func(css chunkSources, access *tableAccessTracker) bool {
 		for _, haver := range css {
 			if ae.IsSet() {
 				return false
@@ -175,6 +182,7 @@ func (ts tableSet) getManyCompressed(ctx context.Context, reqs []getRecord, foun
 				offsets, remaining := rp.findOffsets(reqs)
 
 				if len(offsets) > 0 {
+					access.record(haver)
 					rp.getManyCompressedAtOffsets(ctx, reqs, offsets, foundCmpChunks, wg, ae, stats)
 				}
 
@@ -195,7 +203,7 @@ func (ts tableSet) getManyCompressed(ctx context.Context, reqs []getRecord, foun
 		return true
 	}
 
-	return f(ts.novel) && f(ts.upstream)
+	return f(ts.novel, nil) && f(ts.upstream, ts.access)
 }
 
 func (ts tableSet) calcReads(reqs []getRecord, blockSize uint64) (reads int, split, remaining bool, err error) {
@@ -348,6 +356,7 @@ func (ts tableSet) Prepend(ctx context.Context, mt *memTable, stats *Stats) tabl
 		upstream: make(chunkSources, len(ts.upstream)),
 		p:        ts.p,
 		rl:       ts.rl,
+		access:   ts.access,
 	}
 	newTs.novel[0] = newPersistingChunkSource(ctx, mt, ts, ts.p, ts.rl, stats)
 	copy(newTs.novel[1:], ts.novel)
@@ -382,6 +391,7 @@ func (ts tableSet) Flatten() (tableSet, error) {
 		upstream: make(chunkSources, 0, ts.Size()),
 		p:        ts.p,
 		rl:       ts.rl,
+		access:   ts.access,
 	}
 
 	for _, src := range ts.novel {
@@ -408,6 +418,7 @@ func (ts tableSet) Rebase(ctx context.Context, specs []tableSpec, stats *Stats)
 		upstream: make(chunkSources, 0, len(specs)),
 		p:        ts.p,
 		rl:       ts.rl,
+		access:   ts.access,
 	}
 
 	// Rebase the novel tables, skipping those that are actually empty (usually due to de-duping during table compaction)
@@ -478,6 +489,8 @@ func (ts tableSet) Rebase(ctx context.Context, specs []tableSpec, stats *Stats)
 	return merged, nil
 }
 
+// ToSpecs returns the specs of the non-empty tables in |ts|, ordered by name so that manifests written from the same
+// tables are identical.
 func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 	tableSpecs := make([]tableSpec, 0, ts.Size())
 	for _, src := range ts.novel {
@@ -505,7 +518,7 @@ func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 		}
 
 		if cnt <= 0 {
-			return nil, errors.New("no upstream chunks")
+			return nil, newStoreError(ErrCorrupt, "no upstream chunks")
 		}
 
 		h, err := src.hash()
@@ -516,5 +529,9 @@ func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 
 		tableSpecs = append(tableSpecs, tableSpec{h, cnt})
 	}
+
+	sort.Slice(tableSpecs, func(i, j int) bool {
+		return bytes.Compare(tableSpecs[i].name[:], tableSpecs[j].name[:]) < 0
+	})
 	return tableSpecs, nil
 }
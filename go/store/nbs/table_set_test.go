@@ -22,10 +22,15 @@
 package nbs
 
 import (
+	"bytes"
 	"context"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var testChunks = [][]byte{[]byte("hello2"), []byte("goodbye2"), []byte("badbye2")}
@@ -59,7 +64,104 @@ func TestTableSetPrepend(t *testing.T) {
 	secondSpecs, err := ts.ToSpecs()
 	assert.NoError(err)
 	assert.Len(secondSpecs, 2)
-	assert.Equal(firstSpecs, secondSpecs[1:])
+	assert.Subset(secondSpecs, firstSpecs)
+}
+
+// blockingTablePersister records the memtables it persists, and holds up persisting those in |blocked| until their
+// channels are closed.
+type blockingTablePersister struct {
+	tablePersister
+	blocked map[*memTable]chan struct{}
+
+	mu        sync.Mutex
+	persisted []*memTable
+}
+
+func (btp *blockingTablePersister) Persist(ctx context.Context, mt *memTable, haver chunkReader, stats *Stats) (chunkSource, error) {
+	btp.mu.Lock()
+	btp.persisted = append(btp.persisted, mt)
+	btp.mu.Unlock()
+
+	if release, ok := btp.blocked[mt]; ok {
+		<-release
+	}
+
+	return btp.tablePersister.Persist(ctx, mt, haver, stats)
+}
+
+func (btp *blockingTablePersister) persistedMemTables() []*memTable {
+	btp.mu.Lock()
+	defer btp.mu.Unlock()
+	return append([]*memTable(nil), btp.persisted...)
+}
+
+func TestTableSetPrependAppendingInOrder(t *testing.T) {
+	ctx := context.Background()
+
+	mt1 := newMemTable(testMemTableSize)
+	mt1.addChunk(computeAddr(testChunks[0]), testChunks[0])
+	mt2 := newMemTable(testMemTableSize)
+	mt2.addChunk(computeAddr(testChunks[1]), testChunks[1])
+	mt2.addChunk(computeAddr(testChunks[2]), testChunks[2])
+
+	release := make(chan struct{})
+	p := &blockingTablePersister{tablePersister: newFakeTablePersister(), blocked: map[*memTable]chan struct{}{mt1: release}}
+	ts := newTableSet(p)
+	ts = ts.PrependAppending(ctx, mt1, 1<<20, &Stats{})
+	ts = ts.PrependAppending(ctx, mt2, 1<<20, &Stats{})
+	require.Len(t, ts.novel, 1)
+
+	// while the first append is held up, the second waits for it, and the chunks of both are read from memory
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []*memTable{mt1}, p.persistedMemTables())
+	for _, c := range testChunks {
+		has, err := ts.has(computeAddr(c))
+		require.NoError(t, err)
+		assert.True(t, has)
+	}
+
+	close(release)
+	specs, err := ts.ToSpecs()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.Equal(t, uint32(len(testChunks)), specs[0].chunkCount)
+	assert.Equal(t, []*memTable{mt1, mt2}, p.persistedMemTables())
+
+	for _, c := range testChunks {
+		data, err := ts.get(ctx, computeAddr(c), &Stats{})
+		require.NoError(t, err)
+		assert.Equal(t, c, data)
+	}
+}
+
+func TestTableSetToSpecsOrder(t *testing.T) {
+	assert := assert.New(t)
+	ts := newFakeTableSet()
+	for _, data := range testChunks {
+		mt := newMemTable(testMemTableSize)
+		mt.addChunk(computeAddr(data), data)
+		ts = ts.Prepend(context.Background(), mt, &Stats{})
+	}
+
+	specs, err := ts.ToSpecs()
+	assert.NoError(err)
+	assert.Len(specs, len(testChunks))
+	assert.True(sort.SliceIsSorted(specs, func(i, j int) bool {
+		return bytes.Compare(specs[i].name[:], specs[j].name[:]) < 0
+	}))
+
+	again, err := ts.ToSpecs()
+	assert.NoError(err)
+	assert.Equal(specs, again)
+
+	// tables rebuilt from the specs are opened in no particular order
+	for i := 0; i < 10; i++ {
+		rebuilt, err := tableSet{p: ts.p, rl: ts.rl}.Rebase(context.Background(), specs, &Stats{})
+		assert.NoError(err)
+		rebuiltSpecs, err := rebuilt.ToSpecs()
+		assert.NoError(err)
+		assert.Equal(specs, rebuiltSpecs)
+	}
 }
 
 func TestTableSetToSpecsExcludesEmptyTable(t *testing.T) {
@@ -190,6 +190,89 @@ func TestHasManySequentialPrefix(t *testing.T) {
 	}
 }
 
+func TestHasManySeek(t *testing.T) {
+	assert := assert.New(t)
+
+	count := 1 << 12
+	chunks := make([][]byte, count)
+	for i := range chunks {
+		chunks[i] = []byte(fmt.Sprintf("data%d", i))
+	}
+
+	tableData, _, err := buildTable(chunks)
+	assert.NoError(err)
+	ti, err := parseTableIndex(tableData)
+	assert.NoError(err)
+	tr := newTableReader(ti, tableReaderAtFromBytes(tableData), fileBlockSize)
+
+	// every present address is followed by an absent one
+	hashes := hash.HashSet{}
+	for i := 0; i < count; i += 97 {
+		hashes.Insert(hash.Hash(computeAddr(chunks[i])))
+		hashes.Insert(hash.Of([]byte(fmt.Sprintf("absent%d", i))))
+	}
+
+	scanned, sought := toHasRecords(hashes), toHasRecords(hashes)
+	scanRemaining, err := tr.scanHasMany(scanned, false)
+	assert.NoError(err)
+	seekRemaining, err := tr.scanHasMany(sought, true)
+	assert.NoError(err)
+	assert.True(seekIndexForHas(len(sought), uint32(count)))
+
+	assert.Equal(scanRemaining, seekRemaining)
+	for i, r := range sought {
+		assert.Equal(*scanned[i].a, *r.a)
+		assert.Equal(scanned[i].has, r.has)
+
+		has, err := tr.has(*r.a)
+		assert.NoError(err)
+		assert.Equal(has, r.has)
+	}
+}
+
+func BenchmarkHasMany(b *testing.B) {
+	const numTables, chunksPerTable = 64, 1 << 12
+
+	var trs []tableReader
+	var present []addr
+	for i := 0; i < numTables; i++ {
+		chunks := make([][]byte, chunksPerTable)
+		for j := range chunks {
+			chunks[j] = []byte(fmt.Sprintf("table%d-chunk%d", i, j))
+		}
+
+		tableData, _, err := buildTable(chunks)
+		assert.NoError(b, err)
+		ti, err := parseTableIndex(tableData)
+		assert.NoError(b, err)
+		trs = append(trs, newTableReader(ti, tableReaderAtFromBytes(tableData), fileBlockSize))
+		present = append(present, computeAddr(chunks[0]))
+	}
+
+	for _, size := range []int{64, 1 << 16} {
+		hashes := hash.HashSet{}
+		for i := 0; len(hashes) < size; i++ {
+			if i < len(present) && i%2 == 0 {
+				hashes.Insert(hash.Hash(present[i]))
+			} else {
+				hashes.Insert(hash.Of([]byte(fmt.Sprintf("absent%d", i))))
+			}
+		}
+
+		for _, seek := range []bool{false, true} {
+			b.Run(fmt.Sprintf("hashes=%d/seek=%t", size, seek), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					reqs := toHasRecords(hashes)
+					for _, tr := range trs {
+						_, err := tr.scanHasMany(reqs, seek)
+						assert.NoError(b, err)
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestGetMany(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,195 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// PartialCommitError is returned by TeeChunkStore.Commit when the commit succeeded on the primary store but failed on
+// the secondary, and the primary could not be rolled back to its previous root.
+type PartialCommitError struct {
+	// SecondaryErr is the reason the secondary commit failed. It is nil if the secondary store rejected the commit
+	// because its root did not match.
+	SecondaryErr error
+
+	// RollbackErr is the reason the primary could not be rolled back. It is nil if the rollback was rejected because
+	// the primary's root had already moved on.
+	RollbackErr error
+}
+
+func (e *PartialCommitError) Error() string {
+	return fmt.Sprintf("commit succeeded on primary but not on secondary (%v), and primary rollback failed (%v)", e.SecondaryErr, e.RollbackErr)
+}
+
+// TeeChunkStore is a chunks.ChunkStore which writes to two stores, as when migrating from one store to another.
+// Reads are served by the primary store and fall back to the secondary when a chunk is missing from the primary.
+type TeeChunkStore struct {
+	primary   chunks.ChunkStore
+	secondary chunks.ChunkStore
+}
+
+// NewTeeChunkStore returns a TeeChunkStore which writes to both |primary| and |secondary|.
+func NewTeeChunkStore(primary, secondary chunks.ChunkStore) *TeeChunkStore {
+	return &TeeChunkStore{primary, secondary}
+}
+
+func (tcs *TeeChunkStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk, error) {
+	c, err := tcs.primary.Get(ctx, h)
+
+	if err != nil {
+		return chunks.EmptyChunk, err
+	}
+
+	if !c.IsEmpty() {
+		return c, nil
+	}
+
+	return tcs.secondary.Get(ctx, h)
+}
+
+func (tcs *TeeChunkStore) GetMany(ctx context.Context, hashes hash.HashSet, foundChunks chan<- *chunks.Chunk) error {
+	remaining := make(hash.HashSet, len(hashes))
+	for h := range hashes {
+		remaining.Insert(h)
+	}
+
+	primaryChunks := make(chan *chunks.Chunk)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for c := range primaryChunks {
+			remaining.Remove(c.Hash())
+			foundChunks <- c
+		}
+	}()
+
+	err := tcs.primary.GetMany(ctx, hashes, primaryChunks)
+	close(primaryChunks)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	return tcs.secondary.GetMany(ctx, remaining, foundChunks)
+}
+
+func (tcs *TeeChunkStore) Has(ctx context.Context, h hash.Hash) (bool, error) {
+	has, err := tcs.primary.Has(ctx, h)
+
+	if err != nil || has {
+		return has, err
+	}
+
+	return tcs.secondary.Has(ctx, h)
+}
+
+func (tcs *TeeChunkStore) HasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
+	absent, err := tcs.primary.HasMany(ctx, hashes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(absent) == 0 {
+		return absent, nil
+	}
+
+	return tcs.secondary.HasMany(ctx, absent)
+}
+
+func (tcs *TeeChunkStore) Put(ctx context.Context, c chunks.Chunk) error {
+	err := tcs.primary.Put(ctx, c)
+
+	if err != nil {
+		return err
+	}
+
+	return tcs.secondary.Put(ctx, c)
+}
+
+func (tcs *TeeChunkStore) Version() string {
+	return tcs.primary.Version()
+}
+
+func (tcs *TeeChunkStore) Rebase(ctx context.Context) error {
+	err := tcs.primary.Rebase(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return tcs.secondary.Rebase(ctx)
+}
+
+func (tcs *TeeChunkStore) Root(ctx context.Context) (hash.Hash, error) {
+	return tcs.primary.Root(ctx)
+}
+
+// Commit commits to the primary store and then to the secondary. If the secondary commit fails, the primary is
+// rolled back to |last| and the secondary's result is returned. If the rollback fails as well, a *PartialCommitError
+// is returned and the stores have diverged.
+func (tcs *TeeChunkStore) Commit(ctx context.Context, current, last hash.Hash) (bool, error) {
+	success, err := tcs.primary.Commit(ctx, current, last)
+
+	if err != nil || !success {
+		return success, err
+	}
+
+	success, secondaryErr := tcs.secondary.Commit(ctx, current, last)
+
+	if secondaryErr == nil && success {
+		return true, nil
+	}
+
+	rolledBack, rollbackErr := tcs.primary.Commit(ctx, last, current)
+
+	if rollbackErr != nil || !rolledBack {
+		return false, &PartialCommitError{SecondaryErr: secondaryErr, RollbackErr: rollbackErr}
+	}
+
+	return false, secondaryErr
+}
+
+func (tcs *TeeChunkStore) Stats() interface{} {
+	return tcs.primary.Stats()
+}
+
+func (tcs *TeeChunkStore) StatsSummary() string {
+	return tcs.primary.StatsSummary()
+}
+
+func (tcs *TeeChunkStore) Close() error {
+	err := tcs.primary.Close()
+	secondaryErr := tcs.secondary.Close()
+
+	if err == nil {
+		err = secondaryErr
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/constants"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+func makeTeeTestStores(t *testing.T) (primary, secondary *NomsBlockStore, cleanup func()) {
+	ctx := context.Background()
+	primaryDir, err := ioutil.TempDir("", "tee_primary")
+	require.NoError(t, err)
+	secondaryDir, err := ioutil.TempDir("", "tee_secondary")
+	require.NoError(t, err)
+
+	primary, err = NewLocalStore(ctx, constants.FormatDefaultString, primaryDir, testMemTableSize)
+	require.NoError(t, err)
+	secondary, err = NewLocalStore(ctx, constants.FormatDefaultString, secondaryDir, testMemTableSize)
+	require.NoError(t, err)
+
+	return primary, secondary, func() {
+		os.RemoveAll(primaryDir)
+		os.RemoveAll(secondaryDir)
+	}
+}
+
+func TestTeeChunkStoreWritesToBoth(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary, cleanup := makeTeeTestStores(t)
+	defer cleanup()
+	tee := NewTeeChunkStore(primary, secondary)
+
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, tee.Put(ctx, c))
+	success, err := tee.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, success)
+
+	for _, cs := range []chunks.ChunkStore{primary, secondary} {
+		root, err := cs.Root(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, c.Hash(), root)
+
+		found, err := cs.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), found.Data())
+	}
+
+	assert.NoError(t, tee.Close())
+}
+
+func TestTeeChunkStoreReadsFallBackToSecondary(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary, cleanup := makeTeeTestStores(t)
+	defer cleanup()
+	tee := NewTeeChunkStore(primary, secondary)
+
+	inBoth := chunks.NewChunk([]byte("both"))
+	require.NoError(t, tee.Put(ctx, inBoth))
+	onlySecondary := chunks.NewChunk([]byte("secondary"))
+	require.NoError(t, secondary.Put(ctx, onlySecondary))
+
+	found, err := tee.Get(ctx, onlySecondary.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, onlySecondary.Data(), found.Data())
+
+	has, err := tee.Has(ctx, onlySecondary.Hash())
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	missing := chunks.NewChunk([]byte("missing")).Hash()
+	hashes := hash.NewHashSet(inBoth.Hash(), onlySecondary.Hash(), missing)
+	absent, err := tee.HasMany(ctx, hashes)
+	require.NoError(t, err)
+	assert.Equal(t, hash.NewHashSet(missing), absent)
+
+	foundChunks := make(chan *chunks.Chunk, len(hashes))
+	require.NoError(t, tee.GetMany(ctx, hashes, foundChunks))
+	close(foundChunks)
+	foundHashes := hash.HashSet{}
+	for c := range foundChunks {
+		foundHashes.Insert(c.Hash())
+	}
+	assert.Equal(t, hash.NewHashSet(inBoth.Hash(), onlySecondary.Hash()), foundHashes)
+
+	success, err := tee.Commit(ctx, inBoth.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, success)
+	assert.NoError(t, tee.Close())
+}
+
+func TestTeeChunkStoreCommitRollsBackPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary, cleanup := makeTeeTestStores(t)
+	defer cleanup()
+	tee := NewTeeChunkStore(primary, secondary)
+
+	// Move the secondary's root so it rejects the tee's commit.
+	diverged := chunks.NewChunk([]byte("diverged"))
+	require.NoError(t, secondary.Put(ctx, diverged))
+	success, err := secondary.Commit(ctx, diverged.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, success)
+
+	c := chunks.NewChunk([]byte("abc"))
+	require.NoError(t, tee.Put(ctx, c))
+	success, err = tee.Commit(ctx, c.Hash(), hash.Hash{})
+	require.NoError(t, err)
+	assert.False(t, success)
+
+	root, err := primary.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, hash.Hash{}, root)
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/hash"
+)
+
+// ErrTransactionDone is returned when using a Transaction which has been committed or rolled back.
+var ErrTransactionDone = errors.New("transaction already committed or rolled back")
+
+// Transaction buffers chunks for a NomsBlockStore until they're committed along with a new root, or rolled back. The
+// chunks are held apart from the store's own pending chunks, so they are invisible to readers of the store, and are
+// not persisted by its Commit or Flush, until the Transaction commits. A Transaction is not safe for concurrent use.
+type Transaction struct {
+	nbs  *NomsBlockStore
+	last hash.Hash
+	mts  []*memTable
+	done bool
+}
+
+// Begin starts a Transaction against the store's current root.
+func (nbs *NomsBlockStore) Begin() *Transaction {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return &Transaction{nbs: nbs, last: nbs.upstream.root}
+}
+
+// Put adds |c| to the Transaction.
+func (tx *Transaction) Put(ctx context.Context, c chunks.Chunk) error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+
+	if len(c.Data()) == 0 {
+		return ErrEmptyChunk
+	}
+
+	a := addr(c.Hash())
+	if len(tx.mts) > 0 && tx.mts[len(tx.mts)-1].addChunk(a, c.Data()) {
+		return nil
+	}
+
+	mt := newMemTable(tx.nbs.mtSize)
+	if !mt.addChunk(a, c.Data()) {
+		return errors.New("failed to add chunk")
+	}
+
+	tx.mts = append(tx.mts, mt)
+	return nil
+}
+
+// Get returns the chunk with hash |h| from the Transaction, or from the store if it wasn't Put in the Transaction.
+func (tx *Transaction) Get(ctx context.Context, h hash.Hash) (chunks.Chunk, error) {
+	if tx.done {
+		return chunks.EmptyChunk, ErrTransactionDone
+	}
+
+	for _, mt := range tx.mts {
+		data, err := mt.get(ctx, addr(h), tx.nbs.stats)
+
+		if err != nil {
+			return chunks.EmptyChunk, err
+		}
+
+		if data != nil {
+			return chunks.NewChunkWithHash(h, data), nil
+		}
+	}
+
+	return tx.nbs.Get(ctx, h)
+}
+
+// Has returns whether the chunk with hash |h| was Put in the Transaction or is in the store.
+func (tx *Transaction) Has(ctx context.Context, h hash.Hash) (bool, error) {
+	if tx.done {
+		return false, ErrTransactionDone
+	}
+
+	for _, mt := range tx.mts {
+		if has, err := mt.has(addr(h)); err != nil || has {
+			return has, err
+		}
+	}
+
+	return tx.nbs.Has(ctx, h)
+}
+
+// Commit persists the Transaction's chunks and moves the store's root to |root| in a single manifest update. It fails
+// without error, as Commit does, if the store's root has moved since the Transaction began. A failed commit leaves the
+// manifest untouched and the Transaction open, so that it can still be rolled back. From the moment it starts to
+// commit, the Transaction's chunks are visible to the store, and so may also be committed by a concurrent Commit of
+// the store.
+func (tx *Transaction) Commit(ctx context.Context, root hash.Hash) (bool, error) {
+	if tx.done {
+		return false, ErrTransactionDone
+	}
+
+	if err := tx.nbs.checkWritable(); err != nil {
+		return false, err
+	}
+
+	srcs := tx.addToStore(ctx)
+	success, err := tx.nbs.Commit(ctx, root, tx.last)
+
+	if err != nil || !success {
+		tx.nbs.removeNovelSources(srcs)
+		return success, err
+	}
+
+	tx.mts = nil
+	tx.done = true
+	return true, nil
+}
+
+// Rollback discards the Transaction's chunks. The store and its manifest are left as they were. Rolling back a
+// Transaction which is already done is a no-op, so a deferred Rollback can follow a successful Commit.
+func (tx *Transaction) Rollback() {
+	tx.mts = nil
+	tx.done = true
+}
+
+// addToStore adds the Transaction's chunks to the tables of its store, and returns the sources which hold them.
+func (tx *Transaction) addToStore(ctx context.Context) []*persistingChunkSource {
+	tx.nbs.mu.Lock()
+	defer tx.nbs.mu.Unlock()
+
+	var srcs []*persistingChunkSource
+	for _, mt := range tx.mts {
+		tx.nbs.tables = tx.nbs.tables.Prepend(ctx, mt, tx.nbs.stats)
+		srcs = append(srcs, tx.nbs.tables.novel[0].(*persistingChunkSource))
+	}
+
+	return srcs
+}
+
+// removeNovelSources removes |srcs| from the store's tables, if they're still novel.
+func (nbs *NomsBlockStore) removeNovelSources(srcs []*persistingChunkSource) {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+
+	remove := make(map[*persistingChunkSource]bool, len(srcs))
+	for _, src := range srcs {
+		remove[src] = true
+	}
+
+	novel := make(chunkSources, 0, len(nbs.tables.novel))
+	for _, src := range nbs.tables.novel {
+		if pcs, ok := src.(*persistingChunkSource); !ok || !remove[pcs] {
+			novel = append(novel, src)
+		}
+	}
+
+	nbs.tables.novel = novel
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liquidata-inc/dolt/go/store/chunks"
+	"github.com/liquidata-inc/dolt/go/store/types"
+)
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	testDir := makeBatchCommitTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	st, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+	require.NoError(t, err)
+
+	assertInStore := func(st *NomsBlockStore, c chunks.Chunk, expected bool) {
+		has, err := st.Has(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, expected, has)
+	}
+
+	reopen := func() {
+		require.NoError(t, st.Close())
+		st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+		require.NoError(t, err)
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		c1, c2 := chunks.NewChunk([]byte("abc")), chunks.NewChunk([]byte("def"))
+		tx := st.Begin()
+		defer tx.Rollback()
+		require.NoError(t, tx.Put(ctx, c1))
+		require.NoError(t, tx.Put(ctx, c2))
+
+		// the chunks are only visible through the transaction until it commits
+		has, err := tx.Has(ctx, c1.Hash())
+		require.NoError(t, err)
+		assert.True(t, has)
+		got, err := tx.Get(ctx, c2.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c2.Data(), got.Data())
+		assertInStore(st, c1, false)
+
+		success, err := tx.Commit(ctx, c2.Hash())
+		require.NoError(t, err)
+		require.True(t, success)
+		assert.Equal(t, c2.Hash(), manifestRoot(t, testDir))
+		assert.Equal(t, ErrTransactionDone, tx.Put(ctx, c1))
+
+		reopen()
+		assertInStore(st, c1, true)
+		assertInStore(st, c2, true)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+
+		c := chunks.NewChunk([]byte("ghi"))
+		tx := st.Begin()
+		require.NoError(t, tx.Put(ctx, c))
+		tx.Rollback()
+		_, err = tx.Commit(ctx, c.Hash())
+		assert.Equal(t, ErrTransactionDone, err)
+
+		// a commit of the store itself doesn't pick up the rolled back chunk
+		other := chunks.NewChunk([]byte("jkl"))
+		require.NoError(t, st.Put(ctx, other))
+		success, err := st.Commit(ctx, other.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, success)
+
+		reopen()
+		assertInStore(st, other, true)
+		assertInStore(st, c, false)
+	})
+
+	t.Run("failed commit", func(t *testing.T) {
+		root, err := st.Root(ctx)
+		require.NoError(t, err)
+
+		c := chunks.NewChunk([]byte("mno"))
+		tx := st.Begin()
+		require.NoError(t, tx.Put(ctx, c))
+
+		interloper, err := NewLocalStore(ctx, types.Format_Default.VersionString(), testDir, defaultMemTableSize)
+		require.NoError(t, err)
+		moved := chunks.NewChunk([]byte("pqr"))
+		require.NoError(t, interloper.Put(ctx, moved))
+		success, err := interloper.Commit(ctx, moved.Hash(), root)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.NoError(t, interloper.Close())
+
+		success, err = tx.Commit(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.False(t, success)
+		assert.Equal(t, moved.Hash(), manifestRoot(t, testDir))
+		assertInStore(st, c, false)
+		tx.Rollback()
+
+		other := chunks.NewChunk([]byte("stu"))
+		require.NoError(t, st.Put(ctx, other))
+		success, err = st.Commit(ctx, other.Hash(), moved.Hash())
+		require.NoError(t, err)
+		require.True(t, success)
+
+		reopen()
+		assertInStore(st, c, false)
+		assertInStore(st, other, true)
+	})
+
+	require.NoError(t, st.Close())
+}